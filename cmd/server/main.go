@@ -6,23 +6,40 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
 	"github.com/ZSC714725/transcodemanager/internal/api"
+	"github.com/ZSC714725/transcodemanager/internal/auth"
 	"github.com/ZSC714725/transcodemanager/internal/config"
 	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+	"github.com/ZSC714725/transcodemanager/internal/hook"
 	"github.com/ZSC714725/transcodemanager/internal/logger"
+	"github.com/ZSC714725/transcodemanager/internal/mqtt"
+	"github.com/ZSC714725/transcodemanager/internal/redact"
 	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/ZSC714725/transcodemanager/internal/watchdog"
+	"github.com/ZSC714725/transcodemanager/internal/watcher"
+	"github.com/ZSC714725/transcodemanager/internal/webhook"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
 )
 
 func main() {
 	configPath := flag.String("config", "", "Path to YAML config file")
 	bind := flag.String("bind", "", "Bind address (overrides config)")
 	ffmpegBin := flag.String("ffmpeg", "", "FFmpeg binary path (overrides config)")
+	dryRun := flag.Bool("dry-run", false, "Simulate all tasks without executing FFmpeg")
 	flag.Parse()
 
 	cfg := config.Default()
@@ -42,46 +59,355 @@ func main() {
 	if *ffmpegBin != "" {
 		ffmpegPath = *ffmpegBin
 	}
+	if *dryRun {
+		cfg.DryRun.Enable = true
+	}
 
-	logger := logger.New("transcodemanager")
+	redactor, err := redact.New(cfg.Redact.Patterns)
+	if err != nil {
+		log.Fatalf("Redact patterns: %v", err)
+	}
+
+	level, err := logger.ParseLevel(cfg.Logging.Level)
+	if err != nil {
+		log.Fatalf("logging.level: %v", err)
+	}
+	var base logger.Logger
+	switch cfg.Logging.Format {
+	case "text":
+		base = logger.New("transcodemanager", level)
+	case "json":
+		base = logger.NewJSON("transcodemanager", level)
+	default:
+		log.Fatalf("Unknown logging.format %q, must be text or json", cfg.Logging.Format)
+	}
+	logger := logger.WithRedactor(base, redactor)
+
+	validatorIn, validatorOut, err := newValidators(cfg.FFmpeg.Validator)
+	if err != nil {
+		log.Fatalf("Validators: %v", err)
+	}
 
 	ff, err := ffmpeg.New(ffmpeg.Config{
-		Binary:      ffmpegPath,
-		MaxLogLines: 100,
+		Binary:              ffmpegPath,
+		Binaries:            cfg.FFmpeg.Binaries,
+		BinaryDir:           cfg.FFmpeg.BinaryDir,
+		MaxLogLines:         100,
+		ProbeTimeout:        time.Duration(cfg.FFmpeg.ProbeTimeout) * time.Second,
+		FFprobe:             cfg.FFmpeg.FFprobePath,
+		FFprobeTimeout:      time.Duration(cfg.FFmpeg.FFprobeTimeout) * time.Second,
+		DryRun:              cfg.DryRun.Enable,
+		DryRunFPS:           cfg.DryRun.FPS,
+		LogDir:              cfg.TaskLog.Dir,
+		LogFileSize:         cfg.TaskLog.MaxBytes,
+		LogFileKeep:         cfg.TaskLog.MaxFiles,
+		ProgressSmoothing:   cfg.FFmpeg.ProgressSmoothing,
+		ProgressHistory:     cfg.FFmpeg.ProgressHistory,
+		SkillsCheckInterval: time.Duration(max(cfg.FFmpeg.SkillsCheck, 0)) * time.Second,
+		Logger:              logger,
+		ValidatorInput:      validatorIn,
+		ValidatorOutput:     validatorOut,
 	})
 	if err != nil {
 		log.Fatalf("FFmpeg init: %v", err)
 	}
+	for _, w := range ff.Skills().Warnings {
+		logger.Error("skills: %s", w)
+	}
 
-	store := task.NewStore(ff, logger)
-	handler := api.NewHandler(store, ff)
+	presets, err := task.NewPresetStore(filepath.Join(cfg.Data.Dir, "presets.json"))
+	if err != nil {
+		log.Fatalf("Load presets: %v", err)
+	}
+
+	credentials, err := task.NewCredentialStore(filepath.Join(cfg.Data.Dir, "credentials.json"), cfg.Credentials.Key)
+	if err != nil {
+		log.Fatalf("Load credentials: %v", err)
+	}
+
+	hooks, err := hook.New(hook.Config{
+		Dir:           cfg.Hooks.Dir,
+		Timeout:       time.Duration(cfg.Hooks.Timeout) * time.Second,
+		MaxConcurrent: cfg.Hooks.MaxConcurrent,
+		Hooks: map[string][]string{
+			hook.EventStart:    cfg.Hooks.OnStart,
+			hook.EventFailed:   cfg.Hooks.OnFailed,
+			hook.EventFinished: cfg.Hooks.OnFinished,
+			hook.EventStalled:  cfg.Hooks.OnStalled,
+		},
+		Logger: logger,
+	})
+	if err != nil {
+		log.Fatalf("Hooks: %v", err)
+	}
+
+	var publisher mqtt.Publisher
+	if cfg.MQTT.Broker != "" {
+		publisher, err = mqtt.New(mqtt.Config{
+			Broker:      cfg.MQTT.Broker,
+			Username:    cfg.MQTT.Username,
+			Password:    cfg.MQTT.Password,
+			ClientID:    cfg.MQTT.ClientID,
+			TopicPrefix: cfg.MQTT.TopicPrefix,
+			QoS:         cfg.MQTT.QoS,
+			QueueSize:   cfg.MQTT.QueueSize,
+			Logger:      logger,
+		})
+		if err != nil {
+			log.Fatalf("MQTT: %v", err)
+		}
+	}
+
+	store := task.NewStore(ff, logger, task.StoreConfig{
+		MemoryBudget:      cfg.Memory.BudgetBytes,
+		MaxConcurrent:     cfg.Tasks.MaxConcurrent,
+		Preempt:           cfg.Tasks.Preempt,
+		Notifier:          webhook.New(cfg.Webhooks, logger),
+		MQTT:              publisher,
+		MQTTInterval:      time.Duration(cfg.MQTT.ProgressInterval) * time.Second,
+		ReportDir:         cfg.TaskLog.Dir,
+		ReportKeep:        cfg.TaskLog.Reports,
+		HistoryRuns:       cfg.TaskLog.HistoryRuns,
+		Credentials:       credentials,
+		Hooks:             hooks,
+		ValidateProtocols: cfg.FFmpeg.ValidateProtocols,
+		ValidateEncoders:  cfg.FFmpeg.ValidateEncoders,
+	})
+	var chaos task.Chaos
+	if cfg.Chaos.Enable {
+		chaos = task.NewChaos(store, logger, task.ChaosConfig{
+			DefaultTTL: time.Duration(cfg.Chaos.DefaultTTL) * time.Second,
+			MaxTTL:     time.Duration(cfg.Chaos.MaxTTL) * time.Second,
+		})
+	}
+	if cfg.Auth.Username != "" && cfg.Auth.Password == "" {
+		log.Fatalf("auth.username is set without auth.password")
+	}
+
+	switch cfg.Watchdog.Action {
+	case watchdog.ActionNone, watchdog.ActionExit:
+	default:
+		log.Fatalf("Unknown watchdog action %q", cfg.Watchdog.Action)
+	}
+	dog := watchdog.New(watchdog.Config{
+		Interval:  time.Duration(cfg.Watchdog.Interval) * time.Second,
+		Timeout:   time.Duration(cfg.Watchdog.Timeout) * time.Second,
+		Threshold: cfg.Watchdog.Threshold,
+		Action:    cfg.Watchdog.Action,
+	}, []watchdog.Check{
+		watchdog.LockCheck("store_lock", store.Ping),
+		watchdog.WriteCheck("data_dir", cfg.Data.Dir),
+	}, logger)
+
+	var watcherConfigs []watcher.Config
+	for _, w := range cfg.Watchers {
+		watcherConfigs = append(watcherConfigs, watcher.Config{
+			Name:      w.Name,
+			Dir:       w.Dir,
+			Glob:      w.Glob,
+			Preset:    w.Preset,
+			Output:    w.Output,
+			Variables: w.Variables,
+			Settle:    time.Duration(w.Settle) * time.Second,
+			Rescan:    time.Duration(w.Rescan) * time.Second,
+			After:     w.After,
+			MoveTo:    w.MoveTo,
+		})
+	}
+	watchers, err := watcher.New(watcherConfigs, store, presets, logger)
+	if err != nil {
+		log.Fatalf("Watchers: %v", err)
+	}
+
+	audit, err := task.NewAuditLog(task.AuditConfig{
+		File:       cfg.Audit.File,
+		MaxEntries: cfg.Audit.MaxEntries,
+		Redactor:   redactor,
+	}, logger)
+	if err != nil {
+		log.Fatalf("Audit log: %v", err)
+	}
+
+	handler := api.NewHandler(store, presets, ff, api.Config{
+		Redactor:         redactor,
+		RevealToken:      cfg.Redact.RevealToken,
+		BundleLogLines:   cfg.Debug.BundleLogLines,
+		BundleMaxBytes:   cfg.Debug.BundleMaxBytes,
+		BundleInterval:   time.Duration(cfg.Debug.BundleInterval) * time.Second,
+		Chaos:            chaos,
+		Watchdog:         dog,
+		Operations:       task.NewOperations(store, logger),
+		Watchers:         watchers,
+		Credentials:      credentials,
+		ReloadValidators: func() error { return reloadValidators(*configPath, ff) },
+		Auth: auth.New(auth.Config{
+			Username:   cfg.Auth.Username,
+			Password:   cfg.Auth.Password,
+			Tokens:     cfg.Auth.Tokens,
+			SessionTTL: time.Duration(cfg.Auth.SessionTTL) * time.Second,
+		}),
+		Audit: audit,
+	})
 
 	r := gin.Default()
-	r.Use(gin.Recovery(), cors.Default())
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowAllOrigins = true
+	corsConfig.ExposeHeaders = []string{"X-Total-Count"}
+	r.Use(gin.Recovery(), cors.New(corsConfig))
 
 	// 静态前端
 	webDir := "web"
 	indexPath := filepath.Join(webDir, "index.html")
 	r.GET("/", func(c *gin.Context) { c.File(indexPath) })
 
-	v3 := r.Group("/api/v3")
+	r.GET("/metrics", handler.RequireAuth, handler.Metrics)
+	r.GET("/readyz", handler.Readyz)
+
+	r.POST("/api/v3/login", handler.Login)
+	r.POST("/api/v3/logout", handler.Logout)
+
+	// 配置了用户名或令牌时需要认证
+	v3 := r.Group("/api/v3", handler.RequireAuth)
 	{
+		v3.GET("/system", handler.System)
+
 		v3.GET("/skills", handler.Skills)
 		v3.POST("/skills/reload", handler.ReloadSkills)
+		v3.POST("/validators/reload", handler.ReloadValidators)
+
+		v3.GET("/presets", handler.ListPresets)
+		v3.POST("/presets", handler.AddPreset)
+		v3.GET("/presets/:name", handler.GetPreset)
+		v3.PUT("/presets/:name", handler.UpdatePreset)
+		v3.DELETE("/presets/:name", handler.DeletePreset)
+
+		v3.GET("/credentials", handler.ListCredentials)
+		v3.POST("/credentials", handler.AddCredential)
+		v3.GET("/credentials/:name", handler.GetCredential)
+		v3.PUT("/credentials/:name", handler.UpdateCredential)
+		v3.DELETE("/credentials/:name", handler.DeleteCredential)
+
+		v3.GET("/operations", handler.ListOperations)
+		v3.GET("/operations/:id", handler.GetOperation)
+
+		v3.GET("/watchers", handler.ListWatchers)
 
 		v3.GET("/process", handler.ListProcesses)
 		v3.POST("/process", handler.AddProcess)
+		v3.GET("/process/summary", handler.Summary)
+		v3.POST("/process/validate", handler.ValidateProcess)
+		v3.PUT("/process/command", handler.BatchCommand)
+		v3.POST("/process/command", handler.BatchCommand)
+		v3.GET("/reference/:ref", handler.GetReference)
+		v3.PUT("/reference/:ref/command", handler.ReferenceCommand)
 		v3.GET("/process/:id", handler.GetProcess)
 		v3.PUT("/process/:id", handler.UpdateProcess)
 		v3.DELETE("/process/:id", handler.DeleteProcess)
 		v3.GET("/process/:id/config", handler.GetConfig)
 		v3.GET("/process/:id/state", handler.GetState)
+		v3.GET("/process/:id/history", handler.GetAudit)
+		v3.GET("/process/:id/state/history", handler.ListTransitions)
+		v3.DELETE("/process/:id/state/history", handler.ClearTransitions)
 		v3.GET("/process/:id/report", handler.GetReport)
+		v3.GET("/process/:id/report/file", handler.GetLogFile)
+		v3.GET("/process/:id/report/download", handler.DownloadReport)
+		v3.GET("/process/:id/progress/history", handler.GetProgressHistory)
+		v3.GET("/process/:id/report/history", handler.ListHistory)
+		v3.GET("/process/:id/report/history/:n", handler.GetHistory)
+		v3.GET("/process/:id/report/files", handler.ListReportFiles)
+		v3.GET("/process/:id/report/files/:name", handler.DownloadReportFile)
+		v3.GET("/process/:id/debug-bundle", handler.DebugBundle)
+		v3.GET("/process/:id/probe", handler.Probe)
+		v3.GET("/process/:id/metadata/:key", handler.GetMetadata)
+		v3.PUT("/process/:id/metadata/:key", handler.SetMetadata)
 		v3.PUT("/process/:id/command", handler.Command)
+
+		// 故障注入仅在配置开启时可用
+		if chaos != nil {
+			v3.GET("/chaos", handler.ListFaults)
+			v3.DELETE("/chaos/:id", handler.RemoveFault)
+			v3.POST("/process/:id/chaos", handler.InjectFault)
+		}
+	}
+
+	if cfg.DryRun.Enable {
+		log.Printf("Dry-run mode: FFmpeg is never executed, tasks are simulated")
+	}
+	if chaos != nil {
+		log.Printf("Chaos endpoints enabled: faults can be injected into tasks")
 	}
+	srv := &http.Server{Addr: bindAddr, Handler: r}
+	tlsConfig, certs, err := serverTLS(cfg.Server.TLS)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	srv.TLSConfig = tlsConfig
 
-	log.Printf("TranscodeManager listening on %s (Web UI: /)", bindAddr)
-	if err := r.Run(bindAddr); err != nil {
+	var redirect *http.Server
+	if certs != nil && cfg.Server.TLS.RedirectBind != "" {
+		redirect = &http.Server{Addr: cfg.Server.TLS.RedirectBind, Handler: redirectHandler(bindAddr)}
+		go func() {
+			log.Printf("Redirecting HTTP on %s to HTTPS", redirect.Addr)
+			if err := redirect.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("Redirect server: %v", err)
+			}
+		}()
+	}
+
+	listener, err := net.Listen("tcp", bindAddr)
+	if err != nil {
 		log.Fatalf("Server: %v", err)
 	}
+	if certs != nil {
+		log.Printf("TranscodeManager listening on %s with HTTPS (Web UI: /)", bindAddr)
+	} else {
+		log.Printf("TranscodeManager listening on %s (Web UI: /)", bindAddr)
+	}
+	go func() {
+		if err := serve(srv, listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server: %v", err)
+		}
+	}()
+
+	// 退出前停止所有任务，避免遗留 FFmpeg 子进程
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for s := range sig {
+		if s != syscall.SIGHUP {
+			log.Printf("Received %s, shutting down", s)
+			break
+		}
+		if err := reloadValidators(*configPath, ff); err != nil {
+			log.Printf("Reload validators: %v, keeping the current ones", err)
+		} else {
+			log.Printf("Reloaded validators")
+		}
+		if certs == nil {
+			continue
+		}
+		if err := certs.reload(); err != nil {
+			log.Printf("Reload TLS certificate: %v, keeping the current one", err)
+		} else {
+			log.Printf("Reloaded TLS certificate")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout)*time.Second)
+	defer cancel()
+	if redirect != nil {
+		redirect.Shutdown(ctx)
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("HTTP shutdown: %v", err)
+	}
+	watchers.Close()
+	if killed := store.Shutdown(ctx); len(killed) != 0 {
+		log.Printf("Force-killed %d tasks that didn't stop in time: %s", len(killed), strings.Join(killed, ", "))
+	}
+	if publisher != nil {
+		// 发布停止时的状态变化
+		publisher.Close(5 * time.Second)
+	}
+	audit.Close()
+	log.Printf("Shutdown complete")
 }
@@ -0,0 +1,94 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/ZSC714725/transcodemanager/internal/config"
+)
+
+// serverTLS returns the TLS config of the server and its certificate, both
+// nil if server.tls isn't set. The keypair is loaded here, so that a bad
+// config fails before binding.
+func serverTLS(cfg config.TLSConfig) (*tls.Config, *certReloader, error) {
+	if cfg.CertFile == "" && cfg.KeyFile == "" {
+		return nil, nil, nil
+	}
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, nil, errors.New("server.tls needs both cert_file and key_file")
+	}
+	certs, err := newCertReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+	return &tls.Config{GetCertificate: certs.getCertificate}, certs, nil
+}
+
+// serve serves HTTPS on l if srv has a TLS config, HTTP otherwise
+func serve(srv *http.Server, l net.Listener) error {
+	if srv.TLSConfig != nil {
+		return srv.ServeTLS(l, "", "")
+	}
+	return srv.Serve(l)
+}
+
+// certReloader serves a certificate that can be reloaded from its files
+type certReloader struct {
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+	lock     sync.RWMutex
+}
+
+// newCertReloader loads the certificate, so that a bad path fails before
+// binding
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reload reads the files again, the current certificate stays on error
+func (c *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	c.cert = &cert
+	c.lock.Unlock()
+	return nil
+}
+
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.cert, nil
+}
+
+// redirectHandler redirects plain HTTP requests to HTTPS on the port of
+// httpsBind
+func redirectHandler(httpsBind string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsBind)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/config"
+)
+
+// writeKeypair writes a self-signed certificate for 127.0.0.1 and its key
+// to dir and returns their paths
+func writeKeypair(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestServerTLS(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := writeKeypair(t, dir, "a")
+	otherCert, _ := writeKeypair(t, dir, "b")
+
+	tests := []struct {
+		name    string
+		config  config.TLSConfig
+		enabled bool
+		wantErr bool
+	}{
+		{"disabled", config.TLSConfig{}, false, false},
+		{"keypair", config.TLSConfig{CertFile: cert, KeyFile: key}, true, false},
+		{"cert only", config.TLSConfig{CertFile: cert}, false, true},
+		{"key only", config.TLSConfig{KeyFile: key}, false, true},
+		{"missing file", config.TLSConfig{CertFile: filepath.Join(dir, "none.crt"), KeyFile: key}, false, true},
+		{"key of another cert", config.TLSConfig{CertFile: otherCert, KeyFile: key}, false, true},
+		{"not a cert", config.TLSConfig{CertFile: key, KeyFile: key}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, certs, err := serverTLS(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if (tlsConfig != nil) != tt.enabled || (certs != nil) != tt.enabled {
+				t.Fatalf("TLS config %v, certificate %v, want enabled %v", tlsConfig != nil, certs != nil, tt.enabled)
+			}
+		})
+	}
+}
+
+func TestServe(t *testing.T) {
+	cert, key := writeKeypair(t, t.TempDir(), "a")
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			w.Write([]byte("https"))
+		} else {
+			w.Write([]byte("http"))
+		}
+	})
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+
+	tests := []struct {
+		name   string
+		config config.TLSConfig
+		scheme string
+	}{
+		{"plain", config.TLSConfig{}, "http"},
+		{"tls", config.TLSConfig{CertFile: cert, KeyFile: key}, "https"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, _, err := serverTLS(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := &http.Server{Handler: handler, TLSConfig: tlsConfig}
+			go serve(srv, l)
+			defer srv.Close()
+
+			resp, err := client.Get(tt.scheme + "://" + l.Addr().String() + "/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if (resp.TLS != nil) != (tt.scheme == "https") {
+				t.Fatalf("TLS %v, want %s", resp.TLS != nil, tt.scheme)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/ZSC714725/transcodemanager/internal/config"
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+)
+
+// newValidators compiles the input and output address rules
+func newValidators(c config.ValidatorConfig) (ffmpeg.Validator, ffmpeg.Validator, error) {
+	in, err := ffmpeg.NewValidator(c.Input.Allow, c.Input.Block)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ffmpeg.validator.input: %w", err)
+	}
+	out, err := ffmpeg.NewValidator(c.Output.Allow, c.Output.Block)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ffmpeg.validator.output: %w", err)
+	}
+	return in, out, nil
+}
+
+// reloadValidators reads the address rules from the config file again. They
+// apply to tasks added or updated afterwards, running tasks are kept. The
+// current rules stay on error.
+func reloadValidators(path string, ff ffmpeg.FFmpeg) error {
+	cfg := config.Default()
+	if path != "" {
+		var err error
+		if cfg, err = config.Load(path); err != nil {
+			return err
+		}
+	}
+	in, out, err := newValidators(cfg.FFmpeg.Validator)
+	if err != nil {
+		return err
+	}
+	ff.SetValidators(in, out)
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+// ProcessAuditEntry is a change made to a task through the API
+type ProcessAuditEntry struct {
+	Timestamp int64               `json:"timestamp"`
+	Action    string              `json:"action"` // add, update, delete or the command
+	Actor     string              `json:"actor"`  // user name, token #n or anonymous
+	Remote    string              `json:"remote,omitempty"`
+	Changes   []ProcessConfigDiff `json:"changes,omitempty"`
+}
+
+// ProcessConfigDiff is a config field that changed, secrets are masked
+type ProcessConfigDiff struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"` // null for an added task
+	After  any    `json:"after"`  // null for a deleted task
+}
+
+// audit records a change of a task made by the request
+func (h *Handler) audit(c *gin.Context, id, action string, before, after *task.Config) {
+	if h.config.Audit != nil {
+		h.config.Audit.Record(id, action, actor(c), before, after)
+	}
+}
+
+// GetAudit GET /api/v3/process/:id/history lists who added, updated,
+// deleted or commanded the task, oldest first. Deleted tasks keep their
+// history.
+func (h *Handler) GetAudit(c *gin.Context) {
+	if h.config.Audit == nil {
+		errResp(c, http.StatusNotFound, "Audit disabled", "")
+		return
+	}
+	id := c.Param("id")
+	entries := h.config.Audit.Get(id)
+	if len(entries) == 0 {
+		if _, err := h.store.Get(id); err != nil {
+			errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
+			return
+		}
+	}
+
+	resp := make([]ProcessAuditEntry, 0, len(entries))
+	for _, e := range entries {
+		entry := ProcessAuditEntry{Timestamp: e.Time.Unix(), Action: e.Action, Actor: e.Actor, Remote: e.Remote}
+		for _, ch := range e.Changes {
+			entry.Changes = append(entry.Changes, ProcessConfigDiff{Field: ch.Field, Before: ch.Before, After: ch.After})
+		}
+		resp = append(resp, entry)
+	}
+	c.JSON(http.StatusOK, resp)
+}
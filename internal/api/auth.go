@@ -0,0 +1,106 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/auth"
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+// sessionCookie holds the session token of the web UI
+const sessionCookie = "tm_session"
+
+// actorKey holds the name of the caller in the gin context, see actor
+const actorKey = "actor"
+
+// LoginRequest for POST /api/v3/login
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// LoginResponse is the session token, also set as cookie
+type LoginResponse struct {
+	Token     string `json:"token"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// requestToken returns the bearer token or the session cookie
+func requestToken(c *gin.Context) string {
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		return token
+	}
+	token, _ := c.Cookie(sessionCookie)
+	return token
+}
+
+// RequireAuth rejects requests without a valid token or session, if
+// authentication is configured
+func (h *Handler) RequireAuth(c *gin.Context) {
+	a := h.config.Auth
+	if a == nil || !a.Enabled() {
+		c.Next()
+		return
+	}
+	if token := requestToken(c); a.Check(token) {
+		c.Set(actorKey, a.Who(token))
+		c.Next()
+		return
+	}
+	errResp(c, http.StatusUnauthorized, "Unauthorized", "login or send Authorization: Bearer <token>")
+	c.Abort()
+}
+
+// actor returns who sent the request, anonymous without authentication
+func actor(c *gin.Context) task.Actor {
+	name := c.GetString(actorKey)
+	if name == "" {
+		name = "anonymous"
+	}
+	return task.Actor{Name: name, Remote: c.ClientIP()}
+}
+
+// Login POST /api/v3/login
+func (h *Handler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp(c, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+	if h.config.Auth == nil {
+		errResp(c, http.StatusNotFound, "Login disabled", auth.ErrLoginDisabled.Error())
+		return
+	}
+
+	s, err := h.config.Auth.Login(req.Username, req.Password)
+	if err == auth.ErrLoginDisabled {
+		errResp(c, http.StatusNotFound, "Login disabled", err.Error())
+		return
+	}
+	if err != nil {
+		errResp(c, http.StatusUnauthorized, "Login failed", err.Error())
+		return
+	}
+
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(sessionCookie, s.Token, int(time.Until(s.ExpiresAt).Seconds()), "/", "", c.Request.TLS != nil, true)
+	c.JSON(http.StatusOK, LoginResponse{Token: s.Token, ExpiresAt: s.ExpiresAt.Unix()})
+}
+
+// Logout POST /api/v3/logout ends the session of the request
+func (h *Handler) Logout(c *gin.Context) {
+	if h.config.Auth != nil {
+		h.config.Auth.Logout(requestToken(c))
+	}
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(sessionCookie, "", -1, "/", "", c.Request.TLS != nil, true)
+	c.JSON(http.StatusOK, "OK")
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+// ChaosRequest injects a fault into a task
+type ChaosRequest struct {
+	Fault  string `json:"fault" binding:"required"` // kill, stall, fail_start, delay_reconnect
+	Signal string `json:"signal"`                   // kill, default SIGKILL
+	Delay  uint64 `json:"delay_seconds"`            // delay_reconnect
+	TTL    uint64 `json:"ttl_seconds"`
+}
+
+// ChaosInjection is an active fault
+type ChaosInjection struct {
+	ID        string `json:"id"`
+	ProcessID string `json:"process_id"`
+	Fault     string `json:"fault"`
+	Signal    string `json:"signal,omitempty"`
+	Delay     uint64 `json:"delay_seconds,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// InjectFault POST /api/v3/process/:id/chaos
+func (h *Handler) InjectFault(c *gin.Context) {
+	id := c.Param("id")
+
+	var req ChaosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp(c, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+
+	inj, err := h.config.Chaos.Inject(id, task.Fault{
+		Kind:   req.Fault,
+		Signal: req.Signal,
+		Delay:  time.Duration(req.Delay) * time.Second,
+		TTL:    time.Duration(req.TTL) * time.Second,
+	})
+	if err != nil {
+		if err == task.ErrNotFound {
+			errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
+			return
+		}
+		errResp(c, http.StatusBadRequest, "Injection failed", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, injectionToAPI(inj))
+}
+
+// ListFaults GET /api/v3/chaos
+func (h *Handler) ListFaults(c *gin.Context) {
+	list := h.config.Chaos.List()
+	resp := make([]ChaosInjection, len(list))
+	for i, inj := range list {
+		resp[i] = injectionToAPI(inj)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// RemoveFault DELETE /api/v3/chaos/:id
+func (h *Handler) RemoveFault(c *gin.Context) {
+	if err := h.config.Chaos.Remove(c.Param("id")); err != nil {
+		errResp(c, http.StatusNotFound, "Unknown injection ID", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, "OK")
+}
+
+func injectionToAPI(inj task.Injection) ChaosInjection {
+	return ChaosInjection{
+		ID:        inj.ID,
+		ProcessID: inj.TaskID,
+		Fault:     inj.Fault.Kind,
+		Signal:    inj.Fault.Signal,
+		Delay:     uint64(inj.Fault.Delay.Seconds()),
+		CreatedAt: inj.CreatedAt.Unix(),
+		ExpiresAt: inj.ExpiresAt.Unix(),
+	}
+}
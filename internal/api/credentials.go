@@ -0,0 +1,150 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+// Credential is a named secret, referenced as {secret:name} in addresses
+// and options. The value is write-only.
+type Credential struct {
+	Name      string `json:"name"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// CredentialRequest creates or updates a credential, Name is taken from
+// the path on update
+type CredentialRequest struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ListCredentials GET /api/v3/credentials
+func (h *Handler) ListCredentials(c *gin.Context) {
+	out := []Credential{}
+	if h.config.Credentials != nil {
+		for _, cred := range h.config.Credentials.List() {
+			out = append(out, credentialToAPI(cred))
+		}
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// AddCredential POST /api/v3/credentials
+func (h *Handler) AddCredential(c *gin.Context) {
+	var req CredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp(c, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+	if h.config.Credentials == nil {
+		errResp(c, http.StatusBadRequest, "Invalid credential", task.ErrCredentialsDisabled.Error())
+		return
+	}
+
+	cred, err := h.config.Credentials.Add(req.Name, req.Value)
+	if err != nil {
+		if err == task.ErrCredentialExists || err == task.ErrInvalidCredential || err == task.ErrCredentialsDisabled {
+			errResp(c, http.StatusBadRequest, "Invalid credential", err.Error())
+			return
+		}
+		errResp(c, http.StatusInternalServerError, "Save failed", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, credentialToAPI(cred))
+}
+
+// GetCredential GET /api/v3/credentials/:name
+func (h *Handler) GetCredential(c *gin.Context) {
+	if h.config.Credentials == nil {
+		errResp(c, http.StatusNotFound, "Unknown credential", task.ErrCredentialNotFound.Error())
+		return
+	}
+	cred, err := h.config.Credentials.Get(c.Param("name"))
+	if err != nil {
+		errResp(c, http.StatusNotFound, "Unknown credential", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, credentialToAPI(cred))
+}
+
+// UpdateCredential PUT /api/v3/credentials/:name replaces the value. Tasks
+// use it from their next run.
+func (h *Handler) UpdateCredential(c *gin.Context) {
+	var req CredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp(c, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+	if h.config.Credentials == nil {
+		errResp(c, http.StatusNotFound, "Unknown credential", task.ErrCredentialNotFound.Error())
+		return
+	}
+
+	cred, err := h.config.Credentials.Update(c.Param("name"), req.Value)
+	if err != nil {
+		switch err {
+		case task.ErrCredentialNotFound:
+			errResp(c, http.StatusNotFound, "Unknown credential", err.Error())
+		case task.ErrInvalidCredential:
+			errResp(c, http.StatusBadRequest, "Invalid credential", err.Error())
+		default:
+			errResp(c, http.StatusInternalServerError, "Save failed", err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, credentialToAPI(cred))
+}
+
+// DeleteCredential DELETE /api/v3/credentials/:name, rejected while tasks
+// reference the credential
+func (h *Handler) DeleteCredential(c *gin.Context) {
+	name := c.Param("name")
+	if h.config.Credentials == nil {
+		errResp(c, http.StatusNotFound, "Unknown credential", task.ErrCredentialNotFound.Error())
+		return
+	}
+	if _, err := h.config.Credentials.Get(name); err != nil {
+		errResp(c, http.StatusNotFound, "Unknown credential", err.Error())
+		return
+	}
+
+	var users []string
+	tasks, _ := h.store.List(task.ListFilter{Sort: "id"})
+	for _, t := range tasks {
+		if slices.Contains(t.Config.Credentials(), name) {
+			users = append(users, t.ID)
+		}
+	}
+	if len(users) != 0 {
+		errResp(c, http.StatusConflict, "Credential in use", task.ErrCredentialInUse.Error()+": "+strings.Join(users, ", "))
+		return
+	}
+
+	if err := h.config.Credentials.Delete(name); err != nil {
+		if errors.Is(err, task.ErrCredentialNotFound) {
+			errResp(c, http.StatusNotFound, "Unknown credential", err.Error())
+			return
+		}
+		errResp(c, http.StatusInternalServerError, "Save failed", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, "OK")
+}
+
+func credentialToAPI(c task.Credential) Credential {
+	return Credential{Name: c.Name, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+// DebugBundle collects everything needed to file an upstream FFmpeg bug
+type DebugBundle struct {
+	CreatedAt int64          `json:"created_at"`
+	Host      DebugHost      `json:"host"`
+	FFmpeg    DebugFFmpeg    `json:"ffmpeg"`
+	Config    *ProcessConfig `json:"config"`
+	State     ProcessState   `json:"state"`
+	History   DebugHistory   `json:"history"`
+	Report    ProcessReport  `json:"report"`
+	Truncated bool           `json:"truncated"`
+}
+
+// DebugHost describes the machine running the manager
+type DebugHost struct {
+	Hostname  string `json:"hostname"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	NumCPU    int    `json:"num_cpu"`
+	GoVersion string `json:"go_version"`
+}
+
+// DebugFFmpeg is an excerpt of the detected skills
+type DebugFFmpeg struct {
+	Binary        string   `json:"binary"`
+	Version       string   `json:"version"`
+	Compiler      string   `json:"compiler"`
+	Configuration string   `json:"configuration"`
+	Libraries     []string `json:"libraries"`
+	HWAccels      []string `json:"hwaccels"`
+}
+
+// DebugHistory are the cumulative state counts of the process
+type DebugHistory struct {
+	Finished  uint64 `json:"finished"`
+	Starting  uint64 `json:"starting"`
+	Running   uint64 `json:"running"`
+	Finishing uint64 `json:"finishing"`
+	Failed    uint64 `json:"failed"`
+	Killed    uint64 `json:"killed"`
+}
+
+// DebugBundle GET /api/v3/process/:id/debug-bundle
+func (h *Handler) DebugBundle(c *gin.Context) {
+	id := c.Param("id")
+
+	t, err := h.store.Get(id)
+	if err != nil {
+		errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
+		return
+	}
+
+	h.bundleLock.Lock()
+	if wait := h.config.BundleInterval - time.Since(h.bundleLast); wait > 0 {
+		h.bundleLock.Unlock()
+		c.Header("Retry-After", fmt.Sprintf("%d", int(wait.Seconds())+1))
+		errResp(c, http.StatusTooManyRequests, "Too many requests", "debug bundles are rate-limited")
+		return
+	}
+	h.bundleLast = time.Now()
+	h.bundleLock.Unlock()
+
+	data, err := json.MarshalIndent(h.newDebugBundle(t), "", "  ")
+	if err != nil {
+		errResp(c, http.StatusInternalServerError, "Bundle failed", err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("debug-%s-%s.json", t.ID, time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", "attachment; filename=\""+filename+"\"")
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+func (h *Handler) newDebugBundle(t *task.Task) DebugBundle {
+	r := h.config.Redactor
+
+	b := DebugBundle{
+		CreatedAt: time.Now().Unix(),
+		Config:    taskToProcessConfig(t),
+		State:     taskToProcessState(t),
+		Report:    taskToProcessReport(t),
+	}
+
+	b.Host.Hostname, _ = os.Hostname()
+	b.Host.OS = runtime.GOOS
+	b.Host.Arch = runtime.GOARCH
+	b.Host.NumCPU = runtime.NumCPU()
+	b.Host.GoVersion = runtime.Version()
+
+	sk, _ := h.ffmpeg.BinarySkills(t.Config.Binary)
+	b.FFmpeg.Binary = h.ffmpeg.Binary(t.Config.Binary)
+	b.FFmpeg.Version = sk.FFmpeg.Version
+	b.FFmpeg.Compiler = sk.FFmpeg.Compiler
+	b.FFmpeg.Configuration = sk.FFmpeg.Configuration
+	for _, lib := range sk.FFmpeg.Libraries {
+		b.FFmpeg.Libraries = append(b.FFmpeg.Libraries, lib.Name+" "+lib.Linked)
+	}
+	for _, hw := range sk.HWAccels {
+		b.FFmpeg.HWAccels = append(b.FFmpeg.HWAccels, hw.Id)
+	}
+
+	states := t.Status().States
+	b.History = DebugHistory{
+		Finished:  states.Finished,
+		Starting:  states.Starting,
+		Running:   states.Running,
+		Finishing: states.Finishing,
+		Failed:    states.Failed,
+		Killed:    states.Killed,
+	}
+
+	redactProcessConfig(r, b.Config)
+	redactProcessState(r, &b.State)
+	redactProcessReport(r, &b.Report)
+
+	if n := h.config.BundleLogLines; n > 0 && len(b.Report.Log) > n {
+		b.Report.Log = b.Report.Log[len(b.Report.Log)-n:]
+		b.Truncated = true
+	}
+
+	// 超出大小限制时逐步丢弃较早的日志
+	for h.config.BundleMaxBytes > 0 && len(b.Report.Log) > 0 {
+		data, err := json.Marshal(b)
+		if err != nil || len(data) <= h.config.BundleMaxBytes {
+			break
+		}
+		b.Report.Log = b.Report.Log[len(b.Report.Log)/2:]
+		if len(b.Report.Log) == 1 {
+			b.Report.Log = b.Report.Log[:0]
+		}
+		b.Truncated = true
+	}
+
+	return b
+}
@@ -6,24 +6,73 @@
 package api
 
 import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"maps"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/gin-gonic/gin"
+	"github.com/ZSC714725/transcodemanager/internal/auth"
 	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg/parse"
+	"github.com/ZSC714725/transcodemanager/internal/process"
+	"github.com/ZSC714725/transcodemanager/internal/redact"
 	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/ZSC714725/transcodemanager/internal/watchdog"
+	"github.com/ZSC714725/transcodemanager/internal/watcher"
+	"github.com/gin-gonic/gin"
 )
 
+// Config for the API handler
+type Config struct {
+	Redactor       redact.Redactor
+	RevealToken    string
+	BundleLogLines int
+	BundleMaxBytes int
+	BundleInterval time.Duration
+	Chaos          task.Chaos // only used if the chaos routes are registered
+	Watchdog       watchdog.Watchdog
+	Operations     task.Operations
+	Watchers       watcher.Watchers
+	Credentials    task.CredentialStore // nil disables the credentials API
+	// ReloadValidators reads the address rules from the config file again
+	ReloadValidators func() error
+	Auth             auth.Auth     // nil allows every request
+	Audit            task.AuditLog // nil disables the audit history
+}
+
 // Handler holds dependencies
 type Handler struct {
-	store  task.Store
-	ffmpeg ffmpeg.FFmpeg
+	store   task.Store
+	presets task.PresetStore
+	ffmpeg  ffmpeg.FFmpeg
+	config  Config
+
+	bundleLast time.Time
+	bundleLock sync.Mutex
 }
 
 // NewHandler creates API handler
-func NewHandler(store task.Store, ff ffmpeg.FFmpeg) *Handler {
-	return &Handler{store: store, ffmpeg: ff}
+func NewHandler(store task.Store, presets task.PresetStore, ff ffmpeg.FFmpeg, config Config) *Handler {
+	if config.Redactor == nil {
+		config.Redactor, _ = redact.New(nil)
+	}
+	return &Handler{store: store, presets: presets, ffmpeg: ff, config: config}
+}
+
+// redactor returns the redactor for a request. Secrets are only revealed for
+// ?reveal=true together with a matching X-Reveal-Token header.
+func (h *Handler) redactor(c *gin.Context) redact.Redactor {
+	if c.Query("reveal") == "true" && len(h.config.RevealToken) != 0 &&
+		subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Reveal-Token")), []byte(h.config.RevealToken)) == 1 {
+		return redact.Nop()
+	}
+	return h.config.Redactor
 }
 
 func errResp(c *gin.Context, code int, msg, detail string) {
@@ -44,23 +93,71 @@ func (h *Handler) AddProcess(c *gin.Context) {
 	}
 
 	cfg := requestToConfig(&req)
+	cfg.SkipValidation = c.Query("skip_validation") == "true"
 	// Autostart 由前端请求决定，默认不自动启动
 
-	t, err := h.store.Add(cfg)
-	if err != nil {
-		if err == task.ErrTaskExists {
-			errResp(c, http.StatusBadRequest, "Task exists", err.Error())
+	// 预设只填充请求中未设置的字段
+	if len(req.Preset) != 0 {
+		preset, err := h.presets.Get(req.Preset)
+		if err != nil {
+			errResp(c, http.StatusBadRequest, "Unknown preset", err.Error())
 			return
 		}
-		if err == task.ErrInvalidInputAddress || err == task.ErrInvalidOutputAddress {
-			errResp(c, http.StatusBadRequest, "Invalid address", err.Error())
+		preset.Apply(cfg)
+	}
+
+	if c.Query("dryrun") == "true" {
+		v, err := h.store.DryRun(cfg)
+		if err != nil {
+			addErrResp(c, err)
 			return
 		}
-		errResp(c, http.StatusBadRequest, "Invalid config", err.Error())
+		r := h.redactor(c)
+		resp := DryRunResponse{
+			ProcessConfig: redactProcessConfig(r, configToProcessConfig(cfg)),
+			Command:       r.RedactAll(v.Command),
+			Warnings:      r.RedactAll(v.Warnings),
+		}
+		if resp.Warnings == nil {
+			resp.Warnings = []string{}
+		}
+		c.JSON(http.StatusOK, resp)
 		return
 	}
 
-	c.JSON(http.StatusOK, taskToProcessConfig(t))
+	t, err := h.store.Add(cfg)
+	if err != nil {
+		addErrResp(c, err)
+		return
+	}
+	h.audit(c, t.ID, task.AuditAdd, nil, t.Config)
+
+	c.JSON(http.StatusOK, redactProcessConfig(h.redactor(c), taskToProcessConfig(t)))
+}
+
+// addErrResp reports an error of Store.Add or Store.DryRun
+func addErrResp(c *gin.Context, err error) {
+	if err == task.ErrTaskExists {
+		errResp(c, http.StatusBadRequest, "Task exists", err.Error())
+		return
+	}
+	if err == task.ErrInvalidInputAddress || err == task.ErrInvalidOutputAddress {
+		errResp(c, http.StatusBadRequest, "Invalid address", err.Error())
+		return
+	}
+	if errors.Is(err, task.ErrUnknownEncoder) {
+		errResp(c, http.StatusBadRequest, "Unknown encoder", err.Error()+" (use ?skip_validation=true to skip the check)")
+		return
+	}
+	if errors.Is(err, task.ErrUnsupportedProtocol) {
+		errResp(c, http.StatusBadRequest, "Unsupported protocol", err.Error()+" (use ?skip_validation=true to skip the check)")
+		return
+	}
+	if errors.Is(err, task.ErrIncompatibleOutput) {
+		errResp(c, http.StatusBadRequest, "Incompatible output", err.Error()+" (use ?skip_validation=true to skip the check)")
+		return
+	}
+	errResp(c, http.StatusBadRequest, "Invalid config", err.Error())
 }
 
 // ListProcesses GET /api/v3/process
@@ -77,17 +174,77 @@ func (h *Handler) ListProcesses(c *gin.Context) {
 		}
 	}
 
-	tasks := h.store.List(ids, reference)
+	var states []string
+	for _, s := range strings.Split(c.DefaultQuery("state", ""), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		if !task.ValidState(s) {
+			errResp(c, http.StatusBadRequest, "Invalid state", task.ErrInvalidState.Error())
+			return
+		}
+		states = append(states, s)
+	}
+
+	list := task.ListFilter{
+		IDs:       ids,
+		Reference: reference,
+		States:    states,
+		Sort:      c.DefaultQuery("sort", ""),
+	}
+	if !task.ValidSort(list.Sort) {
+		errResp(c, http.StatusBadRequest, "Invalid sort", task.ErrInvalidSort.Error())
+		return
+	}
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		list.Desc = true
+	default:
+		errResp(c, http.StatusBadRequest, "Invalid order", "Known: asc, desc")
+		return
+	}
+	var err error
+	if list.Limit, err = queryInt(c, "limit"); err != nil {
+		errResp(c, http.StatusBadRequest, "Invalid limit", err.Error())
+		return
+	}
+	if list.Offset, err = queryInt(c, "offset"); err != nil {
+		errResp(c, http.StatusBadRequest, "Invalid offset", err.Error())
+		return
+	}
+
+	tasks, total := h.store.List(list)
 	procs := make([]Process, 0, len(tasks))
+	r := h.redactor(c)
 
+	withMetadata := c.Query("metadata") == "true"
 	for _, t := range tasks {
 		p := taskToProcess(t, filter)
+		redactProcess(r, &p)
+		if withMetadata {
+			p.Metadata = t.AllMetadata()
+		}
 		procs = append(procs, p)
 	}
 
+	c.Header("X-Total-Count", strconv.Itoa(total))
 	c.JSON(http.StatusOK, procs)
 }
 
+// queryInt parses a non-negative integer query parameter, 0 if absent
+func queryInt(c *gin.Context, key string) (int, error) {
+	v := c.Query(key)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", key)
+	}
+	return n, nil
+}
+
 // GetProcess GET /api/v3/process/:id
 func (h *Handler) GetProcess(c *gin.Context) {
 	id := c.Param("id")
@@ -99,13 +256,23 @@ func (h *Handler) GetProcess(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, taskToProcess(t, filter))
+	p := taskToProcess(t, filter)
+	redactProcess(h.redactor(c), &p)
+	if c.Query("metadata") == "true" {
+		p.Metadata = t.AllMetadata()
+	}
+	c.JSON(http.StatusOK, p)
 }
 
 // DeleteProcess DELETE /api/v3/process/:id
 func (h *Handler) DeleteProcess(c *gin.Context) {
 	id := c.Param("id")
 
+	t, err := h.store.Get(id)
+	if err != nil {
+		errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
+		return
+	}
 	if err := h.store.Stop(id); err != nil {
 		errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
 		return
@@ -115,6 +282,7 @@ func (h *Handler) DeleteProcess(c *gin.Context) {
 		errResp(c, http.StatusInternalServerError, "Delete failed", err.Error())
 		return
 	}
+	h.audit(c, id, task.AuditDelete, t.Config, nil)
 
 	c.JSON(http.StatusOK, "OK")
 }
@@ -135,19 +303,43 @@ func (h *Handler) UpdateProcess(c *gin.Context) {
 	}
 
 	cfg := requestToConfig(&req)
+	cfg.SkipValidation = c.Query("skip_validation") == "true"
 	cfg.ID = id
 
-	t, err := h.store.Update(id, cfg)
+	// Update 替换 t.Config，不修改原配置
+	var before *task.Config
+	if t, err := h.store.Get(id); err == nil {
+		before = t.Config
+	}
+	t, changed, err := h.store.Update(id, cfg)
 	if err != nil {
 		if err == task.ErrNotFound {
 			errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
 			return
 		}
+		if errors.Is(err, task.ErrUnknownEncoder) {
+			errResp(c, http.StatusBadRequest, "Unknown encoder", err.Error()+" (use ?skip_validation=true to skip the check)")
+			return
+		}
+		if errors.Is(err, task.ErrUnsupportedProtocol) {
+			errResp(c, http.StatusBadRequest, "Unsupported protocol", err.Error()+" (use ?skip_validation=true to skip the check)")
+			return
+		}
+		if errors.Is(err, task.ErrIncompatibleOutput) {
+			errResp(c, http.StatusBadRequest, "Incompatible output", err.Error()+" (use ?skip_validation=true to skip the check)")
+			return
+		}
 		errResp(c, http.StatusBadRequest, "Invalid config", err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, taskToProcessConfig(t))
+	if changed {
+		h.audit(c, id, task.AuditUpdate, before, t.Config)
+	}
+	c.JSON(http.StatusOK, UpdateResponse{
+		ProcessConfig: redactProcessConfig(h.redactor(c), taskToProcessConfig(t)),
+		Unchanged:     !changed,
+	})
 }
 
 // GetConfig GET /api/v3/process/:id/config
@@ -160,7 +352,7 @@ func (h *Handler) GetConfig(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, taskToProcessConfig(t))
+	c.JSON(http.StatusOK, redactProcessConfig(h.redactor(c), taskToProcessConfig(t)))
 }
 
 // GetState GET /api/v3/process/:id/state
@@ -173,29 +365,8 @@ func (h *Handler) GetState(c *gin.Context) {
 		return
 	}
 
-	status := t.Status()
-
-	state := ProcessState{
-		Order:     status.Order,
-		State:     status.State,
-		Runtime:   int64(status.Duration.Seconds()),
-		Reconnect: -1,
-		Memory:    status.Memory.Current,
-		CPU:       status.CPU.Current,
-		Command:   t.Config.CreateCommand(),
-	}
-
-	prog := t.Progress()
-	state.Progress = &Progress{
-		Frame:     prog.Frame,
-		Size:      prog.Size,
-		Time:      prog.Time,
-		Speed:     prog.Speed,
-		Drop:      prog.Drop,
-		Dup:       prog.Dup,
-		Quantizer: prog.Quantizer,
-	}
-
+	state := taskToProcessState(t)
+	redactProcessState(h.redactor(c), &state)
 	c.JSON(http.StatusOK, state)
 }
 
@@ -209,20 +380,126 @@ func (h *Handler) GetReport(c *gin.Context) {
 		return
 	}
 
-	report := ProcessReport{Prelude: []string{}}
+	if c.Query("download") == "true" {
+		h.downloadLog(c, t)
+		return
+	}
 
-	lines := t.Log()
-	report.Log = make([][2]string, len(lines))
-	for i, line := range lines {
-		report.Log[i] = [2]string{
-			line.Timestamp.Format("2006-01-02 15:04:05.000"),
-			line.Data,
+	r := h.redactor(c)
+	report := taskToProcessReport(t)
+	filter, err := parseLogFilter(c)
+	if err != nil {
+		errResp(c, http.StatusBadRequest, "Invalid filter", err.Error())
+		return
+	}
+	if c.Query("after") != "" || c.Query("limit") != "" || filter != nil {
+		after, err := strconv.ParseUint(c.DefaultQuery("after", "0"), 10, 64)
+		if err != nil {
+			errResp(c, http.StatusBadRequest, "Invalid after", err.Error())
+			return
 		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+		if err != nil || limit < 0 {
+			errResp(c, http.StatusBadRequest, "Invalid limit", "limit must be a non-negative integer")
+			return
+		}
+		var lines []process.Line
+		var gap bool
+		if filter == nil {
+			lines, gap = t.LogAfter(after, limit)
+		} else {
+			lines, gap = t.LogAfter(after, 0)
+			lines = filter.apply(lines, r, limit)
+		}
+		report = linesToProcessReport(lines, after)
+		report.Gap = gap
+		report.Prelude = preludeToAPI(t)
 	}
-
+	redactProcessReport(r, &report)
 	c.JSON(http.StatusOK, report)
 }
 
+// logFilter selects log lines by ?grep=, ?since= and ?last=, all of which
+// must match
+type logFilter struct {
+	grep  *regexp.Regexp
+	since time.Time
+	last  int
+}
+
+// parseLogFilter returns the filter of the request, nil if there is none
+func parseLogFilter(c *gin.Context) (*logFilter, error) {
+	if c.Query("grep") == "" && c.Query("since") == "" && c.Query("last") == "" {
+		return nil, nil
+	}
+
+	f := &logFilter{}
+	if s := c.Query("grep"); s != "" {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("grep: %w", err)
+		}
+		f.grep = re
+	}
+	if s := c.Query("since"); s != "" {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			f.since = time.Unix(n, 0)
+		} else if t, err := time.Parse(time.RFC3339, s); err == nil {
+			f.since = t
+		} else {
+			return nil, errors.New("since must be RFC 3339 or Unix seconds")
+		}
+	}
+	if s := c.Query("last"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return nil, errors.New("last must be a positive integer")
+		}
+		f.last = n
+	}
+	return f, nil
+}
+
+// apply returns the matching lines, the first limit of them if limit > 0
+// and then the last f.last. Lines are matched redacted, so that grep can't
+// probe for secrets.
+func (f *logFilter) apply(lines []process.Line, r redact.Redactor, limit int) []process.Line {
+	var out []process.Line
+	for _, line := range lines {
+		if line.Timestamp.Before(f.since) {
+			continue
+		}
+		if f.grep != nil && !f.grep.MatchString(r.Redact(line.Data)) {
+			continue
+		}
+		out = append(out, line)
+		if limit > 0 && len(out) >= limit {
+			break
+		}
+	}
+	if f.last > 0 && len(out) > f.last {
+		out = out[len(out)-f.last:]
+	}
+	return out
+}
+
+// GetProgressHistory GET /api/v3/process/:id/progress/history returns the
+// progress samples of the current run, oldest first
+func (h *Handler) GetProgressHistory(c *gin.Context) {
+	t, err := h.store.Get(c.Param("id"))
+	if err != nil {
+		errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
+		return
+	}
+
+	samples := t.ProgressHistory()
+	out := make([]ProgressSample, len(samples))
+	for i, s := range samples {
+		out[i] = ProgressSample{Timestamp: s.Time.UnixMilli(), Progress: *progressToAPI(s.Progress)}
+	}
+	c.JSON(http.StatusOK, out)
+}
+
 // Command PUT /api/v3/process/:id/command
 func (h *Handler) Command(c *gin.Context) {
 	id := c.Param("id")
@@ -232,21 +509,24 @@ func (h *Handler) Command(c *gin.Context) {
 		errResp(c, http.StatusBadRequest, "Invalid JSON", err.Error())
 		return
 	}
-
-	var err error
-	switch req.Command {
-	case "start":
-		err = h.store.Start(id)
-	case "stop":
-		err = h.store.Stop(id)
-	case "restart":
-		err = h.store.Restart(id)
-	default:
-		errResp(c, http.StatusBadRequest, "Unknown command", "Known: start, stop, restart")
+	if req.Command == "test" {
+		h.testRun(c, id)
 		return
 	}
 
-	if err != nil {
+	if err := h.command(actor(c), id, req.Command, req.Mode); err != nil {
+		if err == errUnknownCommand {
+			errResp(c, http.StatusBadRequest, "Unknown command", "Known: "+knownCommands+", test")
+			return
+		}
+		if err == task.ErrInvalidStopMode {
+			errResp(c, http.StatusBadRequest, "Unknown mode", err.Error())
+			return
+		}
+		if errors.Is(err, task.ErrUncleanStop) {
+			errResp(c, http.StatusConflict, "Not restarted", err.Error())
+			return
+		}
 		errResp(c, http.StatusBadRequest, "Command failed", err.Error())
 		return
 	}
@@ -254,67 +534,269 @@ func (h *Handler) Command(c *gin.Context) {
 	c.JSON(http.StatusOK, "OK")
 }
 
-// Skills GET /api/v3/skills
+// batchWorkers bounds the number of commands a batch runs concurrently
+const batchWorkers = 8
+
+// BatchCommand PUT or POST /api/v3/process/command
+func (h *Handler) BatchCommand(c *gin.Context) {
+	var req BatchCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp(c, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+	h.batchCommand(c, &req)
+}
+
+// batchCommand applies the command of req to the tasks it selects
+func (h *Handler) batchCommand(c *gin.Context, req *BatchCommandRequest) {
+	// 未选择任务时不默认作用于全部任务
+	if len(req.IDs) == 0 && req.Reference == "" && !req.All {
+		errResp(c, http.StatusBadRequest, "No process selected", "set ids or reference, or all to true")
+		return
+	}
+	if req.Command == task.OperationRollingRestart {
+		h.rollingRestart(c, req)
+		return
+	}
+	if _, ok := commands[req.Command]; !ok {
+		errResp(c, http.StatusBadRequest, "Unknown command", "Known: "+knownCommands+", "+task.OperationRollingRestart)
+		return
+	}
+	if !task.ValidStopMode(req.Mode) {
+		errResp(c, http.StatusBadRequest, "Unknown mode", task.ErrInvalidStopMode.Error())
+		return
+	}
+
+	tasks, _ := h.store.List(task.ListFilter{IDs: req.IDs, Reference: req.Reference, Sort: "id"})
+
+	results := make([]CommandResult, 0, len(tasks)+len(req.IDs))
+	found := make(map[string]bool, len(tasks))
+	for _, t := range tasks {
+		found[t.ID] = true
+		results = append(results, CommandResult{ID: t.ID})
+	}
+	for _, id := range req.IDs {
+		if !found[id] {
+			found[id] = true
+			results = append(results, CommandResult{ID: id, Error: task.ErrNotFound.Error()})
+		}
+	}
+
+	who := actor(c)
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < batchWorkers && w < len(tasks); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := h.command(who, results[i].ID, req.Command, req.Mode); err != nil {
+					results[i].Error = err.Error()
+				}
+			}
+		}()
+	}
+	for i := range tasks {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	c.JSON(http.StatusOK, results)
+}
+
+var errUnknownCommand = errors.New("unknown command")
+
+const knownCommands = "start, stop, restart, pause, resume"
+
+var commands = map[string]func(s task.Store, id string) error{
+	"start":   task.Store.Start,
+	"stop":    task.Store.Stop,
+	"restart": task.Store.Restart,
+	"pause":   task.Store.Pause,
+	"resume":  task.Store.Resume,
+}
+
+// command applies a process command to a task and records it in the audit
+// history. mode is the stop mode, it is ignored by the other commands.
+func (h *Handler) command(who task.Actor, id, command, mode string) error {
+	fn, ok := commands[command]
+	if !ok {
+		return errUnknownCommand
+	}
+	var err error
+	switch {
+	case command == "stop":
+		err = h.store.StopMode(id, mode)
+	case command == "restart" && mode != "":
+		err = h.store.RestartMode(id, mode)
+	default:
+		err = fn(h.store, id)
+	}
+	if err == nil && h.config.Audit != nil {
+		h.config.Audit.Record(id, command, who, nil, nil)
+	}
+	return err
+}
+
+// Skills GET /api/v3/skills, ?binary= selects a named binary
 func (h *Handler) Skills(c *gin.Context) {
-	sk := h.ffmpeg.Skills()
-	c.JSON(http.StatusOK, skillsToAPI(sk))
+	h.skills(c)
 }
 
-// ReloadSkills POST /api/v3/skills/reload
+// ReloadSkills POST /api/v3/skills/reload probes all binaries again and
+// returns the skills of ?binary=
 func (h *Handler) ReloadSkills(c *gin.Context) {
-	if err := h.ffmpeg.ReloadSkills(); err != nil {
+	if err := h.ffmpeg.ReloadSkills(c.Request.Context()); err != nil {
+		errResp(c, http.StatusInternalServerError, "Reload failed", err.Error())
+		return
+	}
+	h.skills(c)
+}
+
+func (h *Handler) skills(c *gin.Context) {
+	name := c.Query("binary")
+	sk, err := h.ffmpeg.BinarySkills(name)
+	if err != nil {
+		errResp(c, http.StatusBadRequest, "Unknown binary", err.Error())
+		return
+	}
+	resp := skillsToAPI(sk)
+	resp.Binary = name
+	if resp.Binary == "" {
+		resp.Binary = ffmpeg.DefaultBinary
+	}
+	resp.Binaries = h.ffmpeg.Binaries()
+	c.JSON(http.StatusOK, resp)
+}
+
+// ReloadValidators POST /api/v3/validators/reload applies the address rules
+// of the config file to tasks added or updated afterwards
+func (h *Handler) ReloadValidators(c *gin.Context) {
+	if h.config.ReloadValidators == nil {
+		errResp(c, http.StatusNotFound, "Not supported", "")
+		return
+	}
+	if err := h.config.ReloadValidators(); err != nil {
 		errResp(c, http.StatusInternalServerError, "Reload failed", err.Error())
 		return
 	}
-	sk := h.ffmpeg.Skills()
-	c.JSON(http.StatusOK, skillsToAPI(sk))
+	c.JSON(http.StatusOK, "OK")
 }
 
 func requestToConfig(req *ProcessConfigRequest) *task.Config {
 	cfg := &task.Config{
-		ID:             req.ID,
-		Reference:      req.Reference,
-		Options:        req.Options,
-		Reconnect:      req.Reconnect,
-		ReconnectDelay: req.ReconnectDelay,
-		Autostart:      req.Autostart,
-		StaleTimeout:   req.StaleTimeout,
-		LimitCPU:       req.Limits.CPU,
-		LimitMemory:    req.Limits.Memory * 1024 * 1024,
-		LimitWaitFor:   req.Limits.WaitFor,
+		ID:                 req.ID,
+		Reference:          req.Reference,
+		Options:            req.Options,
+		Reconnect:          req.Reconnect,
+		ReconnectDelay:     req.ReconnectDelay,
+		ReconnectBackoff:   req.ReconnectBackoff,
+		ReconnectMaxDelay:  req.ReconnectMaxDelay,
+		MaxReconnects:      req.MaxReconnects,
+		Autostart:          req.Autostart,
+		Priority:           req.Priority,
+		StaleTimeout:       req.StaleTimeout,
+		StaleOn:            req.StaleOn,
+		RuntimeLimit:       req.RuntimeLimit,
+		StartAt:            req.StartAt,
+		StopAt:             req.StopAt,
+		Schedule:           req.Schedule,
+		StopSignal:         req.StopSignal,
+		Nice:               req.Nice,
+		IONiceClass:        req.IONiceClass,
+		Binary:             req.Binary,
+		MaxOutputBandwidth: req.MaxOutputBandwidth,
+		Scte35Monitor:      req.Scte35Monitor,
+		Scte35Timeout:      req.Scte35Timeout,
+		PreChecks:          req.PreChecks,
+		Env:                req.Env,
+		Timezone:           req.Timezone,
+		Variables:          req.Variables,
+		Labels:             req.Labels,
+		Hooks:              req.Hooks,
+		DebugReport:        req.DebugReport,
+		ProgressPipe:       req.ProgressPipe,
+		LogLines:           req.LogLines,
+		LimitCPU:           req.Limits.CPU,
+		LimitMemory:        req.Limits.Memory * 1024 * 1024,
+		LimitWaitFor:       req.Limits.WaitFor,
 	}
 
 	for _, io := range req.Input {
 		cfg.Input = append(cfg.Input, task.ConfigIO{ID: io.ID, Address: io.Address, Options: io.Options})
 	}
 	for _, io := range req.Output {
-		cfg.Output = append(cfg.Output, task.ConfigIO{ID: io.ID, Address: io.Address, Options: io.Options})
+		out := task.ConfigIO{ID: io.ID, Address: io.Address, Options: io.Options, Isolated: io.Isolated}
+		if io.SoftStop != nil {
+			out.SoftStop = &task.SoftStop{Timeout: io.SoftStop.Timeout, Endlist: io.SoftStop.Endlist}
+		}
+		cfg.Output = append(cfg.Output, out)
 	}
 
 	return cfg
 }
 
 func taskToProcessConfig(t *task.Task) *ProcessConfig {
+	cfg := configToProcessConfig(t.Config)
+	cfg.ID = t.ID
+	cfg.Reference = t.Reference
+	return cfg
+}
+
+// configToProcessConfig converts a config that may not belong to a task yet
+func configToProcessConfig(config *task.Config) *ProcessConfig {
 	cfg := &ProcessConfig{
-		ID:              t.ID,
-		Type:            "ffmpeg",
-		Reference:       t.Reference,
-		Options:         t.Config.Options,
-		Reconnect:       t.Config.Reconnect,
-		ReconnectDelay:  t.Config.ReconnectDelay,
-		Autostart:       t.Config.Autostart,
-		StaleTimeout:    t.Config.StaleTimeout,
+		ID:                 config.ID,
+		Hash:               config.Hash(),
+		Type:               "ffmpeg",
+		Reference:          config.Reference,
+		Options:            config.Options,
+		Reconnect:          config.Reconnect,
+		ReconnectDelay:     config.ReconnectDelay,
+		ReconnectBackoff:   config.ReconnectBackoff,
+		ReconnectMaxDelay:  config.ReconnectMaxDelay,
+		MaxReconnects:      config.MaxReconnects,
+		Autostart:          config.Autostart,
+		Priority:           config.Priority,
+		StaleTimeout:       config.StaleTimeout,
+		StaleOn:            config.StaleOn,
+		RuntimeLimit:       config.RuntimeLimit,
+		StartAt:            config.StartAt,
+		StopAt:             config.StopAt,
+		Schedule:           config.Schedule,
+		StopSignal:         config.StopSignal,
+		Nice:               config.Nice,
+		IONiceClass:        config.IONiceClass,
+		Binary:             config.Binary,
+		MaxOutputBandwidth: config.MaxOutputBandwidth,
+		Scte35Monitor:      config.Scte35Monitor,
+		Scte35Timeout:      config.Scte35Timeout,
+		PreChecks:          config.PreChecks,
+		Env:                config.Env,
+		Timezone:           config.Timezone,
+		EffectiveTimezone:  config.EffectiveTimezone(),
+		Variables:          maps.Clone(config.Variables),
+		Labels:             maps.Clone(config.Labels),
+		Hooks:              maps.Clone(config.Hooks),
+		DebugReport:        config.DebugReport,
+		ProgressPipe:       config.ProgressPipe,
+		LogLines:           config.LogLines,
 		Limits: ProcessConfigLimits{
-			CPU:     t.Config.LimitCPU,
-			Memory:  t.Config.LimitMemory / 1024 / 1024,
-			WaitFor: t.Config.LimitWaitFor,
+			CPU:     config.LimitCPU,
+			Memory:  config.LimitMemory / 1024 / 1024,
+			WaitFor: config.LimitWaitFor,
 		},
 	}
-	for _, io := range t.Config.Input {
+	for _, io := range config.Input {
 		cfg.Input = append(cfg.Input, ProcessConfigIO{ID: io.ID, Address: io.Address, Options: io.Options})
 	}
-	for _, io := range t.Config.Output {
-		cfg.Output = append(cfg.Output, ProcessConfigIO{ID: io.ID, Address: io.Address, Options: io.Options})
+	for _, io := range config.Output {
+		out := ProcessConfigIO{ID: io.ID, Address: io.Address, Options: io.Options, Isolated: io.Isolated}
+		if io.SoftStop != nil {
+			out.SoftStop = &ProcessSoftStop{Timeout: io.SoftStop.Timeout, Endlist: io.SoftStop.Endlist}
+		}
+		cfg.Output = append(cfg.Output, out)
 	}
 	return cfg
 }
@@ -338,26 +820,13 @@ func taskToProcess(t *task.Task, filter string) Process {
 	}
 
 	if includeState {
-		status := t.Status()
-		p.State = &ProcessState{
-			Order:     status.Order,
-			State:     status.State,
-			Runtime:   int64(status.Duration.Seconds()),
-			Reconnect: -1,
-			Memory:    status.Memory.Current,
-			CPU:       status.CPU.Current,
-			Command:   t.Config.CreateCommand(),
-		}
-		prog := t.Progress()
-		p.State.Progress = &Progress{
-			Frame: prog.Frame, Size: prog.Size, Time: prog.Time, Speed: prog.Speed,
-			Drop: prog.Drop, Dup: prog.Dup, Quantizer: prog.Quantizer,
-		}
+		state := taskToProcessState(t)
+		p.State = &state
 	}
 
 	if includeReport {
 		lines := t.Log()
-		report := ProcessReport{Prelude: []string{}}
+		report := ProcessReport{Prelude: preludeToAPI(t)}
 		report.Log = make([][2]string, len(lines))
 		for i, line := range lines {
 			report.Log[i] = [2]string{strconv.FormatInt(line.Timestamp.Unix(), 10), line.Data}
@@ -367,3 +836,189 @@ func taskToProcess(t *task.Task, filter string) Process {
 
 	return p
 }
+
+func taskToProcessState(t *task.Task) ProcessState {
+	status := t.Status()
+
+	state := ProcessState{
+		Order:             status.Order,
+		State:             status.State,
+		Runtime:           int64(status.Duration.Seconds()),
+		Uptime:            int64(status.Uptime.Seconds()),
+		Started:           int64(status.Started.Seconds()),
+		StartedUptime:     int64(status.StartedUptime.Seconds()),
+		Reconnects:        status.Reconnects,
+		ReconnectAttempts: status.ReconnectAttempts,
+		ReconnectsLeft:    status.ReconnectsLeft,
+		ExitCode:          status.ExitCode,
+		Reconnect:         -1,
+		StopReason:        status.StopReason,
+		StoppedBy:         status.StoppedBy,
+		Memory:            status.Memory.Current,
+		CPU:               status.CPU.Current,
+		Buffers:           t.Memory(),
+		Command:           t.Config.CreateCommand(),
+	}
+	if status.Reconnect >= 0 {
+		// 向上取整，倒计时未结束前不显示 0
+		state.Reconnect = int64((status.Reconnect + time.Second - 1) / time.Second)
+		state.ReconnectDelay = status.ReconnectDelay.Seconds()
+	}
+	if t.IsQueued() {
+		state.Order = "queued"
+		state.State = "queued"
+		state.QueuePosition = t.QueuePosition()
+	}
+
+	state.Progress = progressToAPI(t.Progress())
+	if outputs := t.Outputs(); len(outputs) != 0 {
+		state.Outputs = make(map[int]*Progress, len(outputs))
+		for i, prog := range outputs {
+			state.Outputs[i] = progressToAPI(prog)
+		}
+	}
+	state.FailedOutputs = t.FailedOutputs()
+	if prio := status.Priority; prio != nil {
+		state.OSPriority = &ProcessPriority{Nice: prio.Nice, IONiceClass: prio.IOClass, IONiceLevel: prio.IOLevel}
+	}
+	if scte35, ok := t.Scte35(); ok {
+		state.Scte35 = &Scte35State{
+			In:       scte35.In,
+			Out:      scte35.Out,
+			LastIn:   unixOrZero(scte35.LastIn),
+			LastOut:  unixOrZero(scte35.LastOut),
+			Stalled:  scte35.Stalled,
+			Diverged: scte35.Diverged,
+		}
+	}
+	state.LastLog = t.LastLog()
+	if e := t.LastError(); e != nil {
+		state.LastError = &ProcessError{
+			Category:  e.Category,
+			Message:   e.Message,
+			Timestamp: e.Time.Unix(),
+		}
+	}
+	for _, check := range t.Checks() {
+		state.Checks = append(state.Checks, ProcessCheck{
+			Name:      check.Name,
+			Target:    check.Target,
+			OK:        check.OK,
+			Message:   check.Message,
+			Duration:  check.Duration.Milliseconds(),
+			Timestamp: check.Time.Unix(),
+		})
+	}
+
+	return state
+}
+
+// unixOrZero returns the unix time of t, 0 for the zero time
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func progressToAPI(prog parse.Progress) *Progress {
+	return &Progress{
+		Frame:      prog.Frame,
+		FPS:        prog.FPS,
+		Size:       prog.Size,
+		Time:       prog.Time,
+		Bitrate:    prog.Bitrate,
+		Speed:      prog.Speed,
+		BitrateAvg: prog.BitrateAvg,
+		SpeedAvg:   prog.SpeedAvg,
+		Duration:   prog.Duration,
+		ETA:        prog.ETA(),
+		Drop:       prog.Drop,
+		Dup:        prog.Dup,
+		Quantizer:  prog.Quantizer,
+	}
+}
+
+func taskToProcessReport(t *task.Task) ProcessReport {
+	lines, gap := t.LogAfter(0, 0)
+	report := linesToProcessReport(lines, 0)
+	report.Gap = gap
+	report.Prelude = preludeToAPI(t)
+	return report
+}
+
+func preludeToAPI(t *task.Task) []string {
+	prelude := t.Prelude()
+	if prelude == nil {
+		prelude = []string{}
+	}
+	return prelude
+}
+
+// linesToProcessReport converts log lines, after is the cursor the lines
+// were requested with
+func linesToProcessReport(lines []process.Line, after uint64) ProcessReport {
+	report := ProcessReport{Prelude: []string{}, LastSeq: after}
+
+	report.Log = make([][2]string, len(lines))
+	for i, line := range lines {
+		report.Log[i] = [2]string{
+			line.Timestamp.Format("2006-01-02 15:04:05.000"),
+			line.Data,
+		}
+	}
+	if len(lines) != 0 {
+		report.FirstSeq = lines[0].Seq
+		report.LastSeq = lines[len(lines)-1].Seq
+	}
+
+	return report
+}
+
+func redactProcess(r redact.Redactor, p *Process) {
+	if p.Config != nil {
+		redactProcessConfig(r, p.Config)
+	}
+	if p.State != nil {
+		redactProcessState(r, p.State)
+	}
+	if p.Report != nil {
+		redactProcessReport(r, p.Report)
+	}
+}
+
+func redactProcessConfig(r redact.Redactor, cfg *ProcessConfig) *ProcessConfig {
+	cfg.Env = r.RedactAll(cfg.Env)
+	cfg.Options = r.RedactAll(cfg.Options)
+	for k, v := range cfg.Variables {
+		cfg.Variables[k] = r.Redact(v)
+	}
+	for i := range cfg.Input {
+		cfg.Input[i].Address = r.Redact(cfg.Input[i].Address)
+		cfg.Input[i].Options = r.RedactAll(cfg.Input[i].Options)
+	}
+	for i := range cfg.Output {
+		cfg.Output[i].Address = r.Redact(cfg.Output[i].Address)
+		cfg.Output[i].Options = r.RedactAll(cfg.Output[i].Options)
+	}
+	return cfg
+}
+
+func redactProcessState(r redact.Redactor, state *ProcessState) {
+	state.Command = r.RedactAll(state.Command)
+	state.LastLog = r.Redact(state.LastLog)
+	if state.LastError != nil {
+		state.LastError.Message = r.Redact(state.LastError.Message)
+	}
+	for i := range state.Checks {
+		state.Checks[i].Target = r.Redact(state.Checks[i].Target)
+		state.Checks[i].Message = r.Redact(state.Checks[i].Message)
+	}
+}
+
+func redactProcessReport(r redact.Redactor, report *ProcessReport) {
+	report.Prelude = r.RedactAll(report.Prelude)
+	for i := range report.Log {
+		report.Log[i][1] = r.Redact(report.Log[i][1])
+	}
+}
@@ -0,0 +1,477 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter serves the process routes of a handler whose store
+// simulates the processes
+func newTestRouter(t *testing.T, config Config) (*gin.Engine, task.Store) {
+	t.Helper()
+	ff, err := ffmpeg.New(ffmpeg.Config{Binary: "ffmpeg", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := task.NewStore(ff, logger.New("", logger.LevelError), task.StoreConfig{})
+	t.Cleanup(func() { store.Shutdown(context.Background()) })
+	presets, err := task.NewPresetStore(filepath.Join(t.TempDir(), "presets.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(store, presets, ff, config)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	v3 := r.Group("/api/v3")
+	v3.GET("/process", h.ListProcesses)
+	v3.POST("/process", h.AddProcess)
+	v3.GET("/process/summary", h.Summary)
+	v3.POST("/process/validate", h.ValidateProcess)
+	v3.PUT("/process/command", h.BatchCommand)
+	v3.POST("/process/command", h.BatchCommand)
+	v3.GET("/process/:id", h.GetProcess)
+	v3.PUT("/process/:id", h.UpdateProcess)
+	v3.GET("/process/:id/config", h.GetConfig)
+	v3.GET("/process/:id/state", h.GetState)
+	v3.GET("/process/:id/history", h.GetAudit)
+	v3.PUT("/process/:id/command", h.Command)
+	return r, store
+}
+
+// request sends a request with a JSON body to r
+func request(t *testing.T, r http.Handler, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRedactOptions(t *testing.T) {
+	r, _ := newTestRouter(t, Config{})
+	const secret = "s3cr3tkey"
+
+	req := map[string]any{
+		"id":      "redact",
+		"options": []string{"-headers", "Referer: rtmp://example.com/live/" + secret},
+		"input": []map[string]any{{
+			"id":      "in",
+			"address": "/tmp/in.mp4",
+			"options": []string{"-passphrase_url", "srt://example.com:9000?passphrase=" + secret},
+		}},
+		"output": []map[string]any{{
+			"id":      "out",
+			"address": "/tmp/out.mp4",
+			"options": []string{"-f", "tee", "[f=flv]rtmp://example.com/live/" + secret},
+		}},
+	}
+	for _, path := range []string{"/api/v3/process?dryrun=true", "/api/v3/process"} {
+		w := request(t, r, http.MethodPost, path, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("POST %s: %d %s", path, w.Code, w.Body)
+		}
+		if strings.Contains(w.Body.String(), secret) {
+			t.Errorf("POST %s reveals the secret: %s", path, w.Body)
+		}
+	}
+	for _, path := range []string{"/api/v3/process", "/api/v3/process/redact", "/api/v3/process/redact/config"} {
+		w := request(t, r, http.MethodGet, path, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", path, w.Code, w.Body)
+		}
+		if strings.Contains(w.Body.String(), secret) {
+			t.Errorf("GET %s reveals the secret: %s", path, w.Body)
+		}
+	}
+}
+
+func TestRedactEnv(t *testing.T) {
+	r, _ := newTestRouter(t, Config{})
+	const secret = "wJalrXUtnFEMI"
+
+	req := map[string]any{
+		"id":          "env",
+		"environment": []string{"AWS_SECRET_ACCESS_KEY=" + secret, "LD_LIBRARY_PATH=/opt/ffmpeg/lib"},
+		"input":       []map[string]any{{"id": "in", "address": "/tmp/in.mp4"}},
+		"output":      []map[string]any{{"id": "out", "address": "/tmp/out.mp4"}},
+	}
+	if w := request(t, r, http.MethodPost, "/api/v3/process", req); w.Code != http.StatusOK {
+		t.Fatalf("add: %d %s", w.Code, w.Body)
+	}
+	for _, path := range []string{"/api/v3/process", "/api/v3/process/env", "/api/v3/process/env/config", "/api/v3/process/env/state"} {
+		w := request(t, r, http.MethodGet, path, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", path, w.Code, w.Body)
+		}
+		if strings.Contains(w.Body.String(), secret) {
+			t.Errorf("GET %s reveals the secret: %s", path, w.Body)
+		}
+	}
+
+	w := request(t, r, http.MethodGet, "/api/v3/process/env/config", nil)
+	var config ProcessConfig
+	if err := json.Unmarshal(w.Body.Bytes(), &config); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"AWS_SECRET_ACCESS_KEY=***", "LD_LIBRARY_PATH=/opt/ffmpeg/lib"}
+	if len(config.Env) != len(want) || config.Env[0] != want[0] || config.Env[1] != want[1] {
+		t.Fatalf("environment %v, want %v", config.Env, want)
+	}
+}
+
+func TestBatchCommandSelector(t *testing.T) {
+	r, store := newTestRouter(t, Config{})
+	for _, id := range []string{"a", "b"} {
+		req := map[string]any{
+			"id":     id,
+			"input":  []map[string]any{{"id": "in", "address": "/tmp/" + id + ".mp4"}},
+			"output": []map[string]any{{"id": "out", "address": "/tmp/" + id + "-out.mp4"}},
+		}
+		if w := request(t, r, http.MethodPost, "/api/v3/process", req); w.Code != http.StatusOK {
+			t.Fatalf("add %s: %d %s", id, w.Code, w.Body)
+		}
+	}
+
+	// 没有选择任务时拒绝，而不是作用于全部任务
+	w := request(t, r, http.MethodPut, "/api/v3/process/command", map[string]any{"command": "start"})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("no selector: %d %s, want 400", w.Code, w.Body)
+	}
+	for _, id := range []string{"a", "b"} {
+		if tk, _ := store.Get(id); tk.State() != "finished" {
+			t.Fatalf("%s is %s after a rejected command", id, tk.State())
+		}
+	}
+
+	w = request(t, r, http.MethodPut, "/api/v3/process/command", map[string]any{"command": "start", "all": true})
+	if w.Code != http.StatusOK {
+		t.Fatalf("all: %d %s", w.Code, w.Body)
+	}
+	var results []CommandResult
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Error != "" || results[1].Error != "" {
+		t.Fatalf("results %+v, want a and b started", results)
+	}
+}
+
+func TestBatchCommandPartial(t *testing.T) {
+	r, store := newTestRouter(t, Config{})
+	req := map[string]any{
+		"id":     "a",
+		"input":  []map[string]any{{"id": "in", "address": "/tmp/a.mp4"}},
+		"output": []map[string]any{{"id": "out", "address": "/tmp/a-out.mp4"}},
+	}
+	if w := request(t, r, http.MethodPost, "/api/v3/process", req); w.Code != http.StatusOK {
+		t.Fatalf("add: %d %s", w.Code, w.Body)
+	}
+
+	for _, method := range []string{http.MethodPut, http.MethodPost} {
+		w := request(t, r, method, "/api/v3/process/command", map[string]any{"command": "restart", "ids": []string{"a", "unknown"}})
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: %d %s", method, w.Code, w.Body)
+		}
+		var results []CommandResult
+		if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+			t.Fatal(err)
+		}
+		want := []CommandResult{{ID: "a"}, {ID: "unknown", Error: task.ErrNotFound.Error()}}
+		if len(results) != len(want) || results[0] != want[0] || results[1] != want[1] {
+			t.Fatalf("%s: results %+v, want %+v", method, results, want)
+		}
+		if tk, _ := store.Get("a"); tk.State() != "running" {
+			t.Fatalf("%s: a is %s, want running", method, tk.State())
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	r, store := newTestRouter(t, Config{})
+	for _, id := range []string{"a", "b", "c"} {
+		config := &task.Config{
+			ID:        id,
+			Reference: "channel-" + id,
+			Input:     []task.ConfigIO{{ID: "in", Address: "/tmp/" + id + ".mp4"}},
+			Output:    []task.ConfigIO{{ID: "out", Address: "/tmp/" + id + "-out.mp4"}},
+		}
+		if id == "a" {
+			config.Reference = "channel-b"
+		}
+		if _, err := store.Add(config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := store.Start(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	summary := func(path string) ProcessSummary {
+		t.Helper()
+		w := request(t, r, http.MethodGet, path, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: %d %s", path, w.Code, w.Body)
+		}
+		var s ProcessSummary
+		if err := json.Unmarshal(w.Body.Bytes(), &s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	s := summary("/api/v3/process/summary")
+	if s.Tasks != 3 || s.States["running"] != 2 || s.States["finished"] != 1 || len(s.States) != 2 {
+		t.Fatalf("summary %+v, want 2 running and 1 finished of 3", s)
+	}
+	// 模拟进程没有资源占用，总和为 0 而不是漏算
+	if s.CPU != 0 || s.Memory != 0 {
+		t.Fatalf("cpu %f, memory %d of simulated processes", s.CPU, s.Memory)
+	}
+
+	if err := store.Stop("b"); err != nil {
+		t.Fatal(err)
+	}
+	s = summary("/api/v3/process/summary?reference=channel-b")
+	if s.Tasks != 2 || s.States["running"] != 1 || s.States["finished"] != 1 {
+		t.Fatalf("summary of channel-b %+v, want 1 running and 1 finished of 2", s)
+	}
+}
+
+func TestAuditUpdate(t *testing.T) {
+	audit, err := task.NewAuditLog(task.AuditConfig{}, logger.New("", logger.LevelError))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := newTestRouter(t, Config{Audit: audit})
+	req := map[string]any{
+		"id":        "a",
+		"reference": "channel-1",
+		"options":   []string{"-re"},
+		"input":     []map[string]any{{"id": "in", "address": "/tmp/a.mp4"}},
+		"output":    []map[string]any{{"id": "out", "address": "/tmp/a-out.mp4"}},
+	}
+	if w := request(t, r, http.MethodPost, "/api/v3/process", req); w.Code != http.StatusOK {
+		t.Fatalf("add: %d %s", w.Code, w.Body)
+	}
+	req["options"] = []string{"-re", "-loglevel", "error"}
+	if w := request(t, r, http.MethodPut, "/api/v3/process/a", req); w.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", w.Code, w.Body)
+	}
+
+	w := request(t, r, http.MethodGet, "/api/v3/process/a/history", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("history: %d %s", w.Code, w.Body)
+	}
+	var entries []ProcessAuditEntry
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Action != task.AuditAdd || entries[1].Action != task.AuditUpdate {
+		t.Fatalf("entries %+v, want add and update", entries)
+	}
+	if entries[1].Actor != "anonymous" || entries[1].Timestamp == 0 {
+		t.Fatalf("update by %q at %d", entries[1].Actor, entries[1].Timestamp)
+	}
+
+	// 只记录变化的字段
+	changes := entries[1].Changes
+	if len(changes) != 1 || changes[0].Field != "options" {
+		t.Fatalf("changes %+v, want options only", changes)
+	}
+	before, _ := json.Marshal(changes[0].Before)
+	after, _ := json.Marshal(changes[0].After)
+	if string(before) != `["-re"]` || string(after) != `["-re","-loglevel","error"]` {
+		t.Fatalf("options %s -> %s", before, after)
+	}
+}
+
+func TestValidateProcess(t *testing.T) {
+	r, store := newTestRouter(t, Config{})
+	validate := func(outputOptions []string) ValidateResponse {
+		t.Helper()
+		req := map[string]any{
+			"id":     "a",
+			"input":  []map[string]any{{"id": "in", "address": "/tmp/a.mp4"}},
+			"output": []map[string]any{{"id": "out", "address": "/tmp/a-out.mp4", "options": outputOptions}},
+		}
+		w := request(t, r, http.MethodPost, "/api/v3/process/validate", req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("validate: %d %s", w.Code, w.Body)
+		}
+		var resp ValidateResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := validate([]string{"-c:v", "libx264", "-f", "mp4"})
+	if want := []string{"-i", "/tmp/a.mp4", "-c:v", "libx264", "-f", "mp4", "/tmp/a-out.mp4"}; strings.Join(resp.Command, " ") != strings.Join(want, " ") {
+		t.Fatalf("command %q, want %q", resp.Command, want)
+	}
+	if len(resp.Warnings) != 0 {
+		t.Fatalf("warnings %q for a valid config", resp.Warnings)
+	}
+
+	resp = validate([]string{"-f", "nosuchmuxer"})
+	if len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], "nosuchmuxer") {
+		t.Fatalf("warnings %q, want one about the unknown muxer", resp.Warnings)
+	}
+
+	// 只校验，不创建任务
+	if tasks, _ := store.List(task.ListFilter{}); len(tasks) != 0 {
+		t.Fatalf("%d tasks after validating, want none", len(tasks))
+	}
+}
+
+func TestListProcessesPaging(t *testing.T) {
+	r, store := newTestRouter(t, Config{})
+	for _, id := range []string{"c", "e", "a", "d", "b"} {
+		if _, err := store.Add(&task.Config{
+			ID:        id,
+			Reference: "ref",
+			Input:     []task.ConfigIO{{ID: "in", Address: "/tmp/" + id + ".mp4"}},
+			Output:    []task.ConfigIO{{ID: "out", Address: "/tmp/" + id + "-out.mp4"}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"d", "e"} {
+		if err := store.Start(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list := func(query string) (string, string) {
+		t.Helper()
+		w := request(t, r, http.MethodGet, "/api/v3/process?"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: %d %s", query, w.Code, w.Body)
+		}
+		var procs []Process
+		if err := json.Unmarshal(w.Body.Bytes(), &procs); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, p := range procs {
+			ids = append(ids, p.ID)
+		}
+		return strings.Join(ids, ","), w.Header().Get("X-Total-Count")
+	}
+
+	tests := []struct {
+		query, ids, total string
+	}{
+		// 创建时间相同时按 ID 排序，顺序稳定
+		{"", "a,b,c,d,e", "5"},
+		{"sort=id", "a,b,c,d,e", "5"},
+		{"sort=id&order=desc", "e,d,c,b,a", "5"},
+		{"sort=id&limit=2", "a,b", "5"},
+		{"sort=id&limit=2&offset=2", "c,d", "5"},
+		{"sort=id&limit=2&offset=4", "e", "5"},
+		{"sort=id&offset=5", "", "5"},
+		{"sort=state", "a,b,c,d,e", "5"},
+		{"sort=state&order=desc", "e,d,c,b,a", "5"},
+		{"sort=id&id=e,a,c&limit=2", "a,c", "3"},
+		{"sort=id&reference=ref&offset=1&limit=1", "b", "5"},
+	}
+	for _, tt := range tests {
+		ids, total := list(tt.query)
+		if ids != tt.ids || total != tt.total {
+			t.Errorf("%q: ids %q, total %s, want %q, total %s", tt.query, ids, total, tt.ids, tt.total)
+		}
+	}
+
+	for _, query := range []string{"sort=name", "limit=-1", "offset=x", "order=up"} {
+		if w := request(t, r, http.MethodGet, "/api/v3/process?"+query, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%q: %d, want 400", query, w.Code)
+		}
+	}
+}
+
+func TestListProcessesState(t *testing.T) {
+	r, store := newTestRouter(t, Config{})
+	for _, id := range []string{"idle", "run1", "run2", "paused"} {
+		ref := "live"
+		if id == "run2" {
+			ref = "vod"
+		}
+		if _, err := store.Add(&task.Config{
+			ID:        id,
+			Reference: ref,
+			Input:     []task.ConfigIO{{ID: "in", Address: "/tmp/" + id + ".mp4"}},
+			Output:    []task.ConfigIO{{ID: "out", Address: "/tmp/" + id + "-out.mp4"}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"run1", "run2", "paused"} {
+		if err := store.Start(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Pause("paused"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query, ids string
+	}{
+		{"state=running", "run1,run2"},
+		{"state=running,paused", "paused,run1,run2"},
+		{"state=finished", "idle"},
+		{"state=failed", ""},
+		{"state=running,%20finished", "idle,run1,run2"},
+		// 与 reference、id 过滤组合
+		{"state=running&reference=live", "run1"},
+		{"state=running,finished&id=idle,run2", "idle,run2"},
+	}
+	for _, tt := range tests {
+		w := request(t, r, http.MethodGet, "/api/v3/process?sort=id&"+tt.query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: %d %s", tt.query, w.Code, w.Body)
+		}
+		var procs []Process
+		if err := json.Unmarshal(w.Body.Bytes(), &procs); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, p := range procs {
+			ids = append(ids, p.ID)
+		}
+		if got := strings.Join(ids, ","); got != tt.ids {
+			t.Errorf("%q: %q, want %q", tt.query, got, tt.ids)
+		}
+	}
+
+	if w := request(t, r, http.MethodGet, "/api/v3/process?state=sleeping", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown state: %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+// ProcessRun is a completed run of a task
+type ProcessRun struct {
+	Number     uint64        `json:"number"`
+	StartedAt  int64         `json:"started_at"`
+	EndedAt    int64         `json:"ended_at"`
+	State      string        `json:"exec"`
+	ExitCode   int           `json:"exit_code"`
+	StopReason string        `json:"stop_reason,omitempty"`
+	StoppedBy  string        `json:"stopped_by,omitempty"`
+	LastError  *ProcessError `json:"last_error,omitempty"`
+	Lines      int           `json:"lines"`
+}
+
+// ProcessRunReport is a completed run with the log it left
+type ProcessRunReport struct {
+	ProcessRun
+	Prelude []string    `json:"prelude"`
+	Log     [][2]string `json:"log"`
+}
+
+// ProcessTransition is a state change of a task
+type ProcessTransition struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Timestamp int64  `json:"timestamp"`
+	Reason    string `json:"reason,omitempty"` // stop reason, stopped_by or last error category of an ended run
+}
+
+// ListTransitions GET /api/v3/process/:id/state/history lists the last
+// state changes, oldest first
+func (h *Handler) ListTransitions(c *gin.Context) {
+	t, err := h.store.Get(c.Param("id"))
+	if err != nil {
+		errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
+		return
+	}
+
+	resp := []ProcessTransition{}
+	for _, tr := range t.Transitions() {
+		resp = append(resp, ProcessTransition{From: tr.From, To: tr.To, Timestamp: tr.Time.Unix(), Reason: tr.Reason})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ClearTransitions DELETE /api/v3/process/:id/state/history
+func (h *Handler) ClearTransitions(c *gin.Context) {
+	t, err := h.store.Get(c.Param("id"))
+	if err != nil {
+		errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
+		return
+	}
+	t.ClearTransitions()
+	c.JSON(http.StatusOK, "OK")
+}
+
+// ListHistory GET /api/v3/process/:id/report/history lists the completed
+// runs, newest first
+func (h *Handler) ListHistory(c *gin.Context) {
+	t, err := h.store.Get(c.Param("id"))
+	if err != nil {
+		errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
+		return
+	}
+
+	r := h.redactor(c)
+	resp := []ProcessRun{}
+	for _, run := range t.History() {
+		pr := runToAPI(run)
+		if pr.LastError != nil {
+			pr.LastError.Message = r.Redact(pr.LastError.Message)
+		}
+		resp = append(resp, pr)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetHistory GET /api/v3/process/:id/report/history/:n returns the log of
+// the run with number n
+func (h *Handler) GetHistory(c *gin.Context) {
+	t, err := h.store.Get(c.Param("id"))
+	if err != nil {
+		errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
+		return
+	}
+	n, err := strconv.ParseUint(c.Param("n"), 10, 64)
+	if err != nil {
+		errResp(c, http.StatusBadRequest, "Invalid run number", err.Error())
+		return
+	}
+
+	for _, run := range t.History() {
+		if run.Number != n {
+			continue
+		}
+		resp := ProcessRunReport{ProcessRun: runToAPI(run), Prelude: []string{}}
+		report := linesToProcessReport(run.Log, 0)
+		resp.Log = report.Log
+		if run.Prelude != nil {
+			resp.Prelude = run.Prelude
+		}
+
+		r := h.redactor(c)
+		if resp.LastError != nil {
+			resp.LastError.Message = r.Redact(resp.LastError.Message)
+		}
+		resp.Prelude = r.RedactAll(resp.Prelude)
+		for i := range resp.Log {
+			resp.Log[i][1] = r.Redact(resp.Log[i][1])
+		}
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+	errResp(c, http.StatusNotFound, "Unknown run", "run "+c.Param("n")+" isn't in the history")
+}
+
+func runToAPI(run task.Run) ProcessRun {
+	pr := ProcessRun{
+		Number:     run.Number,
+		StartedAt:  unixOrZero(run.Start),
+		EndedAt:    unixOrZero(run.End),
+		State:      run.State,
+		ExitCode:   run.ExitCode,
+		StopReason: run.StopReason,
+		StoppedBy:  run.StoppedBy,
+		Lines:      len(run.Log),
+	}
+	if run.Error != nil {
+		pr.LastError = &ProcessError{
+			Category:  run.Error.Category,
+			Message:   run.Error.Message,
+			Timestamp: run.Error.Time.Unix(),
+		}
+	}
+	return pr
+}
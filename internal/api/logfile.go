@@ -0,0 +1,108 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+// maxLogLine bounds a line read from a log file
+const maxLogLine = 1024 * 1024
+
+// GetLogFile GET /api/v3/process/:id/report/file streams the task log
+// files, like /report?download=true
+func (h *Handler) GetLogFile(c *gin.Context) {
+	t, err := h.store.Get(c.Param("id"))
+	if err != nil {
+		errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
+		return
+	}
+	h.downloadLog(c, t)
+}
+
+// DownloadReport GET /api/v3/process/:id/report/download sends the in-memory
+// log as text, one "timestamp message" per line. The prelude comes first if
+// its lines were already pushed out of the log.
+func (h *Handler) DownloadReport(c *gin.Context) {
+	t, err := h.store.Get(c.Param("id"))
+	if err != nil {
+		errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
+		return
+	}
+
+	// 先取快照，写响应时不持有解析器的锁
+	prelude := t.Prelude()
+	lines := t.Log()
+
+	r := h.redactor(c)
+	name := fmt.Sprintf("%s_%s.log", url.PathEscape(t.ID), time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	c.Status(http.StatusOK)
+
+	w := bufio.NewWriter(c.Writer)
+	if len(prelude) != 0 && (len(lines) == 0 || lines[0].Data != prelude[0]) {
+		for _, line := range prelude {
+			w.WriteString(r.Redact(line))
+			w.WriteByte('\n')
+		}
+		w.WriteString("...\n")
+	}
+	for _, line := range lines {
+		w.WriteString(line.Timestamp.Format("2006-01-02 15:04:05.000"))
+		w.WriteByte(' ')
+		w.WriteString(r.Redact(line.Data))
+		w.WriteByte('\n')
+	}
+	w.Flush()
+}
+
+// downloadLog streams the task's log files, oldest first, redacted line by line
+func (h *Handler) downloadLog(c *gin.Context, t *task.Task) {
+	// 先打开全部文件，传输过程中发生轮转也不影响已打开的文件
+	var files []*os.File
+	for _, path := range t.LogFiles() {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		errResp(c, http.StatusNotFound, "No log file", "task log files are disabled or the task hasn't logged yet")
+		return
+	}
+
+	h.streamRedacted(c, files, url.PathEscape(t.ID)+".log")
+}
+
+// streamRedacted sends files as one attachment, redacted line by line
+func (h *Handler) streamRedacted(c *gin.Context, files []*os.File, name string) {
+	r := h.redactor(c)
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	c.Status(http.StatusOK)
+
+	w := bufio.NewWriter(c.Writer)
+	for _, f := range files {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), maxLogLine)
+		for scanner.Scan() {
+			w.WriteString(r.Redact(scanner.Text()))
+			w.WriteByte('\n')
+		}
+	}
+	w.Flush()
+}
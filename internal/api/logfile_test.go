@@ -0,0 +1,115 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+func TestLogFileRotation(t *testing.T) {
+	tmp := t.TempDir()
+	logDir := filepath.Join(tmp, "logs")
+	binary := filepath.Join(tmp, "ffmpeg")
+	script := `#!/bin/sh
+case " $* " in *" -i "*)
+	i=0
+	while [ $i -lt 500 ]; do printf 'line %03d of the ffmpeg output\n' $i >&2; i=$((i+1)); done
+	exit 0;;
+esac
+echo "ffmpeg version 6.0"
+`
+	if err := os.WriteFile(binary, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ff, err := ffmpeg.New(ffmpeg.Config{Binary: binary, LogDir: logDir, LogFileSize: 8 * 1024, LogFileKeep: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := task.NewStore(ff, logger.New("", logger.LevelError), task.StoreConfig{})
+	t.Cleanup(func() { store.Shutdown(context.Background()) })
+	presets, err := task.NewPresetStore(filepath.Join(tmp, "presets.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(store, presets, ff, Config{})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/v3/process/:id/report", h.GetReport)
+	r.GET("/api/v3/process/:id/report/file", h.GetLogFile)
+
+	tk, err := store.Add(&task.Config{
+		ID:     "rotate",
+		Input:  []task.ConfigIO{{ID: "in", Address: "/tmp/in.mp4"}},
+		Output: []task.ConfigIO{{ID: "out", Address: "/tmp/out.mp4"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Start("rotate"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 日志由单独的协程写入，等到最后一行落盘
+	var body string
+	for deadline := time.Now().Add(5 * time.Second); !strings.Contains(body, "line 499"); time.Sleep(20 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the last line, state %s", tk.Status().State)
+		}
+		w := request(t, r, http.MethodGet, "/api/v3/process/rotate/report/file", nil)
+		body = w.Body.String()
+	}
+
+	files := tk.LogFiles()
+	if len(files) < 3 {
+		t.Fatalf("log files %q, want the 500 lines rotated into several", files)
+	}
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > 8*1024 {
+			t.Fatalf("%s has %d bytes, want at most 8 KiB", path, info.Size())
+		}
+	}
+
+	// 两种下载方式都返回按顺序拼接的全部行
+	for _, path := range []string{"/api/v3/process/rotate/report/file", "/api/v3/process/rotate/report?download=true"} {
+		w := request(t, r, http.MethodGet, path, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: %d %s", path, w.Code, w.Body)
+		}
+		var got []string
+		for _, line := range strings.Split(strings.TrimSpace(w.Body.String()), "\n") {
+			if _, msg, ok := strings.Cut(line, " "); ok && strings.HasPrefix(msg, "line ") {
+				got = append(got, msg)
+			}
+		}
+		if len(got) != 500 {
+			t.Fatalf("%s: %d lines, want 500", path, len(got))
+		}
+		for i, line := range got {
+			if want := fmt.Sprintf("line %03d of the ffmpeg output", i); line != want {
+				t.Fatalf("%s: line %d is %q, want %q", path, i, line, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+// GetMetadata GET /api/v3/process/:id/metadata/:key
+func (h *Handler) GetMetadata(c *gin.Context) {
+	t, err := h.store.Get(c.Param("id"))
+	if err != nil {
+		errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
+		return
+	}
+	value := t.Metadata(c.Param("key"))
+	if value == nil {
+		errResp(c, http.StatusNotFound, "Unknown metadata key", "no metadata is stored under "+c.Param("key"))
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", value)
+}
+
+// SetMetadata PUT /api/v3/process/:id/metadata/:key stores any JSON value,
+// null removes the key
+func (h *Handler) SetMetadata(c *gin.Context) {
+	t, err := h.store.Get(c.Param("id"))
+	if err != nil {
+		errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
+		return
+	}
+	value, err := io.ReadAll(io.LimitReader(c.Request.Body, task.MaxMetadataSize+1))
+	if err != nil {
+		errResp(c, http.StatusBadRequest, "Invalid metadata", err.Error())
+		return
+	}
+	if err := t.SetMetadata(c.Param("key"), value); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, task.ErrMetadataTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		errResp(c, status, "Invalid metadata", err.Error())
+		return
+	}
+	if value := t.Metadata(c.Param("key")); value != nil {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", value)
+		return
+	}
+	c.JSON(http.StatusOK, nil)
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+// Operation is a long-running batch command, e.g. a rolling restart
+type Operation struct {
+	ID         string            `json:"id"`
+	Kind       string            `json:"kind"`
+	State      string            `json:"state"` // running, done or aborted
+	Total      int               `json:"total"`
+	Done       []string          `json:"done"`
+	Failed     map[string]string `json:"failed"`
+	Pending    []string          `json:"pending"`
+	CreatedAt  int64             `json:"created_at"`
+	FinishedAt int64             `json:"finished_at,omitempty"`
+}
+
+func operationToAPI(op task.Operation) Operation {
+	out := Operation{
+		ID:        op.ID,
+		Kind:      op.Kind,
+		State:     op.State,
+		Total:     len(op.Tasks),
+		Done:      op.Done,
+		Failed:    op.Failed,
+		Pending:   op.Pending(),
+		CreatedAt: op.CreatedAt.Unix(),
+	}
+	if !op.FinishedAt.IsZero() {
+		out.FinishedAt = op.FinishedAt.Unix()
+	}
+	if out.Done == nil {
+		out.Done = []string{}
+	}
+	if out.Pending == nil {
+		out.Pending = []string{}
+	}
+	return out
+}
+
+// rollingRestart starts a rolling restart of the selected tasks and
+// responds with the operation
+func (h *Handler) rollingRestart(c *gin.Context, req *BatchCommandRequest) {
+	tasks, _ := h.store.List(task.ListFilter{IDs: req.IDs, Reference: req.Reference, Sort: "id"})
+
+	found := make(map[string]bool, len(tasks))
+	ids := make([]string, 0, len(tasks))
+	for _, t := range tasks {
+		found[t.ID] = true
+		ids = append(ids, t.ID)
+	}
+	var missing []string
+	for _, id := range req.IDs {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) != 0 {
+		errResp(c, http.StatusNotFound, "Unknown process ID", strings.Join(missing, ", "))
+		return
+	}
+
+	op := h.config.Operations.RollingRestart(ids, task.RolloutConfig{
+		Parallel:    req.Parallel,
+		Timeout:     time.Duration(req.Timeout) * time.Second,
+		MaxFailures: req.MaxFailures,
+	})
+	for _, id := range ids {
+		h.audit(c, id, task.OperationRollingRestart, nil, nil)
+	}
+	c.JSON(http.StatusAccepted, operationToAPI(op))
+}
+
+// ListOperations GET /api/v3/operations
+func (h *Handler) ListOperations(c *gin.Context) {
+	list := h.config.Operations.List()
+	resp := make([]Operation, len(list))
+	for i, op := range list {
+		resp[i] = operationToAPI(op)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetOperation GET /api/v3/operations/:id
+func (h *Handler) GetOperation(c *gin.Context) {
+	op, err := h.config.Operations.Get(c.Param("id"))
+	if err != nil {
+		errResp(c, http.StatusNotFound, "Unknown operation ID", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, operationToAPI(op))
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+// Preset is a named partial process config
+type Preset struct {
+	Name              string              `json:"name"`
+	Options           []string            `json:"options"`
+	InputOptions      []string            `json:"input_options"`
+	OutputOptions     []string            `json:"output_options"`
+	Reconnect         bool                `json:"reconnect"`
+	ReconnectDelay    uint64              `json:"reconnect_delay_seconds"`
+	ReconnectBackoff  float64             `json:"reconnect_backoff"`
+	ReconnectMaxDelay uint64              `json:"reconnect_max_delay_seconds"`
+	MaxReconnects     int                 `json:"max_reconnects"`
+	StaleTimeout      uint64              `json:"stale_timeout_seconds"`
+	StaleOn           string              `json:"stale_on"`
+	Limits            ProcessConfigLimits `json:"limits"`
+	CreatedAt         int64               `json:"created_at"`
+	UpdatedAt         int64               `json:"updated_at"`
+}
+
+// ListPresets GET /api/v3/presets
+func (h *Handler) ListPresets(c *gin.Context) {
+	presets := h.presets.List()
+	out := make([]Preset, 0, len(presets))
+	for _, p := range presets {
+		out = append(out, presetToAPI(p))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// AddPreset POST /api/v3/presets
+func (h *Handler) AddPreset(c *gin.Context) {
+	var req Preset
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp(c, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+
+	p, err := h.presets.Add(apiToPreset(&req))
+	if err != nil {
+		if err == task.ErrPresetExists || err == task.ErrInvalidPreset {
+			errResp(c, http.StatusBadRequest, "Invalid preset", err.Error())
+			return
+		}
+		errResp(c, http.StatusInternalServerError, "Save failed", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, presetToAPI(p))
+}
+
+// GetPreset GET /api/v3/presets/:name
+func (h *Handler) GetPreset(c *gin.Context) {
+	p, err := h.presets.Get(c.Param("name"))
+	if err != nil {
+		errResp(c, http.StatusNotFound, "Unknown preset", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, presetToAPI(p))
+}
+
+// UpdatePreset PUT /api/v3/presets/:name
+func (h *Handler) UpdatePreset(c *gin.Context) {
+	var req Preset
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp(c, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+
+	p, err := h.presets.Update(c.Param("name"), apiToPreset(&req))
+	if err != nil {
+		if err == task.ErrPresetNotFound {
+			errResp(c, http.StatusNotFound, "Unknown preset", err.Error())
+			return
+		}
+		errResp(c, http.StatusInternalServerError, "Save failed", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, presetToAPI(p))
+}
+
+// DeletePreset DELETE /api/v3/presets/:name
+func (h *Handler) DeletePreset(c *gin.Context) {
+	if err := h.presets.Delete(c.Param("name")); err != nil {
+		if err == task.ErrPresetNotFound {
+			errResp(c, http.StatusNotFound, "Unknown preset", err.Error())
+			return
+		}
+		errResp(c, http.StatusInternalServerError, "Save failed", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, "OK")
+}
+
+func apiToPreset(p *Preset) *task.Preset {
+	return &task.Preset{
+		Name:              p.Name,
+		Options:           p.Options,
+		InputOptions:      p.InputOptions,
+		OutputOptions:     p.OutputOptions,
+		Reconnect:         p.Reconnect,
+		ReconnectDelay:    p.ReconnectDelay,
+		ReconnectBackoff:  p.ReconnectBackoff,
+		ReconnectMaxDelay: p.ReconnectMaxDelay,
+		MaxReconnects:     p.MaxReconnects,
+		StaleTimeout:      p.StaleTimeout,
+		StaleOn:           p.StaleOn,
+		LimitCPU:          p.Limits.CPU,
+		LimitMemory:       p.Limits.Memory * 1024 * 1024,
+		LimitWaitFor:      p.Limits.WaitFor,
+	}
+}
+
+func presetToAPI(p *task.Preset) Preset {
+	return Preset{
+		Name:              p.Name,
+		Options:           p.Options,
+		InputOptions:      p.InputOptions,
+		OutputOptions:     p.OutputOptions,
+		Reconnect:         p.Reconnect,
+		ReconnectDelay:    p.ReconnectDelay,
+		ReconnectBackoff:  p.ReconnectBackoff,
+		ReconnectMaxDelay: p.ReconnectMaxDelay,
+		MaxReconnects:     p.MaxReconnects,
+		StaleTimeout:      p.StaleTimeout,
+		StaleOn:           p.StaleOn,
+		Limits: ProcessConfigLimits{
+			CPU:     p.LimitCPU,
+			Memory:  p.LimitMemory / 1024 / 1024,
+			WaitFor: p.LimitWaitFor,
+		},
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+	}
+}
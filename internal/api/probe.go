@@ -0,0 +1,146 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg/probe"
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+// ProbeStream is a stream of the probed input
+type ProbeStream struct {
+	Index      int     `json:"index"`
+	Type       string  `json:"type"`
+	Codec      string  `json:"codec"`
+	Profile    string  `json:"profile,omitempty"`
+	Width      int     `json:"width,omitempty"`
+	Height     int     `json:"height,omitempty"`
+	PixFmt     string  `json:"pix_fmt,omitempty"`
+	FPS        float64 `json:"fps,omitempty"`
+	SampleRate int     `json:"sample_rate,omitempty"`
+	Channels   int     `json:"channels,omitempty"`
+	Layout     string  `json:"channel_layout,omitempty"`
+	Bitrate    uint64  `json:"bitrate_bit,omitempty"`
+	Duration   float64 `json:"duration_seconds,omitempty"`
+}
+
+// ProbeFormat is the container of the probed input
+type ProbeFormat struct {
+	Name     string  `json:"name"`
+	LongName string  `json:"long_name"`
+	Bitrate  uint64  `json:"bitrate_bit,omitempty"`
+	Duration float64 `json:"duration_seconds,omitempty"`
+	Size     uint64  `json:"size_bytes,omitempty"`
+}
+
+// ProbeInput is the media metadata of one input, or why it couldn't be
+// probed
+type ProbeInput struct {
+	ID       string        `json:"id"`
+	Address  string        `json:"address"`
+	Error    string        `json:"error,omitempty"`
+	Format   *ProbeFormat  `json:"format,omitempty"`
+	Streams  []ProbeStream `json:"streams,omitempty"`
+	ProbedAt int64         `json:"probed_at,omitempty"`
+}
+
+// ProbeResponse is the media metadata of a task's inputs. Format and
+// Streams are those of the first input that could be probed.
+type ProbeResponse struct {
+	Format   ProbeFormat   `json:"format"`
+	Streams  []ProbeStream `json:"streams"`
+	ProbedAt int64         `json:"probed_at"`
+	Inputs   []ProbeInput  `json:"inputs"`
+}
+
+// Probe GET /api/v3/process/:id/probe
+func (h *Handler) Probe(c *gin.Context) {
+	id := c.Param("id")
+	refresh := c.Query("refresh") == "true"
+
+	results, err := h.store.Probe(c.Request.Context(), id, refresh)
+	if err != nil {
+		if err == task.ErrNotFound {
+			errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
+			return
+		}
+		if errors.Is(err, ffmpeg.ErrNoFFprobe) {
+			errResp(c, http.StatusServiceUnavailable, "Probe unavailable", err.Error())
+			return
+		}
+		errResp(c, http.StatusInternalServerError, "Probe failed", err.Error())
+		return
+	}
+
+	redactor := h.redactor(c)
+	resp := ProbeResponse{Streams: []ProbeStream{}, Inputs: make([]ProbeInput, len(results))}
+	var failed []string
+	found := false
+	for i, r := range results {
+		in := ProbeInput{ID: r.ID, Address: redactor.Redact(r.Address)}
+		if r.Err != nil {
+			// ffprobe 的错误信息可能包含输入地址
+			in.Error = redactor.Redact(r.Err.Error())
+			failed = append(failed, r.ID+": "+in.Error)
+		} else {
+			format := probeFormatToAPI(r.Result)
+			in.Format = &format
+			in.Streams = probeStreamsToAPI(r.Result)
+			in.ProbedAt = r.Result.Time
+			if !found {
+				resp.Format, resp.Streams, resp.ProbedAt = format, in.Streams, in.ProbedAt
+				found = true
+			}
+		}
+		resp.Inputs[i] = in
+	}
+
+	// 只有全部输入都失败时才报错，部分失败在 inputs 中体现
+	if !found && len(failed) != 0 {
+		errResp(c, http.StatusBadGateway, "Probe failed", strings.Join(failed, "; "))
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func probeFormatToAPI(r probe.Result) ProbeFormat {
+	return ProbeFormat{
+		Name:     r.Format.Name,
+		LongName: r.Format.LongName,
+		Bitrate:  r.Format.Bitrate,
+		Duration: r.Format.Duration,
+		Size:     r.Format.Size,
+	}
+}
+
+func probeStreamsToAPI(r probe.Result) []ProbeStream {
+	streams := make([]ProbeStream, len(r.Streams))
+	for i, s := range r.Streams {
+		streams[i] = ProbeStream{
+			Index:      s.Index,
+			Type:       s.Type,
+			Codec:      s.Codec,
+			Profile:    s.Profile,
+			Width:      s.Width,
+			Height:     s.Height,
+			PixFmt:     s.PixFmt,
+			FPS:        s.FrameRate,
+			SampleRate: s.SampleRate,
+			Channels:   s.Channels,
+			Layout:     s.Layout,
+			Bitrate:    s.Bitrate,
+			Duration:   s.Duration,
+		}
+	}
+	return streams
+}
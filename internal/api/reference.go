@@ -0,0 +1,65 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+// Reference is the combined view of the tasks sharing a reference, e.g. the
+// tasks of a channel
+type Reference struct {
+	Reference string         `json:"reference"`
+	IDs       []string       `json:"ids"`
+	States    map[string]int `json:"states"` // 各状态（含 queued）的任务数
+	CPU       float64        `json:"cpu_usage"`
+	Memory    uint64         `json:"memory_bytes"`
+	Buffers   uint64         `json:"buffer_bytes"`
+}
+
+// GetReference GET /api/v3/reference/:ref
+func (h *Handler) GetReference(c *gin.Context) {
+	ref := c.Param("ref")
+	tasks, _ := h.store.List(task.ListFilter{Reference: ref, Sort: "id"})
+	if len(tasks) == 0 {
+		errResp(c, http.StatusNotFound, "Unknown reference", "no process has the reference "+ref)
+		return
+	}
+
+	out := Reference{Reference: ref, IDs: make([]string, 0, len(tasks)), States: map[string]int{}}
+	for _, t := range tasks {
+		status := t.Status()
+		out.IDs = append(out.IDs, t.ID)
+		out.States[t.State()]++
+		out.CPU += status.CPU.Current
+		out.Memory += status.Memory.Current
+		out.Buffers += t.Memory()
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// ReferenceCommand PUT /api/v3/reference/:ref/command applies a command to
+// every task with the reference, like /process/command with reference set.
+// Failures are reported per task, they don't stop the others.
+func (h *Handler) ReferenceCommand(c *gin.Context) {
+	var req BatchCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp(c, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+	req.Reference = c.Param("ref")
+	req.IDs = nil
+
+	tasks, _ := h.store.List(task.ListFilter{Reference: req.Reference})
+	if len(tasks) == 0 {
+		errResp(c, http.StatusNotFound, "Unknown reference", "no process has the reference "+req.Reference)
+		return
+	}
+	h.batchCommand(c, &req)
+}
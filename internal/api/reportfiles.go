@@ -0,0 +1,61 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package api
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportFile is an FFmpeg report of a run, see debug_report
+type ReportFile struct {
+	Name     string `json:"name"`
+	Size     int64  `json:"size_bytes"`
+	Modified int64  `json:"modified_at"`
+}
+
+// ListReportFiles GET /api/v3/process/:id/report/files
+func (h *Handler) ListReportFiles(c *gin.Context) {
+	files, err := h.store.ReportFiles(c.Param("id"))
+	if err != nil {
+		errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
+		return
+	}
+
+	resp := []ReportFile{}
+	for _, f := range files {
+		resp = append(resp, ReportFile{Name: f.Name, Size: f.Size, Modified: f.ModTime.Unix()})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DownloadReportFile GET /api/v3/process/:id/report/files/:name
+func (h *Handler) DownloadReportFile(c *gin.Context) {
+	files, err := h.store.ReportFiles(c.Param("id"))
+	if err != nil {
+		errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
+		return
+	}
+
+	// 只接受列表中的文件名，不拼接用户给出的路径
+	name := c.Param("name")
+	for _, f := range files {
+		if f.Name != name {
+			continue
+		}
+		file, err := os.Open(f.Path)
+		if err != nil {
+			errResp(c, http.StatusNotFound, "Unknown report file", err.Error())
+			return
+		}
+		defer file.Close()
+		h.streamRedacted(c, []*os.File{file}, f.Name)
+		return
+	}
+	errResp(c, http.StatusNotFound, "Unknown report file", name)
+}
@@ -11,6 +11,9 @@ import (
 
 // SkillsResponse for API
 type SkillsResponse struct {
+	Binary   string   `json:"binary"`   // name of the binary
+	Binaries []string `json:"binaries"` // all configured binaries
+
 	FFmpeg struct {
 		Version       string `json:"version"`
 		Compiler      string `json:"compiler"`
@@ -22,8 +25,17 @@ type SkillsResponse struct {
 		} `json:"libraries"`
 	} `json:"ffmpeg"`
 
-	Filters  []struct{ ID string `json:"id"`; Name string `json:"name"` } `json:"filter"`
-	HWAccels []struct{ ID string `json:"id"`; Name string `json:"name"` } `json:"hwaccels"`
+	Filters []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"filter"`
+	HWAccels []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"hwaccels"`
+	// HWEncoders are the hardware encoders of the build, working if one
+	// test frame could be encoded on this host
+	HWEncoders []SkillsHWEncoder `json:"hwencoders"`
 
 	Codecs struct {
 		Audio    []SkillsCodec `json:"audio"`
@@ -32,14 +44,34 @@ type SkillsResponse struct {
 	} `json:"codecs"`
 
 	Formats struct {
-		Demuxers []struct{ ID string `json:"id"`; Name string `json:"name"` } `json:"demuxers"`
-		Muxers   []struct{ ID string `json:"id"`; Name string `json:"name"` } `json:"muxers"`
+		Demuxers []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"demuxers"`
+		Muxers []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"muxers"`
 	} `json:"formats"`
 
 	Protocols struct {
-		Input  []struct{ ID string `json:"id"`; Name string `json:"name"` } `json:"input"`
-		Output []struct{ ID string `json:"id"`; Name string `json:"name"` } `json:"output"`
+		Input []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"input"`
+		Output []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"output"`
 	} `json:"protocols"`
+
+	Warnings []string `json:"warnings"`
+}
+
+type SkillsHWEncoder struct {
+	ID      string `json:"id"`
+	Working bool   `json:"working"`
+	Error   string `json:"error,omitempty"`
 }
 
 type SkillsCodec struct {
@@ -68,14 +100,31 @@ func skillsToAPI(s skills.Skills) SkillsResponse {
 		}{lib.Name, lib.Compiled, lib.Linked}
 	}
 
-	resp.Filters = make([]struct{ ID string `json:"id"`; Name string `json:"name"` }, len(s.Filters))
+	resp.Filters = make([]struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}, len(s.Filters))
 	for i, f := range s.Filters {
-		resp.Filters[i] = struct{ ID string `json:"id"`; Name string `json:"name"` }{f.Id, f.Name}
+		resp.Filters[i] = struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}{f.Id, f.Name}
 	}
 
-	resp.HWAccels = make([]struct{ ID string `json:"id"`; Name string `json:"name"` }, len(s.HWAccels))
+	resp.HWAccels = make([]struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}, len(s.HWAccels))
 	for i, h := range s.HWAccels {
-		resp.HWAccels[i] = struct{ ID string `json:"id"`; Name string `json:"name"` }{h.Id, h.Name}
+		resp.HWAccels[i] = struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}{h.Id, h.Name}
+	}
+
+	resp.HWEncoders = make([]SkillsHWEncoder, len(s.HWEncoders))
+	for i, e := range s.HWEncoders {
+		resp.HWEncoders[i] = SkillsHWEncoder{ID: e.Id, Working: e.Working, Error: e.Error}
 	}
 
 	resp.Codecs.Audio = make([]SkillsCodec, len(s.Codecs.Audio))
@@ -91,22 +140,51 @@ func skillsToAPI(s skills.Skills) SkillsResponse {
 		resp.Codecs.Subtitle[i] = SkillsCodec{ID: c.Id, Name: c.Name, Encoders: c.Encoders, Decoders: c.Decoders}
 	}
 
-	resp.Formats.Demuxers = make([]struct{ ID string `json:"id"`; Name string `json:"name"` }, len(s.Formats.Demuxers))
+	resp.Formats.Demuxers = make([]struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}, len(s.Formats.Demuxers))
 	for i, f := range s.Formats.Demuxers {
-		resp.Formats.Demuxers[i] = struct{ ID string `json:"id"`; Name string `json:"name"` }{f.Id, f.Name}
+		resp.Formats.Demuxers[i] = struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}{f.Id, f.Name}
 	}
-	resp.Formats.Muxers = make([]struct{ ID string `json:"id"`; Name string `json:"name"` }, len(s.Formats.Muxers))
+	resp.Formats.Muxers = make([]struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}, len(s.Formats.Muxers))
 	for i, f := range s.Formats.Muxers {
-		resp.Formats.Muxers[i] = struct{ ID string `json:"id"`; Name string `json:"name"` }{f.Id, f.Name}
+		resp.Formats.Muxers[i] = struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}{f.Id, f.Name}
 	}
 
-	resp.Protocols.Input = make([]struct{ ID string `json:"id"`; Name string `json:"name"` }, len(s.Protocols.Input))
+	resp.Protocols.Input = make([]struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}, len(s.Protocols.Input))
 	for i, pr := range s.Protocols.Input {
-		resp.Protocols.Input[i] = struct{ ID string `json:"id"`; Name string `json:"name"` }{pr.Id, pr.Name}
+		resp.Protocols.Input[i] = struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}{pr.Id, pr.Name}
 	}
-	resp.Protocols.Output = make([]struct{ ID string `json:"id"`; Name string `json:"name"` }, len(s.Protocols.Output))
+	resp.Protocols.Output = make([]struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}, len(s.Protocols.Output))
 	for i, pr := range s.Protocols.Output {
-		resp.Protocols.Output[i] = struct{ ID string `json:"id"`; Name string `json:"name"` }{pr.Id, pr.Name}
+		resp.Protocols.Output[i] = struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}{pr.Id, pr.Name}
+	}
+
+	resp.Warnings = s.Warnings
+	if resp.Warnings == nil {
+		resp.Warnings = []string{}
 	}
 
 	return resp
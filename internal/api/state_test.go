@@ -0,0 +1,85 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+func TestStateLastLog(t *testing.T) {
+	// 能力探测读 stdout，任务运行时的日志写 stderr
+	binary := filepath.Join(t.TempDir(), "ffmpeg")
+	script := `#!/bin/sh
+echo "ffmpeg version 6.0"
+echo "Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'in.mp4':" >&2
+echo "frame=   25 fps=0.0 q=28.0 size=     256kB time=00:00:01.00 bitrate=2097.2kbits/s speed=2.0x" >&2
+echo "Stream mapping:" >&2
+echo "frame=   50 fps=0.0 q=28.0 size=     512kB time=00:00:02.00 bitrate=2097.2kbits/s speed=2.0x" >&2
+`
+	if err := os.WriteFile(binary, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ff, err := ffmpeg.New(ffmpeg.Config{Binary: binary})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := task.NewStore(ff, logger.New("", logger.LevelError), task.StoreConfig{})
+	t.Cleanup(func() { store.Shutdown(context.Background()) })
+	presets, err := task.NewPresetStore(filepath.Join(t.TempDir(), "presets.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(store, presets, ff, Config{})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/v3/process/:id/state", h.GetState)
+
+	tk, err := store.Add(&task.Config{
+		ID:     "lastlog",
+		Input:  []task.ConfigIO{{ID: "in", Address: "/tmp/in.mp4"}},
+		Output: []task.ConfigIO{{ID: "out", Address: "/tmp/out.mp4"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Start("lastlog"); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); tk.Status().State != "finished"; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the run to finish, state %s", tk.Status().State)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v3/process/lastlog/state", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var state ProcessState
+	if err := json.Unmarshal(w.Body.Bytes(), &state); err != nil {
+		t.Fatal(err)
+	}
+	// 进度行不算日志行
+	if want := "Stream mapping:"; state.LastLog != want {
+		t.Fatalf("last_logline %q, want %q", state.LastLog, want)
+	}
+}
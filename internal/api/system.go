@@ -0,0 +1,163 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/ZSC714725/transcodemanager/internal/process"
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+// SystemInfo describes the manager itself
+type SystemInfo struct {
+	Tasks   int `json:"tasks"`
+	Buffers struct {
+		Total   uint64 `json:"total_bytes"` // 日志、进度采样、运行历史与状态变化，受 budget 限制
+		Budget  uint64 `json:"budget_bytes"`
+		Trimmed uint64 `json:"trimmed"`
+		Audit   uint64 `json:"audit_bytes"` // 内存中的操作记录，不计入 total
+	} `json:"buffers"`
+	Runtime struct {
+		Goroutines int    `json:"goroutines"`
+		HeapAlloc  uint64 `json:"heap_alloc_bytes"`
+		Sys        uint64 `json:"sys_bytes"`
+	} `json:"runtime"`
+}
+
+// System GET /api/v3/system
+func (h *Handler) System(c *gin.Context) {
+	info := SystemInfo{}
+	_, info.Tasks = h.store.List(task.ListFilter{})
+
+	mem := h.store.MemoryUsage()
+	info.Buffers.Total = mem.Total
+	info.Buffers.Budget = mem.Budget
+	info.Buffers.Trimmed = mem.Trimmed
+	if h.config.Audit != nil {
+		info.Buffers.Audit = h.config.Audit.Memory()
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	info.Runtime.Goroutines = runtime.NumGoroutine()
+	info.Runtime.HeapAlloc = ms.HeapAlloc
+	info.Runtime.Sys = ms.Sys
+
+	c.JSON(http.StatusOK, info)
+}
+
+// ProcessSummary totals the tasks, e.g. for a dashboard header
+type ProcessSummary struct {
+	Tasks  int            `json:"tasks"`
+	States map[string]int `json:"states"`       // 按状态（含 queued）的任务数
+	CPU    float64        `json:"cpu_usage"`    // 运行中任务的 CPU 之和
+	Memory uint64         `json:"memory_bytes"` // 运行中任务的内存之和
+}
+
+// Summary GET /api/v3/process/summary, ?reference= limits it to a reference
+func (h *Handler) Summary(c *gin.Context) {
+	tasks, _ := h.store.List(task.ListFilter{Reference: c.Query("reference")})
+
+	summary := ProcessSummary{Tasks: len(tasks), States: map[string]int{}}
+	for _, t := range tasks {
+		status := t.Status()
+		summary.States[t.State()]++
+		if status.State == "running" {
+			summary.CPU += status.CPU.Current
+			summary.Memory += status.Memory.Current
+		}
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// Readyz GET /readyz fails while the watchdog's checks fail. It doesn't
+// touch the store, so it answers even if the store is wedged.
+func (h *Handler) Readyz(c *gin.Context) {
+	if h.config.Watchdog != nil {
+		if ok, failing := h.config.Watchdog.Ready(); !ok {
+			errResp(c, http.StatusServiceUnavailable, "Not ready", "failing checks: "+strings.Join(failing, ", "))
+			return
+		}
+	}
+	c.JSON(http.StatusOK, "OK")
+}
+
+// Metrics GET /metrics in Prometheus text format
+func (h *Handler) Metrics(c *gin.Context) {
+	var b strings.Builder
+
+	if h.config.Watchdog != nil {
+		failures := h.config.Watchdog.Failures()
+		names := make([]string, 0, len(failures))
+		for name := range failures {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		writeMetric(&b, "transcodemanager_watchdog_failures_total", "counter", "Number of failed watchdog checks.")
+		for _, name := range names {
+			fmt.Fprintf(&b, "transcodemanager_watchdog_failures_total{check=%q} %d\n", name, failures[name])
+		}
+	}
+
+	tasks, _ := h.store.List(task.ListFilter{Sort: "id"})
+	mem := h.store.MemoryUsage()
+
+	writeMetric(&b, "transcodemanager_tasks", "gauge", "Number of tasks.")
+	fmt.Fprintf(&b, "transcodemanager_tasks %d\n", len(tasks))
+
+	writeMetric(&b, "transcodemanager_buffer_bytes_total", "gauge", "Approximate bytes held by all task buffers.")
+	fmt.Fprintf(&b, "transcodemanager_buffer_bytes_total %d\n", mem.Total)
+
+	writeMetric(&b, "transcodemanager_buffer_budget_bytes", "gauge", "Memory budget for all task buffers.")
+	fmt.Fprintf(&b, "transcodemanager_buffer_budget_bytes %d\n", mem.Budget)
+
+	writeMetric(&b, "transcodemanager_buffer_trimmed_total", "counter", "Number of times a task buffer was shrunk to fit the budget.")
+	fmt.Fprintf(&b, "transcodemanager_buffer_trimmed_total %d\n", mem.Trimmed)
+
+	if h.config.Audit != nil {
+		writeMetric(&b, "transcodemanager_audit_bytes", "gauge", "Approximate bytes held by the audit entries in memory.")
+		fmt.Fprintf(&b, "transcodemanager_audit_bytes %d\n", h.config.Audit.Memory())
+	}
+
+	writeMetric(&b, "transcodemanager_task_buffer_bytes", "gauge", "Approximate bytes held by a task's buffers.")
+	for _, t := range tasks {
+		fmt.Fprintf(&b, "transcodemanager_task_buffer_bytes{id=%q} %d\n", t.ID, t.Memory())
+	}
+
+	statuses := make([]process.Status, len(tasks))
+	for i, t := range tasks {
+		statuses[i] = t.Status()
+	}
+	writeMetric(&b, "transcodemanager_task_uptime_seconds_total", "counter", "Time a task's FFmpeg spent running, across all runs.")
+	for i, t := range tasks {
+		fmt.Fprintf(&b, "transcodemanager_task_uptime_seconds_total{id=%q} %.3f\n", t.ID, statuses[i].Uptime.Seconds())
+	}
+	writeMetric(&b, "transcodemanager_task_reconnects_total", "counter", "Number of automatic restarts of a task.")
+	for i, t := range tasks {
+		fmt.Fprintf(&b, "transcodemanager_task_reconnects_total{id=%q} %d\n", t.ID, statuses[i].Reconnects)
+	}
+	writeMetric(&b, "transcodemanager_task_state", "gauge", "State of a task; stopped_by is set if its last run was asked to stop.")
+	for i, t := range tasks {
+		fmt.Fprintf(&b, "transcodemanager_task_state{id=%q,state=%q,stopped_by=%q} 1\n", t.ID, t.State(), statuses[i].StoppedBy)
+	}
+	writeMetric(&b, "transcodemanager_task_failures_total", "counter", "Number of runs of a task that FFmpeg ended with an error.")
+	for i, t := range tasks {
+		fmt.Fprintf(&b, "transcodemanager_task_failures_total{id=%q} %d\n", t.ID, statuses[i].States.Failed)
+	}
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+func writeMetric(b *strings.Builder, name, typ, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
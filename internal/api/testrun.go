@@ -0,0 +1,56 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+// TestResult is the outcome of the test command: the task's command run
+// for one second into the null muxer
+type TestResult struct {
+	OK       bool     `json:"ok"`
+	ExitCode int      `json:"exit_code"` // -1 if it was killed
+	TimedOut bool     `json:"timed_out"`
+	Duration float64  `json:"duration_seconds"`
+	Command  []string `json:"command"`
+	Errors   []string `json:"errors"` // 前几行错误输出
+}
+
+// testRun runs the test command of PUT /api/v3/process/:id/command
+func (h *Handler) testRun(c *gin.Context, id string) {
+	res, err := h.store.Test(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, task.ErrNotFound) {
+			errResp(c, http.StatusNotFound, "Unknown process ID", err.Error())
+			return
+		}
+		if errors.Is(err, task.ErrTestRunning) {
+			errResp(c, http.StatusConflict, "Test running", err.Error())
+			return
+		}
+		errResp(c, http.StatusInternalServerError, "Test failed", h.redactor(c).Redact(err.Error()))
+		return
+	}
+
+	r := h.redactor(c)
+	out := TestResult{
+		OK:       res.OK,
+		ExitCode: res.ExitCode,
+		TimedOut: res.TimedOut,
+		Duration: res.Duration.Seconds(),
+		Command:  r.RedactAll(res.Command),
+		Errors:   r.RedactAll(res.Lines),
+	}
+	if out.Errors == nil {
+		out.Errors = []string{}
+	}
+	c.JSON(http.StatusOK, out)
+}
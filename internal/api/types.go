@@ -5,11 +5,21 @@
 
 package api
 
+import "encoding/json"
+
 // ProcessConfigIO is API input/output
 type ProcessConfigIO struct {
-	ID      string   `json:"id"`
-	Address string   `json:"address"`
-	Options []string `json:"options"`
+	ID       string           `json:"id"`
+	Address  string           `json:"address"`
+	Options  []string         `json:"options"`
+	Isolated bool             `json:"isolated,omitempty"`
+	SoftStop *ProcessSoftStop `json:"soft_stop,omitempty"` // 仅 HLS 输出
+}
+
+// ProcessSoftStop of an HLS output
+type ProcessSoftStop struct {
+	Timeout uint64 `json:"timeout_seconds"` // 等待分片结束的最长时间，默认 10 秒
+	Endlist bool   `json:"endlist"`         // FFmpeg 未写入时补上 #EXT-X-ENDLIST
 }
 
 // ProcessConfigLimits for API
@@ -21,79 +31,240 @@ type ProcessConfigLimits struct {
 
 // ProcessConfigRequest for Add/Update
 type ProcessConfigRequest struct {
-	ID             string              `json:"id"`
-	Reference      string              `json:"reference"`
-	Input          []ProcessConfigIO    `json:"input" binding:"required"`
-	Output         []ProcessConfigIO    `json:"output" binding:"required"`
-	Options        []string             `json:"options"`
-	Reconnect      bool                `json:"reconnect"`
-	ReconnectDelay uint64              `json:"reconnect_delay_seconds"`
-	Autostart      bool                `json:"autostart"`
-	StaleTimeout   uint64              `json:"stale_timeout_seconds"`
-	Limits         ProcessConfigLimits `json:"limits"`
+	ID                 string              `json:"id"`
+	Reference          string              `json:"reference"`
+	Input              []ProcessConfigIO   `json:"input" binding:"required"`
+	Output             []ProcessConfigIO   `json:"output" binding:"required"`
+	Options            []string            `json:"options"`
+	Reconnect          bool                `json:"reconnect"`
+	ReconnectDelay     uint64              `json:"reconnect_delay_seconds"`
+	ReconnectBackoff   float64             `json:"reconnect_backoff"`
+	ReconnectMaxDelay  uint64              `json:"reconnect_max_delay_seconds"`
+	MaxReconnects      int                 `json:"max_reconnects"`
+	Autostart          bool                `json:"autostart"`
+	Priority           int                 `json:"priority"` // 排队时优先级高的先启动，修改时不重启任务
+	StaleTimeout       uint64              `json:"stale_timeout_seconds"`
+	StaleOn            string              `json:"stale_on"`
+	RuntimeLimit       uint64              `json:"runtime_limit_seconds"`     // 启动后运行多久自动停止（含重连），0 为不限制
+	StartAt            int64               `json:"start_at"`                  // 在该 Unix 时间启动，0 为不定时，修改时不重启任务
+	StopAt             int64               `json:"stop_at"`                   // 在该 Unix 时间停止，须晚于 start_at
+	Schedule           string              `json:"schedule"`                  // cron 表达式，如 "0 3 * * *" 或 "@every 1h"，按 timezone 计算，到点时未在运行则启动
+	StopSignal         string              `json:"stop_signal"`               // 正常停止发送的信号：int（默认）、term 或 quit
+	Nice               int                 `json:"nice"`                      // FFmpeg 的 nice 值（-20 到 19），0 为继承，仅 Linux
+	IONiceClass        string              `json:"ionice_class"`              // FFmpeg 的 IO 调度类别：realtime、best-effort 或 idle，仅 Linux
+	Binary             string              `json:"binary"`                    // ffmpeg.binaries 中的名称，为空使用默认的 FFmpeg
+	MaxOutputBandwidth uint64              `json:"max_output_bandwidth_kbps"` // srt、udp 输出的带宽上限，0 为不限制
+	Scte35Monitor      bool                `json:"scte35_monitor"`            // 统计 SCTE-35 数据包，输出须为 MPEG-TS 并映射数据流
+	Scte35Timeout      uint64              `json:"scte35_timeout_seconds"`    // 超过该时间未收到 SCTE-35 即告警，默认 300
+	PreChecks          string              `json:"prestart_checks"`           // "", warn or abort
+	Env                []string            `json:"environment"`               // KEY=VALUE, FFmpeg 不继承服务的环境变量
+	Timezone           string              `json:"timezone"`                  // IANA 时区，如 Asia/Shanghai
+	Variables          map[string]string   `json:"variables"`                 // 地址与选项中 {name} 占位符的值
+	Labels             map[string]string   `json:"labels"`                    // 自定义标签，监视目录创建的任务带 origin=watcher
+	Hooks              map[string][]string `json:"hooks"`                     // 任务事件（on_start、on_failed、on_finished、on_stalled）执行的脚本，须位于 hooks.dir
+	DebugReport        bool                `json:"debug_report"`              // FFmpeg 为每次运行写完整报告，下一次运行生效
+	ProgressPipe       bool                `json:"progress_pipe"`             // 通过 -progress pipe:3 读取进度，stderr 只用于日志，不支持 Windows
+	LogLines           int                 `json:"log_lines"`                 // 内存中保留的日志行数（最多 100000），0 使用全局默认值
+	Limits             ProcessConfigLimits `json:"limits"`
+	Preset             string              `json:"preset"`
 }
 
 // Process represents a task in API response
 type Process struct {
-	ID        string          `json:"id"`
-	Type      string          `json:"type"`
-	Reference string          `json:"reference"`
-	CreatedAt int64           `json:"created_at"`
-	UpdatedAt int64           `json:"updated_at"`
-	Config    *ProcessConfig  `json:"config,omitempty"`
-	State     *ProcessState   `json:"state,omitempty"`
-	Report    *ProcessReport  `json:"report,omitempty"`
+	ID        string                     `json:"id"`
+	Type      string                     `json:"type"`
+	Reference string                     `json:"reference"`
+	CreatedAt int64                      `json:"created_at"`
+	UpdatedAt int64                      `json:"updated_at"`
+	Config    *ProcessConfig             `json:"config,omitempty"`
+	State     *ProcessState              `json:"state,omitempty"`
+	Report    *ProcessReport             `json:"report,omitempty"`
+	Metadata  map[string]json.RawMessage `json:"metadata,omitempty"` // 仅在 ?metadata=true 时返回
 }
 
 // ProcessConfig in API format
 type ProcessConfig struct {
-	ID             string              `json:"id"`
-	Type          string               `json:"type"`
-	Reference     string               `json:"reference"`
-	Input         []ProcessConfigIO    `json:"input"`
-	Output        []ProcessConfigIO    `json:"output"`
-	Options       []string             `json:"options"`
-	Reconnect     bool                 `json:"reconnect"`
-	ReconnectDelay uint64             `json:"reconnect_delay_seconds"`
-	Autostart     bool                 `json:"autostart"`
-	StaleTimeout  uint64               `json:"stale_timeout_seconds"`
-	Limits        ProcessConfigLimits  `json:"limits"`
+	ID                 string              `json:"id"`
+	Hash               string              `json:"config_hash"` // stable hash of the normalized config
+	Type               string              `json:"type"`
+	Reference          string              `json:"reference"`
+	Input              []ProcessConfigIO   `json:"input"`
+	Output             []ProcessConfigIO   `json:"output"`
+	Options            []string            `json:"options"`
+	Reconnect          bool                `json:"reconnect"`
+	ReconnectDelay     uint64              `json:"reconnect_delay_seconds"`
+	ReconnectBackoff   float64             `json:"reconnect_backoff"`
+	ReconnectMaxDelay  uint64              `json:"reconnect_max_delay_seconds"`
+	MaxReconnects      int                 `json:"max_reconnects"`
+	Autostart          bool                `json:"autostart"`
+	Priority           int                 `json:"priority"`
+	StaleTimeout       uint64              `json:"stale_timeout_seconds"`
+	StaleOn            string              `json:"stale_on"`
+	RuntimeLimit       uint64              `json:"runtime_limit_seconds"`
+	StartAt            int64               `json:"start_at"`
+	StopAt             int64               `json:"stop_at"`
+	Schedule           string              `json:"schedule"`
+	StopSignal         string              `json:"stop_signal"`
+	Nice               int                 `json:"nice"`
+	IONiceClass        string              `json:"ionice_class"`
+	Binary             string              `json:"binary"`
+	MaxOutputBandwidth uint64              `json:"max_output_bandwidth_kbps"`
+	Scte35Monitor      bool                `json:"scte35_monitor"`
+	Scte35Timeout      uint64              `json:"scte35_timeout_seconds"`
+	PreChecks          string              `json:"prestart_checks"`
+	Env                []string            `json:"environment"`
+	Timezone           string              `json:"timezone"`
+	EffectiveTimezone  string              `json:"effective_timezone"` // timezone, or the system zone if unset
+	Variables          map[string]string   `json:"variables"`
+	Labels             map[string]string   `json:"labels"`
+	Hooks              map[string][]string `json:"hooks"`
+	DebugReport        bool                `json:"debug_report"`
+	ProgressPipe       bool                `json:"progress_pipe"`
+	LogLines           int                 `json:"log_lines"`
+	Limits             ProcessConfigLimits `json:"limits"`
+}
+
+// UpdateResponse is the config after an update. Unchanged is set if the
+// new config was equivalent to the old one and the task wasn't touched.
+type UpdateResponse struct {
+	*ProcessConfig
+	Unchanged bool `json:"unchanged"`
+}
+
+// DryRunResponse is the config POST /process?dryrun=true would add, with
+// the command it would run
+type DryRunResponse struct {
+	*ProcessConfig
+	Command  []string `json:"command"`
+	Warnings []string `json:"warnings"`
 }
 
 // ProcessState for API
 type ProcessState struct {
-	Order     string    `json:"order"`
-	State     string    `json:"exec"`
-	Runtime   int64     `json:"runtime_seconds"`
-	Reconnect int64     `json:"reconnect_seconds"`
-	LastLog   string    `json:"last_logline"`
-	Progress  *Progress  `json:"progress"`
-	Memory    uint64    `json:"memory_bytes"`
-	CPU       float64   `json:"cpu_usage"`
-	Command   []string  `json:"command"`
+	Order             string            `json:"order"`
+	State             string            `json:"exec"`
+	Runtime           int64             `json:"runtime_seconds"`
+	Uptime            int64             `json:"uptime_seconds"`         // 所有运行累计的运行（含暂停）时间
+	Started           int64             `json:"started_seconds"`        // 自 start 命令起的时间，order 为 stop 时为 0
+	StartedUptime     int64             `json:"started_uptime_seconds"` // 自 start 命令起累计的运行（含暂停）时间，含各次重连
+	Reconnects        uint64            `json:"reconnects"`             // 自动重连次数
+	ReconnectAttempts int               `json:"reconnect_attempts"`     // 上次稳定运行后的连续重连次数，受 max_reconnects 限制
+	ReconnectsLeft    int               `json:"reconnects_left"`        // 剩余重连次数，-1 表示不限，未开启重连时为 0
+	ExitCode          int               `json:"exit_code"`              // 上一次运行的退出码，-1 表示尚未退出或被信号结束
+	Reconnect         int64             `json:"reconnect_seconds"`
+	ReconnectDelay    float64           `json:"reconnect_delay_seconds,omitempty"` // 等待中的重连的总间隔，开启退避时逐次增长
+	QueuePosition     int               `json:"queue_position,omitempty"`          // 排队中时在启动队列中的位置，从 1 开始
+	StopReason        string            `json:"stop_reason,omitempty"`             // stale, progress_stalled, runtime_limit, preempted, shutdown or stop_<mode>
+	StoppedBy         string            `json:"stopped_by,omitempty"`              // user, system or limit if the last run was asked to stop
+	LastLog           string            `json:"last_logline"`
+	LastError         *ProcessError     `json:"last_error,omitempty"`
+	Checks            []ProcessCheck    `json:"checks,omitempty"` // of the last start, if prestart_checks is set
+	Progress          *Progress         `json:"progress"`
+	Outputs           map[int]*Progress `json:"outputs,omitempty"`
+	FailedOutputs     map[string]string `json:"failed_outputs,omitempty"` // isolated outputs that failed, by output ID
+	Scte35            *Scte35State      `json:"scte35,omitempty"`         // if scte35_monitor is set
+	OSPriority        *ProcessPriority  `json:"os_priority,omitempty"`    // read back from the running FFmpeg if nice or ionice_class is set
+	Memory            uint64            `json:"memory_bytes"`
+	CPU               float64           `json:"cpu_usage"`
+	Buffers           uint64            `json:"buffer_bytes"`
+	Command           []string          `json:"command"`
+}
+
+// ProcessError is a classified FFmpeg error
+type ProcessError struct {
+	Category  string `json:"category"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// ProcessPriority is the CPU and I/O priority of the running FFmpeg
+type ProcessPriority struct {
+	Nice        int    `json:"nice"`
+	IONiceClass string `json:"ionice_class"` // empty if FFmpeg has no explicit class
+	IONiceLevel int    `json:"ionice_level"`
+}
+
+// Scte35State counts the SCTE-35 packets of the current run
+type Scte35State struct {
+	In       uint64 `json:"in"`
+	Out      uint64 `json:"out"`
+	LastIn   int64  `json:"last_in"` // unix time, 0 if there was none
+	LastOut  int64  `json:"last_out"`
+	Stalled  bool   `json:"stalled"`
+	Diverged bool   `json:"diverged"`
+}
+
+// ProcessCheck is the result of a pre-start check
+type ProcessCheck struct {
+	Name      string `json:"name"`
+	Target    string `json:"target"`
+	OK        bool   `json:"ok"`
+	Message   string `json:"message,omitempty"`
+	Duration  int64  `json:"duration_ms"`
+	Timestamp int64  `json:"timestamp"`
 }
 
 // Progress from FFmpeg parser
 type Progress struct {
-	Frame     uint64  `json:"frame"`
-	Size      uint64  `json:"size_bytes"`
-	Time      float64 `json:"time_seconds"`
-	Speed     float64 `json:"speed"`
-	Drop      uint64  `json:"drop"`
-	Dup       uint64  `json:"dup"`
-	Quantizer float64 `json:"q"`
+	Frame   uint64  `json:"frame"`
+	FPS     float64 `json:"fps"`
+	Size    uint64  `json:"size_bytes"`
+	Time    float64 `json:"time_seconds"`
+	Bitrate float64 `json:"bitrate_kbit"`
+	Speed   float64 `json:"speed"`
+	// BitrateAvg and SpeedAvg are smoothed, for display
+	BitrateAvg float64 `json:"bitrate_kbit_avg"`
+	SpeedAvg   float64 `json:"speed_avg"`
+	Duration   float64 `json:"duration_seconds"` // 第一个输入的时长，直播等未知时为 0
+	ETA        float64 `json:"eta_seconds"`      // 按平滑后的速度估计的剩余时间，时长或速度未知时为 -1
+	Drop       uint64  `json:"drop"`
+	Dup        uint64  `json:"dup"`
+	Quantizer  float64 `json:"q"`
+}
+
+// ProgressSample is the progress at a point in time, for charts
+type ProgressSample struct {
+	Timestamp int64 `json:"timestamp_ms"` // Unix 毫秒
+	Progress
 }
 
-// ProcessReport for logs
+// ProcessReport for logs. The lines of Log are numbered consecutively from
+// FirstSeq to LastSeq; pass LastSeq as ?after= to get the following ones.
 type ProcessReport struct {
 	CreatedAt int64       `json:"created_at"`
-	Prelude   []string    `json:"prelude"`
+	Prelude   []string    `json:"prelude"` // FFmpeg 本次运行在第一行进度之前的输出（版本、流信息、映射），最多 200 行
 	Log       [][2]string `json:"log"`
+	FirstSeq  uint64      `json:"first_seq"` // 0 if Log is empty
+	LastSeq   uint64      `json:"last_seq"`  // the cursor for the next request
+	Gap       bool        `json:"gap"`       // lines before Log were lost, they fell out of the buffer or the log was reset
 }
 
 // CommandRequest for start/stop/restart
 type CommandRequest struct {
 	Command string `json:"command" binding:"required"`
+	Mode    string `json:"mode"` // stop: normal, soft or kill
+}
+
+// BatchCommandRequest applies a command to the tasks selected by ids and/or
+// reference, like ListProcesses does. Without either, all must be set to
+// select every task.
+type BatchCommandRequest struct {
+	Command   string   `json:"command" binding:"required"`
+	Mode      string   `json:"mode"`
+	IDs       []string `json:"ids"`
+	Reference string   `json:"reference"`
+	All       bool     `json:"all"`
+
+	// rolling_restart
+	Parallel    int    `json:"parallel"`        // 同时重启的任务数，默认 1
+	Timeout     uint64 `json:"timeout_seconds"` // 等待单个任务恢复健康的时间，默认 30
+	MaxFailures int    `json:"max_failures"`    // 失败多少个任务后中止，默认 1
+}
+
+// CommandResult is the outcome of a command for one task
+type CommandResult struct {
+	ID    string `json:"id"`
+	Error string `json:"error,omitempty"`
 }
 
 // ErrorResponse for API errors
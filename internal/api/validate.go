@@ -0,0 +1,59 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ValidateResponse is the command a config would run, with the problems
+// found by cross-checking it against the FFmpeg skills
+type ValidateResponse struct {
+	Command  []string `json:"command"`
+	Warnings []string `json:"warnings"`
+}
+
+// ValidateProcess POST /api/v3/process/validate
+func (h *Handler) ValidateProcess(c *gin.Context) {
+	var req ProcessConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp(c, http.StatusBadRequest, "Invalid JSON", err.Error())
+		return
+	}
+
+	if len(req.Input) == 0 || len(req.Output) == 0 {
+		errResp(c, http.StatusBadRequest, "At least one input and one output required", "")
+		return
+	}
+
+	cfg := requestToConfig(&req)
+	if len(req.Preset) != 0 {
+		preset, err := h.presets.Get(req.Preset)
+		if err != nil {
+			errResp(c, http.StatusBadRequest, "Unknown preset", err.Error())
+			return
+		}
+		preset.Apply(cfg)
+	}
+
+	v, err := h.store.Validate(cfg)
+	if err != nil {
+		errResp(c, http.StatusBadRequest, "Invalid config", h.redactor(c).Redact(err.Error()))
+		return
+	}
+
+	r := h.redactor(c)
+	resp := ValidateResponse{
+		Command:  r.RedactAll(v.Command),
+		Warnings: r.RedactAll(v.Warnings),
+	}
+	if resp.Warnings == nil {
+		resp.Warnings = []string{}
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WatcherStatus is the state of a watched directory
+type WatcherStatus struct {
+	Name         string `json:"name"`
+	Dir          string `json:"dir"`
+	FilesSeen    uint64 `json:"files_seen"`
+	TasksCreated uint64 `json:"tasks_created"`
+	Errors       uint64 `json:"errors"`
+	Pending      int    `json:"pending"` // files waiting to settle
+	Running      int    `json:"running"` // created tasks that haven't finished
+	LastError    string `json:"last_error,omitempty"`
+	LastErrorAt  int64  `json:"last_error_at,omitempty"`
+}
+
+// ListWatchers GET /api/v3/watchers
+func (h *Handler) ListWatchers(c *gin.Context) {
+	list := []WatcherStatus{}
+	if h.config.Watchers != nil {
+		for _, s := range h.config.Watchers.Status() {
+			out := WatcherStatus{
+				Name:         s.Name,
+				Dir:          s.Dir,
+				FilesSeen:    s.Seen,
+				TasksCreated: s.Created,
+				Errors:       s.Errors,
+				Pending:      s.Pending,
+				Running:      s.Running,
+				LastError:    s.LastError,
+			}
+			if !s.LastErrorAt.IsZero() {
+				out.LastErrorAt = s.LastErrorAt.Unix()
+			}
+			list = append(list, out)
+		}
+	}
+	c.JSON(http.StatusOK, list)
+}
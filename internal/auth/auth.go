@@ -0,0 +1,187 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrLoginDisabled      = errors.New("login is not configured")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+)
+
+// Config for authentication. Without a username and tokens every request
+// is allowed.
+type Config struct {
+	Username   string
+	Password   string
+	Tokens     []string      // static bearer tokens for automation
+	SessionTTL time.Duration // default 12h
+}
+
+// Session is a login session
+type Session struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// Auth checks credentials
+type Auth interface {
+	// Enabled reports whether requests need credentials
+	Enabled() bool
+	// Login creates a session for a username and password
+	Login(username, password string) (Session, error)
+	// Logout ends the session of a token
+	Logout(token string)
+	// Check reports whether a token is a static token or the token of a
+	// live session
+	Check(token string) bool
+	// Who names the holder of a valid token: the username for a session,
+	// token #n for the nth static token. Empty if the token isn't valid.
+	Who(token string) string
+}
+
+type auth struct {
+	config   Config
+	key      []byte
+	sessions map[string]time.Time // session ID -> expiry
+	lock     sync.Mutex
+}
+
+// New creates Auth. Session tokens are signed with a random key, so they
+// don't survive a restart.
+func New(config Config) Auth {
+	if config.SessionTTL <= 0 {
+		config.SessionTTL = 12 * time.Hour
+	}
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	return &auth{
+		config:   config,
+		key:      key,
+		sessions: make(map[string]time.Time),
+	}
+}
+
+func (a *auth) Enabled() bool {
+	return a.config.Username != "" || len(a.config.Tokens) != 0
+}
+
+func (a *auth) Login(username, password string) (Session, error) {
+	if a.config.Username == "" {
+		return Session{}, ErrLoginDisabled
+	}
+	// 两项都比较，耗时不泄露哪一项错误
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(a.config.Username))
+	passOK := subtle.ConstantTimeCompare([]byte(password), []byte(a.config.Password))
+	if userOK&passOK != 1 {
+		return Session{}, ErrInvalidCredentials
+	}
+
+	id := make([]byte, 16)
+	rand.Read(id)
+	expires := time.Now().Add(a.config.SessionTTL)
+	payload := hex.EncodeToString(id) + "." + strconv.FormatInt(expires.Unix(), 10)
+
+	a.lock.Lock()
+	a.prune()
+	a.sessions[hex.EncodeToString(id)] = expires
+	a.lock.Unlock()
+
+	return Session{Token: payload + "." + a.sign(payload), ExpiresAt: expires}, nil
+}
+
+func (a *auth) Logout(token string) {
+	if id, ok := a.verify(token); ok {
+		a.lock.Lock()
+		delete(a.sessions, id)
+		a.lock.Unlock()
+	}
+}
+
+func (a *auth) Check(token string) bool {
+	if token == "" {
+		return false
+	}
+	for _, t := range a.config.Tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			return true
+		}
+	}
+
+	id, ok := a.verify(token)
+	if !ok {
+		return false
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	expires, ok := a.sessions[id]
+	return ok && time.Now().Before(expires)
+}
+
+func (a *auth) Who(token string) string {
+	if token == "" {
+		return ""
+	}
+	for i, t := range a.config.Tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			return "token #" + strconv.Itoa(i+1)
+		}
+	}
+	if a.Check(token) {
+		return a.config.Username
+	}
+	return ""
+}
+
+// verify checks the signature and expiry of a session token and returns
+// the session ID
+func (a *auth) verify(token string) (string, bool) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return "", false
+	}
+	payload, sig := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(a.sign(payload))) {
+		return "", false
+	}
+	id, exp, ok := strings.Cut(payload, ".")
+	if !ok {
+		return "", false
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() >= unix {
+		return "", false
+	}
+	return id, true
+}
+
+func (a *auth) sign(payload string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// prune removes expired sessions, the caller must hold the lock
+func (a *auth) prune() {
+	now := time.Now()
+	for id, expires := range a.sessions {
+		if !now.Before(expires) {
+			delete(a.sessions, id)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package auth
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAuth() *auth {
+	return New(Config{Username: "admin", Password: "secret", Tokens: []string{"t1", "t2"}}).(*auth)
+}
+
+func TestLogin(t *testing.T) {
+	a := newTestAuth()
+	if _, err := a.Login("admin", "wrong"); err != ErrInvalidCredentials {
+		t.Fatalf("login with a wrong password: %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, err := New(Config{Tokens: []string{"t1"}}).Login("admin", "secret"); err != ErrLoginDisabled {
+		t.Fatalf("login without a username: %v, want %v", err, ErrLoginDisabled)
+	}
+
+	s, err := a.Login("admin", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := time.Until(s.ExpiresAt); ttl < 11*time.Hour || ttl > 12*time.Hour {
+		t.Fatalf("session expires in %s, want 12h", ttl)
+	}
+	if !a.Check(s.Token) || a.Who(s.Token) != "admin" {
+		t.Fatalf("valid session: check %v, who %q", a.Check(s.Token), a.Who(s.Token))
+	}
+
+	// 注销后签名仍然有效，但服务端已无此会话
+	a.Logout(s.Token)
+	if a.Check(s.Token) || a.Who(s.Token) != "" {
+		t.Fatal("session still valid after logout")
+	}
+}
+
+func TestTokens(t *testing.T) {
+	a := newTestAuth()
+	if !a.Enabled() || New(Config{}).Enabled() {
+		t.Fatal("enabled only with a username or tokens")
+	}
+	if !a.Check("t2") || a.Who("t2") != "token #2" {
+		t.Fatalf("static token: check %v, who %q", a.Check("t2"), a.Who("t2"))
+	}
+	for _, token := range []string{"", "t3", "t1 "} {
+		if a.Check(token) || a.Who(token) != "" {
+			t.Fatalf("token %q accepted", token)
+		}
+	}
+}
+
+func TestTamperedToken(t *testing.T) {
+	a := newTestAuth()
+	s, err := a.Login("admin", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(s.Token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token %q, want id.expiry.signature", s.Token)
+	}
+	later := strconv.FormatInt(time.Now().Add(48*time.Hour).Unix(), 10)
+
+	tests := map[string]string{
+		"expiry":    parts[0] + "." + later + "." + parts[2],
+		"signature": parts[0] + "." + parts[1] + "." + strings.Repeat("0", len(parts[2])),
+		"id":        strings.Repeat("0", len(parts[0])) + "." + parts[1] + "." + parts[2],
+		"truncated": parts[0] + "." + parts[1],
+		"garbage":   "not-a-token",
+	}
+	for name, token := range tests {
+		if a.Check(token) {
+			t.Errorf("%s tampered token accepted", name)
+		}
+	}
+
+	// 另一个实例的签名密钥不同，重启后旧会话失效
+	if newTestAuth().Check(s.Token) {
+		t.Error("token of another instance accepted")
+	}
+}
+
+func TestExpiredToken(t *testing.T) {
+	a := newTestAuth()
+	// 签名正确、会话仍在，但已过期
+	id := "0123456789abcdef0123456789abcdef"
+	expires := time.Now().Add(-time.Second)
+	payload := id + "." + strconv.FormatInt(expires.Unix(), 10)
+	a.sessions[id] = time.Now().Add(time.Hour)
+	if token := payload + "." + a.sign(payload); a.Check(token) {
+		t.Fatal("expired token accepted")
+	}
+
+	short := New(Config{Username: "admin", Password: "secret", SessionTTL: time.Second})
+	s, err := short.Login("admin", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !short.Check(s.Token) {
+		t.Fatal("fresh session rejected")
+	}
+	time.Sleep(time.Until(s.ExpiresAt) + 100*time.Millisecond)
+	if short.Check(s.Token) {
+		t.Fatal("session valid after its TTL")
+	}
+}
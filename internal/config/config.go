@@ -13,25 +13,213 @@ import (
 
 // Config 应用配置
 type Config struct {
-	Server  ServerConfig  `yaml:"server"`
-	FFmpeg  FFmpegConfig  `yaml:"ffmpeg"`
+	Server      ServerConfig      `yaml:"server"`
+	FFmpeg      FFmpegConfig      `yaml:"ffmpeg"`
+	Data        DataConfig        `yaml:"data"`
+	Memory      MemoryConfig      `yaml:"memory"`
+	Tasks       TasksConfig       `yaml:"tasks"`
+	DryRun      DryRunConfig      `yaml:"dry_run"`
+	Chaos       ChaosConfig       `yaml:"chaos"`
+	Redact      RedactConfig      `yaml:"redact"`
+	Debug       DebugConfig       `yaml:"debug"`
+	TaskLog     TaskLogConfig     `yaml:"task_log"`
+	Watchdog    WatchdogConfig    `yaml:"watchdog"`
+	Auth        AuthConfig        `yaml:"auth"`
+	Audit       AuditConfig       `yaml:"audit"`
+	Credentials CredentialsConfig `yaml:"credentials"`
+	Hooks       HooksConfig       `yaml:"hooks"`
+	Webhooks    []string          `yaml:"webhooks"` // 任务状态变化时 POST 通知的地址
+	MQTT        MQTTConfig        `yaml:"mqtt"`
+	Logging     LoggingConfig     `yaml:"logging"`
+	Watchers    []WatcherConfig   `yaml:"watchers"`
 }
 
 // ServerConfig 服务配置
 type ServerConfig struct {
-	Bind string `yaml:"bind"`
+	Bind            string    `yaml:"bind"`
+	TLS             TLSConfig `yaml:"tls"`
+	ShutdownTimeout uint64    `yaml:"shutdown_timeout_seconds"` // 退出时停止所有任务的总时限，超时的 FFmpeg 被强制结束
+}
+
+// TLSConfig HTTPS 配置，证书与私钥都设置时启用
+type TLSConfig struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	Cert         string `yaml:"cert"`          // cert_file 的简写，两者都设置时以 cert_file 为准
+	Key          string `yaml:"key"`           // key_file 的简写
+	RedirectBind string `yaml:"redirect_bind"` // 非空时在此地址监听 HTTP 并重定向到 HTTPS
 }
 
 // FFmpegConfig FFmpeg 配置
 type FFmpegConfig struct {
-	Path string `yaml:"path"`
+	Path              string            `yaml:"path"`
+	ProbeTimeout      uint64            `yaml:"probe_timeout_seconds"`         // 单次能力探测（-codecs 等）的超时
+	FFprobePath       string            `yaml:"ffprobe_path"`                  // 为空时使用 FFmpeg 同目录或 PATH 中的 ffprobe
+	FFprobeTimeout    uint64            `yaml:"ffprobe_timeout_seconds"`       // 探测输入流的超时
+	Binaries          map[string]string `yaml:"binaries"`                      // 额外的 FFmpeg 构建，名称 -> 路径，任务以 binary 选择
+	BinaryDir         string            `yaml:"binary_dir"`                    // 非空时任务的 binary 也可以是此目录中的可执行文件（路径或文件名）
+	SkillsCheck       int               `yaml:"skills_check_interval_seconds"` // 检查 FFmpeg 文件是否变化（原地升级）并重新探测能力的间隔，默认 60，负数关闭
+	ProgressSmoothing float64           `yaml:"progress_smoothing"`            // speed_avg、bitrate_kbit_avg 中最新值的权重（0 到 1），默认 0.2，1 为不平滑
+	ProgressHistory   int               `yaml:"progress_history"`              // 每个任务保留的进度采样数，默认 600（约 5 分钟），负数关闭
+	Validator         ValidatorConfig   `yaml:"validator"`
+	ValidateProtocols bool              `yaml:"validate_protocols"` // 拒绝 FFmpeg 不支持的协议的地址（普通路径视为 file），默认开启
+	ValidateEncoders  bool              `yaml:"validate_encoders"`  // 拒绝能力列表中没有的编码器，关闭时只记录日志，默认开启
+}
+
+// ValidatorConfig 输入、输出地址的校验规则
+type ValidatorConfig struct {
+	Input  ValidatorRules `yaml:"input"`
+	Output ValidatorRules `yaml:"output"`
+}
+
+// ValidatorRules 正则表达式列表：匹配 block 中任一条的地址被拒绝；allow 非空时地址须匹配其中一条
+type ValidatorRules struct {
+	Allow []string `yaml:"allow"`
+	Block []string `yaml:"block"`
+}
+
+// HooksConfig 任务事件钩子配置
+type HooksConfig struct {
+	Dir           string   `yaml:"dir"`             // 钩子脚本只能位于此目录，为空时禁用钩子
+	Timeout       uint64   `yaml:"timeout_seconds"` // 单个钩子的超时，默认 30
+	MaxConcurrent int      `yaml:"max_concurrent"`  // 同时执行的钩子数，默认 4
+	OnStart       []string `yaml:"on_start"`        // 所有任务的钩子，先于任务自己的钩子执行
+	OnFailed      []string `yaml:"on_failed"`
+	OnFinished    []string `yaml:"on_finished"`
+	OnStalled     []string `yaml:"on_stalled"`
+}
+
+// MQTTConfig 向 MQTT 发布任务状态与进度，broker 为空时不发布
+type MQTTConfig struct {
+	Broker           string `yaml:"broker"` // 如 tcp://localhost:1883、ssl://host:8883、ws://host/mqtt
+	Username         string `yaml:"username"`
+	Password         string `yaml:"password"`
+	ClientID         string `yaml:"client_id"`                 // 默认 transcodemanager-<主机名>
+	TopicPrefix      string `yaml:"topic_prefix"`              // 默认 transcodemanager
+	QoS              byte   `yaml:"qos"`                       // 0 到 2，状态消息至少为 1
+	ProgressInterval uint64 `yaml:"progress_interval_seconds"` // 发布进度的间隔，默认 5
+	QueueSize        int    `yaml:"queue_size"`                // 待发布消息上限，默认 1024，满时丢弃最旧的
+}
+
+// LoggingConfig 服务日志配置
+type LoggingConfig struct {
+	Format string `yaml:"format"` // text（默认）或 json，json 时每行一个 {"level","ts","msg","fields"} 对象
+	Level  string `yaml:"level"`  // debug、info（默认）或 error，低于此级别的日志不输出
+}
+
+// DataConfig 数据目录配置
+type DataConfig struct {
+	Dir string `yaml:"dir"`
+}
+
+// MemoryConfig 内存预算配置
+type MemoryConfig struct {
+	BudgetBytes uint64 `yaml:"budget_bytes"` // 所有任务日志缓冲的总预算，超出时裁剪最大的缓冲
+}
+
+// TasksConfig 任务调度配置
+type TasksConfig struct {
+	MaxConcurrent int  `yaml:"max_concurrent"` // 同时运行的任务上限，超出的启动请求按优先级排队，0 为不限制
+	Preempt       bool `yaml:"preempt"`        // 达到上限时高优先级任务抢占运行中优先级最低的任务，被抢占的任务重新排队
+}
+
+// DryRunConfig 演练模式配置：不执行 FFmpeg，任务由模拟进程运行
+type DryRunConfig struct {
+	Enable bool    `yaml:"enable"`
+	FPS    float64 `yaml:"fps"` // 模拟进度的帧率
+}
+
+// ChaosConfig 故障注入配置，用于演练告警与恢复
+type ChaosConfig struct {
+	Enable     bool   `yaml:"enable"`
+	DefaultTTL uint64 `yaml:"default_ttl_seconds"` // 注入未指定 TTL 时的有效期
+	MaxTTL     uint64 `yaml:"max_ttl_seconds"`     // 注入有效期上限
+}
+
+// RedactConfig 敏感信息脱敏配置
+type RedactConfig struct {
+	Patterns    []string `yaml:"patterns"`     // 为空时使用内置规则
+	RevealToken string   `yaml:"reveal_token"` // 非空时允许携带 X-Reveal-Token 的请求使用 ?reveal=true 查看原文
+}
+
+// DebugConfig 调试包配置
+type DebugConfig struct {
+	BundleLogLines int    `yaml:"bundle_log_lines"`
+	BundleMaxBytes int    `yaml:"bundle_max_bytes"`
+	BundleInterval uint64 `yaml:"bundle_interval_seconds"`
+}
+
+// TaskLogConfig 任务日志文件配置
+type TaskLogConfig struct {
+	Dir         string `yaml:"dir"`          // 非空时每个任务的非进度日志写入 <dir>/<id>.log
+	MaxBytes    int64  `yaml:"max_bytes"`    // 文件达到此大小时轮转为 <id>.log.1
+	MaxFiles    int    `yaml:"max_files"`    // 轮转后保留的旧文件数（<id>.log.1 到 <id>.log.N），默认 1
+	Reports     int    `yaml:"reports"`      // debug_report 任务在 <dir>/<id>/ 下保留的 FFmpeg 报告数
+	HistoryRuns int    `yaml:"history_runs"` // 每个任务在内存中保留日志的已结束运行数，默认 5，负数关闭
+}
+
+// WatchdogConfig 自检配置：定期检查存储锁与数据目录，失败时 /readyz 返回 503
+type WatchdogConfig struct {
+	Interval  uint64 `yaml:"interval_seconds"`
+	Timeout   uint64 `yaml:"timeout_seconds"` // 单项检查超时
+	Threshold int    `yaml:"threshold"`       // 连续失败多少轮后执行 action
+	Action    string `yaml:"action"`          // "" 仅报告，"exit" 退出进程由守护进程重启
+}
+
+// AuthConfig 认证配置，用户名与令牌都为空时不需要认证
+type AuthConfig struct {
+	Username   string   `yaml:"username"` // Web 控制台登录用户名
+	Password   string   `yaml:"password"`
+	Tokens     []string `yaml:"tokens"`              // 供脚本使用的静态令牌（Authorization: Bearer <token>）
+	SessionTTL uint64   `yaml:"session_ttl_seconds"` // 登录会话有效期
+}
+
+// AuditConfig 审计配置：记录通过 API 添加、修改、删除及启停任务的操作者与配置变化
+type AuditConfig struct {
+	File       string `yaml:"file"`        // 非空时每条记录以 JSON 行追加写入此文件，启动时读回
+	MaxEntries int    `yaml:"max_entries"` // 每个任务在内存中保留的记录数
+}
+
+// CredentialsConfig 凭据配置，凭据加密保存在数据目录的 credentials.json
+type CredentialsConfig struct {
+	Key string `yaml:"key"` // 加密密钥（任意字符串），为空时不能添加凭据
+}
+
+// WatcherConfig 监视目录：目录中出现的文件自动创建一次性转码任务
+type WatcherConfig struct {
+	Name      string            `yaml:"name"`
+	Dir       string            `yaml:"dir"`
+	Glob      string            `yaml:"glob"`           // 匹配文件名，默认 "*"，忽略隐藏文件
+	Preset    string            `yaml:"preset"`         // 任务使用的预设
+	Output    string            `yaml:"output"`         // 输出地址，可用 {file} {filename} {name} {ext} {dir}
+	Variables map[string]string `yaml:"variables"`      // 任务变量，值中可用同样的文件占位符
+	Settle    uint64            `yaml:"settle_seconds"` // 文件大小与修改时间保持不变多久后才创建任务，避免处理未复制完的文件
+	Rescan    uint64            `yaml:"rescan_seconds"` // 定期扫描目录，补上遗漏的文件事件
+	After     string            `yaml:"after"`          // 任务成功结束后处理源文件："" 保留，move 或 delete
+	MoveTo    string            `yaml:"move_to"`        // after: move 的目标目录
 }
 
 // Default 返回默认配置
 func Default() *Config {
 	return &Config{
-		Server: ServerConfig{Bind: ":8080"},
-		FFmpeg: FFmpegConfig{Path: "ffmpeg"},
+		Server:   ServerConfig{Bind: ":8080", ShutdownTimeout: 30},
+		FFmpeg:   FFmpegConfig{Path: "ffmpeg", ProbeTimeout: 5, FFprobeTimeout: 15, SkillsCheck: 60, ProgressSmoothing: 0.2, ProgressHistory: 600, ValidateProtocols: true, ValidateEncoders: true},
+		Data:     DataConfig{Dir: "data"},
+		Memory:   MemoryConfig{BudgetBytes: 256 * 1024 * 1024},
+		DryRun:   DryRunConfig{FPS: 25},
+		Chaos:    ChaosConfig{DefaultTTL: 60, MaxTTL: 600},
+		TaskLog:  TaskLogConfig{MaxBytes: 10 * 1024 * 1024, MaxFiles: 1, Reports: 5, HistoryRuns: 5},
+		Watchdog: WatchdogConfig{Interval: 10, Timeout: 5, Threshold: 3},
+		Auth:     AuthConfig{SessionTTL: 12 * 3600},
+		Audit:    AuditConfig{MaxEntries: 100},
+		Hooks:    HooksConfig{Timeout: 30, MaxConcurrent: 4},
+		MQTT:     MQTTConfig{TopicPrefix: "transcodemanager", ProgressInterval: 5, QueueSize: 1024},
+		Logging:  LoggingConfig{Format: "text", Level: "info"},
+		Debug: DebugConfig{
+			BundleLogLines: 500,
+			BundleMaxBytes: 1024 * 1024,
+			BundleInterval: 10,
+		},
 	}
 }
 
@@ -55,9 +243,102 @@ func Load(path string) (*Config, error) {
 	if cfg.Server.Bind == "" {
 		cfg.Server.Bind = ":8080"
 	}
+	if cfg.Server.TLS.CertFile == "" {
+		cfg.Server.TLS.CertFile = cfg.Server.TLS.Cert
+	}
+	if cfg.Server.TLS.KeyFile == "" {
+		cfg.Server.TLS.KeyFile = cfg.Server.TLS.Key
+	}
+	if cfg.Server.ShutdownTimeout == 0 {
+		cfg.Server.ShutdownTimeout = 30
+	}
 	if cfg.FFmpeg.Path == "" {
 		cfg.FFmpeg.Path = "ffmpeg"
 	}
+	if cfg.FFmpeg.ProbeTimeout == 0 {
+		cfg.FFmpeg.ProbeTimeout = 5
+	}
+	if cfg.FFmpeg.FFprobeTimeout == 0 {
+		cfg.FFmpeg.FFprobeTimeout = 15
+	}
+	if cfg.FFmpeg.SkillsCheck == 0 {
+		cfg.FFmpeg.SkillsCheck = 60
+	}
+	if cfg.FFmpeg.ProgressSmoothing <= 0 || cfg.FFmpeg.ProgressSmoothing > 1 {
+		cfg.FFmpeg.ProgressSmoothing = 0.2
+	}
+	if cfg.FFmpeg.ProgressHistory == 0 {
+		cfg.FFmpeg.ProgressHistory = 600
+	}
+	if cfg.Data.Dir == "" {
+		cfg.Data.Dir = "data"
+	}
+	if cfg.Memory.BudgetBytes == 0 {
+		cfg.Memory.BudgetBytes = 256 * 1024 * 1024
+	}
+	if cfg.DryRun.FPS <= 0 {
+		cfg.DryRun.FPS = 25
+	}
+	if cfg.Chaos.DefaultTTL == 0 {
+		cfg.Chaos.DefaultTTL = 60
+	}
+	if cfg.Chaos.MaxTTL == 0 {
+		cfg.Chaos.MaxTTL = 600
+	}
+	if cfg.Debug.BundleLogLines <= 0 {
+		cfg.Debug.BundleLogLines = 500
+	}
+	if cfg.Debug.BundleMaxBytes <= 0 {
+		cfg.Debug.BundleMaxBytes = 1024 * 1024
+	}
+	if cfg.Debug.BundleInterval == 0 {
+		cfg.Debug.BundleInterval = 10
+	}
+	if cfg.TaskLog.MaxBytes <= 0 {
+		cfg.TaskLog.MaxBytes = 10 * 1024 * 1024
+	}
+	if cfg.TaskLog.MaxFiles <= 0 {
+		cfg.TaskLog.MaxFiles = 1
+	}
+	if cfg.TaskLog.Reports <= 0 {
+		cfg.TaskLog.Reports = 5
+	}
+	if cfg.TaskLog.HistoryRuns == 0 {
+		cfg.TaskLog.HistoryRuns = 5
+	}
+	if cfg.Watchdog.Interval == 0 {
+		cfg.Watchdog.Interval = 10
+	}
+	if cfg.Watchdog.Timeout == 0 {
+		cfg.Watchdog.Timeout = 5
+	}
+	if cfg.Watchdog.Threshold <= 0 {
+		cfg.Watchdog.Threshold = 3
+	}
+	if cfg.Auth.SessionTTL == 0 {
+		cfg.Auth.SessionTTL = 12 * 3600
+	}
+	if cfg.Hooks.Timeout == 0 {
+		cfg.Hooks.Timeout = 30
+	}
+	if cfg.Hooks.MaxConcurrent <= 0 {
+		cfg.Hooks.MaxConcurrent = 4
+	}
+	if cfg.Logging.Format == "" {
+		cfg.Logging.Format = "text"
+	}
+	if cfg.Logging.Level == "" {
+		cfg.Logging.Level = "info"
+	}
+	if cfg.MQTT.TopicPrefix == "" {
+		cfg.MQTT.TopicPrefix = "transcodemanager"
+	}
+	if cfg.MQTT.ProgressInterval == 0 {
+		cfg.MQTT.ProgressInterval = 5
+	}
+	if cfg.MQTT.QueueSize <= 0 {
+		cfg.MQTT.QueueSize = 1024
+	}
 
 	return cfg, nil
 }
@@ -0,0 +1,41 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadTLS(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		certFile string
+		keyFile  string
+	}{
+		{"none", "server:\n  bind: \":8443\"\n", "", ""},
+		{"files", "server:\n  tls:\n    cert_file: a.crt\n    key_file: a.key\n", "a.crt", "a.key"},
+		{"short keys", "server:\n  tls:\n    cert: b.crt\n    key: b.key\n", "b.crt", "b.key"},
+		{"both", "server:\n  tls:\n    cert_file: a.crt\n    key_file: a.key\n    cert: b.crt\n    key: b.key\n", "a.crt", "a.key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0600); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tls := cfg.Server.TLS; tls.CertFile != tt.certFile || tls.KeyFile != tt.keyFile {
+				t.Fatalf("cert_file %q, key_file %q, want %q, %q", tls.CertFile, tls.KeyFile, tt.certFile, tt.keyFile)
+			}
+		})
+	}
+}
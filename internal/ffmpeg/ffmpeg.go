@@ -6,12 +6,21 @@
 package ffmpeg
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/ZSC714725/transcodemanager/internal/ffmpeg/parse"
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg/probe"
 	"github.com/ZSC714725/transcodemanager/internal/ffmpeg/skills"
 	"github.com/ZSC714725/transcodemanager/internal/logger"
 	"github.com/ZSC714725/transcodemanager/internal/process"
@@ -20,128 +29,482 @@ import (
 // FFmpeg manages FFmpeg binary and skills
 type FFmpeg interface {
 	New(config ProcessConfig) (process.Process, error)
-	NewParser(log logger.Logger, id, ref string) parse.Parser
+	NewParser(log logger.Logger, id, ref string, logLines int) parse.Parser
 	ValidateInput(address string) bool
 	ValidateOutput(address string) bool
+	// SetValidators replaces the address validators, nil allows everything.
+	// Only addresses checked afterwards are affected.
+	SetValidators(input, output Validator)
+	// Skills of the default binary
 	Skills() skills.Skills
-	ReloadSkills() error
+	// BinarySkills returns the skills of a named binary, "" is the default.
+	// A binary in Config.BinaryDir has those of the default.
+	BinarySkills(name string) (skills.Skills, error)
+	// ReloadSkills probes all binaries again. A binary that fails keeps
+	// its skills. Binaries are also reloaded when their file changes, see
+	// Config.SkillsCheckInterval.
+	ReloadSkills(ctx context.Context) error
+	// Probe runs ffprobe on an address with input options
+	Probe(ctx context.Context, address string, options []string) (probe.Result, error)
+	// TestRun runs a named binary with args until it exits, for at most
+	// timeout. It only fails if the binary can't be started.
+	TestRun(ctx context.Context, binary string, args, env []string, timeout time.Duration) (TestResult, error)
+	// Binary returns the path of a named binary, "" is the default. Other
+	// names are executables in Config.BinaryDir. It is empty for an unknown
+	// name.
+	Binary(name string) string
+	// Binaries returns the names of the binaries, sorted
+	Binaries() []string
 }
 
+// DefaultBinary is the name of Config.Binary
+const DefaultBinary = "default"
+
+// ErrNoFFprobe is returned by Probe if no ffprobe binary was found
+var ErrNoFFprobe = errors.New("ffprobe not found")
+
+// ErrUnknownBinary is returned for a binary name that isn't configured
+var ErrUnknownBinary = errors.New("unknown ffmpeg binary")
+
 // ProcessConfig for creating a process
 type ProcessConfig struct {
-	Reconnect      bool
-	ReconnectDelay time.Duration
-	StaleTimeout   time.Duration
-	Command        []string
-	Parser         process.Parser
-	Logger         logger.Logger
-	OnExit         func()
-	OnStart        func()
-	OnStateChange  func(from, to string)
+	ID                string // of the task, added to the log messages
+	Reference         string
+	Binary            string // name of the binary, "" is the default
+	Reconnect         bool
+	ReconnectDelay    time.Duration
+	ReconnectBackoff  float64
+	ReconnectMaxDelay time.Duration
+	MaxReconnects     int
+	StaleTimeout      time.Duration
+	StaleOn           string
+	RuntimeLimit      time.Duration
+	StopSignal        syscall.Signal                        // default SIGINT
+	Nice              int                                   // Linux only, 0 keeps the inherited niceness
+	IOClass           string                                // Linux only, realtime, best-effort or idle
+	ProgressPipe      bool                                  // see process.Config.ProgressPipe
+	Stdin             io.Reader                             // see process.Config.Stdin
+	Env               []string                              // KEY=VALUE, FFmpeg doesn't inherit the server's environment
+	RunEnv            func() []string                       // appended to Env, called before every run
+	RunArgs           func(args []string) ([]string, error) // replaces Command before every run
+	Command           []string
+	Parser            process.Parser
+	Logger            logger.Logger
+	OnExit            func()
+	OnStart           func()
+	OnStateChange     func(from, to string)
 }
 
 // Config for FFmpeg
 type Config struct {
-	Binary           string
-	MaxLogLines      int
-	ValidatorInput   Validator
-	ValidatorOutput  Validator
+	Binary   string
+	Binaries map[string]string // further binaries by name, e.g. cuda
+	// BinaryDir allows tasks to select any executable in it as binary, by
+	// path or relative to it. Empty allows the named binaries only.
+	BinaryDir         string
+	MaxLogLines       int
+	ProbeTimeout      time.Duration // 单次能力探测的超时
+	FFprobe           string        // 为空时使用 FFmpeg 同目录或 PATH 中的 ffprobe
+	FFprobeTimeout    time.Duration
+	DryRun            bool    // 不执行 FFmpeg，使用模拟进程与内置能力列表
+	DryRunFPS         float64 // 模拟进度的帧率
+	LogDir            string  // 非空时每个任务的日志同时写入此目录下的 <id>.log
+	LogFileSize       int64   // 日志文件轮转大小
+	LogFileKeep       int     // 轮转后保留的旧文件数
+	ProgressSmoothing float64 // see parse.Config.Smoothing
+	ProgressHistory   int     // see parse.Config.ProgressHistory
+	// SkillsCheckInterval is how often the binaries are checked for a
+	// changed modification time or size, e.g. after an upgrade in place,
+	// to reload their skills. 0 disables the check.
+	SkillsCheckInterval time.Duration
+	Logger              logger.Logger // for the skills check, optional
+	ValidatorInput      Validator
+	ValidatorOutput     Validator
 }
 
 type ffmpeg struct {
-	binary      string
-	validatorIn Validator
-	validatorOut Validator
-	skills      skills.Skills
-	logLines    int
-	skillsLock  sync.RWMutex
+	binary          string
+	binaries        map[string]*binary // by name, including the default
+	binaryDir       string             // resolved Config.BinaryDir
+	validatorIn     Validator
+	validatorOut    Validator
+	validatorLock   sync.RWMutex
+	skills          skills.Skills
+	logLines        int
+	timeout         time.Duration
+	ffprobe         string
+	ffprobeTimeout  time.Duration
+	dryRun          bool
+	dryRunFPS       float64
+	logDir          string
+	logFileSize     int64
+	logFileKeep     int
+	smoothing       float64
+	progressHistory int
+	logger          logger.Logger
+	skillsLock      sync.RWMutex
+	reloadLock      sync.Mutex // serializes reloads, a slow probe must not overwrite a newer one
+}
+
+// binary is an FFmpeg build and its skills, guarded by skillsLock. modTime
+// and size are those of the file when the skills were probed.
+type binary struct {
+	path    string
+	skills  skills.Skills
+	modTime time.Time
+	size    int64
 }
 
 // New creates FFmpeg
 func New(config Config) (FFmpeg, error) {
-	binary, err := exec.LookPath(config.Binary)
-	if err != nil {
-		return nil, fmt.Errorf("invalid ffmpeg binary: %w", err)
-	}
-
 	f := &ffmpeg{
-		binary:      binary,
-		logLines:    config.MaxLogLines,
+		binary:          config.Binary,
+		logLines:        config.MaxLogLines,
+		timeout:         config.ProbeTimeout,
+		ffprobeTimeout:  config.FFprobeTimeout,
+		dryRun:          config.DryRun,
+		dryRunFPS:       config.DryRunFPS,
+		logDir:          config.LogDir,
+		logFileSize:     config.LogFileSize,
+		logFileKeep:     config.LogFileKeep,
+		smoothing:       config.ProgressSmoothing,
+		progressHistory: config.ProgressHistory,
+		logger:          config.Logger,
 	}
 
 	if f.logLines <= 0 {
 		f.logLines = 100
 	}
 
-	if config.ValidatorInput != nil {
-		f.validatorIn = config.ValidatorInput
-	} else {
-		f.validatorIn, _ = NewValidator(nil, nil)
+	f.SetValidators(config.ValidatorInput, config.ValidatorOutput)
+
+	if config.BinaryDir != "" {
+		dir, err := filepath.EvalSymlinks(config.BinaryDir)
+		if err != nil {
+			return nil, fmt.Errorf("ffmpeg.binary_dir: %w", err)
+		}
+		if f.binaryDir, err = filepath.Abs(dir); err != nil {
+			return nil, fmt.Errorf("ffmpeg.binary_dir: %w", err)
+		}
 	}
-	if config.ValidatorOutput != nil {
-		f.validatorOut = config.ValidatorOutput
-	} else {
-		f.validatorOut, _ = NewValidator(nil, nil)
+
+	paths := map[string]string{DefaultBinary: config.Binary}
+	for name, path := range config.Binaries {
+		if name == DefaultBinary || name == "" {
+			return nil, fmt.Errorf("invalid ffmpeg binary name %q", name)
+		}
+		paths[name] = path
 	}
+	f.binaries = make(map[string]*binary, len(paths))
 
-	s, err := skills.New(f.binary)
-	if err != nil {
-		return nil, fmt.Errorf("invalid ffmpeg: %w", err)
+	if f.dryRun {
+		for name, path := range paths {
+			f.binaries[name] = &binary{path: path, skills: skills.Fixture()}
+		}
+		f.skills = f.binaries[DefaultBinary].skills
+		return f, nil
+	}
+
+	for name, path := range paths {
+		p, err := exec.LookPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ffmpeg binary %s: %w", name, err)
+		}
+		modTime, size, _ := statBinary(p)
+		s, err := skills.New(context.Background(), p, f.timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ffmpeg %s: %w", name, err)
+		}
+		f.binaries[name] = &binary{path: p, skills: s, modTime: modTime, size: size}
+	}
+	f.binary = f.binaries[DefaultBinary].path
+	f.skills = f.binaries[DefaultBinary].skills
+	f.ffprobe = lookFFprobe(config.FFprobe, f.binary)
+
+	if config.SkillsCheckInterval > 0 {
+		if f.logger == nil {
+			f.logger = logger.New("ffmpeg", logger.LevelInfo)
+		}
+		go f.watchSkills(config.SkillsCheckInterval)
 	}
-	f.skills = s
 
 	return f, nil
 }
 
+// statBinary returns the modification time and size of a binary, following
+// symlinks such as /etc/alternatives
+func statBinary(path string) (time.Time, int64, bool) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	return fi.ModTime(), fi.Size(), true
+}
+
+// watchSkills reloads the skills of the binaries whose file changed
+func (f *ffmpeg) watchSkills(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		f.checkSkills()
+	}
+}
+
+func (f *ffmpeg) checkSkills() {
+	f.reloadLock.Lock()
+	defer f.reloadLock.Unlock()
+
+	for _, name := range f.Binaries() {
+		b := f.binaries[name]
+		modTime, size, ok := statBinary(b.path)
+		if !ok {
+			// 升级过程中文件可能暂时不存在，下次再检查
+			continue
+		}
+		f.skillsLock.RLock()
+		changed := !modTime.Equal(b.modTime) || size != b.size
+		f.skillsLock.RUnlock()
+		if !changed {
+			continue
+		}
+
+		f.logger.Info("skills: %s (%s) changed, reloading", name, b.path)
+		if err := f.reload(context.Background(), name, b); err != nil {
+			f.logger.Error("%s, keeping the previous skills", err)
+			// 文件再次变化（如复制完成）时重试，而不是每次检查都重试
+			f.skillsLock.Lock()
+			b.modTime, b.size = modTime, size
+			f.skillsLock.Unlock()
+			continue
+		}
+		sk, _ := f.BinarySkills(name)
+		f.logger.Info("skills: %s reloaded, version %s", name, sk.FFmpeg.Version)
+		for _, w := range sk.Warnings {
+			f.logger.Error("skills: %s: %s", name, w)
+		}
+	}
+}
+
+// reload probes a binary again, the caller must hold reloadLock. On error
+// the binary keeps its skills.
+func (f *ffmpeg) reload(ctx context.Context, name string, b *binary) error {
+	modTime, size, _ := statBinary(b.path)
+	s, err := skills.New(ctx, b.path, f.timeout)
+	if err != nil {
+		return fmt.Errorf("reload skills of %s: %w", name, err)
+	}
+
+	f.skillsLock.Lock()
+	defer f.skillsLock.Unlock()
+	b.skills = s
+	b.modTime = modTime
+	b.size = size
+	if name == DefaultBinary {
+		f.skills = s
+	}
+	return nil
+}
+
+// lookFFprobe finds ffprobe, preferring the one next to FFmpeg. It returns
+// an empty path if there is none.
+func lookFFprobe(path, ffmpegBinary string) string {
+	if path != "" {
+		p, _ := exec.LookPath(path)
+		return p
+	}
+	sibling := filepath.Join(filepath.Dir(ffmpegBinary), "ffprobe"+filepath.Ext(ffmpegBinary))
+	if p, err := exec.LookPath(sibling); err == nil {
+		return p
+	}
+	p, _ := exec.LookPath("ffprobe")
+	return p
+}
+
 func (f *ffmpeg) New(config ProcessConfig) (process.Process, error) {
-	return process.New(process.Config{
-		Binary:         f.binary,
-		Args:           config.Command,
-		Reconnect:      config.Reconnect,
-		ReconnectDelay: config.ReconnectDelay,
-		StaleTimeout:   config.StaleTimeout,
-		Parser:         config.Parser,
-		Logger:         wrapLogger(config.Logger),
-		OnStart:        config.OnStart,
-		OnExit:         config.OnExit,
-		OnStateChange:  config.OnStateChange,
-	})
+	path := f.Binary(config.Binary)
+	if path == "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBinary, config.Binary)
+	}
+
+	pc := process.Config{
+		Binary:            path,
+		Args:              config.Command,
+		Reconnect:         config.Reconnect,
+		ReconnectDelay:    config.ReconnectDelay,
+		ReconnectBackoff:  config.ReconnectBackoff,
+		ReconnectMaxDelay: config.ReconnectMaxDelay,
+		MaxReconnects:     config.MaxReconnects,
+		Env:               config.Env,
+		RunEnv:            config.RunEnv,
+		RunArgs:           config.RunArgs,
+		StaleTimeout:      config.StaleTimeout,
+		StaleOn:           config.StaleOn,
+		RuntimeLimit:      config.RuntimeLimit,
+		StopSignal:        config.StopSignal,
+		Nice:              config.Nice,
+		IOClass:           config.IOClass,
+		ProgressPipe:      config.ProgressPipe,
+		Stdin:             config.Stdin,
+		Parser:            config.Parser,
+		Logger:            wrapLogger(config.Logger, config.ID, config.Reference),
+		OnStart:           config.OnStart,
+		OnExit:            config.OnExit,
+		OnStateChange:     config.OnStateChange,
+	}
+
+	if f.dryRun {
+		return process.NewSimulator(process.SimulatorConfig{Config: pc, FPS: f.dryRunFPS})
+	}
+	return process.New(pc)
 }
 
-func (f *ffmpeg) NewParser(log logger.Logger, id, ref string) parse.Parser {
-	return parse.New(parse.Config{LogLines: f.logLines})
+func (f *ffmpeg) NewParser(log logger.Logger, id, ref string, logLines int) parse.Parser {
+	if logLines <= 0 {
+		logLines = f.logLines
+	}
+	config := parse.Config{LogLines: logLines, Smoothing: f.smoothing, ProgressHistory: f.progressHistory}
+	if f.logDir != "" {
+		// ID 由用户指定，转义后作为文件名
+		config.LogFile = filepath.Join(f.logDir, url.PathEscape(id)+".log")
+		config.LogFileSize = f.logFileSize
+		config.LogFileKeep = f.logFileKeep
+		config.Logger = wrapLogger(log, id, ref)
+	}
+	return parse.New(config)
 }
 
 func (f *ffmpeg) ValidateInput(address string) bool {
+	f.validatorLock.RLock()
+	defer f.validatorLock.RUnlock()
 	return f.validatorIn.IsValid(address)
 }
 
 func (f *ffmpeg) ValidateOutput(address string) bool {
+	f.validatorLock.RLock()
+	defer f.validatorLock.RUnlock()
 	return f.validatorOut.IsValid(address)
 }
 
+func (f *ffmpeg) SetValidators(input, output Validator) {
+	if input == nil {
+		input, _ = NewValidator(nil, nil)
+	}
+	if output == nil {
+		output, _ = NewValidator(nil, nil)
+	}
+	f.validatorLock.Lock()
+	f.validatorIn = input
+	f.validatorOut = output
+	f.validatorLock.Unlock()
+}
+
+func (f *ffmpeg) Binary(name string) string {
+	if name == "" {
+		name = DefaultBinary
+	}
+	if b, ok := f.binaries[name]; ok {
+		return b.path
+	}
+	return f.lookBinary(name)
+}
+
+// lookBinary returns the path of an executable in the binary dir, relative
+// names are looked up in it. Symlinks must not lead out of it. It returns
+// "" if the binary isn't allowed.
+func (f *ffmpeg) lookBinary(name string) string {
+	if f.binaryDir == "" {
+		return ""
+	}
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(f.binaryDir, path)
+	}
+	path, err := exec.LookPath(path)
+	if err != nil {
+		return ""
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil || filepath.Dir(path) != f.binaryDir {
+		return ""
+	}
+	return path
+}
+
+func (f *ffmpeg) Binaries() []string {
+	names := make([]string, 0, len(f.binaries))
+	for name := range f.binaries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (f *ffmpeg) Skills() skills.Skills {
 	f.skillsLock.RLock()
 	defer f.skillsLock.RUnlock()
 	return f.skills
 }
 
-func (f *ffmpeg) ReloadSkills() error {
-	s, err := skills.New(f.binary)
-	if err != nil {
-		return fmt.Errorf("reload skills: %w", err)
+func (f *ffmpeg) BinarySkills(name string) (skills.Skills, error) {
+	if name == "" {
+		name = DefaultBinary
 	}
-	f.skillsLock.Lock()
-	f.skills = s
-	f.skillsLock.Unlock()
-	return nil
+	f.skillsLock.RLock()
+	defer f.skillsLock.RUnlock()
+	b, ok := f.binaries[name]
+	if !ok {
+		// 按路径选择的构建不探测，沿用默认构建的能力
+		if f.lookBinary(name) != "" {
+			return f.binaries[DefaultBinary].skills, nil
+		}
+		return skills.Skills{}, fmt.Errorf("%w: %s", ErrUnknownBinary, name)
+	}
+	return b.skills, nil
+}
+
+func (f *ffmpeg) ReloadSkills(ctx context.Context) error {
+	if f.dryRun {
+		return nil
+	}
+
+	f.reloadLock.Lock()
+	defer f.reloadLock.Unlock()
+
+	var errs []error
+	for _, name := range f.Binaries() {
+		if err := f.reload(ctx, name, f.binaries[name]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f *ffmpeg) Probe(ctx context.Context, address string, options []string) (probe.Result, error) {
+	if f.dryRun {
+		r := probe.Fixture()
+		r.Time = time.Now().Unix()
+		return r, nil
+	}
+	if f.ffprobe == "" {
+		return probe.Result{}, ErrNoFFprobe
+	}
+	return probe.Run(ctx, f.ffprobe, address, options, f.ffprobeTimeout)
 }
 
-func wrapLogger(l logger.Logger) *loggerWrapper {
+// wrapLogger adds the task_id and reference fields of a task to l
+func wrapLogger(l logger.Logger, id, ref string) *loggerWrapper {
 	if l == nil {
 		return &loggerWrapper{prefix: ""}
 	}
-	return &loggerWrapper{logger: l, prefix: ""}
+	fields := logger.Fields{}
+	if id != "" {
+		fields["task_id"] = id
+	}
+	if ref != "" {
+		fields["reference"] = ref
+	}
+	return &loggerWrapper{logger: logger.WithFields(l, fields), prefix: ""}
 }
 
 type loggerWrapper struct {
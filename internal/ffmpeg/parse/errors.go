@@ -0,0 +1,65 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package parse
+
+import (
+	"strings"
+	"time"
+)
+
+// Error categories recognized in FFmpeg's stderr
+const (
+	ErrorConnectionRefused = "connection_refused"
+	ErrorTimeout           = "timeout"
+	ErrorNotFound          = "not_found"
+	ErrorInvalidData       = "invalid_data"
+	ErrorPermissionDenied  = "permission_denied"
+	ErrorUnauthorized      = "unauthorized"
+	ErrorForbidden         = "forbidden"
+	ErrorHTTP              = "http"
+	ErrorUnknownEncoder    = "unknown_encoder"
+	ErrorOutOfMemory       = "out_of_memory"
+	ErrorExitRequested     = "exit_requested"
+)
+
+// Error is a classified error line
+type Error struct {
+	Category string
+	Message  string
+	Time     time.Time
+}
+
+// errorPatterns are checked in order, the first match wins
+var errorPatterns = []struct {
+	category string
+	patterns []string
+}{
+	{ErrorConnectionRefused, []string{"Connection refused"}},
+	{ErrorTimeout, []string{"Connection timed out", "Operation timed out"}},
+	{ErrorNotFound, []string{"No such file or directory", "Server returned 404", "HTTP error 404"}},
+	{ErrorInvalidData, []string{"Invalid data found when processing input"}},
+	{ErrorPermissionDenied, []string{"Permission denied"}},
+	// 401、403 先于其他 HTTP 错误
+	{ErrorUnauthorized, []string{"Server returned 401", "HTTP error 401"}},
+	{ErrorForbidden, []string{"Server returned 403", "HTTP error 403"}},
+	{ErrorHTTP, []string{"Server returned 4", "Server returned 5", "HTTP error"}},
+	{ErrorUnknownEncoder, []string{"Unknown encoder", "Encoder not found", "not found for output stream", "Automatic encoder selection failed"}},
+	{ErrorOutOfMemory, []string{"Cannot allocate memory", "Out of memory", "out of memory", "OUT_OF_MEMORY"}},
+	{ErrorExitRequested, []string{"Immediate exit requested"}},
+}
+
+// classify returns the category of an error line, empty if the line isn't
+// a known error
+func classify(line string) string {
+	for _, e := range errorPatterns {
+		for _, p := range e.patterns {
+			if strings.Contains(line, p) {
+				return e.category
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package parse
+
+import "testing"
+
+func TestLastError(t *testing.T) {
+	p := New(Config{})
+	if p.LastError() != nil {
+		t.Fatal("error before any output")
+	}
+
+	sample := []string{
+		"ffmpeg version 6.0 Copyright (c) 2000-2023 the FFmpeg developers",
+		"[tcp @ 0x5581f2c0c840] Starting connection attempt to 10.0.0.5 port 1935",
+		"frame=  100 fps= 25 q=28.0 size=    1024kB time=00:00:04.00 bitrate=2097.2kbits/s speed=1.00x",
+		"[tcp @ 0x5581f2c0c840] Connection to tcp://10.0.0.5:1935 failed: Connection refused",
+		"rtmp://10.0.0.5/live/stream: Connection refused",
+	}
+	for _, line := range sample {
+		p.Parse(line)
+	}
+	e := p.LastError()
+	if e == nil {
+		t.Fatal("no error after Connection refused")
+	}
+	if e.Category != ErrorConnectionRefused || e.Message != sample[len(sample)-1] || e.Time.IsZero() {
+		t.Fatalf("last error %+v, want %s with the last line", e, ErrorConnectionRefused)
+	}
+
+	// 之后的非错误行不会清除，新的错误替换之前的
+	p.Parse("Exiting normally, received signal 2.")
+	if e := p.LastError(); e == nil || e.Category != ErrorConnectionRefused {
+		t.Fatalf("last error %+v after a normal line", e)
+	}
+	p.Parse("[in#0 @ 0x55] Error opening input: Server returned 403 Forbidden (access denied)")
+	if e := p.LastError(); e == nil || e.Category != ErrorForbidden {
+		t.Fatalf("last error %+v, want %s", e, ErrorForbidden)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := map[string]string{
+		"/data/in.mp4: No such file or directory":                     ErrorNotFound,
+		"in.ts: Invalid data found when processing input":             ErrorInvalidData,
+		"/archive/out.mp4: Permission denied":                         ErrorPermissionDenied,
+		"Immediate exit requested":                                    ErrorExitRequested,
+		"[http @ 0x55] HTTP error 401 Unauthorized":                   ErrorUnauthorized,
+		"Server returned 503 Service Unavailable":                     ErrorHTTP,
+		"Unknown encoder 'h264_nvenc'":                                ErrorUnknownEncoder,
+		"Stream #0:0: Video: h264 (High), yuv420p, 1920x1080, 25 fps": "",
+	}
+	for line, want := range tests {
+		if got := classify(line); got != want {
+			t.Errorf("classify(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package parse
+
+import (
+	"math"
+	"testing"
+)
+
+func TestETA(t *testing.T) {
+	tests := []struct {
+		name string
+		prog Progress
+		want float64
+	}{
+		{"smoothed speed", Progress{Duration: 100, Time: 40, Speed: 4, SpeedAvg: 2}, 30},
+		{"instantaneous speed", Progress{Duration: 100, Time: 40, Speed: 3}, 20},
+		{"live", Progress{Time: 40, Speed: 1, SpeedAvg: 1}, -1},
+		{"no speed", Progress{Duration: 100, Time: 40}, -1},
+		{"negative speed", Progress{Duration: 100, Time: 40, Speed: -1}, -1},
+		{"past the end", Progress{Duration: 100, Time: 100.5, SpeedAvg: 1}, 0},
+		{"not started", Progress{Duration: 60, SpeedAvg: 0.5}, 120},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.prog.ETA(); math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("ETA() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  float64
+	}{
+		{"vod", []string{
+			"Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'in.mp4':",
+			"  Duration: 00:01:30.50, start: 0.000000, bitrate: 1205 kb/s",
+		}, 90.5},
+		{"hours", []string{
+			"Input #0, matroska,webm, from 'in.mkv':",
+			"  Duration: 02:00:01.00, start: 0.000000, bitrate: 800 kb/s",
+		}, 7201},
+		{"live", []string{
+			"Input #0, flv, from 'rtmp://example.com/live/in':",
+			"  Duration: N/A, start: 0.000000, bitrate: N/A",
+		}, 0},
+		{"first input only", []string{
+			"Input #0, flv, from 'rtmp://example.com/live/in':",
+			"  Duration: N/A, start: 0.000000, bitrate: N/A",
+			"Input #1, mp3, from 'music.mp3':",
+			"  Duration: 00:03:00.00, start: 0.000000, bitrate: 128 kb/s",
+		}, 0},
+		{"outputs ignored", []string{
+			"Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'in.mp4':",
+			"  Duration: 00:00:10.00, start: 0.000000, bitrate: 1205 kb/s",
+			"Output #0, mp4, to 'out.mp4':",
+			"  Duration: 00:00:20.00, start: 0.000000, bitrate: 1205 kb/s",
+		}, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(Config{})
+			for _, line := range tt.lines {
+				p.Parse(line)
+			}
+			if got := p.Progress().Duration; got != tt.want {
+				t.Fatalf("duration %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
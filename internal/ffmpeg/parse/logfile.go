@@ -0,0 +1,225 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package parse
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+)
+
+const (
+	// defaultLogFileSize is the size at which a log file is rotated
+	defaultLogFileSize = 10 * 1024 * 1024
+	// logFileQueue is the number of lines buffered for the writer. Lines
+	// beyond it are dropped rather than blocking the reader.
+	logFileQueue = 4096
+)
+
+// logFile appends log lines to a file. When the file reaches maxBytes it
+// is renamed to path.1, the older ones shift to path.2 and so on up to
+// path.<keep>, and a new file is started.
+//
+// Lines are written by a goroutine, so a slow disk never blocks the reader
+// of the FFmpeg output.
+type logFile struct {
+	path     string
+	maxBytes int64
+	keep     int
+	logger   logger.Logger
+
+	queue   chan string
+	done    chan struct{}
+	dropped uint64 // lines dropped since the last write, guarded by lock
+	closed  bool
+	lock    sync.Mutex
+
+	// used by the writer goroutine only, files and rotate also hold fileLock
+	f        *os.File
+	w        *bufio.Writer
+	size     int64
+	failed   bool // opening failed, don't retry on every line
+	fileLock sync.Mutex
+}
+
+func newLogFile(path string, maxBytes int64, keep int, log logger.Logger) *logFile {
+	if maxBytes <= 0 {
+		maxBytes = defaultLogFileSize
+	}
+	if keep <= 0 {
+		keep = 1
+	}
+	l := &logFile{
+		path:     path,
+		maxBytes: maxBytes,
+		keep:     keep,
+		logger:   log,
+		queue:    make(chan string, logFileQueue),
+		done:     make(chan struct{}),
+	}
+	go l.writer()
+	return l
+}
+
+// write queues a line, it never blocks
+func (l *logFile) write(t time.Time, line string) {
+	data := t.Format("2006-01-02T15:04:05.000Z07:00") + " " + line + "\n"
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.closed {
+		return
+	}
+	if l.dropped > 0 {
+		// 队列有空位后先记录丢弃的行数
+		select {
+		case l.queue <- fmt.Sprintf("%s [log file] %d lines dropped, the disk is too slow\n", t.Format("2006-01-02T15:04:05.000Z07:00"), l.dropped):
+			l.dropped = 0
+		default:
+		}
+	}
+	if l.dropped > 0 {
+		l.dropped++
+		return
+	}
+	select {
+	case l.queue <- data:
+	default:
+		l.dropped++
+	}
+}
+
+// writer writes the queued lines and flushes when the queue is empty, so
+// that the file is complete for downloads while the task runs
+func (l *logFile) writer() {
+	defer close(l.done)
+
+	for data := range l.queue {
+		l.fileLock.Lock()
+		l.writeLine(data)
+		if len(l.queue) == 0 && l.w != nil {
+			l.flush()
+		}
+		l.fileLock.Unlock()
+	}
+
+	l.fileLock.Lock()
+	if l.f != nil {
+		l.flush()
+		l.f.Close()
+		l.f = nil
+	}
+	l.fileLock.Unlock()
+}
+
+// writeLine is called with fileLock held
+func (l *logFile) writeLine(data string) {
+	if l.failed {
+		return
+	}
+	if l.f != nil && l.size > 0 && l.size+int64(len(data)) > l.maxBytes {
+		l.rotate()
+	}
+	if l.f == nil && !l.open() {
+		return
+	}
+
+	n, _ := l.w.WriteString(data)
+	l.size += int64(n)
+}
+
+// flush is called with fileLock held
+func (l *logFile) flush() {
+	if err := l.w.Flush(); err != nil && l.logger != nil {
+		l.logger.Error("write log file %s: %v", l.path, err)
+	}
+}
+
+// open opens the file lazily, so tasks that never run don't create one.
+// The caller must hold fileLock.
+func (l *logFile) open() bool {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		l.fail(err)
+		return false
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		l.fail(err)
+		return false
+	}
+	l.f = f
+	l.w = bufio.NewWriterSize(f, 64*1024)
+	l.size = 0
+	if info, err := f.Stat(); err == nil {
+		l.size = info.Size()
+	}
+	return true
+}
+
+// rotate is called with fileLock held
+func (l *logFile) rotate() {
+	l.flush()
+	l.f.Close()
+	l.f = nil
+
+	// path.<keep> 被覆盖，其余依次后移
+	for i := l.keep - 1; i >= 1; i-- {
+		os.Rename(l.rotated(i), l.rotated(i+1))
+	}
+	if err := os.Rename(l.path, l.rotated(1)); err != nil && l.logger != nil {
+		l.logger.Error("rotate log file %s: %v", l.path, err)
+	}
+}
+
+// rotated returns the path of the nth previous file
+func (l *logFile) rotated(n int) string {
+	return l.path + "." + strconv.Itoa(n)
+}
+
+func (l *logFile) fail(err error) {
+	l.failed = true
+	if l.logger != nil {
+		l.logger.Error("open log file %s: %v", l.path, err)
+	}
+}
+
+// files returns the existing log files, oldest first
+func (l *logFile) files() []string {
+	l.fileLock.Lock()
+	defer l.fileLock.Unlock()
+
+	var files []string
+	for i := l.keep; i >= 0; i-- {
+		path := l.path
+		if i > 0 {
+			path = l.rotated(i)
+		}
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// close writes the queued lines and closes the file
+func (l *logFile) close() error {
+	l.lock.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.lock.Unlock()
+
+	<-l.done
+	return nil
+}
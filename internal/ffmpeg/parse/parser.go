@@ -8,82 +8,230 @@ package parse
 import (
 	"container/ring"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/ZSC714725/transcodemanager/internal/logger"
 	"github.com/ZSC714725/transcodemanager/internal/process"
 )
 
 // Progress holds FFmpeg progress info parsed from stderr
 type Progress struct {
-	Frame    uint64  `json:"frame"`
-	Size     uint64  `json:"size_bytes"`
-	Time     float64 `json:"time_seconds"`
-	Speed    float64 `json:"speed"`
-	Drop     uint64  `json:"drop"`
-	Dup      uint64  `json:"dup"`
+	Frame   uint64  `json:"frame"`
+	FPS     float64 `json:"fps"`
+	Size    uint64  `json:"size_bytes"`
+	Time    float64 `json:"time_seconds"`
+	Bitrate float64 `json:"bitrate_kbit"`
+	Speed   float64 `json:"speed"`
+	// BitrateAvg and SpeedAvg are exponential moving averages of Bitrate
+	// and Speed, see Config.Smoothing
+	BitrateAvg float64 `json:"bitrate_kbit_avg"`
+	SpeedAvg   float64 `json:"speed_avg"`
+	// Duration of the first input, 0 if it is unknown, e.g. live
+	Duration  float64 `json:"duration_seconds"`
+	Drop      uint64  `json:"drop"`
+	Dup       uint64  `json:"dup"`
 	Quantizer float64 `json:"q"`
 }
 
+// ProgressSample is the overall progress at a point in time
+type ProgressSample struct {
+	Time time.Time
+	Progress
+}
+
+// ETA returns the seconds until the first input is transcoded at the
+// smoothed speed, -1 if the duration or the speed is unknown
+func (p Progress) ETA() float64 {
+	speed := p.SpeedAvg
+	if speed <= 0 {
+		speed = p.Speed
+	}
+	if p.Duration <= 0 || speed <= 0 {
+		return -1
+	}
+	// 接近结束时 time 可能略超过 duration
+	return max(p.Duration-p.Time, 0) / speed
+}
+
 // Parser implements process.Parser and parses FFmpeg stderr
 type Parser interface {
 	process.Parser
+	process.ProgressParser
 	Progress() Progress
+	// Outputs returns the progress of each output index, if FFmpeg reports it
+	Outputs() map[int]Progress
+	// Failures returns the error of each failed tee slave, by slave index
+	Failures() map[int]string
+	// LastError returns the most recent classified error of the current or
+	// last run, nil if there is none
+	LastError() *Error
+	// Segments returns the number of HLS segments opened in the current run
+	Segments() uint64
+	// Scte35 returns the SCTE-35 packet counts of the current run
+	Scte35() Scte35
+	// LogAfter returns up to limit lines with a Seq above after, oldest
+	// first; limit <= 0 returns all. gap reports that lines after after are
+	// lost, they fell out of the buffer, or after is ahead of the log.
+	LogAfter(after uint64, limit int) (lines []process.Line, gap bool)
+	// ProgressHistory returns the samples of the overall progress of the
+	// current run, oldest first, up to Config.ProgressHistory
+	ProgressHistory() []ProgressSample
+	// Prelude returns the lines of the current run before its first progress
+	// line: banner, streams and mapping. It is capped at maxPreludeLines.
+	Prelude() []string
+	// LastLine returns the most recent non-progress line. It is kept across
+	// restarts until the new run writes a line.
+	LastLine() string
+	// Memory returns the approximate bytes held by the log buffer
+	Memory() uint64
+	// LogLines returns the capacity of the log buffer
+	LogLines() int
+	// SetLogLines resizes the log buffer, keeping the newest lines
+	SetLogLines(n int)
+	// LogFiles returns the log files, oldest first, nil if there are none
+	LogFiles() []string
+	// Close closes the log file
+	Close() error
 }
 
+// maxPreludeLines caps the prelude of a run
+const maxPreludeLines = 200
+
+// DefaultProgressHistory keeps about 5 minutes of progress, FFmpeg prints
+// about two progress lines a second
+const DefaultProgressHistory = 600
+
+// sampleSize approximates the bytes of a ProgressSample
+const sampleSize = 160
+
+// LineOverhead approximates the per-line cost besides the data itself
+// (timestamp, string header, ring element)
+const LineOverhead = 64
+
 type parser struct {
 	re struct {
-		frame      *regexp.Regexp
-		quantizer  *regexp.Regexp
-		size       *regexp.Regexp
-		sizeBytes  *regexp.Regexp
-		time       *regexp.Regexp
-		timeMs     *regexp.Regexp
-		speed      *regexp.Regexp
-		drop       *regexp.Regexp
-		dup        *regexp.Regexp
+		output      *regexp.Regexp
+		progressKey *regexp.Regexp
+		duration    *regexp.Regexp
+		teeFailed   *regexp.Regexp
+		segment     *regexp.Regexp
+		frame       *regexp.Regexp
+		fps         *regexp.Regexp
+		quantizer   *regexp.Regexp
+		size        *regexp.Regexp
+		sizeBytes   *regexp.Regexp
+		time        *regexp.Regexp
+		timeMs      *regexp.Regexp
+		bitrate     *regexp.Regexp
+		speed       *regexp.Regexp
+		drop        *regexp.Regexp
+		dup         *regexp.Regexp
 	}
 
-	log      *ring.Ring
-	logLines int
-	logStart time.Time
+	log       *ring.Ring
+	logLines  int
+	logStart  time.Time
+	seq       uint64 // of the newest line, kept by ResetLog
+	prelude   []string
+	inPrelude bool // until the first progress line of the run
 
-	progress Progress
-	lock     sync.RWMutex
+	progress   Progress
+	history    *ring.Ring // of ProgressSample, nil if disabled
+	historyLen int
+	outputs    map[int]Progress
+	failures   map[int]string
+	segments   uint64
+	scte35     Scte35
+	// scte35Streams are the scte_35 input streams by file:stream index
+	scte35Streams map[string]bool
+	inInputs      bool // between "Input #" and the output section
+	input         int  // index of the input section the lines are in, -1 outside
+	smoothing     float64
+	lastErr       *Error
+	lastLine      string
+	file          *logFile
+	lock          sync.RWMutex
 }
 
 // Config for the parser
 type Config struct {
 	LogLines int
+	// LogFile, if set, also receives every non-progress line. It is rotated
+	// at LogFileSize bytes, keeping LogFileKeep previous files as LogFile.1
+	// (the newest) to LogFile.<LogFileKeep>, default 1.
+	LogFile     string
+	LogFileSize int64
+	LogFileKeep int
+	Logger      logger.Logger
+	// Smoothing is the weight of the newest value in BitrateAvg and
+	// SpeedAvg, between 0 and 1. 0 is DefaultSmoothing, 1 disables it.
+	Smoothing float64
+	// ProgressHistory is the number of progress samples kept, 0 is
+	// DefaultProgressHistory, negative disables the history
+	ProgressHistory int
 }
 
+// DefaultSmoothing weighs the newest value at 20%, FFmpeg prints about
+// two progress lines a second
+const DefaultSmoothing = 0.2
+
 // New creates a Parser
 func New(config Config) Parser {
 	p := &parser{
-		logLines: config.LogLines,
+		logLines:  config.LogLines,
+		smoothing: config.Smoothing,
 	}
 	if p.logLines <= 0 {
 		p.logLines = 100
 	}
+	if p.smoothing <= 0 || p.smoothing > 1 {
+		p.smoothing = DefaultSmoothing
+	}
+	p.re.output = regexp.MustCompile(`^\[out#([0-9]+)`)
+	p.re.progressKey = regexp.MustCompile(`^(fps|stream_[0-9]+_[0-9]+_q|bitrate|total_size|out_time(_ms|_us)?|dup_frames|drop_frames|speed)=`) // -progress 每行一个字段
+	p.re.duration = regexp.MustCompile(`^\s+Duration: ([0-9]+):([0-9]{2}):([0-9]{2}(?:\.[0-9]+)?)`)
+	p.re.teeFailed = regexp.MustCompile(`Slave muxer #([0-9]+) failed[:,]\s*(.*?)(?:, continuing with .*)?$`)
+	p.re.segment = regexp.MustCompile(`^\[hls @ [^\]]+\] Opening '(.+)' for writing`)
 	p.re.frame = regexp.MustCompile(`frame=\s*([0-9]+)`)
+	p.re.fps = regexp.MustCompile(`fps=\s*([0-9\.]+)`)
 	p.re.quantizer = regexp.MustCompile(`q=\s*([0-9\.]+)`)
 	p.re.size = regexp.MustCompile(`size=\s*([0-9]+)kB`)
 	p.re.time = regexp.MustCompile(`time=\s*([0-9]+):([0-9]{2}):([0-9]{2})\.([0-9]+)`) // 支持 .0 .00 .000 等
-	p.re.timeMs = regexp.MustCompile(`out_time_ms=\s*([0-9]+)`)                         // -progress 输出
-	p.re.sizeBytes = regexp.MustCompile(`total_size=\s*([0-9]+)`)                        // -progress 输出
+	p.re.timeMs = regexp.MustCompile(`out_time_ms=\s*([0-9]+)`)                        // -progress 输出
+	p.re.sizeBytes = regexp.MustCompile(`total_size=\s*([0-9]+)`)                      // -progress 输出
+	p.re.bitrate = regexp.MustCompile(`bitrate=\s*(?:([0-9\.]+)kbits/s|N/A)`)          // 输出大小未知时为 N/A
 	p.re.speed = regexp.MustCompile(`speed=\s*([0-9\.]+)x`)
 	p.re.drop = regexp.MustCompile(`drop=\s*([0-9]+)|drop_frames=\s*([0-9]+)`)
 	p.re.dup = regexp.MustCompile(`dup=\s*([0-9]+)|dup_frames=\s*([0-9]+)`)
 
 	p.log = ring.New(p.logLines)
 	p.logStart = time.Now()
+	p.inPrelude = true
+	p.input = -1
+	if config.ProgressHistory == 0 {
+		config.ProgressHistory = DefaultProgressHistory
+	}
+	if config.ProgressHistory > 0 {
+		p.historyLen = config.ProgressHistory
+		p.history = ring.New(p.historyLen)
+	}
+	if config.LogFile != "" {
+		p.file = newLogFile(config.LogFile, config.LogFileSize, config.LogFileKeep, config.Logger)
+	}
 	return p
 }
 
-func (p *parser) Parse(line string) uint64 {
-	isProgress := strings.Contains(line, "frame=")
+func (p *parser) Parse(line string) process.ParseResult {
+	// 新版 FFmpeg 以 [out#N/...] 前缀输出单路输出的进度
+	output := -1
+	if m := p.re.output.FindStringSubmatch(line); m != nil {
+		output, _ = strconv.Atoi(m[1])
+	}
+	isProgress := strings.Contains(line, "frame=") || (output >= 0 && strings.Contains(line, "time=")) || p.re.progressKey.MatchString(line)
 	now := time.Now()
 
 	if p.logStart.IsZero() {
@@ -93,35 +241,119 @@ func (p *parser) Parse(line string) uint64 {
 	}
 
 	p.lock.Lock()
+	if !isProgress && p.parseScte35(line) {
+		// -debug_ts 每个数据包一行，不计入日志
+		p.lock.Unlock()
+		return process.ParseResult{}
+	}
 	if !isProgress {
-		p.log.Value = process.Line{Timestamp: now, Data: line}
+		p.seq++
+		p.log.Value = process.Line{Seq: p.seq, Timestamp: now, Data: line}
 		p.log = p.log.Next()
+		if strings.TrimSpace(line) != "" {
+			p.lastLine = line
+		}
+		if p.inPrelude && len(p.prelude) < maxPreludeLines {
+			p.prelude = append(p.prelude, line)
+		}
+		p.parseDuration(line)
+		if category := classify(line); category != "" {
+			p.lastErr = &Error{Category: category, Message: line, Time: now}
+		}
+		// 打开新分片即上一个分片已完成，播放列表（.m3u8 / .m3u8.tmp）不计
+		if m := p.re.segment.FindStringSubmatch(line); m != nil && !strings.Contains(m[1], ".m3u8") {
+			p.segments++
+		}
+		// tee 的 onfail=ignore 输出失败后任务继续运行
+		if m := p.re.teeFailed.FindStringSubmatch(line); m != nil {
+			if slave, err := strconv.Atoi(m[1]); err == nil {
+				if p.failures == nil {
+					p.failures = make(map[int]string)
+				}
+				p.failures[slave] = m[2]
+			}
+		}
 		p.lock.Unlock()
-		return 0
+		if p.file != nil {
+			p.file.write(now, line)
+		}
+		return process.ParseResult{}
 	}
 	// progress 行也计入日志，便于查看 frame/speed 等信息
-	p.log.Value = process.Line{Timestamp: now, Data: line}
+	p.inPrelude = false
+	p.seq++
+	p.log.Value = process.Line{Seq: p.seq, Timestamp: now, Data: line}
 	p.log = p.log.Next()
 	defer p.lock.Unlock()
 
+	return p.updateProgress(line, output, now)
+}
+
+// ParseProgress parses a line of -progress output, e.g. out_time_us=...,
+// like a progress line of stderr but without logging it
+func (p *parser) ParseProgress(line string) process.ParseResult {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.inPrelude = false
+	return p.updateProgress(strings.TrimSpace(line), -1, time.Now())
+}
+
+// updateProgress updates the progress of output, -1 for the overall one,
+// with a progress line. The caller must hold the lock.
+func (p *parser) updateProgress(line string, output int, now time.Time) process.ParseResult {
+	prog := p.progress
+	if output >= 0 {
+		prog = p.outputs[output]
+	}
+
+	prev := prog
+	p.parseProgress(line, &prog)
+
+	if output >= 0 {
+		if p.outputs == nil {
+			p.outputs = make(map[int]Progress)
+		}
+		p.outputs[output] = prog
+	} else {
+		p.progress = prog
+		// -progress 每个字段一行，以 speed= 结束一组，只记录一次
+		if p.history != nil && (strings.Count(line, "=") > 1 || strings.HasPrefix(line, "speed=")) {
+			p.history.Value = ProgressSample{Time: now, Progress: prog}
+			p.history = p.history.Next()
+		}
+	}
+
+	return process.ParseResult{
+		Progress: true,
+		Advanced: prog.Frame > prev.Frame || prog.Time > prev.Time,
+	}
+}
+
+// parseProgress updates prog with the fields found in line
+func (p *parser) parseProgress(line string, prog *Progress) {
 	if m := p.re.frame.FindStringSubmatch(line); m != nil {
 		if x, err := strconv.ParseUint(m[1], 10, 64); err == nil {
-			p.progress.Frame = x
+			prog.Frame = x
+		}
+	}
+	if m := p.re.fps.FindStringSubmatch(line); m != nil {
+		if x, err := strconv.ParseFloat(m[1], 64); err == nil {
+			prog.FPS = x
 		}
 	}
 	if m := p.re.quantizer.FindStringSubmatch(line); m != nil {
 		if x, err := strconv.ParseFloat(m[1], 64); err == nil {
-			p.progress.Quantizer = x
+			prog.Quantizer = x
 		}
 	}
 	if m := p.re.size.FindStringSubmatch(line); m != nil {
 		if x, err := strconv.ParseUint(m[1], 10, 64); err == nil {
-			p.progress.Size = x * 1024
+			prog.Size = x * 1024
 		}
 	}
 	if m := p.re.sizeBytes.FindStringSubmatch(line); m != nil {
 		if x, err := strconv.ParseUint(m[1], 10, 64); err == nil {
-			p.progress.Size = x
+			prog.Size = x
 		}
 	}
 	if m := p.re.time.FindStringSubmatch(line); m != nil {
@@ -138,23 +370,33 @@ func (p *parser) Parse(line string) uint64 {
 				frac = float64(x) / div
 			}
 		}
-		p.progress.Time = float64(h*3600+mm*60+s) + frac
+		prog.Time = float64(h*3600+mm*60+s) + frac
 	}
 	if m := p.re.timeMs.FindStringSubmatch(line); m != nil {
 		if x, err := strconv.ParseUint(m[1], 10, 64); err == nil {
-			p.progress.Time = float64(x) / 1000000.0 // out_time_ms 实为微秒
+			prog.Time = float64(x) / 1000000.0 // out_time_ms 实为微秒
+		}
+	}
+	if m := p.re.bitrate.FindStringSubmatch(line); m != nil {
+		if x, err := strconv.ParseFloat(m[1], 64); err == nil {
+			prog.Bitrate = x
+			prog.BitrateAvg = p.smooth(prog.BitrateAvg, x)
+		} else {
+			// N/A 时清零，不保留过时的值
+			prog.Bitrate, prog.BitrateAvg = 0, 0
 		}
 	}
 	if m := p.re.speed.FindStringSubmatch(line); m != nil {
 		if x, err := strconv.ParseFloat(m[1], 64); err == nil {
-			p.progress.Speed = x
+			prog.Speed = x
+			prog.SpeedAvg = p.smooth(prog.SpeedAvg, x)
 		}
 	}
 	if m := p.re.drop.FindStringSubmatch(line); m != nil {
 		for i := 1; i < len(m); i++ {
 			if m[i] != "" {
 				if x, err := strconv.ParseUint(m[i], 10, 64); err == nil {
-					p.progress.Drop = x
+					prog.Drop = x
 					break
 				}
 			}
@@ -164,20 +406,56 @@ func (p *parser) Parse(line string) uint64 {
 		for i := 1; i < len(m); i++ {
 			if m[i] != "" {
 				if x, err := strconv.ParseUint(m[i], 10, 64); err == nil {
-					p.progress.Dup = x
+					prog.Dup = x
 					break
 				}
 			}
 		}
 	}
+}
 
-	return p.progress.Frame
+// parseDuration sets the Duration of the progress from the stream info of
+// the first input. The caller must hold the lock.
+func (p *parser) parseDuration(line string) {
+	if m := reInput.FindStringSubmatch(line); m != nil {
+		p.input, _ = strconv.Atoi(m[1])
+		return
+	}
+	if strings.HasPrefix(line, "Output #") || strings.HasPrefix(line, "Stream mapping:") {
+		p.input = -1
+		return
+	}
+	if p.input != 0 || p.progress.Duration != 0 {
+		return
+	}
+	// 直播等无时长的输入为 Duration: N/A，不匹配
+	if m := p.re.duration.FindStringSubmatch(line); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		mm, _ := strconv.Atoi(m[2])
+		s, _ := strconv.ParseFloat(m[3], 64)
+		p.progress.Duration = float64(h*3600+mm*60) + s
+	}
+}
+
+// smooth returns the moving average avg updated with x, the first value of
+// a run starts it
+func (p *parser) smooth(avg, x float64) float64 {
+	if avg == 0 {
+		return x
+	}
+	return avg + p.smoothing*(x-avg)
 }
 
 func (p *parser) ResetStats() {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 	p.progress = Progress{}
+	p.input = -1
+	p.outputs = nil
+	p.failures = nil
+	p.segments = 0
+	p.scte35 = Scte35{}
+	p.scte35Streams = nil
 }
 
 func (p *parser) ResetLog() {
@@ -185,6 +463,13 @@ func (p *parser) ResetLog() {
 	defer p.lock.Unlock()
 	p.log = ring.New(p.logLines)
 	p.logStart = time.Now()
+	p.prelude = nil
+	p.inPrelude = true
+	if p.history != nil {
+		p.history = ring.New(p.historyLen)
+	}
+	// 退出时会 ResetStats，错误需保留到下次启动
+	p.lastErr = nil
 }
 
 func (p *parser) Log() []process.Line {
@@ -199,8 +484,171 @@ func (p *parser) Log() []process.Line {
 	return out
 }
 
+func (p *parser) LogAfter(after uint64, limit int) ([]process.Line, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	// 游标超前于日志（如任务更新后换了新的解析器）时从头返回
+	ahead := after > p.seq
+	if ahead {
+		after = 0
+	}
+	var out []process.Line
+	p.log.Do(func(v interface{}) {
+		if v == nil || (limit > 0 && len(out) >= limit) {
+			return
+		}
+		if line := v.(process.Line); line.Seq > after {
+			out = append(out, line)
+		}
+	})
+
+	first := p.seq + 1 // 缓冲区为空时下一行的序号
+	if len(out) != 0 {
+		first = out[0].Seq
+	}
+	return out, ahead || first > after+1
+}
+
 func (p *parser) Progress() Progress {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
 	return p.progress
 }
+
+func (p *parser) ProgressHistory() []ProgressSample {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.history == nil {
+		return nil
+	}
+	var out []ProgressSample
+	p.history.Do(func(v interface{}) {
+		if v != nil {
+			out = append(out, v.(ProgressSample))
+		}
+	})
+	return out
+}
+
+func (p *parser) Outputs() map[int]Progress {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if len(p.outputs) == 0 {
+		return nil
+	}
+	out := make(map[int]Progress, len(p.outputs))
+	for k, v := range p.outputs {
+		out[k] = v
+	}
+	return out
+}
+
+func (p *parser) Failures() map[int]string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if len(p.failures) == 0 {
+		return nil
+	}
+	out := make(map[int]string, len(p.failures))
+	for k, v := range p.failures {
+		out[k] = v
+	}
+	return out
+}
+
+func (p *parser) LastError() *Error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.lastErr == nil {
+		return nil
+	}
+	e := *p.lastErr
+	return &e
+}
+
+func (p *parser) Segments() uint64 {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.segments
+}
+
+func (p *parser) Prelude() []string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return slices.Clone(p.prelude)
+}
+
+func (p *parser) LastLine() string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.lastLine
+}
+
+func (p *parser) LogFiles() []string {
+	if p.file == nil {
+		return nil
+	}
+	return p.file.files()
+}
+
+func (p *parser) Close() error {
+	if p.file == nil {
+		return nil
+	}
+	return p.file.close()
+}
+
+func (p *parser) Memory() uint64 {
+	var n uint64
+	p.lock.RLock()
+	p.log.Do(func(v interface{}) {
+		if v != nil {
+			n += uint64(len(v.(process.Line).Data)) + LineOverhead
+		}
+	})
+	for _, line := range p.prelude {
+		n += uint64(len(line)) + LineOverhead
+	}
+	if p.history != nil {
+		p.history.Do(func(v interface{}) {
+			if v != nil {
+				n += sampleSize
+			}
+		})
+	}
+	p.lock.RUnlock()
+	return n
+}
+
+func (p *parser) LogLines() int {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.logLines
+}
+
+func (p *parser) SetLogLines(n int) {
+	if n <= 0 {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var lines []process.Line
+	p.log.Do(func(v interface{}) {
+		if v != nil {
+			lines = append(lines, v.(process.Line))
+		}
+	})
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	p.logLines = n
+	p.log = ring.New(n)
+	for _, l := range lines {
+		p.log.Value = l
+		p.log = p.log.Next()
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package parse
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func progressLine(bitrate, speed float64) string {
+	return fmt.Sprintf("frame=  100 fps= 25 q=28.0 size=    1024kB time=00:00:04.00 bitrate=%.1fkbits/s speed=%.2fx", bitrate, speed)
+}
+
+func TestSmoothingConverges(t *testing.T) {
+	p := New(Config{Smoothing: 0.2})
+
+	// 先稳定在 1000kbit/s、1x，再跳到 3000kbit/s、3x
+	for i := 0; i < 20; i++ {
+		p.Parse(progressLine(1000, 1))
+	}
+	if prog := p.Progress(); math.Abs(prog.BitrateAvg-1000) > 1e-9 || math.Abs(prog.SpeedAvg-1) > 1e-9 {
+		t.Fatalf("steady averages = %v, %v, want 1000, 1", prog.BitrateAvg, prog.SpeedAvg)
+	}
+
+	prevBitrate, prevSpeed := 1000.0, 1.0
+	for i := 0; i < 5; i++ {
+		p.Parse(progressLine(3000, 3))
+		prog := p.Progress()
+		if prog.Bitrate != 3000 || prog.Speed != 3 {
+			t.Fatalf("instantaneous = %v, %v, want 3000, 3", prog.Bitrate, prog.Speed)
+		}
+		// 平均值滞后于瞬时值，但逐步接近
+		if !(prog.BitrateAvg > prevBitrate && prog.BitrateAvg < 3000) {
+			t.Fatalf("line %d: bitrate_kbit_avg = %v, want between %v and 3000", i, prog.BitrateAvg, prevBitrate)
+		}
+		if !(prog.SpeedAvg > prevSpeed && prog.SpeedAvg < 3) {
+			t.Fatalf("line %d: speed_avg = %v, want between %v and 3", i, prog.SpeedAvg, prevSpeed)
+		}
+		prevBitrate, prevSpeed = prog.BitrateAvg, prog.SpeedAvg
+	}
+	for i := 0; i < 100; i++ {
+		p.Parse(progressLine(3000, 3))
+	}
+	if prog := p.Progress(); math.Abs(prog.BitrateAvg-3000) > 1 || math.Abs(prog.SpeedAvg-3) > 0.001 {
+		t.Fatalf("converged averages = %v, %v, want 3000, 3", prog.BitrateAvg, prog.SpeedAvg)
+	}
+}
+
+func TestSmoothingNoisySpeed(t *testing.T) {
+	p := New(Config{})
+	speeds := []float64{0.5, 1.5}
+	for i := 0; i < 200; i++ {
+		p.Parse(progressLine(2000, speeds[i%2]))
+	}
+	// 在 0.5 与 1.5 之间跳动，平均值稳定在 1 附近
+	if avg := p.Progress().SpeedAvg; math.Abs(avg-1) > 0.1 {
+		t.Fatalf("speed_avg = %v, want about 1", avg)
+	}
+}
+
+func TestBitrateNA(t *testing.T) {
+	p := New(Config{})
+	p.Parse(progressLine(2000, 1))
+	p.Parse("frame=  100 fps= 25 q=28.0 size=N/A time=00:00:04.00 bitrate=N/A speed=1x")
+	if prog := p.Progress(); prog.Bitrate != 0 || prog.BitrateAvg != 0 {
+		t.Fatalf("bitrate after N/A = %v, %v, want 0, 0", prog.Bitrate, prog.BitrateAvg)
+	}
+}
+
+func TestOutputs(t *testing.T) {
+	p := New(Config{})
+	// 两路输出，第二路落后；总进度行没有前缀
+	sample := []string{
+		"Output #0, mp4, to '/tmp/720p.mp4':",
+		"Output #1, flv, to 'rtmp://edge/live/360p':",
+		"[out#0/mp4 @ 0x55d4c0a1e2c0] frame=  250 fps= 50 q=28.0 size=    2048kB time=00:00:10.00 bitrate=1677.7kbits/s speed=2.0x",
+		"[out#1/flv @ 0x55d4c0a1f5c0] frame=  200 fps= 40 q=30.0 size=     512kB time=00:00:08.00 bitrate= 524.3kbits/s speed=1.6x",
+		"frame=  250 fps= 50 q=28.0 size=    2560kB time=00:00:10.00 bitrate=2097.2kbits/s speed=2.0x",
+	}
+	for _, line := range sample {
+		p.Parse(line)
+	}
+
+	outputs := p.Outputs()
+	if len(outputs) != 2 {
+		t.Fatalf("outputs %+v, want 2", outputs)
+	}
+	want := map[int]Progress{
+		0: {Frame: 250, FPS: 50, Quantizer: 28, Size: 2048 * 1024, Time: 10, Bitrate: 1677.7, Speed: 2},
+		1: {Frame: 200, FPS: 40, Quantizer: 30, Size: 512 * 1024, Time: 8, Bitrate: 524.3, Speed: 1.6},
+	}
+	for i, w := range want {
+		got := outputs[i]
+		got.BitrateAvg, got.SpeedAvg = 0, 0
+		if got != w {
+			t.Errorf("output %d: %+v, want %+v", i, got, w)
+		}
+	}
+	// 总进度不受单路输出的行影响
+	if prog := p.Progress(); prog.Frame != 250 || prog.Size != 2560*1024 || prog.Bitrate != 2097.2 {
+		t.Fatalf("overall progress %+v, want the unprefixed line", prog)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package parse
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseProgressLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want Progress
+	}{
+		{
+			"video",
+			"frame= 1500 fps= 25 q=28.0 size=    2048kB time=00:01:00.04 bitrate= 279.5kbits/s dup=2 drop=5 speed=1.01x",
+			Progress{Frame: 1500, FPS: 25, Quantizer: 28, Size: 2048 * 1024, Time: 60.04, Bitrate: 279.5, BitrateAvg: 279.5, Speed: 1.01, SpeedAvg: 1.01, Dup: 2, Drop: 5},
+		},
+		{
+			"time with milliseconds",
+			"frame=   10 fps=0.0 q=-1.0 size=       0kB time=01:02:03.456 bitrate=   0.0kbits/s speed=0.5x",
+			Progress{Frame: 10, Size: 0, Time: 3723.456, Speed: 0.5, SpeedAvg: 0.5},
+		},
+		{
+			"unknown size",
+			"frame=  250 fps= 50 q=-0.0 size=N/A time=00:00:10.00 bitrate=N/A speed=2x",
+			Progress{Frame: 250, FPS: 50, Time: 10, Speed: 2, SpeedAvg: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(Config{})
+			if r := p.Parse(tt.line); !r.Progress || !r.Advanced {
+				t.Fatalf("Parse = %+v, want a progress line that advanced", r)
+			}
+			if got := p.Progress(); got != tt.want {
+				t.Fatalf("progress\n%+v, want\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProgressPipe(t *testing.T) {
+	// -progress pipe:3 每个字段一行
+	lines := []string{
+		"frame=300",
+		"fps=29.97",
+		"stream_0_0_q=23.0",
+		"bitrate=1500.5kbits/s",
+		"total_size=1875000",
+		"out_time_us=10000000",
+		"out_time_ms=10000000",
+		"out_time=00:00:10.000000",
+		"dup_frames=1",
+		"drop_frames=3",
+		"speed=1.5x",
+		"progress=continue",
+	}
+	p := New(Config{})
+	for _, line := range lines[:len(lines)-1] {
+		if r := p.ParseProgress(line); !r.Progress {
+			t.Fatalf("ParseProgress(%q) isn't progress", line)
+		}
+	}
+	want := Progress{Frame: 300, FPS: 29.97, Quantizer: 23, Size: 1875000, Time: 10, Bitrate: 1500.5, BitrateAvg: 1500.5, Speed: 1.5, SpeedAvg: 1.5, Dup: 1, Drop: 3}
+	if got := p.Progress(); got != want {
+		t.Fatalf("progress\n%+v, want\n%+v", got, want)
+	}
+	if log := p.Log(); len(log) != 0 {
+		t.Fatalf("-progress lines are logged: %v", log)
+	}
+
+	// 标准错误中的 -progress 字段同样识别为进度，不是日志
+	q := New(Config{})
+	for _, line := range []string{"out_time_ms=2000000", "speed=1x", "bitrate=N/A"} {
+		if r := q.Parse(line); !r.Progress {
+			t.Fatalf("Parse(%q) isn't progress", line)
+		}
+	}
+	if got := q.Progress(); got.Time != 2 || got.Speed != 1 {
+		t.Fatalf("progress %+v, want time 2 and speed 1", got)
+	}
+}
+
+func TestParseNotProgress(t *testing.T) {
+	for _, line := range []string{
+		"Input #0, flv, from 'rtmp://example.com/live/in':",
+		"  Stream #0:0: Video: h264 (High), yuv420p, 1920x1080, 25 fps",
+		"[libx264 @ 0x5555] frame I:10 Avg QP:20.00 size: 50000",
+		"Press [q] to stop, [?] for help",
+	} {
+		p := New(Config{})
+		if r := p.Parse(line); r.Progress {
+			t.Errorf("Parse(%q) is progress", line)
+		}
+	}
+}
+
+func TestProgressHistory(t *testing.T) {
+	p := New(Config{ProgressHistory: 3})
+	for i := 1; i <= 5; i++ {
+		p.Parse(fmt.Sprintf("frame= %d fps= 25 q=28.0 size=    %dkB time=00:00:%02d.00 bitrate= 800.0kbits/s speed=1.0%dx", i*25, i*100, i, i))
+		p.Parse("[hls @ 0x5555] Opening 'out.m3u8.tmp' for writing")
+	}
+
+	// 只保留最新的 3 个采样，从旧到新
+	history := p.ProgressHistory()
+	if len(history) != 3 {
+		t.Fatalf("%d samples, want 3", len(history))
+	}
+	for i, s := range history {
+		n := i + 3
+		if s.Frame != uint64(n*25) || s.Progress.Time != float64(n) || s.Bitrate != 800 || s.Speed != 1+float64(n)/100 {
+			t.Fatalf("sample %d %+v, want frame %d at %ds", i, s.Progress, n*25, n)
+		}
+		if i > 0 && s.Time.Before(history[i-1].Time) {
+			t.Fatalf("sample %d is older than the one before", i)
+		}
+	}
+
+	// 新的运行从空的历史开始
+	p.ResetLog()
+	if history := p.ProgressHistory(); len(history) != 0 {
+		t.Fatalf("%d samples after a reset, want none", len(history))
+	}
+
+	if history := New(Config{ProgressHistory: -1}); history.ProgressHistory() != nil {
+		t.Fatal("history kept although disabled")
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package parse
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Scte35 counts SCTE-35 packets of the current run. FFmpeg only logs
+// packets with -debug_ts.
+type Scte35 struct {
+	In      uint64    // packets read from scte_35 input streams
+	Out     uint64    // data packets written
+	LastIn  time.Time // zero if there was none
+	LastOut time.Time
+}
+
+var (
+	reInput      = regexp.MustCompile(`^Input #([0-9]+),`)
+	reScte35     = regexp.MustCompile(`^\s*Stream #([0-9]+:[0-9]+)[^:]*: Data: scte_35`)
+	reDebugTs    = regexp.MustCompile(`^(?:\[[^\]]*\] )?(demuxer|demuxer\+ffmpeg|decoder|encoder|filter|muxer) (?:->|<-) `)
+	reInputIndex = regexp.MustCompile(`ist_index:([0-9]+)(:[0-9]+)?`)
+)
+
+// parseScte35 tracks the scte_35 input streams and counts the -debug_ts
+// packet lines. It reports whether line is a -debug_ts line, those aren't
+// logged. The caller must hold the lock.
+func (p *parser) parseScte35(line string) bool {
+	m := reDebugTs.FindStringSubmatch(line)
+	if m == nil {
+		// 流信息只在输入部分统计，输出部分的同名行是写出的流
+		if strings.HasPrefix(line, "Input #") {
+			p.inInputs = reInput.MatchString(line)
+		} else if strings.HasPrefix(line, "Output #") || strings.HasPrefix(line, "Stream mapping:") {
+			p.inInputs = false
+		}
+		if p.inInputs {
+			if s := reScte35.FindStringSubmatch(line); s != nil {
+				if p.scte35Streams == nil {
+					p.scte35Streams = make(map[string]bool)
+				}
+				p.scte35Streams[s[1]] = true
+			}
+		}
+		return false
+	}
+
+	if !strings.Contains(line, " type:data ") {
+		return true
+	}
+	now := time.Now()
+	switch m[1] {
+	case "demuxer", "demuxer+ffmpeg":
+		// 新版为 ist_index:文件:流，旧版为全局流序号，只对单个输入准确
+		idx := reInputIndex.FindStringSubmatch(line)
+		if idx == nil {
+			return true
+		}
+		stream := "0:" + idx[1]
+		if idx[2] != "" {
+			stream = idx[1] + idx[2]
+		}
+		if p.scte35Streams[stream] {
+			p.scte35.In++
+			p.scte35.LastIn = now
+		}
+	case "muxer":
+		p.scte35.Out++
+		p.scte35.LastOut = now
+	}
+	return true
+}
+
+func (p *parser) Scte35() Scte35 {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.scte35
+}
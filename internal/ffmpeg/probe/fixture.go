@@ -0,0 +1,50 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package probe
+
+// Fixture returns the probe result of a typical live stream. It is used in
+// dry-run mode where ffprobe is not executed.
+func Fixture() Result {
+	r, _ := Parse([]byte(fixtureOutput))
+	return r
+}
+
+const fixtureOutput = `{
+    "streams": [
+        {
+            "index": 0,
+            "codec_name": "h264",
+            "profile": "High",
+            "codec_type": "video",
+            "width": 1920,
+            "height": 1080,
+            "pix_fmt": "yuv420p",
+            "r_frame_rate": "25/1",
+            "avg_frame_rate": "25/1",
+            "bit_rate": "4000000"
+        },
+        {
+            "index": 1,
+            "codec_name": "aac",
+            "profile": "LC",
+            "codec_type": "audio",
+            "sample_rate": "48000",
+            "channels": 2,
+            "channel_layout": "stereo",
+            "r_frame_rate": "0/0",
+            "avg_frame_rate": "0/0",
+            "bit_rate": "128000"
+        }
+    ],
+    "format": {
+        "filename": "rtmp://live.example.com/app/stream",
+        "nb_streams": 2,
+        "format_name": "flv",
+        "format_long_name": "FLV (Flash Video)",
+        "start_time": "0.000000",
+        "probe_score": 100
+    }
+}`
@@ -0,0 +1,182 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds a probe if no timeout is given
+const DefaultTimeout = 15 * time.Second
+
+// Stream is a media stream of the probed input
+type Stream struct {
+	Index      int
+	Type       string // video, audio, subtitle, data
+	Codec      string
+	Profile    string
+	Width      int
+	Height     int
+	PixFmt     string
+	FrameRate  float64
+	SampleRate int
+	Channels   int
+	Layout     string
+	Bitrate    uint64
+	Duration   float64
+}
+
+// Format is the container of the probed input
+type Format struct {
+	Name     string
+	LongName string
+	Bitrate  uint64
+	Duration float64
+	Size     uint64
+}
+
+// Result of probing an input
+type Result struct {
+	Format  Format
+	Streams []Stream
+	Time    int64 // unix seconds
+}
+
+// ffprobe -print_format json output, numbers are mostly strings
+type ffprobeOutput struct {
+	Streams []struct {
+		Index         int    `json:"index"`
+		CodecType     string `json:"codec_type"`
+		CodecName     string `json:"codec_name"`
+		Profile       string `json:"profile"`
+		Width         int    `json:"width"`
+		Height        int    `json:"height"`
+		PixFmt        string `json:"pix_fmt"`
+		AvgFrameRate  string `json:"avg_frame_rate"`
+		RFrameRate    string `json:"r_frame_rate"`
+		SampleRate    string `json:"sample_rate"`
+		Channels      int    `json:"channels"`
+		ChannelLayout string `json:"channel_layout"`
+		BitRate       string `json:"bit_rate"`
+		Duration      string `json:"duration"`
+	} `json:"streams"`
+	Format struct {
+		FormatName     string `json:"format_name"`
+		FormatLongName string `json:"format_long_name"`
+		BitRate        string `json:"bit_rate"`
+		Duration       string `json:"duration"`
+		Size           string `json:"size"`
+	} `json:"format"`
+}
+
+// Run probes the address with ffprobe. options are input options placed
+// before the address, e.g. -rtsp_transport tcp.
+func Run(ctx context.Context, binary, address string, options []string, timeout time.Duration) (Result, error) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	args := []string{"-v", "error", "-print_format", "json", "-show_streams", "-show_format"}
+	args = append(args, options...)
+	args = append(args, address)
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Env = []string{}
+	cmd.WaitDelay = time.Second
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return Result{}, fmt.Errorf("probe timed out after %s", timeout)
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() != 0 {
+			return Result{}, fmt.Errorf("probe failed: %s", bytes.TrimSpace(stderr.Bytes()))
+		}
+		return Result{}, fmt.Errorf("probe failed: %w", err)
+	}
+
+	return Parse(out)
+}
+
+// Parse parses the JSON output of ffprobe -show_streams -show_format
+func Parse(data []byte) (Result, error) {
+	var out ffprobeOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return Result{}, fmt.Errorf("invalid ffprobe output: %w", err)
+	}
+
+	r := Result{
+		Format: Format{
+			Name:     out.Format.FormatName,
+			LongName: out.Format.FormatLongName,
+			Bitrate:  parseUint(out.Format.BitRate),
+			Duration: parseFloat(out.Format.Duration),
+			Size:     parseUint(out.Format.Size),
+		},
+		Streams: make([]Stream, 0, len(out.Streams)),
+		Time:    time.Now().Unix(),
+	}
+
+	for _, s := range out.Streams {
+		fps := parseRate(s.AvgFrameRate)
+		if fps == 0 {
+			fps = parseRate(s.RFrameRate)
+		}
+		r.Streams = append(r.Streams, Stream{
+			Index:      s.Index,
+			Type:       s.CodecType,
+			Codec:      s.CodecName,
+			Profile:    s.Profile,
+			Width:      s.Width,
+			Height:     s.Height,
+			PixFmt:     s.PixFmt,
+			FrameRate:  fps,
+			SampleRate: int(parseUint(s.SampleRate)),
+			Channels:   s.Channels,
+			Layout:     s.ChannelLayout,
+			Bitrate:    parseUint(s.BitRate),
+			Duration:   parseFloat(s.Duration),
+		})
+	}
+
+	return r, nil
+}
+
+func parseUint(s string) uint64 {
+	x, _ := strconv.ParseUint(s, 10, 64)
+	return x
+}
+
+func parseFloat(s string) float64 {
+	x, _ := strconv.ParseFloat(s, 64)
+	return x
+}
+
+// parseRate parses a rational like 30000/1001, 0/0 yields 0
+func parseRate(s string) float64 {
+	num, den, ok := strings.Cut(s, "/")
+	if !ok {
+		return parseFloat(s)
+	}
+	d := parseFloat(den)
+	if d == 0 {
+		return 0
+	}
+	return parseFloat(num) / d
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package probe
+
+import (
+	"math"
+	"testing"
+)
+
+// 点播文件：时长与码率都有，帧率为 NTSC
+const fileOutput = `{
+    "streams": [
+        {"index": 0, "codec_name": "hevc", "profile": "Main 10", "codec_type": "video", "width": 3840, "height": 2160,
+         "pix_fmt": "yuv420p10le", "r_frame_rate": "60000/1001", "avg_frame_rate": "0/0", "bit_rate": "15000000", "duration": "120.120000"},
+        {"index": 1, "codec_name": "opus", "codec_type": "audio", "sample_rate": "48000", "channels": 6,
+         "channel_layout": "5.1", "r_frame_rate": "0/0", "avg_frame_rate": "0/0", "duration": "120.100000"},
+        {"index": 2, "codec_name": "subrip", "codec_type": "subtitle"}
+    ],
+    "format": {"format_name": "matroska,webm", "format_long_name": "Matroska / WebM",
+               "duration": "120.120000", "size": "226000000", "bit_rate": "15051615"}
+}`
+
+func TestParse(t *testing.T) {
+	r, err := Parse([]byte(fileOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Format != (Format{Name: "matroska,webm", LongName: "Matroska / WebM", Bitrate: 15051615, Duration: 120.12, Size: 226000000}) {
+		t.Fatalf("format %+v", r.Format)
+	}
+	if len(r.Streams) != 3 {
+		t.Fatalf("%d streams, want 3", len(r.Streams))
+	}
+
+	video := r.Streams[0]
+	// avg_frame_rate 为 0/0 时取 r_frame_rate
+	if math.Abs(video.FrameRate-59.94) > 0.01 {
+		t.Fatalf("frame rate %f, want 59.94", video.FrameRate)
+	}
+	video.FrameRate = 0
+	if video != (Stream{Index: 0, Type: "video", Codec: "hevc", Profile: "Main 10", Width: 3840, Height: 2160, PixFmt: "yuv420p10le", Bitrate: 15000000, Duration: 120.12}) {
+		t.Fatalf("video %+v", video)
+	}
+	if audio := r.Streams[1]; audio != (Stream{Index: 1, Type: "audio", Codec: "opus", SampleRate: 48000, Channels: 6, Layout: "5.1", Duration: 120.1}) {
+		t.Fatalf("audio %+v", audio)
+	}
+	if sub := r.Streams[2]; sub != (Stream{Index: 2, Type: "subtitle", Codec: "subrip"}) {
+		t.Fatalf("subtitle %+v", sub)
+	}
+	if r.Time == 0 {
+		t.Fatal("probe time not set")
+	}
+}
+
+func TestParseFixture(t *testing.T) {
+	r := Fixture()
+	if len(r.Streams) != 2 || r.Format.Name != "flv" {
+		t.Fatalf("fixture %+v, want a flv stream with video and audio", r)
+	}
+	if v := r.Streams[0]; v.Width != 1920 || v.Height != 1080 || v.FrameRate != 25 || v.Bitrate != 4000000 {
+		t.Fatalf("video %+v, want 1080p25 at 4 Mbit/s", v)
+	}
+	// 直播流没有时长
+	if r.Format.Duration != 0 || r.Streams[1].Duration != 0 {
+		t.Fatalf("duration of a live stream %f", r.Format.Duration)
+	}
+
+	if _, err := Parse([]byte("Connection refused")); err == nil {
+		t.Fatal("non-JSON output parsed")
+	}
+}
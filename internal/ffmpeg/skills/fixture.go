@@ -0,0 +1,88 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package skills
+
+// Fixture returns a static set of skills of a typical FFmpeg build. It is
+// used in dry-run mode where no FFmpeg binary is executed.
+func Fixture() Skills {
+	c := Skills{}
+
+	c.FFmpeg = parseVersion([]byte(fixtureVersion))
+	c.Filters = parseFilters([]byte(fixtureFilters))
+	c.HWAccels = parseHWAccels([]byte(fixtureHWAccels))
+	c.Codecs = parseCodecs([]byte(fixtureCodecs))
+	c.Formats = parseFormats([]byte(fixtureFormats))
+	c.Protocols = parseProtocols([]byte(fixtureProtocols))
+
+	return c
+}
+
+const fixtureVersion = `ffmpeg version 7.1.0 Copyright (c) 2000-2024 the FFmpeg developers
+built with gcc 13.2.0 (Alpine 13.2.0)
+configuration: --enable-gpl --enable-libx264 --enable-libx265 --enable-libfdk-aac --enable-nonfree
+libavutil      59. 39.100 / 59. 39.100
+libavcodec     61. 19.100 / 61. 19.100
+libavformat    61.  7.100 / 61.  7.100
+libavdevice    61.  3.100 / 61.  3.100
+libavfilter    10.  4.100 / 10.  4.100
+libswscale      8.  3.100 /  8.  3.100
+libswresample   5.  3.100 /  5.  3.100
+`
+
+const fixtureFilters = `Filters:
+ ..C scale             V->V       Scale the input video size and/or convert the image format.
+ ..C fps               V->V       Force constant framerate.
+ TSC overlay           VV->V      Overlay a video source on top of the input.
+ ..C aresample         A->A       Resample audio data.
+ ... null              V->V       Pass the source unchanged to the output.
+`
+
+const fixtureHWAccels = `Hardware acceleration methods:
+`
+
+const fixtureCodecs = `Codecs:
+ DEV.LS h264                 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (encoders: libx264 libx264rgb)
+ DEV.L. hevc                 H.265 / HEVC (High Efficiency Video Coding) (encoders: libx265)
+ DEV.L. mpeg4                MPEG-4 part 2
+ DEAI.S aac                  AAC (Advanced Audio Coding) (decoders: aac aac_fixed) (encoders: aac libfdk_aac)
+ DEA.L. mp3                  MP3 (MPEG audio layer 3) (decoders: mp3float mp3) (encoders: libmp3lame)
+ DEA.L. opus                 Opus (Opus Interactive Audio Codec)
+ DES... subrip               SubRip subtitle (decoders: srt subrip) (encoders: srt subrip)
+ DES... webvtt               WebVTT subtitle
+`
+
+const fixtureFormats = `File formats:
+ DE flv             FLV (Flash Video)
+ DE hls             Apple HTTP Live Streaming
+ D  mov,mp4,m4a,3gp,3g2,mj2 QuickTime / MOV
+  E mp4             MP4 (MPEG-4 Part 14)
+ DE mpegts          MPEG-TS (MPEG-2 Transport Stream)
+ DE null            raw null video
+ DE tee             Multiple muxer tee
+`
+
+const fixtureProtocols = `Supported file protocols:
+Input:
+  file
+  http
+  https
+  pipe
+  rtmp
+  rtmps
+  srt
+  tcp
+  udp
+Output:
+  file
+  http
+  https
+  pipe
+  rtmp
+  rtmps
+  srt
+  tcp
+  udp
+`
@@ -8,12 +8,18 @@ package skills
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"os/exec"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 )
 
+// DefaultTimeout bounds each FFmpeg invocation if no timeout is given
+const DefaultTimeout = 5 * time.Second
+
 // Codec represents a codec with encoders and decoders
 type Codec struct {
 	Id       string
@@ -46,6 +52,14 @@ type HWAccel struct {
 	Name string
 }
 
+// HWEncoder is a hardware encoder of the build and whether it could
+// encode a test frame on this host
+type HWEncoder struct {
+	Id      string
+	Working bool
+	Error   string // first line of FFmpeg's error if it isn't working
+}
+
 // Library represents a linked av library
 type Library struct {
 	Name     string
@@ -62,10 +76,13 @@ type ffmpegInfo struct {
 
 // Skills are the detected capabilities of FFmpeg
 type Skills struct {
-	FFmpeg    ffmpegInfo
-	Filters   []Filter
-	HWAccels  []HWAccel
-	Codecs    struct {
+	FFmpeg   ffmpegInfo
+	Filters  []Filter
+	HWAccels []HWAccel
+	// HWEncoders are the hardware video encoders, e.g. h264_nvenc, each
+	// tried on a test frame
+	HWEncoders []HWEncoder
+	Codecs     struct {
 		Audio    []Codec
 		Video    []Codec
 		Subtitle []Codec
@@ -78,44 +95,132 @@ type Skills struct {
 		Input  []Protocol
 		Output []Protocol
 	}
+	// Warnings lists the probes that failed or timed out; their skills are missing
+	Warnings []string
 }
 
-// New returns all skills that FFmpeg provides
-func New(binary string) (Skills, error) {
+// New returns all skills that FFmpeg provides. Each FFmpeg invocation is
+// bounded by timeout and killed when it expires. Only a failing version probe
+// is an error, other failing probes are reported in Skills.Warnings.
+func New(ctx context.Context, binary string, timeout time.Duration) (Skills, error) {
 	c := Skills{}
 
-	ff, err := getVersion(binary)
-	if ff.Version == "" || err != nil {
-		if err != nil {
-			return Skills{}, fmt.Errorf("can't parse ffmpeg version: %w", err)
-		}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	data, err := run(ctx, timeout, true, binary, "-version")
+	if err != nil {
+		return Skills{}, fmt.Errorf("can't parse ffmpeg version: %w", err)
+	}
+	ff := parseVersion(data)
+	if ff.Version == "" {
 		return Skills{}, fmt.Errorf("can't parse ffmpeg version")
 	}
 	c.FFmpeg = ff
 
-	c.Filters = getFilters(binary)
-	c.HWAccels = getHWAccels(binary)
-
-	codecs := getCodecs(binary)
-	c.Codecs = codecs
+	probe := func(arg string) []byte {
+		data, err := run(ctx, timeout, false, binary, arg)
+		if err != nil {
+			c.Warnings = append(c.Warnings, fmt.Sprintf("ffmpeg %s: %s", arg, err))
+		}
+		return data
+	}
 
-	formats := getFormats(binary)
-	c.Formats = formats
+	c.Filters = parseFilters(probe("-filters"))
+	c.HWAccels = parseHWAccels(probe("-hwaccels"))
+	c.Codecs = parseCodecs(probe("-codecs"))
+	c.Formats = parseFormats(probe("-formats"))
+	c.Protocols = parseProtocols(probe("-protocols"))
 
-	protocols := getProtocols(binary)
-	c.Protocols = protocols
+	c.HWEncoders = probeHWEncoders(hwEncoders(c), func(args ...string) ([]byte, error) {
+		return run(ctx, timeout, true, binary, args...)
+	})
 
 	return c, nil
 }
 
-func getVersion(binary string) (ffmpegInfo, error) {
-	cmd := exec.Command(binary, "-version")
+// hwEncoderSuffixes mark the encoders that need hardware
+var hwEncoderSuffixes = []string{"_nvenc", "_qsv", "_vaapi", "_amf", "_videotoolbox", "_v4l2m2m"}
+
+// hwEncoders returns the hardware video encoders of the build
+func hwEncoders(c Skills) []string {
+	var encoders []string
+	for _, codec := range c.Codecs.Video {
+		for _, enc := range codec.Encoders {
+			for _, suffix := range hwEncoderSuffixes {
+				if strings.HasSuffix(enc, suffix) {
+					encoders = append(encoders, enc)
+					break
+				}
+			}
+		}
+	}
+	return encoders
+}
+
+// hwEncoderArgs encodes one frame of a test source to nowhere. VAAPI
+// encoders need the frame uploaded to the device first.
+func hwEncoderArgs(encoder string) []string {
+	args := []string{"-hide_banner", "-v", "error"}
+	if strings.HasSuffix(encoder, "_vaapi") {
+		args = append(args, "-init_hw_device", "vaapi=va", "-filter_hw_device", "va")
+	}
+	args = append(args, "-f", "lavfi", "-i", "nullsrc=s=256x256", "-frames:v", "1")
+	if strings.HasSuffix(encoder, "_vaapi") {
+		args = append(args, "-vf", "format=nv12,hwupload")
+	}
+	return append(args, "-c:v", encoder, "-f", "null", "-")
+}
+
+// probeHWEncoders tries each encoder concurrently with run, which executes
+// FFmpeg with args
+func probeHWEncoders(encoders []string, run func(args ...string) ([]byte, error)) []HWEncoder {
+	result := make([]HWEncoder, len(encoders))
+	var wg sync.WaitGroup
+	for i, enc := range encoders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result[i] = HWEncoder{Id: enc, Working: true}
+			out, err := run(hwEncoderArgs(enc)...)
+			if err == nil {
+				return
+			}
+			result[i].Working = false
+			// FFmpeg 的第一行错误通常最能说明原因，如找不到设备
+			msg, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+			if msg == "" {
+				msg = err.Error()
+			}
+			result[i].Error = msg
+		}()
+	}
+	wg.Wait()
+	return result
+}
+
+// run executes binary with args and a minimal environment. The process is
+// killed if it doesn't finish within timeout.
+func run(ctx context.Context, timeout time.Duration, combined bool, binary string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binary, args...)
 	cmd.Env = []string{}
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return ffmpegInfo{}, err
+	cmd.WaitDelay = time.Second
+
+	var out []byte
+	var err error
+	if combined {
+		out, err = cmd.CombinedOutput()
+	} else {
+		out, err = cmd.Output()
 	}
-	return parseVersion(out), nil
+	if ctx.Err() != nil {
+		return out, fmt.Errorf("timed out after %s", timeout)
+	}
+	return out, err
 }
 
 func parseVersion(data []byte) ffmpegInfo {
@@ -147,13 +252,6 @@ func parseVersion(data []byte) ffmpegInfo {
 	return f
 }
 
-func getFilters(binary string) []Filter {
-	cmd := exec.Command(binary, "-filters")
-	cmd.Env = []string{}
-	stdout, _ := cmd.Output()
-	return parseFilters(stdout)
-}
-
 func parseFilters(data []byte) []Filter {
 	var filters []Filter
 	re := regexp.MustCompile(`^\s[TSC.]{3} ([0-9A-Za-z_]+)\s+(?:.*?)\s+(.*)?$`)
@@ -167,17 +265,6 @@ func parseFilters(data []byte) []Filter {
 	return filters
 }
 
-func getCodecs(binary string) struct {
-	Audio    []Codec
-	Video    []Codec
-	Subtitle []Codec
-} {
-	cmd := exec.Command(binary, "-codecs")
-	cmd.Env = []string{}
-	stdout, _ := cmd.Output()
-	return parseCodecs(stdout)
-}
-
 func parseCodecs(data []byte) struct {
 	Audio    []Codec
 	Video    []Codec
@@ -223,16 +310,6 @@ func parseCodecs(data []byte) struct {
 	return codecs
 }
 
-func getFormats(binary string) struct {
-	Demuxers []Format
-	Muxers   []Format
-} {
-	cmd := exec.Command(binary, "-formats")
-	cmd.Env = []string{}
-	stdout, _ := cmd.Output()
-	return parseFormats(stdout)
-}
-
 func parseFormats(data []byte) struct {
 	Demuxers []Format
 	Muxers   []Format
@@ -261,16 +338,6 @@ func parseFormats(data []byte) struct {
 	return f
 }
 
-func getProtocols(binary string) struct {
-	Input  []Protocol
-	Output []Protocol
-} {
-	cmd := exec.Command(binary, "-protocols")
-	cmd.Env = []string{}
-	stdout, _ := cmd.Output()
-	return parseProtocols(stdout)
-}
-
 func parseProtocols(data []byte) struct {
 	Input  []Protocol
 	Output []Protocol
@@ -305,13 +372,6 @@ func parseProtocols(data []byte) struct {
 	return p
 }
 
-func getHWAccels(binary string) []HWAccel {
-	cmd := exec.Command(binary, "-hwaccels")
-	cmd.Env = []string{}
-	stdout, _ := cmd.Output()
-	return parseHWAccels(stdout)
-}
-
 func parseHWAccels(data []byte) []HWAccel {
 	var accels []HWAccel
 	re := regexp.MustCompile(`^[A-Za-z0-9]+$`)
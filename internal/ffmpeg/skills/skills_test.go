@@ -0,0 +1,66 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package skills
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHWEncoders(t *testing.T) {
+	var c Skills
+	c.Codecs.Video = []Codec{
+		{Id: "h264", Encoders: []string{"libx264", "h264_nvenc", "h264_qsv", "h264_vaapi"}},
+		{Id: "hevc", Encoders: []string{"libx265", "hevc_nvenc"}},
+	}
+	c.Codecs.Audio = []Codec{{Id: "aac", Encoders: []string{"aac"}}}
+	encoders := hwEncoders(c)
+	if want := []string{"h264_nvenc", "h264_qsv", "h264_vaapi", "hevc_nvenc"}; !slices.Equal(encoders, want) {
+		t.Fatalf("hardware encoders %q, want %q", encoders, want)
+	}
+
+	// 只有 nvenc 可用；qsv 无输出，vaapi 输出多行错误
+	var lock sync.Mutex
+	calls := map[string][]string{}
+	results := probeHWEncoders(encoders, func(args ...string) ([]byte, error) {
+		encoder := args[slices.Index(args, "-c:v")+1]
+		lock.Lock()
+		calls[encoder] = args
+		lock.Unlock()
+		switch {
+		case strings.HasSuffix(encoder, "_nvenc"):
+			return nil, nil
+		case strings.HasSuffix(encoder, "_vaapi"):
+			return []byte("Device creation failed: -22.\nFailed to set value 'vaapi=va' for option 'init_hw_device'\n"), errors.New("exit status 1")
+		}
+		return nil, errors.New("exit status 1")
+	})
+
+	want := []HWEncoder{
+		{Id: "h264_nvenc", Working: true},
+		{Id: "h264_qsv", Error: "exit status 1"},
+		{Id: "h264_vaapi", Error: "Device creation failed: -22."},
+		{Id: "hevc_nvenc", Working: true},
+	}
+	if !slices.Equal(results, want) {
+		t.Fatalf("results %+v, want %+v", results, want)
+	}
+
+	// 每个编码器编码一帧测试画面，VAAPI 先上传到设备
+	if args := strings.Join(calls["h264_nvenc"], " "); args != "-hide_banner -v error -f lavfi -i nullsrc=s=256x256 -frames:v 1 -c:v h264_nvenc -f null -" {
+		t.Fatalf("nvenc probe %q", args)
+	}
+	vaapi := strings.Join(calls["h264_vaapi"], " ")
+	if !strings.Contains(vaapi, "-init_hw_device vaapi=va") || !strings.Contains(vaapi, "-vf format=nv12,hwupload") {
+		t.Fatalf("vaapi probe %q, want the device and upload", vaapi)
+	}
+	if len(calls) != 4 {
+		t.Fatalf("%d encoders probed, want 4", len(calls))
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package ffmpeg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+)
+
+// upgrade replaces the binary like a package manager, with a new file of
+// a later modification time
+func upgrade(t *testing.T, path, script string, n int) {
+	t.Helper()
+	tmp := path + ".new"
+	if err := os.WriteFile(tmp, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Duration(n) * time.Minute)
+	if err := os.Chtimes(tmp, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSkillsReload(t *testing.T) {
+	binary := filepath.Join(t.TempDir(), "ffmpeg")
+	upgrade(t, binary, `echo "ffmpeg version 6.0"`, 0)
+	ff, err := New(Config{Binary: binary, Logger: logger.New("", logger.LevelError)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := ff.(*ffmpeg)
+	version := func() string { return f.Skills().FFmpeg.Version }
+	if version() != "6.0.0" {
+		t.Fatalf("version %s, want 6.0.0", version())
+	}
+
+	// 文件未变化时不重新探测
+	f.checkSkills()
+	if version() != "6.0.0" {
+		t.Fatalf("version %s after a check without change", version())
+	}
+
+	upgrade(t, binary, `echo "ffmpeg version 7.1"`, 1)
+	f.checkSkills()
+	if version() != "7.1.0" {
+		t.Fatalf("version %s after the upgrade, want 7.1.0", version())
+	}
+
+	// 探测失败时保留之前的能力
+	upgrade(t, binary, `echo "segmentation fault" >&2; exit 139`, 2)
+	f.checkSkills()
+	if version() != "7.1.0" {
+		t.Fatalf("version %s after a broken upgrade, want 7.1.0 kept", version())
+	}
+	if err := ff.ReloadSkills(context.Background()); err == nil {
+		t.Fatal("reload of a broken binary succeeded")
+	}
+	if version() != "7.1.0" {
+		t.Fatalf("version %s after a failed reload, want 7.1.0 kept", version())
+	}
+
+	// 并发的重新加载与读取没有数据竞争（-race）
+	upgrade(t, binary, `echo "ffmpeg version 7.2"`, 3)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := ff.ReloadSkills(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			f.checkSkills()
+			ff.Skills()
+		}()
+	}
+	wg.Wait()
+	if version() != "7.2.0" {
+		t.Fatalf("version %s after concurrent reloads, want 7.2.0", version())
+	}
+}
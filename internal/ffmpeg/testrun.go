@@ -0,0 +1,75 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// maxTestLines bounds the lines of a TestResult
+const maxTestLines = 10
+
+// TestResult is how a test run of FFmpeg ended
+type TestResult struct {
+	OK       bool
+	ExitCode int // -1 if FFmpeg was killed
+	TimedOut bool
+	Duration time.Duration
+	// Lines are the first lines FFmpeg wrote to stderr, run with
+	// -loglevel error they are the errors
+	Lines []string
+}
+
+func (f *ffmpeg) TestRun(ctx context.Context, binary string, args, env []string, timeout time.Duration) (TestResult, error) {
+	if f.dryRun {
+		return TestResult{OK: true}, nil
+	}
+	path := f.Binary(binary)
+	if path == "" {
+		return TestResult{}, ErrUnknownBinary
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Env = env
+	if cmd.Env == nil {
+		cmd.Env = []string{}
+	}
+	cmd.WaitDelay = time.Second
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	r := TestResult{
+		OK:       err == nil,
+		ExitCode: cmd.ProcessState.ExitCode(),
+		TimedOut: ctx.Err() == context.DeadlineExceeded,
+		Duration: time.Since(start),
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) && !r.TimedOut {
+		// 未能启动
+		return TestResult{}, err
+	}
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if len(r.Lines) == maxTestLines {
+			break
+		}
+		r.Lines = append(r.Lines, line)
+	}
+	return r, nil
+}
@@ -0,0 +1,228 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package hook
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+)
+
+// Hook events
+const (
+	EventStart    = "on_start"    // the task is running
+	EventFailed   = "on_failed"   // FFmpeg exited with an error
+	EventFinished = "on_finished" // FFmpeg exited successfully
+	EventStalled  = "on_stalled"  // the task was stopped for lack of progress
+)
+
+// Events are the known hook events
+var Events = []string{EventStart, EventFailed, EventFinished, EventStalled}
+
+const (
+	defaultTimeout       = 30 * time.Second
+	defaultMaxConcurrent = 4
+	maxOutputLines       = 50 // lines of a hook's output kept in the task log
+)
+
+var (
+	ErrDisabled     = errors.New("hooks are disabled: hooks.dir isn't configured")
+	ErrUnknownEvent = errors.New("unknown hook event: must be on_start, on_failed, on_finished or on_stalled")
+	ErrNotAllowed   = errors.New("hook must be an executable in hooks.dir")
+)
+
+// Config for a Runner
+type Config struct {
+	// Dir is the only directory hooks may run from. Empty disables hooks.
+	Dir string
+	// Timeout kills a hook that runs longer, default 30s
+	Timeout time.Duration
+	// MaxConcurrent caps the hooks running at a time, default 4. Further
+	// hooks wait for a slot.
+	MaxConcurrent int
+	// Hooks are run for every task, by event
+	Hooks  map[string][]string
+	Logger logger.Logger
+}
+
+// Event describes what a hook is run for. The fields are passed as
+// TM_EVENT, TM_TASK_ID, TM_TASK_REFERENCE, TM_TASK_STATE and TM_TASK_ERROR.
+type Event struct {
+	Name      string
+	ID        string
+	Reference string
+	State     string
+	Error     string
+}
+
+// Runner runs hook commands
+type Runner interface {
+	// Validate checks the hooks of a task: known events and commands that
+	// are executables in the allowed directory
+	Validate(hooks map[string][]string) error
+	// Run runs the global hooks of the event and then the given ones in
+	// the background. Each line of their output is passed to output.
+	Run(e Event, hooks []string, output func(line string))
+}
+
+type runner struct {
+	dir     string
+	timeout time.Duration
+	hooks   map[string][]string
+	slots   chan struct{}
+	logger  logger.Logger
+}
+
+// New creates a Runner. The global hooks are validated like those of a task.
+func New(config Config) (Runner, error) {
+	r := &runner{
+		timeout: config.Timeout,
+		hooks:   config.Hooks,
+		logger:  config.Logger,
+	}
+	if r.timeout <= 0 {
+		r.timeout = defaultTimeout
+	}
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = defaultMaxConcurrent
+	}
+	r.slots = make(chan struct{}, config.MaxConcurrent)
+	if r.logger == nil {
+		r.logger = logger.New("hook", logger.LevelInfo)
+	}
+
+	if config.Dir != "" {
+		dir, err := filepath.EvalSymlinks(config.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("hooks.dir: %w", err)
+		}
+		if r.dir, err = filepath.Abs(dir); err != nil {
+			return nil, fmt.Errorf("hooks.dir: %w", err)
+		}
+	}
+
+	if err := r.Validate(r.hooks); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *runner) Validate(hooks map[string][]string) error {
+	for event, commands := range hooks {
+		if !slices.Contains(Events, event) {
+			return fmt.Errorf("%w: %s", ErrUnknownEvent, event)
+		}
+		for _, command := range commands {
+			if _, err := r.resolve(command); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolve returns the path of a hook command, relative commands are looked
+// up in the allowed directory. Symlinks must not lead out of it.
+func (r *runner) resolve(command string) (string, error) {
+	if r.dir == "" {
+		return "", ErrDisabled
+	}
+
+	path := command
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.dir, path)
+	}
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrNotAllowed, command)
+	}
+	if filepath.Dir(path) != r.dir {
+		return "", fmt.Errorf("%w: %s", ErrNotAllowed, command)
+	}
+	fi, err := os.Stat(path)
+	if err != nil || !fi.Mode().IsRegular() || fi.Mode().Perm()&0111 == 0 {
+		return "", fmt.Errorf("%w: %s", ErrNotAllowed, command)
+	}
+	return path, nil
+}
+
+func (r *runner) Run(e Event, hooks []string, output func(line string)) {
+	commands := append(slices.Clone(r.hooks[e.Name]), hooks...)
+	if len(commands) == 0 {
+		return
+	}
+
+	go func() {
+		for _, command := range commands {
+			r.run(e, command, output)
+		}
+	}()
+}
+
+// run runs one hook and waits for it. Failures are only logged, they never
+// affect the task.
+func (r *runner) run(e Event, command string, output func(line string)) {
+	prefix := fmt.Sprintf("hook %s %s: ", e.Name, filepath.Base(command))
+
+	// 执行前再次检查，脚本可能已被替换为目录外的链接
+	path, err := r.resolve(command)
+	if err != nil {
+		r.logger.Error("task %s: %s%s", e.ID, prefix, err)
+		output(prefix + err.Error())
+		return
+	}
+
+	r.slots <- struct{}{}
+	defer func() { <-r.slots }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Dir = r.dir
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"TM_EVENT=" + e.Name,
+		"TM_TASK_ID=" + e.ID,
+		"TM_TASK_REFERENCE=" + e.Reference,
+		"TM_TASK_STATE=" + e.State,
+		"TM_TASK_ERROR=" + e.Error,
+	}
+	cmd.WaitDelay = time.Second
+
+	out, err := cmd.CombinedOutput()
+	lines := 0
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if lines++; lines > maxOutputLines {
+			output(prefix + "output truncated")
+			break
+		}
+		output(prefix + line)
+	}
+
+	if ctx.Err() != nil {
+		err = fmt.Errorf("timed out after %s", r.timeout)
+	}
+	if err != nil {
+		r.logger.Error("task %s: %s%s", e.ID, prefix, err)
+		output(prefix + err.Error())
+	}
+}
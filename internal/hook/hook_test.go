@@ -0,0 +1,158 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package hook
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+)
+
+// writeHook writes an executable shell script
+func writeHook(t *testing.T, path, script string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestRunner(t *testing.T, config Config) Runner {
+	t.Helper()
+	config.Logger = logger.New("", logger.LevelError)
+	r, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// collect runs the hooks of e and returns their output lines once one of
+// them contains until
+func collect(t *testing.T, r Runner, e Event, hooks []string, until string) []string {
+	t.Helper()
+	lines := make(chan string, 100)
+	r.Run(e, hooks, func(line string) { lines <- line })
+
+	var out []string
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case line := <-lines:
+			out = append(out, line)
+			if strings.Contains(line, until) {
+				return out
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %q, got %q", until, out)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	writeHook(t, filepath.Join(dir, "notify.sh"), "exit 0")
+	writeHook(t, filepath.Join(outside, "evil.sh"), "exit 0")
+	if err := os.Symlink(filepath.Join(outside, "evil.sh"), filepath.Join(dir, "link.sh")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "data.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := newTestRunner(t, Config{Dir: dir})
+
+	tests := []struct {
+		name  string
+		hooks map[string][]string
+		want  error
+	}{
+		{"relative", map[string][]string{EventFailed: {"notify.sh"}}, nil},
+		{"absolute in dir", map[string][]string{EventStart: {filepath.Join(dir, "notify.sh")}}, nil},
+		{"unknown event", map[string][]string{"on_paused": {"notify.sh"}}, ErrUnknownEvent},
+		{"outside", map[string][]string{EventFailed: {filepath.Join(outside, "evil.sh")}}, ErrNotAllowed},
+		{"dot dot", map[string][]string{EventFailed: {"../" + filepath.Base(outside) + "/evil.sh"}}, ErrNotAllowed},
+		{"symlink out", map[string][]string{EventFailed: {"link.sh"}}, ErrNotAllowed},
+		{"not executable", map[string][]string{EventFailed: {"data.txt"}}, ErrNotAllowed},
+		{"missing", map[string][]string{EventFailed: {"missing.sh"}}, ErrNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := r.Validate(tt.hooks); !errors.Is(err, tt.want) {
+				t.Fatalf("Validate = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	disabled := newTestRunner(t, Config{})
+	if err := disabled.Validate(map[string][]string{EventFailed: {"notify.sh"}}); !errors.Is(err, ErrDisabled) {
+		t.Fatalf("Validate without a dir = %v, want %v", err, ErrDisabled)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	writeHook(t, filepath.Join(dir, "global.sh"), `echo "global $TM_EVENT"`)
+	writeHook(t, filepath.Join(dir, "notify.sh"), `echo "$TM_TASK_ID $TM_TASK_REFERENCE $TM_TASK_STATE: $TM_TASK_ERROR"
+echo done`)
+	r := newTestRunner(t, Config{Dir: dir, Hooks: map[string][]string{EventFailed: {"global.sh"}}})
+
+	e := Event{Name: EventFailed, ID: "news", Reference: "channel-1", State: "failed", Error: "Connection refused"}
+	out := collect(t, r, e, []string{"notify.sh"}, "done")
+	// 全局钩子先于任务的钩子运行
+	want := []string{
+		"hook on_failed global.sh: global on_failed",
+		"hook on_failed notify.sh: news channel-1 failed: Connection refused",
+		"hook on_failed notify.sh: done",
+	}
+	if strings.Join(out, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("output %q, want %q", out, want)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	dir := t.TempDir()
+	writeHook(t, filepath.Join(dir, "slow.sh"), "echo started\nsleep 10")
+	r := newTestRunner(t, Config{Dir: dir, Timeout: 200 * time.Millisecond})
+
+	start := time.Now()
+	out := collect(t, r, Event{Name: EventStart, ID: "news"}, []string{"slow.sh"}, "timed out")
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("hook ended after %s, want the 200ms timeout", elapsed)
+	}
+	if out[len(out)-1] != "hook on_start slow.sh: timed out after 200ms" {
+		t.Fatalf("output %q, want a timeout", out)
+	}
+}
+
+func TestRunReplaced(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	writeHook(t, filepath.Join(dir, "notify.sh"), "echo inside")
+	writeHook(t, filepath.Join(outside, "evil.sh"), "echo outside")
+	r := newTestRunner(t, Config{Dir: dir})
+	if err := r.Validate(map[string][]string{EventFailed: {"notify.sh"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 校验之后被替换为目录外的链接，运行时拒绝
+	if err := os.Remove(filepath.Join(dir, "notify.sh")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "evil.sh"), filepath.Join(dir, "notify.sh")); err != nil {
+		t.Fatal(err)
+	}
+	out := collect(t, r, Event{Name: EventFailed, ID: "news"}, []string{"notify.sh"}, "notify.sh")
+	if !strings.Contains(out[0], ErrNotAllowed.Error()) {
+		t.Fatalf("output %q, want %v", out, ErrNotAllowed)
+	}
+}
@@ -5,7 +5,18 @@
 
 package logger
 
-import "log"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/redact"
+)
 
 // Logger provides a simple logging interface
 type Logger interface {
@@ -14,22 +25,226 @@ type Logger interface {
 	Debug(format string, args ...interface{})
 }
 
+// Level gates the messages of a logger, errors are always written
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelError
+)
+
+// ParseLevel parses debug, info or error
+func ParseLevel(s string) (Level, error) {
+	switch s {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q, must be debug, info or error", s)
+}
+
+// Fields are attached to every message of a logger, e.g. the ID of a task
+type Fields map[string]interface{}
+
+// fieldLogger is implemented by the loggers of this package
+type fieldLogger interface {
+	withFields(fields Fields) Logger
+}
+
+// WithFields returns a Logger that adds fields to every message of l. JSON
+// loggers put them in "fields", text loggers in front of the message as
+// [k1=v1 k2=v2].
+func WithFields(l Logger, fields Fields) Logger {
+	if len(fields) == 0 {
+		return l
+	}
+	if fl, ok := l.(fieldLogger); ok {
+		return fl.withFields(fields)
+	}
+	return &prefixLogger{logger: l, prefix: fields.String()}
+}
+
+// String formats the fields as "[k1=v1 k2=v2] ", sorted by key
+func (f Fields) String() string {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%v", k, f[k])
+	}
+	return "[" + strings.Join(pairs, " ") + "] "
+}
+
+// merge returns a copy of f with fields added
+func (f Fields) merge(fields Fields) Fields {
+	out := make(Fields, len(f)+len(fields))
+	for k, v := range f {
+		out[k] = v
+	}
+	for k, v := range fields {
+		out[k] = v
+	}
+	return out
+}
+
 type defaultLogger struct {
 	prefix string
+	level  Level
+	fields Fields
 }
 
-func New(prefix string) Logger {
-	return &defaultLogger{prefix: prefix}
+// New creates a text Logger that drops the messages below level
+func New(prefix string, level Level) Logger {
+	return &defaultLogger{prefix: prefix, level: level}
 }
 
 func (l *defaultLogger) Info(format string, args ...interface{}) {
-	log.Printf("[INFO] "+l.prefix+format, args...)
+	if l.level <= LevelInfo {
+		l.output("[INFO] ", format, args)
+	}
 }
 
 func (l *defaultLogger) Error(format string, args ...interface{}) {
-	log.Printf("[ERROR] "+l.prefix+format, args...)
+	l.output("[ERROR] ", format, args)
 }
 
 func (l *defaultLogger) Debug(format string, args ...interface{}) {
-	log.Printf("[DEBUG] "+l.prefix+format, args...)
+	if l.level <= LevelDebug {
+		l.output("[DEBUG] ", format, args)
+	}
+}
+
+func (l *defaultLogger) output(level, format string, args []interface{}) {
+	if len(l.fields) == 0 {
+		log.Printf(level+l.prefix+format, args...)
+		return
+	}
+	log.Print(level + l.prefix + l.fields.String() + fmt.Sprintf(format, args...))
+}
+
+func (l *defaultLogger) withFields(fields Fields) Logger {
+	return &defaultLogger{prefix: l.prefix, level: l.level, fields: l.fields.merge(fields)}
+}
+
+type jsonLogger struct {
+	level  Level
+	fields Fields
+}
+
+type jsonEntry struct {
+	Level  string `json:"level"`
+	Time   string `json:"ts"`
+	Msg    string `json:"msg"`
+	Fields Fields `json:"fields"`
+}
+
+// outputLock serializes the lines of all JSON loggers
+var outputLock sync.Mutex
+
+// NewJSON creates a Logger that writes one JSON object per message below
+// level to the output of the standard logger:
+//
+//	{"level":"info","ts":"2026-01-02T15:04:05.000Z","msg":"...","fields":{"logger":"prefix"}}
+func NewJSON(prefix string, level Level) Logger {
+	l := &jsonLogger{level: level, fields: Fields{}}
+	if prefix != "" {
+		l.fields["logger"] = prefix
+	}
+	return l
+}
+
+func (l *jsonLogger) Info(format string, args ...interface{}) {
+	if l.level <= LevelInfo {
+		l.output("info", format, args)
+	}
+}
+
+func (l *jsonLogger) Error(format string, args ...interface{}) {
+	l.output("error", format, args)
+}
+
+func (l *jsonLogger) Debug(format string, args ...interface{}) {
+	if l.level <= LevelDebug {
+		l.output("debug", format, args)
+	}
+}
+
+func (l *jsonLogger) output(level, format string, args []interface{}) {
+	e := jsonEntry{
+		Level:  level,
+		Time:   time.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		Msg:    fmt.Sprintf(format, args...),
+		Fields: l.fields,
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(e); err != nil {
+		// 字段无法编码时退回为字符串
+		e.Fields = make(Fields, len(l.fields))
+		for k, v := range l.fields {
+			e.Fields[k] = fmt.Sprint(v)
+		}
+		buf.Reset()
+		enc.Encode(e)
+	}
+	outputLock.Lock()
+	defer outputLock.Unlock()
+	log.Writer().Write(buf.Bytes())
+}
+
+func (l *jsonLogger) withFields(fields Fields) Logger {
+	return &jsonLogger{level: l.level, fields: l.fields.merge(fields)}
+}
+
+// prefixLogger puts fields in front of the messages of loggers from
+// elsewhere
+type prefixLogger struct {
+	logger Logger
+	prefix string
+}
+
+func (l *prefixLogger) Info(format string, args ...interface{}) {
+	l.logger.Info("%s", l.prefix+fmt.Sprintf(format, args...))
+}
+
+func (l *prefixLogger) Error(format string, args ...interface{}) {
+	l.logger.Error("%s", l.prefix+fmt.Sprintf(format, args...))
+}
+
+func (l *prefixLogger) Debug(format string, args ...interface{}) {
+	l.logger.Debug("%s", l.prefix+fmt.Sprintf(format, args...))
+}
+
+type redactLogger struct {
+	logger   Logger
+	redactor redact.Redactor
+}
+
+// WithRedactor masks secrets in every message before passing it to l
+func WithRedactor(l Logger, r redact.Redactor) Logger {
+	return &redactLogger{logger: l, redactor: r}
+}
+
+func (l *redactLogger) Info(format string, args ...interface{}) {
+	l.logger.Info("%s", l.redactor.Redact(fmt.Sprintf(format, args...)))
+}
+
+func (l *redactLogger) Error(format string, args ...interface{}) {
+	l.logger.Error("%s", l.redactor.Redact(fmt.Sprintf(format, args...)))
+}
+
+func (l *redactLogger) Debug(format string, args ...interface{}) {
+	l.logger.Debug("%s", l.redactor.Redact(fmt.Sprintf(format, args...)))
+}
+
+func (l *redactLogger) withFields(fields Fields) Logger {
+	return &redactLogger{logger: WithFields(l.logger, fields), redactor: l.redactor}
 }
@@ -0,0 +1,123 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"strings"
+	"testing"
+)
+
+// capture returns what fn writes to the standard logger
+func capture(t *testing.T, fn func()) string {
+	t.Helper()
+	var buf bytes.Buffer
+	out, flags := log.Writer(), log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(out)
+		log.SetFlags(flags)
+	})
+	fn()
+	return buf.String()
+}
+
+func TestJSON(t *testing.T) {
+	out := capture(t, func() {
+		l := NewJSON("ffmpeg", LevelDebug)
+		l.Info("started %d tasks", 2)
+		WithFields(l, Fields{"task_id": "news", "reference": "channel-1"}).Error("exit code %d", 1)
+		l.Debug(`quoted "value" <tag>`)
+	})
+
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("%d lines, want 3:\n%s", len(lines), out)
+	}
+	want := []struct {
+		level, msg string
+		fields     map[string]string
+	}{
+		{"info", "started 2 tasks", map[string]string{"logger": "ffmpeg"}},
+		{"error", "exit code 1", map[string]string{"logger": "ffmpeg", "task_id": "news", "reference": "channel-1"}},
+		{"debug", `quoted "value" <tag>`, map[string]string{"logger": "ffmpeg"}},
+	}
+	for i, line := range lines {
+		var e map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("line %d isn't JSON: %s\n%s", i, err, line)
+		}
+		for _, key := range []string{"level", "ts", "msg", "fields"} {
+			if _, ok := e[key]; !ok {
+				t.Fatalf("line %d has no %s: %s", i, key, line)
+			}
+		}
+
+		var entry struct {
+			Level  string            `json:"level"`
+			Msg    string            `json:"msg"`
+			Fields map[string]string `json:"fields"`
+		}
+		json.Unmarshal([]byte(line), &entry)
+		if entry.Level != want[i].level || entry.Msg != want[i].msg {
+			t.Fatalf("line %d is %s %q, want %s %q", i, entry.Level, entry.Msg, want[i].level, want[i].msg)
+		}
+		if len(entry.Fields) != len(want[i].fields) {
+			t.Fatalf("line %d fields %v, want %v", i, entry.Fields, want[i].fields)
+		}
+		for k, v := range want[i].fields {
+			if entry.Fields[k] != v {
+				t.Fatalf("line %d fields %v, want %v", i, entry.Fields, want[i].fields)
+			}
+		}
+	}
+}
+
+func TestLevel(t *testing.T) {
+	tests := []struct {
+		level Level
+		want  []string
+	}{
+		{LevelDebug, []string{"[DEBUG] d", "[INFO] i", "[ERROR] e"}},
+		{LevelInfo, []string{"[INFO] i", "[ERROR] e"}},
+		{LevelError, []string{"[ERROR] e"}},
+	}
+	for _, tt := range tests {
+		out := capture(t, func() {
+			l := New("", tt.level)
+			l.Debug("d")
+			l.Info("i")
+			l.Error("e")
+		})
+		if got := strings.Split(strings.TrimSuffix(out, "\n"), "\n"); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("level %d wrote %q, want %q", tt.level, got, tt.want)
+		}
+	}
+
+	// JSON 日志同样按级别过滤
+	out := capture(t, func() {
+		l := NewJSON("", LevelInfo)
+		l.Debug("d")
+		l.Info("i")
+	})
+	if strings.Contains(out, `"debug"`) || !strings.Contains(out, `"msg":"i"`) {
+		t.Errorf("json logger at info wrote %q, want only the info message", out)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for s, want := range map[string]Level{"debug": LevelDebug, "info": LevelInfo, "error": LevelError} {
+		if got, err := ParseLevel(s); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %d, %v, want %d", s, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("warn"); err == nil {
+		t.Error("ParseLevel(warn) succeeded")
+	}
+}
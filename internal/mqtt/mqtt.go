@@ -0,0 +1,258 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg/parse"
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+)
+
+const (
+	defaultQueueSize = 1024
+	publishTimeout   = 10 * time.Second
+)
+
+// State is published retained on <prefix>/<id>/state on every state change
+type State struct {
+	ID        string `json:"id"`
+	Reference string `json:"reference"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	StoppedBy string `json:"stopped_by,omitempty"` // user, system or limit if the run ended because it was asked to stop
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Progress is published on <prefix>/<id>/progress while a task runs
+type Progress struct {
+	ID        string         `json:"id"`
+	Reference string         `json:"reference"`
+	Progress  parse.Progress `json:"progress"`
+	Timestamp int64          `json:"timestamp"`
+}
+
+// Config for a Publisher
+type Config struct {
+	// Broker is the URL of the broker, e.g. tcp://localhost:1883, ssl:// or
+	// ws://
+	Broker   string
+	Username string
+	Password string
+	// ClientID defaults to transcodemanager-<hostname>
+	ClientID string
+	// TopicPrefix is prepended to the topics, without a trailing slash
+	TopicPrefix string
+	QoS         byte
+	// QueueSize caps the messages waiting to be published, default 1024.
+	// The oldest are dropped when it is full.
+	QueueSize int
+	Logger    logger.Logger
+}
+
+// Publisher publishes task events to an MQTT broker
+type Publisher interface {
+	// State publishes a state change, retained. It never blocks.
+	State(s State)
+	// Progress publishes the progress of a running task. It never blocks.
+	Progress(p Progress)
+	// Clear removes the retained state of a deleted task
+	Clear(id string)
+	// Close publishes the queued messages, for up to timeout, and
+	// disconnects
+	Close(timeout time.Duration)
+}
+
+type message struct {
+	topic    string
+	qos      byte
+	retained bool
+	payload  []byte
+}
+
+type publisher struct {
+	client  paho.Client
+	prefix  string
+	qos     byte
+	queue   chan message
+	closed  bool // guarded by lock, no more messages are queued
+	lock    sync.RWMutex
+	dropped atomic.Uint64
+	done    chan struct{} // closed when publish returns
+	quit    chan struct{} // closed when Close gives up on the queue
+	logger  logger.Logger
+}
+
+// New creates a Publisher and connects in the background. The connection
+// is retried until it succeeds and restored after it is lost.
+func New(config Config) (Publisher, error) {
+	if config.QoS > 2 {
+		return nil, fmt.Errorf("mqtt: invalid qos %d", config.QoS)
+	}
+	p := &publisher{
+		prefix: strings.TrimSuffix(config.TopicPrefix, "/"),
+		qos:    config.QoS,
+		done:   make(chan struct{}),
+		quit:   make(chan struct{}),
+		logger: config.Logger,
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultQueueSize
+	}
+	p.queue = make(chan message, config.QueueSize)
+	if p.logger == nil {
+		p.logger = logger.New("mqtt", logger.LevelInfo)
+	}
+	if config.ClientID == "" {
+		host, _ := os.Hostname()
+		config.ClientID = "transcodemanager-" + host
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(config.Broker).
+		SetClientID(config.ClientID).
+		SetUsername(config.Username).
+		SetPassword(config.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(5 * time.Second).
+		SetMaxReconnectInterval(time.Minute).
+		SetOnConnectHandler(func(paho.Client) {
+			p.logger.Info("mqtt: connected to %s", config.Broker)
+		}).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			p.logger.Error("mqtt: connection to %s lost: %s", config.Broker, err)
+		})
+	p.client = paho.NewClient(opts)
+	// ConnectRetry 时 Connect 不会失败，在后台重试
+	p.client.Connect()
+
+	go p.publish()
+	return p, nil
+}
+
+// escaper keeps task IDs a single topic level without wildcards
+var escaper = strings.NewReplacer("%", "%25", "/", "%2F", "+", "%2B", "#", "%23")
+
+func (p *publisher) topic(id, kind string) string {
+	return p.prefix + "/" + escaper.Replace(id) + "/" + kind
+}
+
+func (p *publisher) State(s State) {
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+	p.enqueue(message{topic: p.topic(s.ID, "state"), qos: p.stateQoS(), retained: true, payload: payload})
+}
+
+func (p *publisher) Progress(pr Progress) {
+	payload, err := json.Marshal(pr)
+	if err != nil {
+		return
+	}
+	p.enqueue(message{topic: p.topic(pr.ID, "progress"), qos: p.qos, payload: payload})
+}
+
+func (p *publisher) Clear(id string) {
+	// 空的保留消息即删除保留消息
+	p.enqueue(message{topic: p.topic(id, "state"), qos: p.stateQoS(), retained: true})
+}
+
+// stateQoS is at least 1, QoS 0 messages are dropped while reconnecting
+func (p *publisher) stateQoS() byte {
+	return max(p.qos, 1)
+}
+
+// enqueue queues m, dropping the oldest message if the queue is full
+func (p *publisher) enqueue(m message) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.closed {
+		return
+	}
+	for {
+		select {
+		case p.queue <- m:
+			return
+		default:
+		}
+		select {
+		case <-p.queue:
+			if n := p.dropped.Add(1); n == 1 || n%100 == 0 {
+				p.logger.Error("mqtt: queue full, %d messages dropped", n)
+			}
+		default:
+		}
+	}
+}
+
+func (p *publisher) publish() {
+	defer close(p.done)
+	for m := range p.queue {
+		if !p.send(m) {
+			return
+		}
+	}
+}
+
+// send publishes m once the broker is connected, again after a timeout
+// or a lost connection. It reports false if Close gave up meanwhile.
+func (p *publisher) send(m message) bool {
+	for {
+		// 断开期间不再取消息，积压由队列承受（丢弃最旧的）
+		for !p.client.IsConnectionOpen() {
+			select {
+			case <-p.quit:
+				return false
+			case <-time.After(time.Second):
+			}
+		}
+		token := p.client.Publish(m.topic, m.qos, m.retained, m.payload)
+		select {
+		case <-p.quit:
+			return false
+		case <-time.After(publishTimeout):
+			p.logger.Error("mqtt: publish %s: timed out, retrying", m.topic)
+			continue
+		case <-token.Done():
+		}
+		if err := token.Error(); err != nil {
+			p.logger.Error("mqtt: publish %s: %s", m.topic, err)
+			if !p.client.IsConnectionOpen() {
+				continue
+			}
+		}
+		return true
+	}
+}
+
+func (p *publisher) Close(timeout time.Duration) {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.queue)
+	p.lock.Unlock()
+
+	select {
+	case <-p.done:
+	case <-time.After(timeout):
+		close(p.quit)
+		<-p.done
+	}
+	p.client.Disconnect(250)
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package mqtt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+)
+
+func TestTopic(t *testing.T) {
+	p := &publisher{prefix: "tm"}
+	tests := map[string]string{
+		"live":      "tm/live/state",
+		"a/b":       "tm/a%2Fb/state",
+		"+#":        "tm/%2B%23/state",
+		"100%/news": "tm/100%25%2Fnews/state",
+	}
+	for id, want := range tests {
+		if got := p.topic(id, "state"); got != want {
+			t.Errorf("topic(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestQueueDropOldest(t *testing.T) {
+	p := &publisher{
+		queue:  make(chan message, 2),
+		logger: logger.New("", logger.LevelError),
+	}
+	for _, topic := range []string{"a", "b", "c", "d"} {
+		p.enqueue(message{topic: topic})
+	}
+
+	if n := p.dropped.Load(); n != 2 {
+		t.Fatalf("dropped %d, want 2", n)
+	}
+	close(p.queue)
+	var topics []string
+	for m := range p.queue {
+		topics = append(topics, m.topic)
+	}
+	if len(topics) != 2 || topics[0] != "c" || topics[1] != "d" {
+		t.Fatalf("queue %v, want [c d]", topics)
+	}
+}
+
+func TestCloseUnreachable(t *testing.T) {
+	// 端口 1 上没有 broker，连接一直在后台重试
+	pub, err := New(Config{Broker: "tcp://127.0.0.1:1", Logger: logger.New("", logger.LevelError)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub.State(State{ID: "a", To: "running"})
+
+	closed := make(chan struct{})
+	go func() {
+		pub.Close(100 * time.Millisecond)
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Close didn't return")
+	}
+	// 发送消息的 goroutine 也已退出
+	select {
+	case <-pub.(*publisher).done:
+	default:
+		t.Fatal("publish still running after Close")
+	}
+}
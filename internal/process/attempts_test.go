@@ -0,0 +1,76 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package process
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReconnectsLeft(t *testing.T) {
+	tests := []struct {
+		name          string
+		enable        bool
+		maxReconnects int
+		attempts      int
+		want          int
+	}{
+		{"disabled", false, 3, 0, 0},
+		{"unlimited", true, 0, 10, -1},
+		{"negative max is unlimited", true, -1, 10, -1},
+		{"none used", true, 3, 0, 3},
+		{"some used", true, 3, 2, 1},
+		{"all used", true, 3, 3, 0},
+		{"more than max", true, 3, 5, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reconnectsLeft(tt.enable, tt.maxReconnects, tt.attempts); got != tt.want {
+				t.Fatalf("reconnectsLeft(%v, %d, %d) = %d, want %d", tt.enable, tt.maxReconnects, tt.attempts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconnectAttemptsReset(t *testing.T) {
+	// 每次运行都超过 ReconnectDelay，连续重连次数随之清零，不会用完
+	p := newTestProcess(t, Config{
+		Binary:         fakeFFmpeg(t, "sleep 0.3\nexit 1"),
+		Reconnect:      true,
+		ReconnectDelay: 100 * time.Millisecond,
+		MaxReconnects:  1,
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	sawReset := false
+	for deadline := time.Now().Add(10 * time.Second); p.Status().Reconnects < 3; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("status %+v, want 3 reconnects", p.Status())
+		}
+		s := p.Status()
+		if s.Order != "start" {
+			t.Fatalf("gave up with %d reconnects, stop reason %q", s.Reconnects, s.StopReason)
+		}
+		switch {
+		case s.State == "running" && s.Duration > 200*time.Millisecond:
+			if s.ReconnectAttempts != 0 || s.ReconnectsLeft != 1 {
+				t.Fatalf("after a healthy run: attempts %d, left %d, want 0, 1", s.ReconnectAttempts, s.ReconnectsLeft)
+			}
+			sawReset = true
+		case s.Reconnect > 0:
+			if s.ReconnectAttempts != 1 || s.ReconnectsLeft != 0 {
+				t.Fatalf("waiting to reconnect: attempts %d, left %d, want 1, 0", s.ReconnectAttempts, s.ReconnectsLeft)
+			}
+		}
+	}
+	if !sawReset {
+		t.Fatal("never saw a healthy run")
+	}
+}
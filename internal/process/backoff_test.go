@@ -0,0 +1,42 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package process
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		delay    time.Duration
+		limit    time.Duration
+		backoff  float64
+		attempts int
+		want     time.Duration
+	}{
+		{"no backoff", time.Second, 0, 0, 5, time.Second},
+		{"backoff 1", time.Second, 0, 1, 5, time.Second},
+		{"below 1 is constant", time.Second, 0, 0.5, 5, time.Second},
+		{"first attempt", time.Second, time.Minute, 2, 0, time.Second},
+		{"doubling", time.Second, time.Minute, 2, 3, 8 * time.Second},
+		{"fractional", 2 * time.Second, 0, 1.5, 2, 4500 * time.Millisecond},
+		{"capped", time.Second, 10 * time.Second, 2, 4, 10 * time.Second},
+		{"capped exactly", time.Second, 8 * time.Second, 2, 3, 8 * time.Second},
+		{"no limit", time.Second, 0, 2, 10, 1024 * time.Second},
+		{"overflow", time.Second, 0, 10, 100, time.Duration(math.MaxInt64)},
+		{"zero delay", 0, time.Minute, 2, 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := backoffDelay(tt.delay, tt.limit, tt.backoff, tt.attempts); got != tt.want {
+				t.Fatalf("backoffDelay(%s, %s, %v, %d) = %s, want %s", tt.delay, tt.limit, tt.backoff, tt.attempts, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package process
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Faults are failures injected for resilience testing
+type Faults struct {
+	// StallOutput stops reading the process' output, as if it hung
+	StallOutput bool
+	// ReconnectDelay is added to the delay of every reconnect
+	ReconnectDelay time.Duration
+}
+
+// Injector is implemented by processes that accept fault injection
+type Injector interface {
+	// Signal sends sig to the running process
+	Signal(sig syscall.Signal) error
+	// SetFaults replaces the active faults
+	SetFaults(f Faults)
+	// FailNextStart makes the next start fail, false disarms it
+	FailNextStart(fail bool)
+}
+
+// faults holds the injected faults of a process
+type faults struct {
+	faults    Faults
+	failStart bool
+	stall     chan struct{} // closed when the stall ends
+	lock      sync.Mutex
+}
+
+func (f *faults) set(faults Faults) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if faults.StallOutput && f.stall == nil {
+		f.stall = make(chan struct{})
+	}
+	if !faults.StallOutput && f.stall != nil {
+		close(f.stall)
+		f.stall = nil
+	}
+	f.faults = faults
+}
+
+// unstall ends a stall, e.g. because the process is stopped
+func (f *faults) unstall() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.stall != nil {
+		close(f.stall)
+		f.stall = nil
+	}
+	f.faults.StallOutput = false
+}
+
+// wait blocks while the output is stalled
+func (f *faults) wait() {
+	f.lock.Lock()
+	stall := f.stall
+	f.lock.Unlock()
+
+	if stall != nil {
+		<-stall
+	}
+}
+
+func (f *faults) stalled() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.stall != nil
+}
+
+func (f *faults) reconnectDelay() time.Duration {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.faults.ReconnectDelay
+}
+
+func (f *faults) setFailStart(fail bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.failStart = fail
+}
+
+// takeFailStart reports and disarms an injected start failure
+func (f *faults) takeFailStart() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if !f.failStart {
+		return nil
+	}
+	f.failStart = false
+	return fmt.Errorf("injected start failure")
+}
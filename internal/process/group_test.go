@@ -0,0 +1,88 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// alive reports whether pid runs. A zombie whose parent is gone counts as
+// dead, in a container nobody may reap it.
+func alive(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return !os.IsNotExist(err)
+	}
+	// pid (comm) state ...
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
+
+// readPID waits for the script to write the PID of its child to path
+func readPID(t *testing.T, path string) int {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		data, _ := os.ReadFile(path)
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+			return pid
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the child PID in %s", path)
+		}
+	}
+}
+
+func TestStopGroup(t *testing.T) {
+	tests := []struct {
+		name  string
+		child string
+		stop  func(p *process) error
+	}{
+		// 前台的子进程与脚本一起收到 SIGINT
+		{"stop", `sh -c 'echo $$ > %s; exec sleep 30'`, func(p *process) error { return p.Stop(true) }},
+		// 忽略 SIGINT 的后台子进程只能随进程组被强制结束
+		{"kill", `sh -c 'trap "" INT; echo $$ > %s; exec sleep 30' & wait`, func(p *process) error { return p.StopWith(StopOptions{Wait: true, Kill: true}) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pidFile := filepath.Join(t.TempDir(), "child")
+			p := newTestProcess(t, Config{
+				Binary: fakeFFmpeg(t, strings.Replace(tt.child, "%s", pidFile, 1)),
+			})
+			if err := p.Start(); err != nil {
+				t.Fatal(err)
+			}
+			pid := readPID(t, pidFile)
+			if !alive(pid) {
+				t.Fatalf("child %d isn't running", pid)
+			}
+
+			// 只结束了脚本时 Stop 会等到子进程自行退出，不能等它返回
+			stopped := make(chan error, 1)
+			go func() { stopped <- tt.stop(p) }()
+			for deadline := time.Now().Add(2 * time.Second); alive(pid); time.Sleep(10 * time.Millisecond) {
+				if time.Now().After(deadline) {
+					syscall.Kill(pid, syscall.SIGKILL)
+					t.Fatalf("child %d still runs after %s", pid, tt.name)
+				}
+			}
+			if err := <-stopped; err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -20,7 +20,7 @@ func NewNullLimiter() Limiter {
 	return &nullLimiter{}
 }
 
-func (l *nullLimiter) Start(pid int) error { return nil }
-func (l *nullLimiter) Stop()               {}
+func (l *nullLimiter) Start(pid int) error        { return nil }
+func (l *nullLimiter) Stop()                      {}
 func (l *nullLimiter) Current() (float64, uint64) { return 0, 0 }
-func (l *nullLimiter) Limits() (float64, uint64)   { return 0, 0 }
+func (l *nullLimiter) Limits() (float64, uint64)  { return 0, 0 }
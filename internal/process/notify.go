@@ -0,0 +1,46 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package process
+
+import "sync"
+
+// stateNotifier calls Config.OnStateChange off the caller's goroutine, one
+// change after the other in the order they happened
+type stateNotifier struct {
+	fn      func(from, to string)
+	lock    sync.Mutex
+	queue   [][2]string
+	running bool
+}
+
+func (n *stateNotifier) notify(from, to string) {
+	if n.fn == nil {
+		return
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.queue = append(n.queue, [2]string{from, to})
+	if !n.running {
+		n.running = true
+		go n.drain()
+	}
+}
+
+func (n *stateNotifier) drain() {
+	for {
+		n.lock.Lock()
+		if len(n.queue) == 0 {
+			n.running = false
+			n.lock.Unlock()
+			return
+		}
+		change := n.queue[0]
+		n.queue = n.queue[1:]
+		n.lock.Unlock()
+
+		n.fn(change[0], change[1])
+	}
+}
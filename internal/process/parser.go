@@ -9,14 +9,29 @@ import "time"
 
 // Parser parses process output (e.g. FFmpeg stderr)
 type Parser interface {
-	Parse(line string) uint64
+	Parse(line string) ParseResult
 	ResetStats()
 	ResetLog()
 	Log() []Line
 }
 
+// ProgressParser is implemented by parsers that can read the key=value
+// lines of -progress, see Config.ProgressPipe. Such lines aren't logged.
+type ProgressParser interface {
+	ParseProgress(line string) ParseResult
+}
+
+// ParseResult tells the process what a parsed line means for stale detection
+type ParseResult struct {
+	Progress bool // the line is a progress line
+	Advanced bool // frame count or media time moved forward
+}
+
 // Line is a timestamped log line
 type Line struct {
+	// Seq numbers the lines of a parser, it never repeats and increases by
+	// one per stored line
+	Seq       uint64
 	Timestamp time.Time
 	Data      string
 }
@@ -0,0 +1,63 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartPaused(t *testing.T) {
+	pids := filepath.Join(t.TempDir(), "pids")
+	p := newTestProcess(t, Config{Binary: fakeFFmpeg(t, "echo $$ >> "+pids+"\nexec sleep 30")})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, p, "running", 5*time.Second)
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if data, _ := os.ReadFile(pids); strings.HasSuffix(string(data), "\n") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the first run")
+		}
+	}
+	pid := p.pid
+
+	if err := p.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	// start 与重连都经过 start，暂停的进程不算运行中但仍在
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	p.order.lock.Lock()
+	err := p.start()
+	p.order.lock.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if data, _ := os.ReadFile(pids); strings.Count(string(data), "\n") != 1 {
+		t.Fatalf("runs %q, want a single one", data)
+	}
+	if s := p.Status().State; s != "paused" || p.pid != pid {
+		t.Fatalf("state %s, pid %d, want paused, %d", s, p.pid, pid)
+	}
+
+	if err := p.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if s := p.Status().State; s != "running" {
+		t.Fatalf("state %s after resume, want running", s)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package process
+
+// I/O scheduling classes for Config.IOClass, named like ionice does
+const (
+	IOClassRealtime   = "realtime"
+	IOClassBestEffort = "best-effort"
+	IOClassIdle       = "idle"
+)
+
+// ioClasses are the kernel's IOPRIO_CLASS_* values by name
+var ioClasses = map[string]int{
+	IOClassRealtime:   1,
+	IOClassBestEffort: 2,
+	IOClassIdle:       3,
+}
+
+// ValidIOClass reports whether class is empty or a known I/O class
+func ValidIOClass(class string) bool {
+	_, ok := ioClasses[class]
+	return ok || class == ""
+}
+
+// ValidNice reports whether nice is a valid niceness
+func ValidNice(nice int) bool {
+	return nice >= -20 && nice <= 19
+}
+
+// Priority of a running process as read back from the OS
+type Priority struct {
+	Nice    int
+	IOClass string // empty if the process has no explicit I/O class
+	IOLevel int
+}
+
+// applyPriority sets the niceness and I/O class of the running process
+// and reads them back. The process runs in its own process group, which
+// covers its threads and children.
+func (p *process) applyPriority() {
+	p.priority.lock.Lock()
+	defer p.priority.lock.Unlock()
+
+	p.priority.applied = nil
+	if p.priority.nice == 0 && p.priority.ioClass == "" {
+		return
+	}
+
+	prio, err := setPriority(int(p.pid), p.priority.nice, p.priority.ioClass)
+	if err != nil {
+		p.logger.Error("set priority of pid %d: %s", p.pid, err)
+		p.parser.Parse("set priority: " + err.Error())
+	}
+	p.priority.applied = prio
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package process
+
+import (
+	"errors"
+	"syscall"
+)
+
+const (
+	ioprioWhoProcess = 1
+	ioprioWhoPgrp    = 2
+	ioprioClassShift = 13
+	// ioprioLevel is the level within the realtime and best-effort classes,
+	// the kernel's default
+	ioprioLevel = 4
+)
+
+// setPriority applies nice and the I/O class to the process group of pid
+// and returns what the process has afterwards, nil if it can't be read
+func setPriority(pid, nice int, ioClass string) (*Priority, error) {
+	var errs []error
+	if nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PGRP, pid, nice); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if class, ok := ioClasses[ioClass]; ok {
+		level := ioprioLevel
+		if ioClass == IOClassIdle {
+			level = 0
+		}
+		value := class<<ioprioClassShift | level
+		if _, _, e := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoPgrp, uintptr(pid), uintptr(value)); e != 0 {
+			errs = append(errs, e)
+		}
+	}
+	return getPriority(pid), errors.Join(errs...)
+}
+
+func getPriority(pid int) *Priority {
+	// 内核返回 20 - nice
+	prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, pid)
+	if err != nil {
+		return nil
+	}
+	p := &Priority{Nice: 20 - prio}
+
+	value, _, e := syscall.Syscall(syscall.SYS_IOPRIO_GET, ioprioWhoProcess, uintptr(pid), 0)
+	if e == 0 {
+		class := int(value) >> ioprioClassShift
+		for name, c := range ioClasses {
+			if c == class {
+				p.IOClass = name
+			}
+		}
+		p.IOLevel = int(value) & (1<<ioprioClassShift - 1)
+	}
+	return p
+}
@@ -0,0 +1,15 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !linux
+
+package process
+
+import "errors"
+
+// setPriority isn't supported, the settings are ignored
+func setPriority(pid, nice int, ioClass string) (*Priority, error) {
+	return nil, errors.New("nice and ionice_class are only supported on Linux, ignored")
+}
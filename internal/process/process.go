@@ -12,9 +12,11 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"os/exec"
 	"runtime"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -26,22 +28,75 @@ type Process interface {
 	Status() Status
 	Start() error
 	Stop(wait bool) error
+	StopWith(opts StopOptions) error
 	Kill(wait bool) error
+	Pause() error
+	Resume() error
+	// Fail records a start that was refused before the process ran, e.g.
+	// by a failed check, as a failed run with err in its log
+	Fail(err error) error
 	IsRunning() bool
 }
 
+// StopOptions for Process.StopWith
+type StopOptions struct {
+	Wait bool
+	// Kill sends SIGKILL instead of SIGINT, FFmpeg doesn't finish its outputs
+	Kill bool
+	// Reason is reported as Status.StopReason
+	Reason string
+	// KillAfter sends SIGKILL if the process hasn't exited this long after
+	// the stop signal, default 5s
+	KillAfter time.Duration
+}
+
+// defaultKillAfter if StopOptions.KillAfter isn't set
+const defaultKillAfter = 5 * time.Second
+
 // Config for a process
 type Config struct {
 	Binary         string
 	Args           []string
 	Reconnect      bool
 	ReconnectDelay time.Duration
-	StaleTimeout   time.Duration
-	Parser         Parser
-	OnStart        func()
-	OnExit         func()
-	OnStateChange  func(from, to string)
-	Logger         Logger
+	// ReconnectBackoff multiplies the delay after each consecutive failed
+	// run, up to ReconnectMaxDelay. 0 or 1 keeps the delay constant.
+	ReconnectBackoff  float64
+	ReconnectMaxDelay time.Duration
+	MaxReconnects     int
+	// Env is the environment of the process, it doesn't inherit ours
+	Env []string
+	// RunEnv returns entries appended to Env, it is called before every run
+	RunEnv func() []string
+	// RunArgs returns the Args of the next run, e.g. with secrets
+	// substituted. It is called before every run, an error fails the run.
+	RunArgs      func(args []string) ([]string, error)
+	StaleTimeout time.Duration
+	// StaleOn selects the lines that reset the stale timer, default
+	// StaleOnMediaTime
+	StaleOn string
+	// RuntimeLimit stops the process this long after Start, reconnects
+	// included. 0 is unlimited.
+	RuntimeLimit time.Duration
+	// StopSignal ends the process gracefully on Unix, default SIGINT. On
+	// Windows the process is always killed.
+	StopSignal syscall.Signal
+	// Nice and IOClass are applied to the process group after every start,
+	// on Linux only. 0 and "" keep what the process inherits.
+	Nice    int
+	IOClass string
+	// ProgressPipe passes a pipe to FFmpeg as fd 3 and feeds what it reads
+	// to the parser, Args must contain -progress pipe:3. Unix only.
+	ProgressPipe bool
+	// Stdin is the standard input of FFmpeg, e.g. for -i pipe:0, nil is
+	// none. Runs after a reconnect continue reading it. Stop ends it: it is
+	// closed if it is an io.Closer and later runs read EOF.
+	Stdin         io.Reader
+	Parser        Parser
+	OnStart       func()
+	OnExit        func()
+	OnStateChange func(from, to string)
+	Logger        Logger
 }
 
 // Status of a process
@@ -51,6 +106,37 @@ type Status struct {
 	Order    string
 	Duration time.Duration
 	Time     time.Time
+	// Reconnect is the time until the pending reconnect, -1 if there is none
+	Reconnect time.Duration
+	// ReconnectDelay is the whole delay of the pending reconnect, with
+	// ReconnectBackoff it grows with ReconnectAttempts. 0 if there is none.
+	ReconnectDelay time.Duration
+	// StopReason is why the last run was stopped, by the process itself or
+	// in StopOptions, empty if there is none
+	StopReason string
+	// StoppedBy is user, system or limit if the last run ended because it
+	// was asked to stop, empty if FFmpeg exited by itself
+	StoppedBy string
+	// Uptime is the total time spent running or paused, across all runs
+	Uptime time.Duration
+	// Started is the time since the order last changed to start and
+	// StartedUptime the time spent running or paused since then, across
+	// reconnects. Both are 0 while the order is stop.
+	Started       time.Duration
+	StartedUptime time.Duration
+	// Reconnects counts the automatic restarts after a run ended
+	Reconnects uint64
+	// ReconnectAttempts counts the reconnects since the last run that lasted
+	// longer than ReconnectDelay, they are limited by MaxReconnects
+	ReconnectAttempts int
+	// ReconnectsLeft is MaxReconnects minus ReconnectAttempts, -1 if the
+	// reconnects are unlimited and 0 without Reconnect
+	ReconnectsLeft int
+	// ExitCode of the last run, -1 if there is none or it was ended by a
+	// signal
+	ExitCode int
+	// Priority of the current run if Nice or IOClass are set, nil otherwise
+	Priority *Priority
 	CPU      struct {
 		Current float64
 		Limit   float64
@@ -69,6 +155,7 @@ type States struct {
 	Finishing uint64
 	Failed    uint64
 	Killed    uint64
+	Paused    uint64
 }
 
 // Logger interface
@@ -78,6 +165,39 @@ type Logger interface {
 	Debug(format string, args ...interface{})
 }
 
+// Stale modes: which parsed lines reset the stale timer
+const (
+	StaleOnOutput    = "output"     // any progress line, even if it repeats the last one
+	StaleOnMediaTime = "media_time" // only progress lines where frame/time advanced, the default
+)
+
+// Reasons for a stop by the process itself
+const (
+	StopReasonStale           = "stale"                // no progress lines for StaleTimeout
+	StopReasonProgressStalled = "progress_stalled"     // progress lines, but frame/time didn't advance
+	StopReasonRuntimeLimit    = "runtime_limit"        // RuntimeLimit reached
+	StopReasonReconnects      = "reconnects_exhausted" // MaxReconnects reached, the process failed
+)
+
+// Who asked a run to stop, see Status.StoppedBy
+const (
+	StoppedByUser   = "user"   // stop, restart, update or delete
+	StoppedBySystem = "system" // e.g. stale, preempted or shutdown
+	StoppedByLimit  = "limit"  // RuntimeLimit reached
+)
+
+// stoppedBy classifies the StopReason of a run that was asked to stop
+func stoppedBy(reason string) string {
+	switch {
+	case reason == "" || strings.HasPrefix(reason, "stop_"):
+		return StoppedByUser
+	case reason == StopReasonRuntimeLimit:
+		return StoppedByLimit
+	default:
+		return StoppedBySystem
+	}
+}
+
 type stateType string
 
 const (
@@ -87,6 +207,7 @@ const (
 	stateFinishing stateType = "finishing"
 	stateFailed    stateType = "failed"
 	stateKilled    stateType = "killed"
+	statePaused    stateType = "paused"
 )
 
 func (s stateType) String() string { return string(s) }
@@ -96,56 +217,97 @@ func (s stateType) IsRunning() bool {
 }
 
 type process struct {
-	binary string
-	args   []string
-	cmd    *exec.Cmd
-	pid    int32
-	stdout io.ReadCloser
-	lastLine string
+	binary       string
+	args         []string
+	env          []string
+	runEnv       func() []string
+	runArgs      func(args []string) ([]string, error)
+	signal       syscall.Signal // StopSignal
+	cmd          *exec.Cmd
+	pid          int32
+	stdout       io.ReadCloser
+	progressPipe bool
+	stdin        *stdinPump    // nil without Config.Stdin
+	stdinExited  chan struct{} // closed when the run fed by stdin exits
 
 	state struct {
 		state  stateType
 		time   time.Time
 		states States
-		lock   sync.Mutex
+		reason string // StopReason of the last run
+		// stopped is set if the last run ended after it was asked to stop
+		stopped bool
+		// uptime of the ended running and paused states
+		uptime     time.Duration
+		started    startedUptime
+		reconnects uint64
+		exitCode   int
+		lock       sync.Mutex
 	}
 	order struct {
 		order string
 		lock  sync.Mutex
 	}
 	parser Parser
-	stale struct {
-		last    time.Time
+	stale  struct {
+		last    time.Time // last line that reset the timer
+		output  time.Time // last progress line, also if it didn't advance
 		timeout time.Duration
+		media   bool
 		cancel  context.CancelFunc
 		lock    sync.Mutex
 	}
+	runtime struct {
+		limit    time.Duration
+		deadline time.Time // of the current Start
+		timer    *time.Timer
+		lock     sync.Mutex
+	}
 	reconn struct {
-		enable bool
-		delay  time.Duration
-		timer  *time.Timer
-		lock   sync.Mutex
+		enable  bool
+		delay   time.Duration
+		backoff float64
+		limit   time.Duration
+		max     int
+		count   int
+		running time.Time
+		timer   *time.Timer
+		at      time.Time     // when the pending reconnect fires
+		wait    time.Duration // delay of the pending reconnect
+		lock    sync.Mutex
+	}
+	priority struct {
+		nice    int
+		ioClass string
+		applied *Priority // read back after the start of the current run
+		lock    sync.Mutex
 	}
 	killTimer     *time.Timer
 	killTimerLock sync.Mutex
 	logger        Logger
 	limits        Limiter
+	faults        faults
 	callbacks     struct {
-		onStart       func()
-		onExit        func()
-		onStateChange func(from, to string)
-		lock          sync.Mutex
+		onStart     func()
+		onExit      func()
+		stateChange stateNotifier // OnStateChange, in order
+		lock        sync.Mutex
 	}
 }
 
 // New creates a new process
 func New(config Config) (Process, error) {
 	p := &process{
-		binary: config.Binary,
-		args:   config.Args,
-		parser: config.Parser,
-		logger: config.Logger,
-		limits: NewSysLimiter(),
+		binary:       config.Binary,
+		args:         config.Args,
+		env:          config.Env,
+		runEnv:       config.RunEnv,
+		runArgs:      config.RunArgs,
+		signal:       config.StopSignal,
+		progressPipe: config.ProgressPipe,
+		parser:       config.Parser,
+		logger:       config.Logger,
+		limits:       NewSysLimiter(),
 	}
 
 	if len(p.binary) == 0 {
@@ -160,15 +322,32 @@ func New(config Config) (Process, error) {
 		p.logger = &nopLogger{}
 	}
 
+	if p.signal == 0 {
+		p.signal = syscall.SIGINT
+	}
+
+	p.priority.nice = config.Nice
+	p.priority.ioClass = config.IOClass
+
+	if config.Stdin != nil {
+		p.stdin = newStdinPump(config.Stdin)
+	}
+
 	p.order.order = "stop"
 	p.initState(stateFinished)
 	p.reconn.enable = config.Reconnect
 	p.reconn.delay = config.ReconnectDelay
+	p.reconn.backoff = config.ReconnectBackoff
+	p.reconn.limit = config.ReconnectMaxDelay
+	p.reconn.max = config.MaxReconnects
 	p.stale.last = time.Now()
+	p.stale.output = p.stale.last
 	p.stale.timeout = config.StaleTimeout
+	p.stale.media = config.StaleOn != StaleOnOutput
+	p.runtime.limit = config.RuntimeLimit
 	p.callbacks.onStart = config.OnStart
 	p.callbacks.onExit = config.OnExit
-	p.callbacks.onStateChange = config.OnStateChange
+	p.callbacks.stateChange.fn = config.OnStateChange
 
 	return p, nil
 }
@@ -178,6 +357,7 @@ func (p *process) initState(state stateType) {
 	defer p.state.lock.Unlock()
 	p.state.state = state
 	p.state.time = time.Now()
+	p.state.exitCode = -1
 }
 
 func (p *process) setState(state stateType) error {
@@ -189,10 +369,15 @@ func (p *process) setState(state stateType) error {
 
 	switch p.state.state {
 	case stateFinished:
-		if state == stateStarting {
+		switch state {
+		case stateStarting:
 			p.state.state = state
 			p.state.states.Starting++
-		} else {
+		case stateFailed:
+			// 重连次数用完，见 giveUp
+			p.state.state = state
+			p.state.states.Failed++
+		default:
 			failed = true
 		}
 	case stateStarting:
@@ -211,9 +396,11 @@ func (p *process) setState(state stateType) error {
 		}
 	case stateRunning:
 		switch state {
-		case stateFinished, stateFinishing, stateFailed, stateKilled:
+		case stateFinished, stateFinishing, stateFailed, stateKilled, statePaused:
 			p.state.state = state
 			switch state {
+			case statePaused:
+				p.state.states.Paused++
 			case stateFinished:
 				p.state.states.Finished++
 			case stateFinishing:
@@ -240,11 +427,32 @@ func (p *process) setState(state stateType) error {
 		default:
 			failed = true
 		}
+	case statePaused:
+		switch state {
+		case stateRunning:
+			p.state.state = state
+		case stateFinished, stateFailed, stateKilled:
+			// 暂停期间进程被外部结束
+			p.state.state = state
+			if state == stateFinished {
+				p.state.states.Finished++
+			} else if state == stateFailed {
+				p.state.states.Failed++
+			} else {
+				p.state.states.Killed++
+			}
+		default:
+			failed = true
+		}
 	case stateFailed, stateKilled:
-		if state == stateStarting {
+		switch {
+		case state == stateStarting:
 			p.state.state = state
 			p.state.states.Starting++
-		} else {
+		case p.state.state == stateKilled && state == stateFailed:
+			p.state.state = state
+			p.state.states.Failed++
+		default:
 			failed = true
 		}
 	default:
@@ -255,10 +463,12 @@ func (p *process) setState(state stateType) error {
 		return fmt.Errorf("can't change from %s to %s", p.state.state, state)
 	}
 
-	p.state.time = time.Now()
-	if p.callbacks.onStateChange != nil {
-		go p.callbacks.onStateChange(prevState.String(), p.state.state.String())
+	if prevState.IsRunning() || prevState == statePaused {
+		p.state.uptime += time.Since(p.state.time)
+		p.state.started.add(p.state.time)
 	}
+	p.state.time = time.Now()
+	p.callbacks.stateChange.notify(prevState.String(), p.state.state.String())
 	return nil
 }
 
@@ -286,6 +496,17 @@ func (p *process) Status() Status {
 	stateTime := p.state.time
 	stateString := p.state.state.String()
 	states := p.state.states
+	reason := p.state.reason
+	stopped := p.state.stopped
+	uptime := p.state.uptime
+	var upSince time.Time
+	if p.state.state.IsRunning() || p.state.state == statePaused {
+		uptime += time.Since(stateTime)
+		upSince = stateTime
+	}
+	started, startedUptime := p.state.started.get(upSince)
+	reconnects := p.state.reconnects
+	exitCode := p.state.exitCode
 	p.state.lock.Unlock()
 
 	p.order.lock.Lock()
@@ -293,16 +514,33 @@ func (p *process) Status() Status {
 	p.order.lock.Unlock()
 
 	s := Status{
-		State:    stateString,
-		States:   states,
-		Order:    order,
-		Duration: time.Since(stateTime),
-		Time:     stateTime,
+		State:      stateString,
+		States:     states,
+		Order:      order,
+		Duration:   time.Since(stateTime),
+		Time:       stateTime,
+		StopReason: reason,
+		Uptime:     uptime,
+		Reconnects: reconnects,
+		ExitCode:   exitCode,
+	}
+	if order == "start" {
+		s.Started, s.StartedUptime = started, startedUptime
+	}
+	s.Reconnect, s.ReconnectDelay = p.reconnectIn()
+	s.ReconnectAttempts, s.ReconnectsLeft = p.reconnectAttempts()
+	if stopped {
+		s.StoppedBy = stoppedBy(reason)
 	}
 	s.CPU.Current = cpu
 	s.CPU.Limit = cpuLimit
 	s.Memory.Current = memory
 	s.Memory.Limit = memoryLimit
+	if s.State == "running" || s.State == "paused" {
+		p.priority.lock.Lock()
+		s.Priority = p.priority.applied
+		p.priority.lock.Unlock()
+	}
 	return s
 }
 
@@ -318,20 +556,54 @@ func (p *process) Start() error {
 		return nil
 	}
 	p.order.order = "start"
+	p.resetReconnects()
+	p.state.lock.Lock()
+	p.state.started.reset(time.Now())
+	p.state.lock.Unlock()
+	if p.runtime.limit > 0 {
+		p.runtime.lock.Lock()
+		p.runtime.deadline = time.Now().Add(p.runtime.limit)
+		p.runtime.lock.Unlock()
+	}
 	return p.start()
 }
 
 func (p *process) start() error {
-	if p.isRunning() {
+	// 暂停的进程仍在，不能再启动一个
+	if p.isRunning() || p.getState() == statePaused {
 		return nil
 	}
 
+	p.resetStop()
+
 	p.unreconnect()
 	p.setState(stateStarting)
 
+	if err := p.faults.takeFailStart(); err != nil {
+		p.setState(stateFailed)
+		p.parser.Parse(err.Error())
+		p.reconnect()
+		return err
+	}
+
+	args := p.args
+	if p.runArgs != nil {
+		var err error
+		if args, err = p.runArgs(p.args); err != nil {
+			p.setState(stateFailed)
+			p.parser.Parse(err.Error())
+			p.reconnect()
+			return err
+		}
+	}
+
 	var err error
-	p.cmd = exec.Command(p.binary, p.args...)
-	p.cmd.Env = []string{}
+	p.cmd = exec.Command(p.binary, args...)
+	setProcessGroup(p.cmd)
+	p.cmd.Env = append([]string{}, p.env...)
+	if p.runEnv != nil {
+		p.cmd.Env = append(p.cmd.Env, p.runEnv()...)
+	}
 
 	p.stdout, err = p.cmd.StderrPipe()
 	if err != nil {
@@ -341,7 +613,37 @@ func (p *process) start() error {
 		return err
 	}
 
-	if err := p.cmd.Start(); err != nil {
+	var stdinW io.WriteCloser
+	if p.stdin != nil {
+		if stdinW, err = p.stdin.pipe(p.cmd); err != nil {
+			p.setState(stateFailed)
+			p.parser.Parse(err.Error())
+			p.reconnect()
+			return err
+		}
+	}
+
+	var progressR, progressW *os.File
+	if p.progressPipe {
+		if progressR, progressW, err = os.Pipe(); err != nil {
+			p.setState(stateFailed)
+			p.parser.Parse(err.Error())
+			p.reconnect()
+			return err
+		}
+		// ExtraFiles[0] 即 FFmpeg 的 fd 3
+		p.cmd.ExtraFiles = []*os.File{progressW}
+	}
+
+	err = p.cmd.Start()
+	if progressW != nil {
+		// 写端只留给 FFmpeg，它退出后读端才能读到 EOF
+		progressW.Close()
+	}
+	if err != nil {
+		if progressR != nil {
+			progressR.Close()
+		}
 		p.setState(stateFailed)
 		p.parser.Parse(err.Error())
 		p.reconnect()
@@ -349,15 +651,26 @@ func (p *process) start() error {
 	}
 
 	p.pid = int32(p.cmd.Process.Pid)
+	if stdinW != nil {
+		p.stdinExited = make(chan struct{})
+		p.stdin.feed(stdinW, p.stdinExited)
+	}
 	p.limits.Start(int(p.pid))
+	p.applyPriority()
 
 	p.setState(stateRunning)
 
+	p.reconn.lock.Lock()
+	p.reconn.running = time.Now()
+	p.reconn.lock.Unlock()
+
 	if p.callbacks.onStart != nil {
 		go p.callbacks.onStart()
 	}
 
-	go p.reader()
+	go p.reader(progressR)
+
+	p.armRuntimeLimit()
 
 	if p.stale.timeout != 0 {
 		p.stale.lock.Lock()
@@ -371,6 +684,10 @@ func (p *process) start() error {
 }
 
 func (p *process) Stop(wait bool) error {
+	return p.StopWith(StopOptions{Wait: wait})
+}
+
+func (p *process) StopWith(opts StopOptions) error {
 	p.order.lock.Lock()
 	defer p.order.lock.Unlock()
 
@@ -378,19 +695,106 @@ func (p *process) Stop(wait bool) error {
 		return nil
 	}
 	p.order.order = "stop"
-	return p.stop(wait)
+	if p.isRunning() || p.getState() == statePaused {
+		p.setStopReason(opts.Reason)
+	}
+	if p.stdin != nil {
+		// FFmpeg 可能阻塞在读 stdin 上
+		p.stdin.close()
+	}
+	return p.stop(opts)
+}
+
+// Pause suspends a running process. A paused process doesn't count as
+// running, but keeps its state and output.
+func (p *process) Pause() error {
+	p.order.lock.Lock()
+	defer p.order.lock.Unlock()
+
+	if p.getState() != stateRunning {
+		return fmt.Errorf("can't pause a process that is %s", p.getStateString())
+	}
+	if err := suspendProcess(p.cmd.Process); err != nil {
+		return err
+	}
+	return p.setState(statePaused)
+}
+
+// Resume continues a paused process
+func (p *process) Resume() error {
+	p.order.lock.Lock()
+	defer p.order.lock.Unlock()
+
+	return p.resume()
+}
+
+func (p *process) resume() error {
+	if p.getState() != statePaused {
+		return fmt.Errorf("can't resume a process that is %s", p.getStateString())
+	}
+	if err := resumeProcess(p.cmd.Process); err != nil {
+		return err
+	}
+
+	p.stale.lock.Lock()
+	p.stale.last = time.Now()
+	p.stale.output = p.stale.last
+	p.stale.lock.Unlock()
+
+	return p.setState(stateRunning)
+}
+
+func (p *process) Fail(err error) error {
+	p.order.lock.Lock()
+	defer p.order.lock.Unlock()
+
+	if p.isRunning() || p.getState() == statePaused {
+		return fmt.Errorf("can't fail a process that is %s", p.getStateString())
+	}
+	p.setState(stateStarting)
+	p.parser.Parse(err.Error())
+	return p.setState(stateFailed)
+}
+
+// Signal sends sig to the running process
+func (p *process) Signal(sig syscall.Signal) error {
+	p.order.lock.Lock()
+	defer p.order.lock.Unlock()
+
+	if !p.isRunning() && p.getState() != statePaused {
+		return fmt.Errorf("can't signal a process that is %s", p.getStateString())
+	}
+	return signalGroup(p.cmd.Process, sig)
+}
+
+func (p *process) SetFaults(f Faults) {
+	p.faults.set(f)
+}
+
+func (p *process) FailNextStart(fail bool) {
+	p.faults.setFailStart(fail)
 }
 
 func (p *process) Kill(wait bool) error {
-	if !p.isRunning() {
+	if !p.isRunning() && p.getState() != statePaused {
 		return nil
 	}
 	p.order.lock.Lock()
 	defer p.order.lock.Unlock()
-	return p.stop(wait)
+	return p.stop(StopOptions{Wait: wait})
 }
 
-func (p *process) stop(wait bool) error {
+func (p *process) stop(opts StopOptions) error {
+	wait := opts.Wait
+	// 读取停滞时进程无法退出
+	p.faults.unstall()
+
+	if p.getState() == statePaused {
+		// 暂停的进程无法响应 SIGINT，需先恢复
+		if err := p.resume(); err != nil {
+			p.logger.Error("resume before stop: %s", err)
+		}
+	}
 	if !p.isRunning() {
 		p.unreconnect()
 		return nil
@@ -413,19 +817,31 @@ func (p *process) stop(wait bool) error {
 			wg.Done()
 		}
 		p.callbacks.lock.Unlock()
+
+		// 恢复原回调，否则下次退出会再次调用 wg.Done
+		defer func() {
+			p.callbacks.lock.Lock()
+			p.callbacks.onExit = cb
+			p.callbacks.lock.Unlock()
+		}()
 	}
 
 	var err error
-	if runtime.GOOS == "windows" {
-		err = p.cmd.Process.Kill()
+	if runtime.GOOS == "windows" || opts.Kill {
+		err = killGroup(p.cmd.Process)
 	} else {
-		err = p.cmd.Process.Signal(os.Interrupt)
+		// 发给整个进程组，包括 FFmpeg 派生的子进程
+		err = signalGroup(p.cmd.Process, p.signal)
 		if err != nil {
-			err = p.cmd.Process.Kill()
+			err = killGroup(p.cmd.Process)
 		} else {
+			killAfter := opts.KillAfter
+			if killAfter <= 0 {
+				killAfter = defaultKillAfter
+			}
 			p.killTimerLock.Lock()
-			p.killTimer = time.AfterFunc(5*time.Second, func() {
-				p.cmd.Process.Kill()
+			p.killTimer = time.AfterFunc(killAfter, func() {
+				killGroup(p.cmd.Process)
 			})
 			p.killTimerLock.Unlock()
 		}
@@ -442,22 +858,124 @@ func (p *process) stop(wait bool) error {
 	return err
 }
 
+// reconnect schedules a restart after the reconnect delay. The caller must
+// hold the order lock. Once MaxReconnects is exhausted the order is dropped
+// to "stop" and the process fails, see giveUp.
 func (p *process) reconnect() {
 	if !p.reconn.enable {
 		return
 	}
 	p.unreconnect()
 
+	if p.runtimeOver() {
+		p.order.order = "stop"
+		p.setStopReason(StopReasonRuntimeLimit)
+		return
+	}
+
 	p.reconn.lock.Lock()
 	defer p.reconn.lock.Unlock()
 
-	p.reconn.timer = time.AfterFunc(p.reconn.delay, func() {
+	if p.reconn.max > 0 && p.reconn.count >= p.reconn.max {
+		p.logger.Error("giving up after %d reconnect attempts", p.reconn.count)
+		p.order.order = "stop"
+		p.giveUp()
+		return
+	}
+	delay := p.reconnectDelay() + p.faults.reconnectDelay()
+	p.reconn.count++
+
+	p.reconn.at = time.Now().Add(delay)
+	p.reconn.wait = delay
+	p.reconn.timer = time.AfterFunc(delay, func() {
 		p.order.lock.Lock()
 		defer p.order.lock.Unlock()
+		p.state.lock.Lock()
+		p.state.reconnects++
+		p.state.lock.Unlock()
 		p.start()
 	})
 }
 
+// giveUp ends the process in the failed state with StopReasonReconnects,
+// also if the last run finished or was killed
+func (p *process) giveUp() {
+	p.setStopReason(StopReasonReconnects)
+	if p.getState() != stateFailed {
+		p.setState(stateFailed)
+	}
+}
+
+// reconnectIn returns the time until the pending reconnect, -1 if none,
+// and its whole delay
+func (p *process) reconnectIn() (time.Duration, time.Duration) {
+	p.reconn.lock.Lock()
+	defer p.reconn.lock.Unlock()
+
+	if p.reconn.timer == nil {
+		return -1, 0
+	}
+	return max(time.Until(p.reconn.at), 0), p.reconn.wait
+}
+
+// reconnectDelay returns the delay for the next attempt. The caller must hold
+// the reconnect lock.
+func (p *process) reconnectDelay() time.Duration {
+	return backoffDelay(p.reconn.delay, p.reconn.limit, p.reconn.backoff, p.reconn.count)
+}
+
+// backoffDelay returns delay multiplied attempts times by backoff, capped at
+// limit if it is set. A backoff of 0 or 1 keeps the delay constant.
+func backoffDelay(delay, limit time.Duration, backoff float64, attempts int) time.Duration {
+	if backoff <= 1 {
+		return delay
+	}
+
+	d := float64(delay)
+	for i := 0; i < attempts; i++ {
+		d *= backoff
+		if limit > 0 && d >= float64(limit) {
+			return limit
+		}
+		if d >= math.MaxInt64 {
+			return time.Duration(math.MaxInt64)
+		}
+	}
+	return time.Duration(d)
+}
+
+// reconnectAttempts returns the attempts counted against MaxReconnects and
+// the attempts left
+func (p *process) reconnectAttempts() (attempts, left int) {
+	p.reconn.lock.Lock()
+	defer p.reconn.lock.Unlock()
+
+	attempts = p.reconn.count
+	if !p.reconn.running.IsZero() && time.Since(p.reconn.running) > p.reconn.delay {
+		// 运行足够久，退出时清零
+		attempts = 0
+	}
+	return attempts, reconnectsLeft(p.reconn.enable, p.reconn.max, attempts)
+}
+
+// reconnectsLeft returns the reconnects left after attempts, -1 if they are
+// unlimited
+func reconnectsLeft(enable bool, maxReconnects, attempts int) int {
+	if !enable {
+		return 0
+	}
+	if maxReconnects <= 0 {
+		return -1
+	}
+	return max(maxReconnects-attempts, 0)
+}
+
+func (p *process) resetReconnects() {
+	p.reconn.lock.Lock()
+	defer p.reconn.lock.Unlock()
+	p.reconn.count = 0
+}
+
 func (p *process) unreconnect() {
 	p.reconn.lock.Lock()
 	defer p.reconn.lock.Unlock()
@@ -466,11 +984,14 @@ func (p *process) unreconnect() {
 		p.reconn.timer.Stop()
 		p.reconn.timer = nil
 	}
+	p.reconn.at = time.Time{}
+	p.reconn.wait = 0
 }
 
 func (p *process) staler(ctx context.Context) {
 	p.stale.lock.Lock()
 	p.stale.last = time.Now()
+	p.stale.output = p.stale.last
 	p.stale.lock.Unlock()
 
 	ticker := time.NewTicker(time.Second)
@@ -482,45 +1003,172 @@ func (p *process) staler(ctx context.Context) {
 			return
 		case t := <-ticker.C:
 			p.stale.lock.Lock()
-			last := p.stale.last
+			if p.getState() == statePaused {
+				// 暂停期间不计入超时
+				p.stale.last = t
+				p.stale.output = t
+			}
+			last, output := p.stale.last, p.stale.output
 			timeout := p.stale.timeout
 			p.stale.lock.Unlock()
 
 			if t.Sub(last).Seconds() > timeout.Seconds() {
-				p.stop(false)
+				// 仍有进度输出但 frame/time 未增加
+				reason := StopReasonStale
+				if t.Sub(output).Seconds() <= timeout.Seconds() {
+					reason = StopReasonProgressStalled
+				}
+				p.logger.Error("stopping, %s for %s", reason, timeout)
+				p.setStopReason(reason)
+				p.stop(StopOptions{})
 				return
 			}
 		}
 	}
 }
 
-func (p *process) reader() {
+// armRuntimeLimit schedules the stop at the runtime deadline of the
+// current Start
+func (p *process) armRuntimeLimit() {
+	if p.runtime.limit <= 0 {
+		return
+	}
+	p.runtime.lock.Lock()
+	defer p.runtime.lock.Unlock()
+
+	if p.runtime.timer != nil {
+		p.runtime.timer.Stop()
+	}
+	p.runtime.timer = time.AfterFunc(max(time.Until(p.runtime.deadline), 0), p.runtimeExceeded)
+}
+
+// runtimeExceeded stops the process for good, like a stop order
+func (p *process) runtimeExceeded() {
+	p.order.lock.Lock()
+	defer p.order.lock.Unlock()
+
+	if p.order.order != "start" {
+		return
+	}
+	p.logger.Info("stopping, runtime limit of %s reached", p.runtime.limit)
+	p.order.order = "stop"
+	p.setStopReason(StopReasonRuntimeLimit)
+	p.stop(StopOptions{})
+}
+
+// runtimeOver reports whether the runtime deadline of the current Start
+// has passed
+func (p *process) runtimeOver() bool {
+	if p.runtime.limit <= 0 {
+		return false
+	}
+	p.runtime.lock.Lock()
+	defer p.runtime.lock.Unlock()
+	return !time.Now().Before(p.runtime.deadline)
+}
+
+func (p *process) disarmRuntimeLimit() {
+	p.runtime.lock.Lock()
+	defer p.runtime.lock.Unlock()
+
+	if p.runtime.timer != nil {
+		p.runtime.timer.Stop()
+		p.runtime.timer = nil
+	}
+}
+
+func (p *process) setStopReason(reason string) {
+	p.state.lock.Lock()
+	p.state.reason = reason
+	p.state.lock.Unlock()
+}
+
+// resetStop clears the stop of the last run before a new one
+func (p *process) resetStop() {
+	p.state.lock.Lock()
+	p.state.reason = ""
+	p.state.stopped = false
+	p.state.lock.Unlock()
+}
+
+// reader parses stderr, and the -progress pipe if it isn't nil, until
+// FFmpeg exits
+func (p *process) reader(progressR *os.File) {
 	scanner := bufio.NewScanner(p.stdout)
 	scanner.Split(scanLine)
 
 	p.parser.ResetStats()
 	p.parser.ResetLog()
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		p.lastLine = line
-		n := p.parser.Parse(line)
-		if n != 0 {
-			p.stale.lock.Lock()
-			p.stale.last = time.Now()
-			p.stale.lock.Unlock()
+	var progress chan struct{}
+	if progressR != nil {
+		progress = make(chan struct{})
+		go p.progressReader(progressR, progress)
+	}
+
+	for {
+		p.faults.wait()
+		if !scanner.Scan() {
+			break
 		}
+		p.parsed(p.parser.Parse(scanner.Text()))
 	}
 
+	// 进度读完后再重置统计，避免退出后残留
+	if progress != nil {
+		<-progress
+	}
 	p.waiter()
 }
 
+// progressReader feeds the -progress output of a run to the parser until
+// FFmpeg closes the pipe
+func (p *process) progressReader(r *os.File, done chan struct{}) {
+	defer close(done)
+	defer r.Close()
+
+	parse := p.parser.Parse
+	if pp, ok := p.parser.(ProgressParser); ok {
+		parse = pp.ParseProgress
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		p.parsed(parse(scanner.Text()))
+	}
+}
+
+// parsed updates the stale detection with the result of a parsed line
+func (p *process) parsed(r ParseResult) {
+	if !r.Progress {
+		return
+	}
+	p.stale.lock.Lock()
+	defer p.stale.lock.Unlock()
+	p.stale.output = time.Now()
+	if r.Advanced || !p.stale.media {
+		p.stale.last = p.stale.output
+	}
+}
+
 func (p *process) waiter() {
-	if err := p.cmd.Wait(); err != nil {
+	err := p.cmd.Wait()
+	if p.stdinExited != nil {
+		close(p.stdinExited)
+		p.stdinExited = nil
+	}
+	// ExitCode 为 -1 表示被信号结束
+	p.state.lock.Lock()
+	p.state.exitCode = p.cmd.ProcessState.ExitCode()
+	// 只有停止经过 finishing，此时的非零退出码不算失败
+	stopped := p.state.state == stateFinishing
+	p.state.stopped = stopped
+	p.state.lock.Unlock()
+
+	if err != nil {
 		if exiterr, ok := err.(*exec.ExitError); ok {
 			status := exiterr.Sys().(syscall.WaitStatus)
 			if status.Exited() {
-				if status.ExitStatus() == 255 {
+				if status.ExitStatus() == 255 || stopped {
 					p.setState(stateFinished)
 				} else {
 					p.setState(stateFailed)
@@ -536,6 +1184,7 @@ func (p *process) waiter() {
 	}
 
 	p.limits.Stop()
+	p.disarmRuntimeLimit()
 
 	p.killTimerLock.Lock()
 	if p.killTimer != nil {
@@ -563,6 +1212,12 @@ func (p *process) waiter() {
 	defer p.order.lock.Unlock()
 
 	if p.order.order == "start" {
+		p.reconn.lock.Lock()
+		if !p.reconn.running.IsZero() && time.Since(p.reconn.running) > p.reconn.delay {
+			p.reconn.count = 0
+		}
+		p.reconn.running = time.Time{}
+		p.reconn.lock.Unlock()
 		p.reconnect()
 	}
 }
@@ -593,10 +1248,12 @@ func scanLine(data []byte, atEOF bool) (advance int, token []byte, err error) {
 
 type nullParser struct{}
 
-func (p *nullParser) Parse(line string) uint64       { return 1 }
-func (p *nullParser) ResetStats()                    {}
-func (p *nullParser) ResetLog()                     {}
-func (p *nullParser) Log() []Line                   { return nil }
+func (p *nullParser) Parse(line string) ParseResult {
+	return ParseResult{Progress: true, Advanced: true}
+}
+func (p *nullParser) ResetStats() {}
+func (p *nullParser) ResetLog()   {}
+func (p *nullParser) Log() []Line { return nil }
 
 type nopLogger struct{}
 
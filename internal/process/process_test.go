@@ -0,0 +1,137 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeFFmpeg writes a shell script that stands in for FFmpeg and returns
+// its path
+func fakeFFmpeg(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newTestProcess creates a process that is killed at the end of the test
+func newTestProcess(t *testing.T, config Config) *process {
+	t.Helper()
+	proc, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := proc.(*process)
+	t.Cleanup(func() { p.StopWith(StopOptions{Wait: true, Kill: true}) })
+	return p
+}
+
+// waitState polls the state of p until it is state or timeout passed
+func waitState(t *testing.T, p Process, state string, timeout time.Duration) {
+	t.Helper()
+	for deadline := time.Now().Add(timeout); p.Status().State != state; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for state %s, state %s", state, p.Status().State)
+		}
+	}
+}
+
+// waitStopped polls p until it isn't running anymore
+func waitStopped(t *testing.T, p Process, timeout time.Duration) {
+	t.Helper()
+	for deadline := time.Now().Add(timeout); p.IsRunning(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the process to stop, state %s", p.Status().State)
+		}
+	}
+}
+
+// frameParser is a minimal Parser, lines with frame= are progress that
+// advanced if the frame count grew. The parse package can't be used here,
+// it imports process.
+type frameParser struct {
+	frame int
+	seq   uint64
+	lines []Line
+	lock  sync.Mutex
+}
+
+var frameRe = regexp.MustCompile(`frame=\s*(\d+)`)
+
+func (p *frameParser) Parse(line string) ParseResult {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.seq++
+	p.lines = append(p.lines, Line{Seq: p.seq, Timestamp: time.Now(), Data: line})
+	m := frameRe.FindStringSubmatch(line)
+	if m == nil {
+		return ParseResult{}
+	}
+	frame, _ := strconv.Atoi(m[1])
+	advanced := frame > p.frame
+	p.frame = max(p.frame, frame)
+	return ParseResult{Progress: true, Advanced: advanced}
+}
+
+func (p *frameParser) ResetStats() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.frame = 0
+}
+
+func (p *frameParser) ResetLog() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.lines = nil
+}
+
+func (p *frameParser) Log() []Line {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]Line{}, p.lines...)
+}
+
+func TestExitCode(t *testing.T) {
+	p := newTestProcess(t, Config{
+		Binary:         fakeFFmpeg(t, "sleep 0.1\nexit 3"),
+		Reconnect:      true,
+		ReconnectDelay: 50 * time.Millisecond,
+	})
+	if s := p.Status(); s.ExitCode != -1 {
+		t.Fatalf("exit code %d before the first run, want -1", s.ExitCode)
+	}
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); p.Status().Reconnects < 2; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("status %+v, want 2 reconnects", p.Status())
+		}
+	}
+	s := p.Status()
+	if s.ExitCode != 3 {
+		t.Fatalf("exit code %d, want 3", s.ExitCode)
+	}
+	if s.States.Failed < 2 {
+		t.Fatalf("%d failed runs, want at least 2", s.States.Failed)
+	}
+	// 每次运行约 0.1 秒，累计运行时间跨越各次运行
+	if s.Uptime < 200*time.Millisecond {
+		t.Fatalf("uptime %s, want at least 200ms", s.Uptime)
+	}
+}
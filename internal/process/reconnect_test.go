@@ -0,0 +1,97 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReconnectsExhausted(t *testing.T) {
+	tests := []struct {
+		name string
+		exit int
+	}{
+		{"failing", 1},
+		{"finishing", 0}, // 正常退出的任务用完重连次数后同样失败
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := filepath.Join(t.TempDir(), "runs")
+			p := newTestProcess(t, Config{
+				Binary:         fakeFFmpeg(t, "echo run >> "+runs+"\nexit "+strconv.Itoa(tt.exit)),
+				Reconnect:      true,
+				ReconnectDelay: 200 * time.Millisecond,
+				MaxReconnects:  2,
+			})
+			// 首次运行加两次重连，再次 start 时重新计数
+			for i := 1; i <= 2; i++ {
+				if err := p.Start(); err != nil {
+					t.Fatal(err)
+				}
+				for deadline := time.Now().Add(5 * time.Second); p.Status().Order != "stop"; time.Sleep(10 * time.Millisecond) {
+					if time.Now().After(deadline) {
+						t.Fatalf("still reconnecting, status %+v", p.Status())
+					}
+				}
+				s := p.Status()
+				if s.State != "failed" || s.StopReason != StopReasonReconnects {
+					t.Fatalf("state %s, stop reason %q, want failed, %q", s.State, s.StopReason, StopReasonReconnects)
+				}
+				if s.Reconnects != uint64(2*i) || s.ReconnectsLeft != 0 {
+					t.Fatalf("reconnects %d, left %d, want %d, 0", s.Reconnects, s.ReconnectsLeft, 2*i)
+				}
+				data, _ := os.ReadFile(runs)
+				if n := strings.Count(string(data), "run"); n != 3*i {
+					t.Fatalf("%d runs, want %d", n, 3*i)
+				}
+			}
+		})
+	}
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	p := newTestProcess(t, Config{
+		Binary:            fakeFFmpeg(t, "exit 1"),
+		Reconnect:         true,
+		ReconnectDelay:    100 * time.Millisecond,
+		ReconnectBackoff:  2,
+		ReconnectMaxDelay: 400 * time.Millisecond,
+		MaxReconnects:     5,
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 记录每次等待重连时的间隔，倒计时结束后 Reconnects 才增加
+	var delays []time.Duration
+	for deadline := time.Now().Add(10 * time.Second); p.Status().Order == "start"; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("still reconnecting after %v", delays)
+		}
+		s := p.Status()
+		if s.Reconnect > 0 && int(s.Reconnects) == len(delays) {
+			delays = append(delays, s.ReconnectDelay)
+		}
+	}
+
+	ms := time.Millisecond
+	want := []time.Duration{100 * ms, 200 * ms, 400 * ms, 400 * ms, 400 * ms}
+	if len(delays) != len(want) {
+		t.Fatalf("delays %v, want %v", delays, want)
+	}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Fatalf("delays %v, want %v", delays, want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package process
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts the process in its own process group, so that
+// signals reach the children it spawns, e.g. of a wrapper script
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// signalGroup sends sig to the process group of proc
+func signalGroup(proc *os.Process, sig syscall.Signal) error {
+	return syscall.Kill(-proc.Pid, sig)
+}
+
+func killGroup(proc *os.Process) error {
+	return signalGroup(proc, syscall.SIGKILL)
+}
+
+func suspendProcess(proc *os.Process) error {
+	return signalGroup(proc, syscall.SIGSTOP)
+}
+
+func resumeProcess(proc *os.Process) error {
+	return signalGroup(proc, syscall.SIGCONT)
+}
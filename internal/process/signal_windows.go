@@ -0,0 +1,34 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build windows
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup does nothing, there are no process groups to signal
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func signalGroup(proc *os.Process, sig syscall.Signal) error {
+	return proc.Signal(sig)
+}
+
+func killGroup(proc *os.Process) error {
+	return proc.Kill()
+}
+
+func suspendProcess(proc *os.Process) error {
+	return fmt.Errorf("pause is not supported on windows")
+}
+
+func resumeProcess(proc *os.Process) error {
+	return fmt.Errorf("resume is not supported on windows")
+}
@@ -0,0 +1,507 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package process
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Simulated behaviour is selected by special tokens anywhere in the args,
+// usually as an input or output address:
+//
+//	dryrun:fail-start       the start fails
+//	dryrun:fail-after=N     the run fails after N seconds
+//	dryrun:finish-after=N   the run finishes after N seconds
+const (
+	simFailStart   = "dryrun:fail-start"
+	simFailAfter   = "dryrun:fail-after="
+	simFinishAfter = "dryrun:finish-after="
+)
+
+// SimulatorConfig for a simulated process
+type SimulatorConfig struct {
+	Config
+	// FPS is the frame rate of the fake progress, default 25
+	FPS float64
+}
+
+type simulator struct {
+	config SimulatorConfig
+	parser Parser
+	logger Logger
+
+	failStart   bool
+	failAfter   time.Duration
+	finishAfter time.Duration
+
+	lock        sync.Mutex
+	state       stateType
+	stateTime   time.Time
+	states      States
+	order       string
+	stop        chan stateType
+	done        chan struct{}
+	paused      bool
+	reconnects  int
+	timer       *time.Timer
+	timerAt     time.Time
+	timerDelay  time.Duration
+	stateChange stateNotifier
+	faults      faults
+	reason      string
+	stopped     bool // the last run ended after it was asked to stop
+	uptime      time.Duration
+	started     startedUptime
+	deadline    time.Time // runtime limit of the current Start
+	limitTimer  *time.Timer
+	restarts    uint64 // automatic restarts, reconnects is reset after a long run
+	exitCode    int
+}
+
+// NewSimulator creates a process that never executes anything. It follows
+// the same states as a real process and feeds fake progress lines to the
+// parser, advancing once per second.
+func NewSimulator(config SimulatorConfig) (Process, error) {
+	s := &simulator{
+		config:    config,
+		parser:    config.Parser,
+		logger:    config.Logger,
+		state:     stateFinished,
+		stateTime: time.Now(),
+		order:     "stop",
+		exitCode:  -1,
+	}
+
+	s.stateChange.fn = config.OnStateChange
+	if s.config.FPS <= 0 {
+		s.config.FPS = 25
+	}
+	if s.parser == nil {
+		s.parser = &nullParser{}
+	}
+	if s.logger == nil {
+		s.logger = &nopLogger{}
+	}
+
+	for _, arg := range config.Args {
+		if strings.Contains(arg, simFailStart) {
+			s.failStart = true
+		}
+		if d, ok := simSeconds(arg, simFailAfter); ok {
+			s.failAfter = d
+		}
+		if d, ok := simSeconds(arg, simFinishAfter); ok {
+			s.finishAfter = d
+		}
+	}
+
+	return s, nil
+}
+
+func simSeconds(arg, token string) (time.Duration, bool) {
+	i := strings.Index(arg, token)
+	if i < 0 {
+		return 0, false
+	}
+	v := arg[i+len(token):]
+	if j := strings.IndexAny(v, "&/?# "); j >= 0 {
+		v = v[:j]
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n * float64(time.Second)), true
+}
+
+// setState changes the state. The caller must hold the lock.
+func (s *simulator) setState(state stateType) {
+	prev := s.state
+	if prev.IsRunning() || prev == statePaused {
+		s.uptime += time.Since(s.stateTime)
+		s.started.add(s.stateTime)
+	}
+	s.state = state
+	s.stateTime = time.Now()
+
+	switch state {
+	case stateFinished:
+		s.states.Finished++
+	case stateStarting:
+		s.states.Starting++
+	case stateRunning:
+		if prev != statePaused {
+			s.states.Running++
+		}
+	case stateFinishing:
+		s.states.Finishing++
+	case stateFailed:
+		s.states.Failed++
+	case stateKilled:
+		s.states.Killed++
+	case statePaused:
+		s.states.Paused++
+	}
+
+	s.stateChange.notify(prev.String(), state.String())
+}
+
+func (s *simulator) Status() Status {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	reconnect, delay := time.Duration(-1), time.Duration(0)
+	if s.timer != nil {
+		reconnect, delay = max(time.Until(s.timerAt), 0), s.timerDelay
+	}
+
+	uptime := s.uptime
+	var upSince time.Time
+	if s.state.IsRunning() || s.state == statePaused {
+		uptime += time.Since(s.stateTime)
+		upSince = s.stateTime
+	}
+	var started, startedUptime time.Duration
+	if s.order == "start" {
+		started, startedUptime = s.started.get(upSince)
+	}
+
+	var by string
+	if s.stopped {
+		by = stoppedBy(s.reason)
+	}
+
+	return Status{
+		State:             s.state.String(),
+		States:            s.states,
+		Order:             s.order,
+		Duration:          time.Since(s.stateTime),
+		Time:              s.stateTime,
+		Reconnect:         reconnect,
+		ReconnectDelay:    delay,
+		StopReason:        s.reason,
+		StoppedBy:         by,
+		Uptime:            uptime,
+		Started:           started,
+		StartedUptime:     startedUptime,
+		Reconnects:        s.restarts,
+		ExitCode:          s.exitCode,
+		ReconnectAttempts: s.reconnects,
+		ReconnectsLeft:    reconnectsLeft(s.config.Reconnect, s.config.MaxReconnects, s.reconnects),
+	}
+}
+
+func (s *simulator) IsRunning() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.state.IsRunning()
+}
+
+func (s *simulator) Start() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.order == "start" {
+		return nil
+	}
+	s.order = "start"
+	s.reconnects = 0
+	s.started.reset(time.Now())
+	if s.config.RuntimeLimit > 0 {
+		s.deadline = time.Now().Add(s.config.RuntimeLimit)
+	}
+	return s.start()
+}
+
+// start begins a simulated run. The caller must hold the lock.
+func (s *simulator) start() error {
+	if s.state.IsRunning() || s.state == statePaused {
+		return nil
+	}
+
+	s.unreconnect()
+	s.reason = ""
+	s.stopped = false
+	s.setState(stateStarting)
+
+	err := s.faults.takeFailStart()
+	if s.failStart {
+		err = fmt.Errorf("simulated start failure")
+	}
+	if err != nil {
+		s.parser.Parse(err.Error())
+		s.setState(stateFailed)
+		s.reconnect()
+		return err
+	}
+
+	s.parser.ResetStats()
+	s.parser.ResetLog()
+	s.setState(stateRunning)
+	s.paused = false
+	s.stop = make(chan stateType, 1)
+	s.done = make(chan struct{})
+
+	if s.config.OnStart != nil {
+		go s.config.OnStart()
+	}
+
+	go s.run(s.stop, s.done)
+
+	if s.config.RuntimeLimit > 0 {
+		s.limitTimer = time.AfterFunc(max(time.Until(s.deadline), 0), s.runtimeExceeded)
+	}
+
+	return nil
+}
+
+// runtimeExceeded stops the simulated process like runtime limit of a
+// real one
+func (s *simulator) runtimeExceeded() {
+	s.lock.Lock()
+	if s.order != "start" {
+		s.lock.Unlock()
+		return
+	}
+	s.order = "stop"
+	s.reason = StopReasonRuntimeLimit
+	s.lock.Unlock()
+
+	s.halt(stateFinished, false)
+}
+
+func (s *simulator) run(stop chan stateType, done chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var frame uint64
+	var elapsed time.Duration
+	var size uint64
+
+	result := stateFinished
+	stopped := false
+loop:
+	for {
+		select {
+		case result = <-stop:
+			stopped = true
+			break loop
+		case <-ticker.C:
+			s.lock.Lock()
+			paused := s.paused
+			s.lock.Unlock()
+			if paused || s.faults.stalled() {
+				continue
+			}
+
+			elapsed += time.Second
+			frame += uint64(s.config.FPS)
+			size += 256 * 1024
+			t := elapsed.Seconds()
+			s.parser.Parse(fmt.Sprintf("frame=%6d fps=%.1f q=28.0 size=%8dkB time=%02d:%02d:%02d.00 bitrate=2097.2kbits/s speed=1.0x",
+				frame, s.config.FPS, size/1024, int(t)/3600, int(t)/60%60, int(t)%60))
+
+			if s.failAfter > 0 && elapsed >= s.failAfter {
+				s.parser.Parse("simulated failure")
+				result = stateFailed
+				break loop
+			}
+			if s.finishAfter > 0 && elapsed >= s.finishAfter {
+				break loop
+			}
+		}
+	}
+
+	s.lock.Lock()
+	if elapsed > s.config.ReconnectDelay {
+		s.reconnects = 0
+	}
+	// 与 FFmpeg 一致：SIGINT 结束为 255，失败为 1，强制结束没有退出码
+	switch result {
+	case stateFinished:
+		s.exitCode = 0
+		if stopped {
+			s.exitCode = 255
+		}
+	case stateFailed:
+		s.exitCode = 1
+	default:
+		s.exitCode = -1
+	}
+	s.setState(result)
+	if s.limitTimer != nil {
+		s.limitTimer.Stop()
+		s.limitTimer = nil
+	}
+	s.parser.ResetStats()
+	if s.config.OnExit != nil {
+		go s.config.OnExit()
+	}
+	close(done)
+	if s.order == "start" {
+		s.reconnect()
+	}
+	s.lock.Unlock()
+}
+
+func (s *simulator) Stop(wait bool) error {
+	return s.StopWith(StopOptions{Wait: wait})
+}
+
+func (s *simulator) StopWith(opts StopOptions) error {
+	s.lock.Lock()
+	if s.order == "stop" {
+		s.lock.Unlock()
+		return nil
+	}
+	s.order = "stop"
+	if s.state.IsRunning() || s.state == statePaused {
+		s.reason = opts.Reason
+	}
+	s.lock.Unlock()
+
+	result := stateFinished
+	if opts.Kill {
+		result = stateKilled
+	}
+	return s.halt(result, opts.Wait)
+}
+
+// Signal ends the simulated run like the signal would end FFmpeg: SIGINT
+// and SIGTERM as finished, any other as killed
+func (s *simulator) Signal(sig syscall.Signal) error {
+	s.lock.Lock()
+	state := s.state
+	s.lock.Unlock()
+	if !state.IsRunning() && state != statePaused {
+		return fmt.Errorf("can't signal a process that is %s", state)
+	}
+
+	result := stateKilled
+	if sig == syscall.SIGINT || sig == syscall.SIGTERM {
+		result = stateFinished
+	}
+	return s.halt(result, false)
+}
+
+func (s *simulator) SetFaults(f Faults) {
+	s.faults.set(f)
+}
+
+func (s *simulator) FailNextStart(fail bool) {
+	s.faults.setFailStart(fail)
+}
+
+func (s *simulator) Kill(wait bool) error {
+	return s.halt(stateKilled, wait)
+}
+
+// halt ends the current run with the given final state
+func (s *simulator) halt(result stateType, wait bool) error {
+	s.lock.Lock()
+	s.unreconnect()
+	if !s.state.IsRunning() && s.state != statePaused {
+		s.lock.Unlock()
+		return nil
+	}
+	if s.state == stateFinishing {
+		s.lock.Unlock()
+		return nil
+	}
+	s.setState(stateFinishing)
+	s.stopped = true
+	done := s.done
+	s.stop <- result
+	s.lock.Unlock()
+
+	if wait {
+		<-done
+	}
+	return nil
+}
+
+func (s *simulator) Pause() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.state != stateRunning {
+		return fmt.Errorf("can't pause a process that is %s", s.state)
+	}
+	s.paused = true
+	s.setState(statePaused)
+	return nil
+}
+
+func (s *simulator) Resume() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.state != statePaused {
+		return fmt.Errorf("can't resume a process that is %s", s.state)
+	}
+	s.paused = false
+	s.setState(stateRunning)
+	return nil
+}
+
+func (s *simulator) Fail(err error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.state.IsRunning() || s.state == statePaused {
+		return fmt.Errorf("can't fail a process that is %s", s.state)
+	}
+	s.setState(stateStarting)
+	s.parser.Parse(err.Error())
+	s.setState(stateFailed)
+	return nil
+}
+
+// reconnect schedules the next run. The caller must hold the lock.
+func (s *simulator) reconnect() {
+	if !s.config.Reconnect {
+		return
+	}
+	s.unreconnect()
+
+	if s.config.RuntimeLimit > 0 && !time.Now().Before(s.deadline) {
+		s.order = "stop"
+		s.reason = StopReasonRuntimeLimit
+		return
+	}
+
+	if s.config.MaxReconnects > 0 && s.reconnects >= s.config.MaxReconnects {
+		s.logger.Error("giving up after %d reconnect attempts", s.reconnects)
+		s.order = "stop"
+		return
+	}
+	delay := backoffDelay(s.config.ReconnectDelay, s.config.ReconnectMaxDelay, s.config.ReconnectBackoff, s.reconnects) + s.faults.reconnectDelay()
+	s.reconnects++
+
+	s.timerAt = time.Now().Add(delay)
+	s.timerDelay = delay
+	s.timer = time.AfterFunc(delay, func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if s.order == "start" {
+			s.restarts++
+			s.start()
+		}
+	})
+}
+
+// unreconnect cancels a pending reconnect. The caller must hold the lock.
+func (s *simulator) unreconnect() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
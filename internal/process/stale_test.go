@@ -0,0 +1,71 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package process
+
+import (
+	"testing"
+	"time"
+)
+
+// repeatedProgress prints the same progress line until it is stopped
+const repeatedProgress = `
+while true; do
+	echo "frame=  100 fps= 25 q=28.0 size=    1024kB time=00:00:04.00 bitrate=2097.2kbits/s speed=1x" >&2
+	sleep 0.1
+done
+`
+
+// advancingProgress prints progress lines with a growing frame count
+const advancingProgress = `
+i=0
+while true; do
+	i=$((i+1))
+	echo "frame= $i fps= 25 q=28.0 size=    1024kB time=00:00:04.00 bitrate=2097.2kbits/s speed=1x" >&2
+	sleep 0.1
+done
+`
+
+func TestStale(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		staleOn string
+		reason  string // empty if the process must keep running
+	}{
+		{"repeated lines", repeatedProgress, "", StopReasonProgressStalled},
+		{"repeated lines, media_time", repeatedProgress, StaleOnMediaTime, StopReasonProgressStalled},
+		{"repeated lines, output", repeatedProgress, StaleOnOutput, ""},
+		{"advancing lines", advancingProgress, "", ""},
+		{"no output", "exec sleep 30", "", StopReasonStale},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProcess(t, Config{
+				Binary:       fakeFFmpeg(t, tt.script),
+				StaleTimeout: time.Second,
+				StaleOn:      tt.staleOn,
+				Parser:       &frameParser{},
+			})
+			if err := p.Start(); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.reason == "" {
+				time.Sleep(3 * time.Second)
+				if s := p.Status(); s.State != "running" {
+					t.Fatalf("state %s (%s), want running", s.State, s.StopReason)
+				}
+				return
+			}
+			waitStopped(t, p, 5*time.Second)
+			if s := p.Status(); s.StopReason != tt.reason {
+				t.Fatalf("stop reason %q, want %q", s.StopReason, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package process
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stateRecorder records the changes passed to Config.OnStateChange
+type stateRecorder struct {
+	changes []string
+	lock    sync.Mutex
+}
+
+func (r *stateRecorder) record(from, to string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.changes = append(r.changes, from+">"+to)
+}
+
+// wait polls until want were recorded, the callbacks are asynchronous
+func (r *stateRecorder) wait(t *testing.T, want []string) {
+	t.Helper()
+	var got []string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		r.lock.Lock()
+		got = append([]string{}, r.changes...)
+		r.lock.Unlock()
+		if len(got) >= len(want) {
+			break
+		}
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("state changes %v, want %v", got, want)
+	}
+}
+
+func TestStateMachine(t *testing.T) {
+	// 收到 SIGINT 后正常退出，相当于 FFmpeg 写完输出。ready 之后 trap 才生效。
+	const loop = "trap 'exit 0' INT\necho ready >&2\nwhile :; do sleep 0.05 & wait $!; done"
+
+	tests := []struct {
+		name   string
+		script string
+		act    func(t *testing.T, p *process)
+		state  string
+		want   []string
+	}{
+		{
+			"stop", loop,
+			func(t *testing.T, p *process) {
+				if err := p.Stop(true); err != nil {
+					t.Fatal(err)
+				}
+			},
+			"finished",
+			[]string{"finished>starting", "starting>running", "running>finishing", "finishing>finished"},
+		},
+		{
+			"exit 0", "exit 0", nil,
+			"finished",
+			[]string{"finished>starting", "starting>running", "running>finished"},
+		},
+		{
+			"exit 1", "exit 1", nil,
+			"failed",
+			[]string{"finished>starting", "starting>running", "running>failed"},
+		},
+		{
+			"exit 255", "exit 255", nil, // FFmpeg 被中断时的退出码
+			"finished",
+			[]string{"finished>starting", "starting>running", "running>finished"},
+		},
+		{
+			"signaled", "kill -9 $$", nil,
+			"killed",
+			[]string{"finished>starting", "starting>running", "running>killed"},
+		},
+		{
+			"kill", loop,
+			func(t *testing.T, p *process) {
+				if err := p.StopWith(StopOptions{Wait: true, Kill: true}); err != nil {
+					t.Fatal(err)
+				}
+			},
+			"killed",
+			[]string{"finished>starting", "starting>running", "running>finishing", "finishing>killed"},
+		},
+		{
+			"pause", loop,
+			func(t *testing.T, p *process) {
+				if err := p.Pause(); err != nil {
+					t.Fatal(err)
+				}
+				if p.IsRunning() {
+					t.Fatal("paused process counts as running")
+				}
+				if err := p.Resume(); err != nil {
+					t.Fatal(err)
+				}
+				if err := p.Stop(true); err != nil {
+					t.Fatal(err)
+				}
+			},
+			"finished",
+			[]string{"finished>starting", "starting>running", "running>paused", "paused>running", "running>finishing", "finishing>finished"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r stateRecorder
+			parser := &frameParser{}
+			p := newTestProcess(t, Config{Binary: fakeFFmpeg(t, tt.script), Parser: parser, OnStateChange: r.record})
+			if err := p.Start(); err != nil {
+				t.Fatal(err)
+			}
+			if tt.act != nil {
+				for deadline := time.Now().Add(5 * time.Second); len(parser.Log()) == 0; time.Sleep(10 * time.Millisecond) {
+					if time.Now().After(deadline) {
+						t.Fatal("timed out waiting for the script to be ready")
+					}
+				}
+				tt.act(t, p)
+			}
+			waitState(t, p, tt.state, 5*time.Second)
+			r.wait(t, tt.want)
+		})
+	}
+}
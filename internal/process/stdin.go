@@ -0,0 +1,134 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package process
+
+import (
+	"io"
+	"os/exec"
+	"sync"
+)
+
+// stdinChunk is the size of the reads from Config.Stdin
+const stdinChunk = 32 * 1024
+
+// stdinPump feeds Config.Stdin to the runs of a process. A single goroutine
+// reads it, so a run after a reconnect continues where the last one stopped
+// and no two runs read it at the same time.
+type stdinPump struct {
+	r       io.Reader
+	chunks  chan []byte   // closed at the end of r
+	done    chan struct{} // closed by close
+	reading sync.Once
+	writing sync.Mutex // held by the writer of a run, runs write in turn
+
+	lock    sync.Mutex
+	pending []byte         // read, but the run it was meant for had ended
+	w       io.WriteCloser // stdin of the current run
+	closed  bool
+}
+
+func newStdinPump(r io.Reader) *stdinPump {
+	return &stdinPump{r: r, chunks: make(chan []byte), done: make(chan struct{})}
+}
+
+// pipe connects the stdin of a run, before cmd.Start. Once the pump is
+// closed the run reads EOF.
+func (s *stdinPump) pipe(cmd *exec.Cmd) (io.WriteCloser, error) {
+	w, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		w.Close()
+	}
+	return w, nil
+}
+
+// feed writes to the stdin of a started run until exited is closed, the
+// input ends or the pump is closed
+func (s *stdinPump) feed(w io.WriteCloser, exited <-chan struct{}) {
+	s.lock.Lock()
+	s.w = w
+	s.lock.Unlock()
+	s.reading.Do(func() { go s.read() })
+	go s.write(w, exited)
+}
+
+func (s *stdinPump) read() {
+	defer close(s.chunks)
+	for {
+		buf := make([]byte, stdinChunk)
+		n, err := s.r.Read(buf)
+		if n > 0 {
+			select {
+			case s.chunks <- buf[:n]:
+			case <-s.done:
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *stdinPump) write(w io.WriteCloser, exited <-chan struct{}) {
+	s.writing.Lock()
+	defer s.writing.Unlock()
+	// 关闭后 FFmpeg 读到 EOF
+	defer w.Close()
+
+	s.lock.Lock()
+	chunk := s.pending
+	s.pending = nil
+	s.lock.Unlock()
+
+	for {
+		if chunk != nil {
+			if _, err := w.Write(chunk); err != nil {
+				// 本次运行已结束，留给下一次运行
+				s.lock.Lock()
+				if !s.closed {
+					s.pending = chunk
+				}
+				s.lock.Unlock()
+				return
+			}
+		}
+		var ok bool
+		select {
+		case chunk, ok = <-s.chunks:
+			if !ok {
+				return
+			}
+		case <-exited:
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// close ends the input: Config.Stdin is closed if it is an io.Closer and
+// the current run reads EOF, so FFmpeg blocked on stdin can exit
+func (s *stdinPump) close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.pending = nil
+	close(s.done)
+	if c, ok := s.r.(io.Closer); ok {
+		c.Close()
+	}
+	if s.w != nil {
+		s.w.Close()
+	}
+}
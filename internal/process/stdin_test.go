@@ -0,0 +1,81 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package process
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStdin(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	p := newTestProcess(t, Config{
+		Binary: fakeFFmpeg(t, "cat > "+out),
+		Stdin:  strings.NewReader("hello\nworld\n"),
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 输入结束后 cat 读到 EOF 正常退出
+	waitStopped(t, p, 5*time.Second)
+	if s := p.Status().State; s != "finished" {
+		t.Fatalf("state %s, want finished", s)
+	}
+	if data, _ := os.ReadFile(out); string(data) != "hello\nworld\n" {
+		t.Fatalf("stdin %q, want %q", data, "hello\nworld\n")
+	}
+}
+
+func TestStdinReconnect(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	r, w := io.Pipe()
+	// 每次运行只读一行，重连后的运行接着读下一行
+	p := newTestProcess(t, Config{
+		Binary:         fakeFFmpeg(t, "read line\necho \"$line\" >> "+out+"\nexit 1"),
+		Stdin:          r,
+		Reconnect:      true,
+		ReconnectDelay: 50 * time.Millisecond,
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := []string{"a", "b", "c"}
+	for i, line := range lines {
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			t.Fatal(err)
+		}
+		// 等这次运行退出，否则下一行会写进它的管道
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+			data, _ := os.ReadFile(out)
+			s := p.Status()
+			if strings.Count(string(data), "\n") == i+1 && (s.State == "failed" || s.Reconnects > uint64(i)) {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for run %d, read %q", i+1, data)
+			}
+		}
+	}
+	if data, _ := os.ReadFile(out); string(data) != "a\nb\nc\n" {
+		t.Fatalf("runs read %q, want %q", data, "a\nb\nc\n")
+	}
+
+	// 停止后输入被关闭
+	if err := p.Stop(true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, "d\n"); err != io.ErrClosedPipe {
+		t.Fatalf("write after stop: err = %v, want %v", err, io.ErrClosedPipe)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package process
+
+import "time"
+
+// startedUptime tracks the uptime since the order last changed to start.
+// It is updated with the state changes, so flapping between runs only adds
+// the time actually spent running or paused.
+type startedUptime struct {
+	at    time.Time     // when the order changed to start, zero if never
+	ended time.Duration // uptime of the states that ended since at
+}
+
+// reset starts counting at now, on a start order
+func (u *startedUptime) reset(now time.Time) {
+	u.at = now
+	u.ended = 0
+}
+
+// add counts a running or paused state entered at since that just ended
+func (u *startedUptime) add(since time.Time) {
+	if u.at.IsZero() {
+		return
+	}
+	if since.Before(u.at) {
+		since = u.at
+	}
+	u.ended += time.Since(since)
+}
+
+// get returns the time since the start order and the uptime since then.
+// since is when the current running or paused state was entered, zero if
+// the process is in another state.
+func (u *startedUptime) get(since time.Time) (started, uptime time.Duration) {
+	if u.at.IsZero() {
+		return 0, 0
+	}
+	uptime = u.ended
+	if !since.IsZero() {
+		if since.Before(u.at) {
+			since = u.at
+		}
+		uptime += time.Since(since)
+	}
+	return time.Since(u.at), uptime
+}
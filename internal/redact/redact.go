@@ -0,0 +1,117 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+//
+// Package redact masks secrets (stream keys, tokens, credentials) in addresses
+// and log lines before they leave the process.
+
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces every redacted secret
+const Mask = "***"
+
+// DefaultPatterns cover URL userinfo, common token query parameters, the
+// stream key path segment of RTMP addresses and the values of environment
+// variables named *_KEY, *_SECRET, *_TOKEN or *PASSWORD*. If a pattern has a
+// capture group, only the first group is masked, otherwise the whole match.
+var DefaultPatterns = []string{
+	`[a-zA-Z][a-zA-Z0-9+.-]*://([^/@\s]+)@`,
+	`(?i)[?&](?:token|key|secret|password|passwd|pass|passphrase|auth|sign|signature|access_token|streamkey|stream_key|txsecret)=([^&\s'"]+)`,
+	`(?i)rtmp[se]?://[^/\s]+/[^/\s]+/([^?\s'"]+)`,
+	`(?i)^(?:(?:\w*_)?(?:KEY|SECRET|TOKEN)|\w*PASSW(?:OR)?D\w*)=(.+)$`,
+}
+
+// Redactor masks secrets in text
+type Redactor interface {
+	Redact(text string) string
+	RedactAll(texts []string) []string
+}
+
+type nopRedactor struct{}
+
+// Nop returns a Redactor that leaves text untouched
+func Nop() Redactor {
+	return &nopRedactor{}
+}
+
+func (r *nopRedactor) Redact(text string) string         { return text }
+func (r *nopRedactor) RedactAll(texts []string) []string { return texts }
+
+type redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// New creates a Redactor from the given expressions. Empty expressions are
+// ignored; if none are given, DefaultPatterns are used.
+func New(patterns []string) (Redactor, error) {
+	r := &redactor{}
+
+	for _, exp := range patterns {
+		exp = strings.TrimSpace(exp)
+		if exp == "" {
+			continue
+		}
+		re, err := regexp.Compile(exp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact expression '%s': %w", exp, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	if len(r.patterns) == 0 {
+		for _, exp := range DefaultPatterns {
+			r.patterns = append(r.patterns, regexp.MustCompile(exp))
+		}
+	}
+
+	return r, nil
+}
+
+func (r *redactor) Redact(text string) string {
+	for _, re := range r.patterns {
+		text = mask(re, text)
+	}
+	return text
+}
+
+func (r *redactor) RedactAll(texts []string) []string {
+	if texts == nil {
+		return nil
+	}
+	out := make([]string, len(texts))
+	for i, t := range texts {
+		out[i] = r.Redact(t)
+	}
+	return out
+}
+
+func mask(re *regexp.Regexp, text string) string {
+	matches := re.FindAllStringSubmatchIndex(text, -1)
+	if matches == nil {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		if start < last {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(Mask)
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// addressKey returns the form in which two addresses that write the same
+// target are equal, "" for targets that may be written more than once:
+// stdout, pipes and the null device
+func addressKey(address string) string {
+	switch addressProtocol(address) {
+	case "pipe":
+		return ""
+	case "file":
+		p := strings.TrimPrefix(address, "file:")
+		if p == "/dev/null" || strings.EqualFold(p, "NUL") {
+			return ""
+		}
+		return filepath.Clean(p)
+	}
+	return address
+}
+
+// validateOutputAddresses rejects outputs that write the same target,
+// FFmpeg would write the file twice at the same time and corrupt it
+func validateOutputAddresses(config *Config) error {
+	seen := make(map[string]string, len(config.Output))
+	for _, out := range config.Output {
+		key := addressKey(out.Address)
+		if key == "" {
+			continue
+		}
+		if id, ok := seen[key]; ok {
+			return fmt.Errorf("%w: outputs %s and %s both write %s", ErrDuplicateOutput, id, out.ID, out.Address)
+		}
+		seen[key] = out.ID
+	}
+	return nil
+}
+
+// warnInPlace logs the warnings of lintInPlace for a task that is added or
+// updated
+func (s *store) warnInPlace(config *Config) {
+	expanded, _ := config.Expand()
+	for _, w := range lintInPlace(expanded) {
+		s.logger.Error("task %s: %s", config.ID, w)
+	}
+}
+
+// lintInPlace warns about outputs that write an input, FFmpeg truncates
+// the file while it still reads it
+func lintInPlace(config *Config) []string {
+	var warnings []string
+	for _, in := range config.Input {
+		key := addressKey(in.Address)
+		if key == "" {
+			continue
+		}
+		for _, out := range config.Output {
+			if addressKey(out.Address) == key {
+				warnings = append(warnings, fmt.Sprintf("output %s overwrites input %s", out.ID, in.ID))
+			}
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDuplicateOutput(t *testing.T) {
+	s := newTestStore(t, StoreConfig{})
+	tests := []struct {
+		name    string
+		outputs []string
+		want    error
+	}{
+		{"identical", []string{"/tmp/a-out.mp4", "/tmp/a-out.mp4"}, ErrDuplicateOutput},
+		{"file prefix", []string{"/tmp/a-out.mp4", "file:/tmp/./a-out.mp4"}, ErrDuplicateOutput},
+		// 占位符替换后相同
+		{"template", []string{"/tmp/{id}-out.mp4", "/tmp/a-out.mp4"}, ErrDuplicateOutput},
+		{"different", []string{"/tmp/a-out.mp4", "/tmp/a-copy.mp4"}, nil},
+		{"pipes", []string{"pipe:1", "pipe:1"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig("a")
+			config.Output = []ConfigIO{{ID: "one", Address: tt.outputs[0]}, {ID: "two", Address: tt.outputs[1]}}
+			if _, err := s.Add(config); !errors.Is(err, tt.want) {
+				t.Fatalf("Add = %v, want %v", err, tt.want)
+			}
+			s.Delete("a")
+		})
+	}
+
+	if _, err := s.Add(testConfig("b")); err != nil {
+		t.Fatal(err)
+	}
+	config := testConfig("b")
+	config.Output = append(config.Output, ConfigIO{ID: "copy", Address: config.Output[0].Address})
+	if _, _, err := s.Update("b", config); !errors.Is(err, ErrDuplicateOutput) {
+		t.Fatalf("Update = %v, want %v", err, ErrDuplicateOutput)
+	}
+}
+
+func TestLintInPlace(t *testing.T) {
+	config := testConfig("a")
+	config.Output = append(config.Output, ConfigIO{ID: "inplace", Address: "file:" + config.Input[0].Address})
+	warnings := lintInPlace(config)
+	if len(warnings) != 1 || warnings[0] != "output inplace overwrites input in" {
+		t.Fatalf("warnings %q, want one for output inplace", warnings)
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+	"github.com/ZSC714725/transcodemanager/internal/redact"
+)
+
+// Audit actions besides the process commands (start, stop, ...)
+const (
+	AuditAdd    = "add"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// auditDeletedTasks bounds the number of deleted tasks whose entries are
+// kept in memory, the oldest are dropped first
+const auditDeletedTasks = 1000
+
+// AuditConfig for an AuditLog
+type AuditConfig struct {
+	// File receives every entry as a line of JSON, it is appended to and
+	// read back on start. Empty keeps the entries in memory only.
+	File string
+	// MaxEntries is the number of entries kept in memory per task,
+	// default 100
+	MaxEntries int
+	// Redactor masks secrets in the recorded configs, optional
+	Redactor redact.Redactor
+}
+
+// Actor is who made a change
+type Actor struct {
+	Name   string // user name, token #n or anonymous
+	Remote string // client IP
+}
+
+// AuditEntry is a change made to a task
+type AuditEntry struct {
+	Task    string         `json:"task"`
+	Time    time.Time      `json:"time"`
+	Action  string         `json:"action"`
+	Actor   string         `json:"actor"`
+	Remote  string         `json:"remote,omitempty"`
+	Changes []ConfigChange `json:"changes,omitempty"`
+}
+
+// ConfigChange is a config field whose value changed, Before is null for
+// an added task and After is null for a deleted one
+type ConfigChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// AuditLog keeps who added, updated, deleted or commanded a task
+type AuditLog interface {
+	// Record adds an entry. before and after are the config before and
+	// after the change, nil if the task didn't exist before or after it.
+	Record(id, action string, actor Actor, before, after *Config)
+	// Get returns the entries of a task, oldest first
+	Get(id string) []AuditEntry
+	// Memory returns the approximate bytes of the entries kept in memory
+	Memory() uint64
+	Close() error
+}
+
+type auditLog struct {
+	config  AuditConfig
+	logger  logger.Logger
+	file    *os.File
+	entries map[string][]AuditEntry
+	deleted []string // deleted tasks, oldest first
+	bytes   uint64   // approximate size of entries
+	lock    sync.Mutex
+}
+
+// NewAuditLog creates an AuditLog and reads back the entries of its file
+func NewAuditLog(config AuditConfig, log logger.Logger) (AuditLog, error) {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = 100
+	}
+	if config.Redactor == nil {
+		config.Redactor = redact.Nop()
+	}
+	a := &auditLog{
+		config:  config,
+		logger:  log,
+		entries: make(map[string][]AuditEntry),
+	}
+	if config.File == "" {
+		return a, nil
+	}
+
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(config.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	a.file = f
+	return a, nil
+}
+
+// load reads the entries of the file
+func (a *auditLog) load() error {
+	f, err := os.Open(a.config.File)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	skipped := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var e AuditEntry
+		// 崩溃时最后一行可能不完整
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || e.Task == "" {
+			skipped++
+			continue
+		}
+		a.add(e)
+	}
+	if skipped != 0 {
+		a.logger.Error("audit: skipped %d invalid lines of %s", skipped, a.config.File)
+	}
+	return scanner.Err()
+}
+
+func (a *auditLog) Record(id, action string, actor Actor, before, after *Config) {
+	e := AuditEntry{
+		Task:    id,
+		Time:    time.Now(),
+		Action:  action,
+		Actor:   actor.Name,
+		Remote:  actor.Remote,
+		Changes: a.diff(before, after),
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.add(e)
+	if a.file == nil {
+		return
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		a.logger.Error("audit: %s", err)
+		return
+	}
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		a.logger.Error("audit: %s", err)
+	}
+}
+
+// auditEntryOverhead approximates the bytes of an AuditEntry besides its
+// strings and changes
+const auditEntryOverhead = 128
+
+// entrySize approximates the bytes of an entry in memory
+func entrySize(e AuditEntry) uint64 {
+	n := uint64(auditEntryOverhead + len(e.Task) + len(e.Action) + len(e.Actor) + len(e.Remote))
+	if len(e.Changes) != 0 {
+		data, _ := json.Marshal(e.Changes)
+		n += uint64(len(data))
+	}
+	return n
+}
+
+// entriesSize approximates the bytes of entries in memory
+func entriesSize(entries []AuditEntry) uint64 {
+	var n uint64
+	for _, e := range entries {
+		n += entrySize(e)
+	}
+	return n
+}
+
+// add keeps an entry in memory, the caller must hold the lock or be load
+func (a *auditLog) add(e AuditEntry) {
+	entries := append(a.entries[e.Task], e)
+	a.bytes += entrySize(e)
+	if len(entries) > a.config.MaxEntries {
+		drop := len(entries) - a.config.MaxEntries
+		a.bytes -= entriesSize(entries[:drop])
+		entries = append(entries[:0:0], entries[drop:]...)
+	}
+	a.entries[e.Task] = entries
+
+	// 已删除任务的记录保留到有更多任务被删除，同 ID 的任务再次添加后不再算已删除
+	if i := slices.Index(a.deleted, e.Task); i >= 0 {
+		a.deleted = slices.Delete(a.deleted, i, i+1)
+	}
+	if e.Action != AuditDelete {
+		return
+	}
+	a.deleted = append(a.deleted, e.Task)
+	if len(a.deleted) > auditDeletedTasks {
+		a.bytes -= entriesSize(a.entries[a.deleted[0]])
+		delete(a.entries, a.deleted[0])
+		a.deleted = a.deleted[1:]
+	}
+}
+
+func (a *auditLog) Get(id string) []AuditEntry {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return append([]AuditEntry{}, a.entries[id]...)
+}
+
+func (a *auditLog) Memory() uint64 {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.bytes
+}
+
+func (a *auditLog) Close() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+// diff returns the fields of the normalized configs that differ, with the
+// secrets masked. A nil config counts as the defaults, so an added task
+// lists the fields it sets and a deleted one those it had set.
+func (a *auditLog) diff(before, after *Config) []ConfigChange {
+	fields := func(c *Config) map[string]json.RawMessage {
+		if c == nil {
+			c = &Config{}
+		}
+		data, _ := json.Marshal(c.Normalized())
+		m := map[string]json.RawMessage{}
+		json.Unmarshal(data, &m)
+		return m
+	}
+	b, f := fields(before), fields(after)
+	names := map[string]bool{}
+	for name := range b {
+		names[name] = true
+	}
+	for name := range f {
+		names[name] = true
+	}
+
+	var changes []ConfigChange
+	for name := range names {
+		if bytes.Equal(b[name], f[name]) {
+			continue
+		}
+		change := ConfigChange{Field: name}
+		if before != nil {
+			change.Before = a.value(b[name])
+		}
+		if after != nil {
+			change.After = a.value(f[name])
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// value decodes a field and masks the secrets in its strings
+func (a *auditLog) value(data json.RawMessage) any {
+	var v any
+	if json.Unmarshal(data, &v) != nil {
+		return nil
+	}
+	var mask func(v any) any
+	mask = func(v any) any {
+		switch v := v.(type) {
+		case string:
+			return a.config.Redactor.Redact(v)
+		case []any:
+			for i := range v {
+				v[i] = mask(v[i])
+			}
+		case map[string]any:
+			for k := range v {
+				v[k] = mask(v[k])
+			}
+		}
+		return v
+	}
+	return mask(v)
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaxOutputBandwidth is enforced by the protocols themselves, FFmpeg has
+// no generic output rate limit. -re would pace to the media's own bitrate,
+// not to a given bandwidth.
+
+// pacingOptions maps a protocol to its URL option for a bandwidth in kbps
+var pacingOptions = map[string]func(kbps uint64) (string, string){
+	// maxbw 单位为字节/秒
+	"srt": func(kbps uint64) (string, string) { return "maxbw", strconv.FormatUint(kbps*1000/8, 10) },
+	// bitrate 单位为比特/秒
+	"udp": func(kbps uint64) (string, string) { return "bitrate", strconv.FormatUint(kbps*1000, 10) },
+}
+
+// paceAddress adds the rate limit for kbps to a network output address.
+// Local files are returned unchanged, as are addresses that set the option
+// themselves.
+func paceAddress(address string, kbps uint64) (string, error) {
+	if kbps == 0 {
+		return address, nil
+	}
+	if _, ok := localPath(address); ok {
+		return address, nil
+	}
+
+	scheme, _, _ := strings.Cut(address, "://")
+	option, ok := pacingOptions[strings.ToLower(scheme)]
+	if !ok {
+		return "", fmt.Errorf("%w, not for %s", ErrBandwidthUnsupported, address)
+	}
+	key, value := option(kbps)
+
+	// 不按 URL 解析重新编码，streamid 等参数中可能有 # 等字符
+	_, query, hasQuery := strings.Cut(address, "?")
+	for _, param := range strings.Split(query, "&") {
+		if strings.HasPrefix(param, key+"=") {
+			return address, nil
+		}
+	}
+	if hasQuery {
+		return address + "&" + key + "=" + value, nil
+	}
+	return address + "?" + key + "=" + value, nil
+}
+
+// paceOutputs applies MaxOutputBandwidth to the output addresses
+func (c *Config) paceOutputs() error {
+	for i, out := range c.Output {
+		address, err := paceAddress(out.Address, c.MaxOutputBandwidth)
+		if err != nil {
+			return fmt.Errorf("output %s: %w", out.ID, err)
+		}
+		c.Output[i].Address = address
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package task
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+)
+
+// writeScript writes an executable shell script
+func writeScript(t *testing.T, path, script string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBinaryDir(t *testing.T) {
+	tmp := t.TempDir()
+	binDir := filepath.Join(tmp, "bin")
+	if err := os.Mkdir(binDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	marker := filepath.Join(tmp, "ran")
+
+	// 默认构建只用于能力探测，按路径选择的构建记录自己被执行
+	writeScript(t, filepath.Join(tmp, "ffmpeg"), `echo "ffmpeg version 6.0"`)
+	writeScript(t, filepath.Join(binDir, "ffmpeg-custom"), `echo "$0 $*" > `+marker)
+	writeScript(t, filepath.Join(tmp, "outside"), `exit 0`)
+	if err := os.Symlink(filepath.Join(tmp, "outside"), filepath.Join(binDir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	ff, err := ffmpeg.New(ffmpeg.Config{Binary: filepath.Join(tmp, "ffmpeg"), BinaryDir: binDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(ff, logger.New("", logger.LevelError), StoreConfig{})
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+
+	config := func(id, binary string) *Config {
+		return &Config{
+			ID:     id,
+			Binary: binary,
+			Input:  []ConfigIO{{ID: "in", Address: "/tmp/" + id + ".mp4"}},
+			Output: []ConfigIO{{ID: "out", Address: "/tmp/" + id + "-out.mp4"}},
+		}
+	}
+
+	for _, binary := range []string{
+		filepath.Join(tmp, "outside"),          // 目录外
+		filepath.Join(binDir, "link"),          // 指向目录外的符号链接
+		filepath.Join(binDir, "missing"),       // 不存在
+		filepath.Join(binDir, "..", "outside"), // 相对路径跳出目录
+		"cuda",                                 // 未配置的名称
+	} {
+		if _, err := s.Add(config("rejected", binary)); !errors.Is(err, ErrUnknownBinary) {
+			t.Errorf("binary %s: err = %v, want %v", binary, err, ErrUnknownBinary)
+		}
+	}
+
+	custom := filepath.Join(binDir, "ffmpeg-custom")
+	for _, binary := range []string{custom, "ffmpeg-custom"} {
+		os.Remove(marker)
+		if _, err := s.Add(config("custom", binary)); err != nil {
+			t.Fatalf("binary %s: %v", binary, err)
+		}
+		if err := s.Start("custom"); err != nil {
+			t.Fatal(err)
+		}
+		var data []byte
+		for deadline := time.Now().Add(time.Second); !strings.HasSuffix(string(data), "\n"); data, _ = os.ReadFile(marker) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s to run", binary)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if argv0, _, _ := strings.Cut(string(data), " "); argv0 != custom {
+			t.Fatalf("binary %s ran %s, want %s", binary, argv0, custom)
+		}
+		if err := s.Delete("custom"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
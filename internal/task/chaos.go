@@ -0,0 +1,227 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+	"github.com/ZSC714725/transcodemanager/internal/process"
+
+	"github.com/lithammer/shortuuid/v4"
+)
+
+// Fault kinds that can be injected
+const (
+	FaultKill           = "kill"            // send a signal to FFmpeg
+	FaultStall          = "stall"           // stop reading FFmpeg's output
+	FaultFailStart      = "fail_start"      // the next start fails
+	FaultDelayReconnect = "delay_reconnect" // add a delay to reconnects
+)
+
+// Signals that a kill fault may send
+var Signals = map[string]syscall.Signal{
+	"SIGINT":  syscall.SIGINT,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGABRT": syscall.SIGABRT,
+	"SIGSEGV": syscall.SIGSEGV,
+}
+
+// Fault to inject into a task
+type Fault struct {
+	Kind   string
+	Signal string        // kill, default SIGKILL
+	Delay  time.Duration // delay_reconnect
+	TTL    time.Duration // default ChaosConfig.DefaultTTL
+}
+
+// Injection is an active fault
+type Injection struct {
+	ID        string
+	TaskID    string
+	Fault     Fault
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Chaos injects faults into tasks for resilience testing. Every injection
+// expires after its TTL, one-shot faults like kill are listed until then too.
+type Chaos interface {
+	Inject(taskID string, fault Fault) (Injection, error)
+	List() []Injection
+	Remove(id string) error
+}
+
+// ChaosConfig for fault injection
+type ChaosConfig struct {
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+}
+
+type chaos struct {
+	store      Store
+	logger     logger.Logger
+	config     ChaosConfig
+	injections map[string]*injection
+	lock       sync.Mutex
+}
+
+type injection struct {
+	Injection
+	timer *time.Timer
+}
+
+// NewChaos creates fault injection for the tasks of store
+func NewChaos(store Store, log logger.Logger, config ChaosConfig) Chaos {
+	if config.DefaultTTL <= 0 {
+		config.DefaultTTL = time.Minute
+	}
+	if config.MaxTTL <= 0 {
+		config.MaxTTL = 10 * time.Minute
+	}
+
+	return &chaos{
+		store:      store,
+		logger:     log,
+		config:     config,
+		injections: make(map[string]*injection),
+	}
+}
+
+func (c *chaos) Inject(taskID string, fault Fault) (Injection, error) {
+	t, err := c.store.Get(taskID)
+	if err != nil {
+		return Injection{}, err
+	}
+	inj, ok := t.proc.(process.Injector)
+	if !ok {
+		return Injection{}, ErrNoInjector
+	}
+
+	if fault.TTL <= 0 {
+		fault.TTL = c.config.DefaultTTL
+	}
+	if fault.TTL > c.config.MaxTTL {
+		fault.TTL = c.config.MaxTTL
+	}
+
+	switch fault.Kind {
+	case FaultKill:
+		if fault.Signal == "" {
+			fault.Signal = "SIGKILL"
+		}
+		sig, ok := Signals[fault.Signal]
+		if !ok {
+			return Injection{}, ErrInvalidFault
+		}
+		if err := inj.Signal(sig); err != nil {
+			return Injection{}, err
+		}
+	case FaultFailStart:
+		inj.FailNextStart(true)
+	case FaultStall:
+	case FaultDelayReconnect:
+		if fault.Delay <= 0 {
+			return Injection{}, ErrInvalidFault
+		}
+	default:
+		return Injection{}, ErrInvalidFault
+	}
+
+	now := time.Now()
+	i := &injection{Injection: Injection{
+		ID:        shortuuid.New(),
+		TaskID:    taskID,
+		Fault:     fault,
+		CreatedAt: now,
+		ExpiresAt: now.Add(fault.TTL),
+	}}
+
+	c.lock.Lock()
+	c.injections[i.ID] = i
+	i.timer = time.AfterFunc(fault.TTL, func() {
+		c.remove(i.ID, "expired")
+	})
+	c.apply(t)
+	c.lock.Unlock()
+
+	c.logger.Info("task %s chaos: injected %s (%s), expires in %s", taskID, fault.Kind, i.ID, fault.TTL)
+	return i.Injection, nil
+}
+
+func (c *chaos) List() []Injection {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	list := make([]Injection, 0, len(c.injections))
+	for _, i := range c.injections {
+		list = append(list, i.Injection)
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].CreatedAt.Before(list[b].CreatedAt) })
+	return list
+}
+
+func (c *chaos) Remove(id string) error {
+	if !c.remove(id, "removed") {
+		return ErrInjectionNotFound
+	}
+	return nil
+}
+
+func (c *chaos) remove(id, reason string) bool {
+	c.lock.Lock()
+	i, ok := c.injections[id]
+	if !ok {
+		c.lock.Unlock()
+		return false
+	}
+	i.timer.Stop()
+	delete(c.injections, id)
+
+	// 任务已删除时无需恢复
+	if t, err := c.store.Get(i.TaskID); err == nil {
+		c.apply(t)
+	}
+	c.lock.Unlock()
+
+	c.logger.Info("task %s chaos: %s %s (%s)", i.TaskID, reason, i.Fault.Kind, id)
+	return true
+}
+
+// apply sets the faults of the task's active injections on its process.
+// The caller must hold the lock.
+func (c *chaos) apply(t *Task) {
+	inj, ok := t.proc.(process.Injector)
+	if !ok {
+		return
+	}
+
+	var faults process.Faults
+	failStart := false
+	for _, i := range c.injections {
+		if i.TaskID != t.ID {
+			continue
+		}
+		switch i.Fault.Kind {
+		case FaultStall:
+			faults.StallOutput = true
+		case FaultDelayReconnect:
+			faults.ReconnectDelay = max(faults.ReconnectDelay, i.Fault.Delay)
+		case FaultFailStart:
+			failStart = true
+		}
+	}
+
+	inj.SetFaults(faults)
+	if !failStart {
+		inj.FailNextStart(false)
+	}
+}
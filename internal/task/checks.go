@@ -0,0 +1,257 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pre-start check modes of Config.PreChecks
+const (
+	PreChecksOff   = ""
+	PreChecksWarn  = "warn"  // failed checks are recorded, the task starts anyway
+	PreChecksAbort = "abort" // a failed check prevents the start
+)
+
+const (
+	checkTimeout = 2 * time.Second // a single check
+	checksTotal  = 3 * time.Second // all checks of a start
+	checkWorkers = 8
+)
+
+// Check is the result of a pre-start check
+type Check struct {
+	Name     string // input_file, output_dir, dial, resolve, http_head
+	Target   string // the address that was checked
+	OK       bool
+	Message  string
+	Duration time.Duration
+	Time     time.Time
+}
+
+type checkFunc func(ctx context.Context) error
+
+type pendingCheck struct {
+	name   string
+	target string
+	run    checkFunc
+}
+
+// defaultPorts for TCP dial checks by URL scheme
+var defaultPorts = map[string]string{
+	"rtmp":  "1935",
+	"rtmps": "443",
+	"rtsp":  "554",
+	"http":  "80",
+	"https": "443",
+}
+
+// preChecks returns the checks for the config's inputs and outputs
+func preChecks(config *Config) []pendingCheck {
+	var checks []pendingCheck
+
+	for _, in := range config.Input {
+		if c, ok := addressCheck(in.Address, true); ok {
+			checks = append(checks, c)
+		}
+	}
+	for _, out := range config.Output {
+		if c, ok := addressCheck(out.Address, false); ok {
+			checks = append(checks, c)
+		}
+	}
+
+	return checks
+}
+
+// addressCheck picks a non-destructive check for an address. Addresses
+// without a known scheme or local path are not checked.
+func addressCheck(address string, input bool) (pendingCheck, bool) {
+	// 本地路径不按 URL 解析，文件名中可能有 %d 等模板
+	if path, ok := localPath(address); ok {
+		if input {
+			if strings.Contains(path, "%") {
+				return pendingCheck{}, false
+			}
+			return pendingCheck{name: "input_file", target: path, run: func(ctx context.Context) error {
+				_, err := os.Stat(path)
+				return err
+			}}, true
+		}
+		dir := filepath.Dir(path)
+		return pendingCheck{name: "output_dir", target: dir, run: func(ctx context.Context) error {
+			return checkDir(dir)
+		}}, true
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return pendingCheck{}, false
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		if input {
+			return pendingCheck{name: "http_head", target: address, run: func(ctx context.Context) error {
+				return checkHead(ctx, address)
+			}}, true
+		}
+	case "srt":
+		// SRT 基于 UDP，无法通过拨号判断可达性，只检查域名解析
+		return pendingCheck{name: "resolve", target: address, run: func(ctx context.Context) error {
+			_, err := net.DefaultResolver.LookupHost(ctx, u.Hostname())
+			return err
+		}}, true
+	}
+
+	port, ok := defaultPorts[u.Scheme]
+	if !ok || u.Hostname() == "" {
+		return pendingCheck{}, false
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	host := net.JoinHostPort(u.Hostname(), port)
+	return pendingCheck{name: "dial", target: address, run: func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", host)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}}, true
+}
+
+// localPath returns the path of a plain or file: address. Other protocols
+// like pipe: are not local paths.
+func localPath(address string) (string, bool) {
+	if path, ok := strings.CutPrefix(address, "file:"); ok {
+		return strings.TrimPrefix(path, "//"), path != ""
+	}
+	if address == "" || address == "-" || (strings.Contains(address, ":") && !filepath.IsAbs(address)) {
+		return "", false
+	}
+	return address, true
+}
+
+// checkDir checks that a file can be created in dir
+func checkDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	f, err := os.CreateTemp(dir, ".transcodemanager-check-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// checkHead sends a HEAD request, a 4xx or 5xx status is a failure
+func checkHead(ctx context.Context, address string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, address, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("server returned %s", resp.Status)
+	}
+	return nil
+}
+
+// runChecks runs the checks in parallel, bounded in time and concurrency
+func runChecks(ctx context.Context, pending []pendingCheck) []Check {
+	ctx, cancel := context.WithTimeout(ctx, checksTotal)
+	defer cancel()
+
+	results := make([]Check, len(pending))
+	sem := make(chan struct{}, checkWorkers)
+	var wg sync.WaitGroup
+
+	for i, p := range pending {
+		wg.Add(1)
+		go func(i int, p pendingCheck) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			cctx, ccancel := context.WithTimeout(ctx, checkTimeout)
+			defer ccancel()
+
+			start := time.Now()
+			err := p.run(cctx)
+			results[i] = Check{
+				Name:     p.name,
+				Target:   p.target,
+				OK:       err == nil,
+				Duration: time.Since(start),
+				Time:     start,
+			}
+			if err != nil {
+				results[i].Message = err.Error()
+			}
+		}(i, p)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// failedChecks returns the names of the failed checks
+func failedChecks(checks []Check) []string {
+	var names []string
+	for _, c := range checks {
+		if !c.OK {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// preCheck runs the task's pre-start checks, if enabled. It fails only in
+// abort mode.
+func (s *store) preCheck(t *Task) error {
+	config, _ := t.Config.Expand()
+	if config.PreChecks == PreChecksOff || t.proc.IsRunning() {
+		return nil
+	}
+
+	checks := runChecks(context.Background(), preChecks(config))
+	t.checksLock.Lock()
+	t.checks = checks
+	t.checksLock.Unlock()
+
+	for _, c := range checks {
+		if !c.OK {
+			s.logger.Error("task %s pre-start check %s %s failed: %s", t.ID, c.Name, c.Target, c.Message)
+		}
+	}
+
+	failed := failedChecks(checks)
+	if len(failed) != 0 && config.PreChecks == PreChecksAbort {
+		return fmt.Errorf("%w: %s", ErrPreChecksFailed, strings.Join(failed, ", "))
+	}
+	return nil
+}
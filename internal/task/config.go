@@ -5,40 +5,241 @@
 
 package task
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+	"github.com/ZSC714725/transcodemanager/internal/process"
+)
+
 // ConfigIO is input/output config
 type ConfigIO struct {
 	ID      string   `json:"id"`
 	Address string   `json:"address"`
 	Options []string `json:"options"`
+	// Isolated outputs are written through tee, their failure doesn't end the task
+	Isolated bool `json:"isolated,omitempty"`
+	// SoftStop makes a soft stop wait for the end of the HLS segment
+	SoftStop *SoftStop `json:"soft_stop,omitempty"`
 }
 
 // Config for a transcoding task
 type Config struct {
-	ID             string     `json:"id"`
-	Reference      string     `json:"reference"`
-	Input          []ConfigIO `json:"input"`
-	Output         []ConfigIO `json:"output"`
-	Options        []string   `json:"options"`
-	Reconnect      bool       `json:"reconnect"`
-	ReconnectDelay uint64     `json:"reconnect_delay_seconds"`
-	Autostart      bool       `json:"autostart"`
-	StaleTimeout   uint64     `json:"stale_timeout_seconds"`
-	LimitCPU       float64    `json:"limit_cpu_usage"`
-	LimitMemory    uint64     `json:"limit_memory_bytes"`
-	LimitWaitFor   uint64     `json:"limit_waitfor_seconds"`
-}
-
-// CreateCommand builds FFmpeg args from config
+	ID                string     `json:"id"`
+	Reference         string     `json:"reference"`
+	Input             []ConfigIO `json:"input"`
+	Output            []ConfigIO `json:"output"`
+	Options           []string   `json:"options"`
+	Reconnect         bool       `json:"reconnect"`
+	ReconnectDelay    uint64     `json:"reconnect_delay_seconds"`
+	ReconnectBackoff  float64    `json:"reconnect_backoff"`
+	ReconnectMaxDelay uint64     `json:"reconnect_max_delay_seconds"`
+	MaxReconnects     int        `json:"max_reconnects"`
+	Autostart         bool       `json:"autostart"`
+	// Priority orders the start queue, higher first. It can be changed
+	// without a restart.
+	Priority     int    `json:"priority"`
+	StaleTimeout uint64 `json:"stale_timeout_seconds"`
+	StaleOn      string `json:"stale_on"`
+	RuntimeLimit uint64 `json:"runtime_limit_seconds"` // stop after this long, 0 is unlimited
+	// StartAt and StopAt start and stop the task at Unix times, 0 is unset.
+	// They can be changed without a restart.
+	StartAt int64 `json:"start_at"`
+	StopAt  int64 `json:"stop_at"`
+	// Schedule starts the task on each tick of a cron expression, in
+	// Timezone, unless it is still running. It can be changed without a
+	// restart.
+	Schedule   string `json:"schedule"`
+	StopSignal string `json:"stop_signal"` // "", int, term or quit
+	// Nice and IONiceClass set the CPU and I/O priority of FFmpeg, on Linux
+	// only. 0 and "" keep the server's.
+	Nice        int    `json:"nice"`
+	IONiceClass string `json:"ionice_class"` // "", realtime, best-effort or idle
+	// Binary names one of ffmpeg.binaries, "" is the default, or is the
+	// path of an executable in ffmpeg.binary_dir
+	Binary string `json:"binary"`
+	// MaxOutputBandwidth limits srt and udp outputs through their URL
+	// options, 0 is unlimited
+	MaxOutputBandwidth uint64 `json:"max_output_bandwidth_kbps"`
+	// Scte35Monitor counts the SCTE-35 packets read and written and warns
+	// if they stall or diverge
+	Scte35Monitor bool              `json:"scte35_monitor"`
+	Scte35Timeout uint64            `json:"scte35_timeout_seconds"` // default 300
+	PreChecks     string            `json:"prestart_checks"`        // "", warn or abort
+	Env           []string          `json:"environment"`            // KEY=VALUE
+	Timezone      string            `json:"timezone"`               // IANA zone, passed to FFmpeg as TZ
+	Variables     map[string]string `json:"variables"`              // values of {name} placeholders
+	Labels        map[string]string `json:"labels"`                 // free-form, e.g. origin=watcher
+	// Hooks are commands run on task events, after the global ones. They
+	// can be changed without a restart.
+	Hooks map[string][]string `json:"hooks"`
+	// DebugReport makes FFmpeg write a full report of every run, it takes
+	// effect on the next run
+	DebugReport bool `json:"debug_report"`
+	// ProgressPipe reads the progress from -progress pipe:3 instead of the
+	// stats lines of stderr, which then only feeds the log. Unix only.
+	ProgressPipe bool `json:"progress_pipe"`
+	// LogLines is the size of the in-memory log, 0 is the global default
+	LogLines     int     `json:"log_lines"`
+	LimitCPU     float64 `json:"limit_cpu_usage"`
+	LimitMemory  uint64  `json:"limit_memory_bytes"`
+	LimitWaitFor uint64  `json:"limit_waitfor_seconds"`
+
+	// SkipValidation skips checking the encoders against the FFmpeg skills,
+	// for option forms the check doesn't understand. It isn't persisted.
+	SkipValidation bool `json:"-"`
+	// Stdin is the standard input of FFmpeg for an input pipe:0 or -, for
+	// programmatic use. It isn't persisted and a stop closes it, see
+	// process.Config.Stdin.
+	Stdin io.Reader `json:"-"`
+}
+
+// stopSignals by stop_signal, empty is the default
+var stopSignals = map[string]syscall.Signal{
+	"":     syscall.SIGINT,
+	"int":  syscall.SIGINT,
+	"term": syscall.SIGTERM,
+	"quit": syscall.SIGQUIT,
+}
+
+// CreateCommand builds FFmpeg args from config, with the placeholders
+// substituted
 func (c *Config) CreateCommand() []string {
+	// 未知占位符与不支持限速的输出在校验时已拒绝
+	c, _ = c.Expand()
+	c.paceOutputs()
+	c.scte35Options()
+
 	var cmd []string
+	if c.ProgressPipe {
+		cmd = append(cmd, "-progress", "pipe:3", "-nostats")
+	}
 	cmd = append(cmd, c.Options...)
 	for _, in := range c.Input {
 		cmd = append(cmd, in.Options...)
 		cmd = append(cmd, "-i", in.Address)
 	}
 	for _, out := range c.Output {
+		if out.Isolated {
+			continue
+		}
 		cmd = append(cmd, out.Options...)
 		cmd = append(cmd, out.Address)
 	}
+	cmd = append(cmd, c.teeCommand()...)
 	return cmd
 }
+
+// Normalized returns a canonical copy of the config: defaults are filled
+// in, settings without effect are zeroed and empty lists are nil. The ID is
+// cleared, it names the config but isn't part of it. Options, inputs and
+// outputs keep their order, it is significant for FFmpeg.
+func (c *Config) Normalized() *Config {
+	n := *c
+	n.ID = ""
+	n.SkipValidation = false
+	n.Stdin = nil
+
+	n.Options = normalizeOptions(c.Options)
+	n.Input = normalizeIO(c.Input)
+	n.Output = normalizeIO(c.Output)
+	n.Env = normalizeOptions(c.Env)
+	if len(c.Variables) == 0 {
+		n.Variables = nil
+	}
+	if len(c.Labels) == 0 {
+		n.Labels = nil
+	}
+	n.Hooks = nil
+	for event, commands := range c.Hooks {
+		if len(commands) == 0 {
+			continue
+		}
+		if n.Hooks == nil {
+			n.Hooks = make(map[string][]string)
+		}
+		n.Hooks[event] = commands
+	}
+	if n.Binary == ffmpeg.DefaultBinary {
+		n.Binary = ""
+	}
+
+	// 0 与 1 都表示固定间隔，上限仅在退避时有效
+	if n.ReconnectBackoff <= 1 {
+		n.ReconnectBackoff = 0
+		n.ReconnectMaxDelay = 0
+	}
+	if n.MaxReconnects < 0 {
+		n.MaxReconnects = 0
+	}
+	if !n.Reconnect {
+		n.ReconnectDelay = 0
+		n.ReconnectBackoff = 0
+		n.ReconnectMaxDelay = 0
+		n.MaxReconnects = 0
+	}
+	if n.StaleOn == "" {
+		n.StaleOn = process.StaleOnMediaTime
+	}
+	if n.StaleTimeout == 0 {
+		n.StaleOn = ""
+	}
+	if n.StopSignal == "" {
+		n.StopSignal = "int"
+	}
+	if !n.Scte35Monitor {
+		n.Scte35Timeout = 0
+	} else if n.Scte35Timeout == 0 {
+		n.Scte35Timeout = defaultScte35Timeout
+	}
+	if n.LimitCPU <= 0 && n.LimitMemory == 0 {
+		n.LimitWaitFor = 0
+	}
+
+	return &n
+}
+
+func normalizeOptions(options []string) []string {
+	if len(options) == 0 {
+		return nil
+	}
+	return slices.Clone(options)
+}
+
+func normalizeIO(ios []ConfigIO) []ConfigIO {
+	if len(ios) == 0 {
+		return nil
+	}
+	n := make([]ConfigIO, len(ios))
+	for i, io := range ios {
+		n[i] = io
+		n[i].Options = normalizeOptions(io.Options)
+	}
+	return n
+}
+
+// validateEnv checks that the environment entries are KEY=VALUE
+func (c *Config) validateEnv() error {
+	for _, env := range c.Env {
+		if key, _, ok := strings.Cut(env, "="); !ok || key == "" {
+			return fmt.Errorf("%w: %q", ErrInvalidEnv, env)
+		}
+	}
+	return nil
+}
+
+// Hash returns a stable hash of the normalized config. Configs that only
+// differ in ways without effect have the same hash.
+func (c *Config) Hash() string {
+	// 结构体按字段顺序序列化，结果是确定的
+	data, _ := json.Marshal(c.Normalized())
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
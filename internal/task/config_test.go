@@ -0,0 +1,101 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func hashTestConfig() *Config {
+	return &Config{
+		ID:        "news",
+		Reference: "channel-1",
+		Input:     []ConfigIO{{ID: "in", Address: "rtmp://origin/live/news", Options: []string{"-re"}}},
+		Output:    []ConfigIO{{ID: "out", Address: "/archive/{id}-{seq}.mp4", Options: []string{"-c", "copy"}}},
+		Reconnect: true, ReconnectDelay: 5, ReconnectBackoff: 2, ReconnectMaxDelay: 60,
+		Variables: map[string]string{"a": "1", "b": "2", "c": "3"},
+		Labels:    map[string]string{"team": "live", "origin": "api"},
+		Hooks:     map[string][]string{"on_failed": {"notify.sh"}, "on_start": {"log.sh"}},
+		Env:       []string{"TZ=UTC"},
+	}
+}
+
+func TestHashRoundTrip(t *testing.T) {
+	config := hashTestConfig()
+	hash := config.Hash()
+
+	// 序列化再解析若干次，哈希不变
+	c := config
+	for i := 0; i < 3; i++ {
+		data, err := json.Marshal(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c = &Config{}
+		if err := json.Unmarshal(data, c); err != nil {
+			t.Fatal(err)
+		}
+		if h := c.Hash(); h != hash {
+			t.Fatalf("round trip %d: hash %s, want %s", i, h, hash)
+		}
+	}
+
+	// 字段顺序不同的 JSON 与 map 的插入顺序不影响哈希
+	reordered := `{
+		"hooks": {"on_start": ["log.sh"], "on_failed": ["notify.sh"]},
+		"labels": {"origin": "api", "team": "live"},
+		"variables": {"c": "3", "b": "2", "a": "1"},
+		"environment": ["TZ=UTC"],
+		"reconnect_max_delay_seconds": 60, "reconnect_backoff": 2, "reconnect_delay_seconds": 5, "reconnect": true,
+		"output": [{"options": ["-c", "copy"], "address": "/archive/{id}-{seq}.mp4", "id": "out"}],
+		"input": [{"options": ["-re"], "address": "rtmp://origin/live/news", "id": "in"}],
+		"reference": "channel-1",
+		"id": "other"
+	}`
+	c = &Config{}
+	if err := json.Unmarshal([]byte(reordered), c); err != nil {
+		t.Fatal(err)
+	}
+	if h := c.Hash(); h != hash {
+		t.Fatalf("reordered JSON: hash %s, want %s", h, hash)
+	}
+}
+
+func TestHashEquivalent(t *testing.T) {
+	hash := hashTestConfig().Hash()
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		same   bool
+	}{
+		{"empty lists", func(c *Config) { c.Options = []string{}; c.Hooks["on_stop"] = nil }, true},
+		{"default binary", func(c *Config) { c.Binary = "default" }, true},
+		{"default stop signal", func(c *Config) { c.StopSignal = "int" }, true},
+		{"stale_on without timeout", func(c *Config) { c.StaleOn = "output" }, true},
+		{"option", func(c *Config) { c.Output[0].Options = []string{"-c:v", "copy"} }, false},
+		{"option order", func(c *Config) { c.Output[0].Options = []string{"copy", "-c"} }, false},
+		{"label", func(c *Config) { c.Labels["team"] = "vod" }, false},
+		{"backoff", func(c *Config) { c.ReconnectBackoff = 1.5 }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := hashTestConfig()
+			tt.change(c)
+			if same := c.Hash() == hash; same != tt.same {
+				t.Fatalf("same hash %v, want %v", same, tt.same)
+			}
+		})
+	}
+
+	// 重连关闭时重连参数不起作用
+	a, b := hashTestConfig(), hashTestConfig()
+	a.Reconnect, b.Reconnect = false, false
+	b.ReconnectDelay, b.ReconnectBackoff = 30, 3
+	if a.Hash() != b.Hash() {
+		t.Fatal("reconnect settings change the hash while reconnect is off")
+	}
+}
@@ -0,0 +1,350 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg/parse"
+	"github.com/ZSC714725/transcodemanager/internal/process"
+)
+
+// Credential is a named secret, e.g. a stream key. Its value is write-only.
+type Credential struct {
+	Name      string
+	CreatedAt int64
+	UpdatedAt int64
+}
+
+// CredentialStore manages credentials persisted encrypted as a JSON file
+type CredentialStore interface {
+	Add(name, value string) (Credential, error)
+	Get(name string) (Credential, error)
+	List() []Credential
+	Update(name, value string) (Credential, error)
+	Delete(name string) error
+	// Value returns the secret, only for the FFmpeg command
+	Value(name string) (string, error)
+}
+
+// storedCredential is the file format, Value is sealed with AES-GCM and
+// prefixed with the nonce
+type storedCredential struct {
+	Name      string `json:"name"`
+	Value     []byte `json:"value"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+type credentialStore struct {
+	path  string
+	aead  cipher.AEAD // nil if no key is configured
+	creds map[string]*storedCredential
+	mu    sync.RWMutex
+}
+
+// NewCredentialStore loads credentials from path and decrypts them with a
+// key derived from key. A missing file is an empty store. Without a key
+// the store is disabled and stays empty.
+func NewCredentialStore(path, key string) (CredentialStore, error) {
+	s := &credentialStore{
+		path:  path,
+		creds: make(map[string]*storedCredential),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if key == "" {
+		if len(data) != 0 {
+			return nil, fmt.Errorf("%s exists, but no key is configured", path)
+		}
+		return s, nil
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	if s.aead, err = cipher.NewGCM(block); err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return s, nil
+	}
+
+	var creds []*storedCredential
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, err
+	}
+	for _, c := range creds {
+		// 提前解密一次，密钥错误时启动即失败
+		if _, err := s.open(c); err != nil {
+			return nil, fmt.Errorf("credential %s: %w", c.Name, err)
+		}
+		s.creds[c.Name] = c
+	}
+	return s, nil
+}
+
+func (s *credentialStore) seal(value string) []byte {
+	nonce := make([]byte, s.aead.NonceSize())
+	rand.Read(nonce)
+	return s.aead.Seal(nonce, nonce, []byte(value), nil)
+}
+
+func (s *credentialStore) open(c *storedCredential) (string, error) {
+	n := s.aead.NonceSize()
+	if len(c.Value) < n {
+		return "", fmt.Errorf("invalid ciphertext")
+	}
+	plain, err := s.aead.Open(nil, c.Value[:n], c.Value[n:], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt: wrong key or corrupted file")
+	}
+	return string(plain), nil
+}
+
+func (s *credentialStore) Add(name, value string) (Credential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.aead == nil {
+		return Credential{}, ErrCredentialsDisabled
+	}
+	if !variableRe.MatchString(name) || value == "" {
+		return Credential{}, ErrInvalidCredential
+	}
+	if _, exists := s.creds[name]; exists {
+		return Credential{}, ErrCredentialExists
+	}
+
+	now := time.Now().Unix()
+	c := &storedCredential{Name: name, Value: s.seal(value), CreatedAt: now, UpdatedAt: now}
+	s.creds[name] = c
+	if err := s.save(); err != nil {
+		delete(s.creds, name)
+		return Credential{}, err
+	}
+	return c.info(), nil
+}
+
+func (s *credentialStore) Get(name string) (Credential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.creds[name]
+	if !ok {
+		return Credential{}, ErrCredentialNotFound
+	}
+	return c.info(), nil
+}
+
+func (s *credentialStore) List() []Credential {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]Credential, 0, len(s.creds))
+	for _, c := range s.creds {
+		out = append(out, c.info())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (s *credentialStore) Update(name, value string) (Credential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.creds[name]
+	if !ok {
+		return Credential{}, ErrCredentialNotFound
+	}
+	if value == "" {
+		return Credential{}, ErrInvalidCredential
+	}
+
+	c := &storedCredential{Name: name, Value: s.seal(value), CreatedAt: old.CreatedAt, UpdatedAt: time.Now().Unix()}
+	s.creds[name] = c
+	if err := s.save(); err != nil {
+		s.creds[name] = old
+		return Credential{}, err
+	}
+	return c.info(), nil
+}
+
+func (s *credentialStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.creds[name]
+	if !ok {
+		return ErrCredentialNotFound
+	}
+
+	delete(s.creds, name)
+	if err := s.save(); err != nil {
+		s.creds[name] = old
+		return err
+	}
+	return nil
+}
+
+func (s *credentialStore) Value(name string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.creds[name]
+	if !ok {
+		return "", ErrCredentialNotFound
+	}
+	return s.open(c)
+}
+
+func (c *storedCredential) info() Credential {
+	return Credential{Name: c.Name, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt}
+}
+
+// save writes all credentials to disk. The caller must hold the lock.
+func (s *credentialStore) save() error {
+	creds := make([]*storedCredential, 0, len(s.creds))
+	for _, c := range s.creds {
+		creds = append(creds, c)
+	}
+	sort.Slice(creds, func(i, j int) bool { return creds[i].Name < creds[j].Name })
+
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// secretRe matches {secret:name}, the placeholders aren't substituted by
+// Expand, only in the argv handed to FFmpeg
+var secretRe = regexp.MustCompile(`\{secret:([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Credentials returns the names of the credentials the config references
+func (c *Config) Credentials() []string {
+	var names []string
+	add := func(s string) {
+		for _, m := range secretRe.FindAllStringSubmatch(s, -1) {
+			if !slices.Contains(names, m[1]) {
+				names = append(names, m[1])
+			}
+		}
+	}
+	for _, opt := range c.Options {
+		add(opt)
+	}
+	for _, ios := range [][]ConfigIO{c.Input, c.Output} {
+		for _, io := range ios {
+			add(io.Address)
+			for _, opt := range io.Options {
+				add(opt)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateCredentials checks that the referenced credentials exist
+func (s *store) validateCredentials(config *Config) error {
+	for _, name := range config.Credentials() {
+		if s.config.Credentials == nil {
+			return ErrCredentialsDisabled
+		}
+		if _, err := s.config.Credentials.Get(name); err != nil {
+			return fmt.Errorf("%w: {secret:%s}", ErrUnknownCredential, name)
+		}
+	}
+	return nil
+}
+
+// secrets substitutes the credentials in the args of a run and masks
+// their values in the lines FFmpeg writes
+type secrets struct {
+	store CredentialStore
+	// values of the current run by placeholder
+	values map[string]string
+	lock   sync.RWMutex
+}
+
+// resolve is process.Config.RunArgs
+func (r *secrets) resolve(args []string) ([]string, error) {
+	values := map[string]string{}
+	out := make([]string, len(args))
+	var err error
+	for i, arg := range args {
+		out[i] = secretRe.ReplaceAllStringFunc(arg, func(m string) string {
+			name := secretRe.FindStringSubmatch(m)[1]
+			if r.store == nil {
+				err = ErrCredentialsDisabled
+				return m
+			}
+			v, e := r.store.Value(name)
+			if e != nil {
+				err = fmt.Errorf("{secret:%s}: %w", name, e)
+				return m
+			}
+			values[m] = v
+			return v
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.lock.Lock()
+	r.values = values
+	r.lock.Unlock()
+	return out, nil
+}
+
+// mask replaces the secret values in line with their placeholders
+func (r *secrets) mask(line string) string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for placeholder, v := range r.values {
+		line = strings.ReplaceAll(line, v, placeholder)
+	}
+	return line
+}
+
+// maskingParser masks secrets before a line is parsed and logged
+type maskingParser struct {
+	parse.Parser
+	secrets *secrets
+}
+
+func (p maskingParser) Parse(line string) process.ParseResult {
+	return p.Parser.Parse(p.secrets.mask(line))
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package task
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+)
+
+func TestCronSchedule(t *testing.T) {
+	tmp := t.TempDir()
+	binary := filepath.Join(tmp, "ffmpeg")
+	// 每次运行在输出地址（事件文件）追加一行，-re 的任务一直运行
+	writeScript(t, binary, `case " $* " in *" -i "*)
+	for a; do out=$a; done
+	echo start >> $out
+	case " $* " in *" -re "*) while :; do sleep 0.05 & wait $!; done;; esac
+	exit 0;;
+esac
+echo "ffmpeg version 6.0"`)
+	ff, err := ffmpeg.New(ffmpeg.Config{Binary: binary})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(ff, logger.New("", logger.LevelError), StoreConfig{})
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+
+	add := func(id string, options ...string) string {
+		config := testConfig(id)
+		config.Input[0].Options = options
+		config.Output[0].Address = filepath.Join(tmp, id)
+		config.Schedule = "@every 1s"
+		if _, err := s.Add(config); err != nil {
+			t.Fatal(err)
+		}
+		return config.Output[0].Address
+	}
+	short := add("short")
+	long := add("long", "-re")
+
+	time.Sleep(3500 * time.Millisecond)
+	// 每次触发都启动已结束的任务
+	if n := len(readEvents(short)); n < 3 {
+		t.Fatalf("short task started %d times in 3.5s, want at least 3", n)
+	}
+	// 上一次运行仍在进行时跳过触发
+	if n := len(readEvents(long)); n != 1 {
+		t.Fatalf("long task started %d times, want 1", n)
+	}
+}
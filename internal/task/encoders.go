@@ -0,0 +1,141 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg/skills"
+)
+
+// maxAlternatives bounds the encoders listed in an unknown encoder error
+const maxAlternatives = 10
+
+// encoderOption returns the stream type an option selects the encoder for:
+// v, a, s or "" for any. ok is false if the option doesn't select an encoder.
+func encoderOption(opt string) (kind string, ok bool) {
+	switch opt {
+	case "-vcodec":
+		return "v", true
+	case "-acodec":
+		return "a", true
+	case "-scodec":
+		return "s", true
+	}
+
+	name, spec, _ := strings.Cut(opt, ":")
+	if name != "-c" && name != "-codec" {
+		return "", false
+	}
+	// -c:v:0 等，只看流类型
+	spec, _, _ = strings.Cut(spec, ":")
+	switch spec {
+	case "v", "V":
+		return "v", true
+	case "a":
+		return "a", true
+	case "s":
+		return "s", true
+	case "d", "t":
+		return "", false
+	}
+	return "", true
+}
+
+// encoders indexes the encoders known to FFmpeg by stream type
+type encoders struct {
+	byKind map[string][]string // v, a, s
+	codecs map[string][]string // codec ID -> encoders
+	known  map[string]bool
+}
+
+func newEncoders(sk skills.Skills) *encoders {
+	e := &encoders{
+		byKind: map[string][]string{},
+		codecs: map[string][]string{},
+		known:  map[string]bool{},
+	}
+	add := func(kind string, codecs []skills.Codec) {
+		for _, c := range codecs {
+			if len(c.Encoders) == 0 {
+				continue
+			}
+			e.codecs[c.Id] = c.Encoders
+			// 编码器名或编解码器名（由 FFmpeg 选择默认编码器）都可用
+			e.known[c.Id] = true
+			for _, enc := range c.Encoders {
+				e.known[enc] = true
+				e.byKind[kind] = append(e.byKind[kind], enc)
+			}
+		}
+	}
+	add("v", sk.Codecs.Video)
+	add("a", sk.Codecs.Audio)
+	add("s", sk.Codecs.Subtitle)
+	return e
+}
+
+// alternatives suggests encoders for an unknown one: those of the codec its
+// name starts with (hevc_nvenc -> hevc), otherwise those of the stream type
+func (e *encoders) alternatives(name, kind string) []string {
+	var alt []string
+	if codec, _, ok := strings.Cut(name, "_"); ok {
+		alt = e.codecs[codec]
+	}
+	if len(alt) == 0 {
+		if kind == "" {
+			for _, k := range []string{"v", "a", "s"} {
+				alt = append(alt, e.byKind[k]...)
+			}
+		} else {
+			alt = e.byKind[kind]
+		}
+	}
+	alt = append([]string(nil), alt...)
+	sort.Strings(alt)
+	if len(alt) > maxAlternatives {
+		alt = alt[:maxAlternatives]
+	}
+	return alt
+}
+
+// validateEncoders checks the encoders selected in the output options
+// against the skills. Input options select decoders and are not checked.
+func validateEncoders(sk skills.Skills, config *Config) error {
+	if errs := unknownEncoders(sk, config); len(errs) != 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// unknownEncoders returns an error for each unknown encoder in the output
+// options
+func unknownEncoders(sk skills.Skills, config *Config) []error {
+	e := newEncoders(sk)
+	if len(e.known) == 0 {
+		// 能力探测失败时无从校验
+		return nil
+	}
+
+	var errs []error
+	for _, out := range config.Output {
+		for i := 0; i+1 < len(out.Options); i++ {
+			kind, ok := encoderOption(out.Options[i])
+			if !ok {
+				continue
+			}
+			i++
+			name := out.Options[i]
+			if name == "copy" || e.known[name] {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("%w: %s in output %s, available: %s", ErrUnknownEncoder, name, out.ID, strings.Join(e.alternatives(name, kind), ", ")))
+		}
+	}
+	return errs
+}
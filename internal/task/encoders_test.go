@@ -0,0 +1,61 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateEncoders(t *testing.T) {
+	strict := newTestStore(t, StoreConfig{ValidateEncoders: true})
+	lenient := newTestStore(t, StoreConfig{})
+	tests := []struct {
+		name    string
+		options []string
+		want    error
+	}{
+		{"bogus video", []string{"-c:v", "h265"}, ErrUnknownEncoder},
+		{"bogus audio", []string{"-c:v", "libx264", "-codec:a", "nosuchaac"}, ErrUnknownEncoder},
+		{"known", []string{"-c:v", "libx264", "-c:a", "aac"}, nil},
+		{"copy", []string{"-c", "copy"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig("a")
+			config.Output[0].Options = tt.options
+			_, err := strict.Add(config)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Add = %v, want %v", err, tt.want)
+			}
+			strict.Delete("a")
+
+			// 关闭校验时只记录日志，自行编译的 FFmpeg 可能有列表外的编码器
+			if _, err := lenient.Add(config); err != nil {
+				t.Fatalf("Add without validate_encoders = %v", err)
+			}
+			lenient.Delete("a")
+		})
+	}
+
+	config := testConfig("a")
+	config.Output[0].Options = []string{"-c:v", "h265"}
+	_, err := strict.Add(config)
+	// 错误说明是哪个输出的哪个编码器，并给出可用的编码器
+	if msg := err.Error(); !strings.Contains(msg, "h265 in output out") || !strings.Contains(msg, "libx265") {
+		t.Fatalf("error %q, want the encoder, output and alternatives", msg)
+	}
+
+	if _, err := strict.Add(testConfig("b")); err != nil {
+		t.Fatal(err)
+	}
+	config = testConfig("b")
+	config.Output[0].Options = []string{"-c:v", "h265"}
+	if _, _, err := strict.Update("b", config); !errors.Is(err, ErrUnknownEncoder) {
+		t.Fatalf("Update = %v, want %v", err, ErrUnknownEncoder)
+	}
+}
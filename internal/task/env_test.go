@@ -0,0 +1,53 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package task
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+)
+
+func TestEnv(t *testing.T) {
+	t.Setenv("TM_INHERITED", "leaked")
+	tmp := t.TempDir()
+	out := filepath.Join(tmp, "env")
+	binary := filepath.Join(tmp, "ffmpeg")
+	// 运行时输出收到的变量，能力探测只看到版本
+	writeScript(t, binary, `case " $* " in *" -i "*) echo "$TM_CUSTOM|$TM_INHERITED" > `+out+`.tmp; mv `+out+`.tmp `+out+`; exit 0;; esac
+echo "ffmpeg version 6.0"`)
+	ff, err := ffmpeg.New(ffmpeg.Config{Binary: binary})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(ff, logger.New("", logger.LevelError), StoreConfig{})
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+
+	config := testConfig("a")
+	config.Env = []string{"TM_CUSTOM=hello world"}
+	if _, err := s.Add(config); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	var data []byte
+	waitFor(t, "the child to echo its environment", func() bool {
+		data, _ = os.ReadFile(out)
+		return len(data) != 0
+	})
+	// 服务自己的环境变量不会传给 FFmpeg
+	if got, want := string(data), "hello world|\n"; got != want {
+		t.Fatalf("child saw %q, want %q", got, want)
+	}
+}
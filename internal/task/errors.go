@@ -8,9 +8,57 @@ package task
 import "errors"
 
 var (
-	ErrNotFound            = errors.New("task not found")
-	ErrTaskExists           = errors.New("task already exists")
-	ErrInvalidConfig        = errors.New("invalid config: need at least one input and one output")
-	ErrInvalidInputAddress  = errors.New("invalid input address")
-	ErrInvalidOutputAddress = errors.New("invalid output address")
+	ErrNotFound                = errors.New("task not found")
+	ErrTaskExists              = errors.New("task already exists")
+	ErrInvalidConfig           = errors.New("invalid config: need at least one input and one output")
+	ErrInvalidInputAddress     = errors.New("invalid input address")
+	ErrInvalidOutputAddress    = errors.New("invalid output address")
+	ErrInvalidStaleOn          = errors.New("invalid stale_on: must be output or media_time")
+	ErrInvalidIsolated         = errors.New("invalid isolated output")
+	ErrInvalidPreChecks        = errors.New("invalid prestart_checks: must be warn or abort")
+	ErrInvalidEnv              = errors.New("invalid environment: entries must be KEY=VALUE")
+	ErrInvalidTimezone         = errors.New("invalid timezone: must be an IANA zone name")
+	ErrInvalidStopAt           = errors.New("invalid stop_at: must be after start_at")
+	ErrInvalidSchedule         = errors.New("invalid schedule: must be a cron expression like 0 3 * * * or @every 1h")
+	ErrUnknownPlaceholder      = errors.New("unknown placeholder")
+	ErrInvalidVariable         = errors.New("invalid variable name")
+	ErrReportDisabled          = errors.New("debug_report requires task_log.dir")
+	ErrInvalidSoftStop         = errors.New("invalid soft_stop: only for HLS outputs")
+	ErrInvalidStopMode         = errors.New("invalid stop mode: must be normal, soft or kill")
+	ErrInvalidStopSignal       = errors.New("invalid stop_signal: must be int, term or quit")
+	ErrInvalidNice             = errors.New("invalid nice: must be between -20 and 19")
+	ErrInvalidIOClass          = errors.New("invalid ionice_class: must be realtime, best-effort or idle")
+	ErrInvalidLogLines         = errors.New("invalid log_lines: must be between 0 and 100000")
+	ErrInvalidHook             = errors.New("invalid hooks")
+	ErrUnknownBinary           = errors.New("unknown binary: must be a name in ffmpeg.binaries or an executable in ffmpeg.binary_dir")
+	ErrBandwidthUnsupported    = errors.New("max_output_bandwidth_kbps is only supported for srt and udp outputs")
+	ErrInvalidScte35           = errors.New("invalid scte35_monitor")
+	ErrProgressPipeUnsupported = errors.New("progress_pipe is not supported on windows")
+	ErrShuttingDown            = errors.New("task manager is shutting down")
+	ErrPreChecksFailed         = errors.New("pre-start checks failed")
+	ErrUnsupportedProtocol     = errors.New("unsupported protocol")
+	ErrUnknownEncoder          = errors.New("unknown encoder")
+	ErrIncompatibleOutput      = errors.New("incompatible output")
+	ErrDuplicateOutput         = errors.New("duplicate output address")
+	ErrTestRunning             = errors.New("a test of the task is already running")
+	ErrUncleanStop             = errors.New("not restarted, the task didn't stop cleanly")
+	ErrInvalidFault            = errors.New("invalid fault: kind must be kill, stall, fail_start or delay_reconnect")
+	ErrNoInjector              = errors.New("process doesn't support fault injection")
+	ErrInjectionNotFound       = errors.New("injection not found")
+	ErrInvalidState            = errors.New("invalid state: must be queued, starting, running, finishing, paused, finished, failed or killed")
+	ErrInvalidSort             = errors.New("invalid sort: must be id, created_at, updated_at, state, cpu or memory")
+	ErrOperationNotFound       = errors.New("operation not found")
+	ErrCredentialNotFound      = errors.New("credential not found")
+	ErrCredentialExists        = errors.New("credential already exists")
+	ErrCredentialInUse         = errors.New("credential is referenced by tasks")
+	ErrInvalidCredential       = errors.New("invalid credential: name must be a letter or _ followed by letters, digits or _, value required")
+	ErrUnknownCredential       = errors.New("unknown credential")
+	ErrCredentialsDisabled     = errors.New("credentials are disabled: set credentials.key")
+	ErrPresetNotFound          = errors.New("preset not found")
+	ErrPresetExists            = errors.New("preset already exists")
+	ErrInvalidPreset           = errors.New("invalid preset: name required")
+	ErrInvalidMetadataKey      = errors.New("invalid metadata key: must be 1 to 128 bytes")
+	ErrInvalidMetadata         = errors.New("invalid metadata: value must be JSON")
+	ErrMetadataTooLarge        = errors.New("metadata value exceeds 64 KiB")
+	ErrTooManyMetadataKeys     = errors.New("too many metadata keys: at most 64 per task")
 )
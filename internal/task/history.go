@@ -0,0 +1,121 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg/parse"
+	"github.com/ZSC714725/transcodemanager/internal/process"
+)
+
+// defaultHistoryRuns is the number of completed runs kept per task if
+// StoreConfig.HistoryRuns isn't set
+const defaultHistoryRuns = 5
+
+// Run is a completed run of a task with the log it left. The log is a copy
+// of the log buffer when the run ended, so it holds at most as many lines.
+type Run struct {
+	Number     uint64 // counts the runs of the task from 1
+	Start      time.Time
+	End        time.Time
+	State      string // finished, failed or killed
+	ExitCode   int
+	StopReason string
+	StoppedBy  string
+	Error      *parse.Error
+	Prelude    []string
+	Log        []process.Line
+}
+
+// History returns the completed runs kept for the task, newest first
+func (t *Task) History() []Run {
+	t.historyLock.Lock()
+	defer t.historyLock.Unlock()
+	out := make([]Run, len(t.history))
+	for i, r := range t.history {
+		out[len(t.history)-1-i] = r
+	}
+	return out
+}
+
+// runOverhead approximates the bytes of a Run besides its lines
+const runOverhead = 256
+
+// historyMemory approximates the bytes held by the kept runs
+func (t *Task) historyMemory() uint64 {
+	t.historyLock.Lock()
+	defer t.historyLock.Unlock()
+	var n uint64
+	for _, r := range t.history {
+		n += runOverhead
+		for _, line := range r.Prelude {
+			n += uint64(len(line)) + parse.LineOverhead
+		}
+		for _, line := range r.Log {
+			n += uint64(len(line.Data)) + parse.LineOverhead
+		}
+		if r.Error != nil {
+			n += uint64(len(r.Error.Message))
+		}
+	}
+	return n
+}
+
+// trimHistory drops the older half of the kept runs, the newest is always
+// kept. It returns the number of runs dropped.
+func (t *Task) trimHistory() int {
+	t.historyLock.Lock()
+	defer t.historyLock.Unlock()
+	drop := len(t.history) / 2
+	if drop > 0 {
+		t.history = append(t.history[:0:0], t.history[drop:]...)
+	}
+	return drop
+}
+
+// recordRun tracks the start of a run and keeps its log when it ends.
+// Lines are stored masked, parser and status are those of the run's
+// process.
+func (s *store) recordRun(t *Task, parser parse.Parser, status process.Status, to string) {
+	keep := s.config.HistoryRuns
+	if keep < 0 {
+		return
+	}
+	if keep == 0 {
+		keep = defaultHistoryRuns
+	}
+
+	t.historyLock.Lock()
+	defer t.historyLock.Unlock()
+
+	switch to {
+	case "starting":
+		t.runStart = time.Now()
+		return
+	case "finished", "failed", "killed":
+	default:
+		return
+	}
+
+	t.runs++
+	r := Run{
+		Number:     t.runs,
+		Start:      t.runStart,
+		End:        time.Now(),
+		State:      to,
+		ExitCode:   status.ExitCode,
+		StopReason: status.StopReason,
+		StoppedBy:  status.StoppedBy,
+		Error:      parser.LastError(),
+		Prelude:    parser.Prelude(),
+		Log:        parser.Log(),
+	}
+	t.history = append(t.history, r)
+	if len(t.history) > keep {
+		t.history = append(t.history[:0:0], t.history[len(t.history)-keep:]...)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"fmt"
+
+	"github.com/ZSC714725/transcodemanager/internal/hook"
+	"github.com/ZSC714725/transcodemanager/internal/process"
+)
+
+// validateHooks checks the events and commands of the task's hooks
+func (s *store) validateHooks(config *Config) error {
+	if len(config.Hooks) == 0 {
+		return nil
+	}
+	if s.config.Hooks == nil {
+		return fmt.Errorf("%w: %w", ErrInvalidHook, hook.ErrDisabled)
+	}
+	if err := s.config.Hooks.Validate(config.Hooks); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidHook, err)
+	}
+	return nil
+}
+
+// runHooks runs the hooks of a state change in the background, their output
+// goes to the task log
+func (s *store) runHooks(t *Task, parser process.Parser, state string) {
+	if s.config.Hooks == nil {
+		return
+	}
+
+	var event string
+	switch state {
+	case "running":
+		event = hook.EventStart
+	case "failed", "finished", "killed":
+		// 无进度被停止时通常以 finished 结束，只报告 on_stalled
+		switch t.Status().StopReason {
+		case process.StopReasonStale, process.StopReasonProgressStalled:
+			event = hook.EventStalled
+		default:
+			if state == "failed" {
+				event = hook.EventFailed
+			} else if state == "finished" {
+				event = hook.EventFinished
+			}
+		}
+	}
+	if event == "" {
+		return
+	}
+
+	var hooks []string
+	if h := t.hooks.Load(); h != nil {
+		hooks = (*h)[event]
+	}
+	e := hook.Event{
+		Name:      event,
+		ID:        t.ID,
+		Reference: t.Reference,
+		State:     state,
+	}
+	if err := t.LastError(); err != nil && event != hook.EventStart {
+		e.Error = err.Message
+	}
+	s.config.Hooks.Run(e, hooks, func(line string) { parser.Parse(line) })
+}
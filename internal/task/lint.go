@@ -0,0 +1,155 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg/skills"
+)
+
+// Validation is the result of Store.Validate
+type Validation struct {
+	Command  []string
+	Warnings []string
+}
+
+// Validate checks a config like Add does, without adding a task. Unknown
+// encoders, incompatible muxers and unsupported protocols are warnings
+// instead of errors.
+func (s *store) Validate(config *Config) (Validation, error) {
+	if len(config.Input) == 0 || len(config.Output) == 0 {
+		return Validation{}, ErrInvalidConfig
+	}
+
+	c := *config
+	c.SkipValidation = true
+	if err := s.validate(&c); err != nil {
+		return Validation{}, err
+	}
+
+	expanded, _ := c.Expand()
+	sk, _ := s.ffmpeg.BinarySkills(c.Binary)
+	v := Validation{Command: c.CreateCommand()}
+	for _, err := range unknownEncoders(sk, expanded) {
+		v.Warnings = append(v.Warnings, err.Error())
+	}
+	v.Warnings = append(v.Warnings, lintFormats(sk, expanded)...)
+	for _, err := range incompatibleOutputs(sk, expanded) {
+		v.Warnings = append(v.Warnings, err.Error())
+	}
+	v.Warnings = append(v.Warnings, lintProtocols(sk, expanded)...)
+	v.Warnings = append(v.Warnings, lintInPlace(expanded)...)
+	return v, nil
+}
+
+func (s *store) DryRun(config *Config) (Validation, error) {
+	if len(config.Input) == 0 || len(config.Output) == 0 {
+		return Validation{}, ErrInvalidConfig
+	}
+	if err := s.validate(config); err != nil {
+		return Validation{}, err
+	}
+	if config.ID != "" {
+		s.mu.RLock()
+		_, exists := s.tasks[config.ID]
+		s.mu.RUnlock()
+		if exists {
+			return Validation{}, ErrTaskExists
+		}
+	}
+	return s.Validate(config)
+}
+
+// formatIDs returns the IDs of formats, "mov,mp4,m4a" lists several
+func formatIDs(formats []skills.Format) map[string]bool {
+	ids := make(map[string]bool)
+	for _, f := range formats {
+		for _, id := range strings.Split(f.Id, ",") {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// lintFormats warns about -f selecting an unknown demuxer, muxers are
+// checked by incompatibleOutputs
+func lintFormats(sk skills.Skills, config *Config) []string {
+	var warnings []string
+	check := func(known map[string]bool, ios []ConfigIO, kind, what string) {
+		if len(known) == 0 {
+			return
+		}
+		for _, io := range ios {
+			for i := 0; i+1 < len(io.Options); i++ {
+				if io.Options[i] == "-f" && !known[io.Options[i+1]] {
+					warnings = append(warnings, fmt.Sprintf("unknown %s %s in %s %s", what, io.Options[i+1], kind, io.ID))
+				}
+			}
+		}
+	}
+	check(formatIDs(sk.Formats.Demuxers), config.Input, "input", "demuxer")
+	return warnings
+}
+
+// lintProtocols warns about addresses with an unsupported protocol
+func lintProtocols(sk skills.Skills, config *Config) []string {
+	var warnings []string
+	for _, err := range unsupportedProtocols(sk, config) {
+		warnings = append(warnings, err.Error())
+	}
+	return warnings
+}
+
+// validateProtocols rejects addresses with a protocol the binary lacks
+func validateProtocols(sk skills.Skills, config *Config) error {
+	if errs := unsupportedProtocols(sk, config); len(errs) != 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// protocolRe matches the protocol prefix of an address, e.g. srt: or
+// pipe:. Single letters are Windows drives.
+var protocolRe = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]+):`)
+
+// addressProtocol returns the protocol FFmpeg uses for an address, file for
+// plain paths
+func addressProtocol(address string) string {
+	if address == "-" {
+		return "pipe"
+	}
+	if m := protocolRe.FindStringSubmatch(address); m != nil {
+		return strings.ToLower(m[1])
+	}
+	return "file"
+}
+
+// unsupportedProtocols returns an error for each address whose protocol
+// isn't in the skills
+func unsupportedProtocols(sk skills.Skills, config *Config) []error {
+	var errs []error
+	check := func(protocols []skills.Protocol, ios []ConfigIO, kind string) {
+		if len(protocols) == 0 {
+			// 能力探测失败时无从校验
+			return
+		}
+		known := make(map[string]bool, len(protocols))
+		for _, p := range protocols {
+			known[p.Id] = true
+		}
+		for _, io := range ios {
+			if p := addressProtocol(io.Address); !known[p] {
+				errs = append(errs, fmt.Errorf("%w %s in %s %s", ErrUnsupportedProtocol, p, kind, io.ID))
+			}
+		}
+	}
+	check(sk.Protocols.Input, config.Input, "input")
+	check(sk.Protocols.Output, config.Output, "output")
+	return errs
+}
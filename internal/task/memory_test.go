@@ -0,0 +1,92 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+)
+
+func TestMemoryAccounting(t *testing.T) {
+	s := newTestStore(t, StoreConfig{MemoryBudget: 1})
+	if _, err := s.Add(testConfig("a")); err != nil {
+		t.Fatal(err)
+	}
+	task, _ := s.Get("a")
+
+	for i := 0; i < 4; i++ {
+		if err := s.Start("a"); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, "a to run", func() bool { return task.State() == "running" })
+		if err := s.Stop("a"); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, "a to stop", func() bool { return len(task.History()) == i+1 })
+	}
+
+	// 运行历史与状态变化计入任务的内存
+	history, transitions := task.historyMemory(), task.transitionsMemory()
+	if history == 0 || transitions == 0 {
+		t.Fatalf("history %d bytes, transitions %d bytes, want both counted", history, transitions)
+	}
+	before := task.Memory()
+	if want := task.parser.Memory() + history + transitions; before != want {
+		t.Fatalf("Memory() = %d, want %d", before, want)
+	}
+	if total := s.MemoryUsage().Total; total != before {
+		t.Fatalf("total %d, want %d", total, before)
+	}
+
+	// 超出预算时丢弃较早的一半运行历史
+	s.(*store).enforceBudget()
+	runs := task.History()
+	if len(runs) != 2 || runs[0].Number != 4 || runs[1].Number != 3 {
+		t.Fatalf("runs after trimming %v, want the newest 2", runs)
+	}
+	if after := task.Memory(); after >= before {
+		t.Fatalf("Memory() = %d after trimming, want less than %d", after, before)
+	}
+	if s.MemoryUsage().Trimmed == 0 {
+		t.Fatal("trimmed isn't counted")
+	}
+}
+
+func TestAuditMemory(t *testing.T) {
+	a, err := NewAuditLog(AuditConfig{MaxEntries: 2}, logger.New("", logger.LevelError))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := a.Memory(); n != 0 {
+		t.Fatalf("empty log holds %d bytes", n)
+	}
+
+	// 超出 MaxEntries 的记录不再计入
+	actor := Actor{Name: "admin", Remote: "127.0.0.1"}
+	a.Record("a", AuditAdd, actor, nil, testConfig("a"))
+	for i := 0; i < 3; i++ {
+		a.Record("a", "start", actor, nil, nil)
+	}
+	if got, want := a.Memory(), entriesSize(a.Get("a")); got != want {
+		t.Fatalf("Memory() = %d, want %d for the kept entries", got, want)
+	}
+
+	// 超出 auditDeletedTasks 的已删除任务不再计入
+	for i := 0; i <= auditDeletedTasks; i++ {
+		id := fmt.Sprint("deleted", i)
+		a.Record(id, AuditDelete, actor, testConfig(id), nil)
+	}
+	var want uint64
+	for i := 1; i <= auditDeletedTasks; i++ {
+		want += entriesSize(a.Get(fmt.Sprint("deleted", i)))
+	}
+	want += entriesSize(a.Get("a"))
+	if got := a.Memory(); got != want {
+		t.Fatalf("Memory() = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+const (
+	// MaxMetadataSize caps the size of a metadata value in bytes
+	MaxMetadataSize = 64 * 1024
+	// MaxMetadataKeys caps the number of metadata keys of a task
+	MaxMetadataKeys = 64
+	maxMetadataKey  = 128
+)
+
+// Metadata returns the value stored under key, nil if there is none
+func (t *Task) Metadata(key string) json.RawMessage {
+	t.metadataLock.RLock()
+	defer t.metadataLock.RUnlock()
+	return t.metadata[key]
+}
+
+// AllMetadata returns a copy of the metadata of the task, nil if there is
+// none
+func (t *Task) AllMetadata() map[string]json.RawMessage {
+	t.metadataLock.RLock()
+	defer t.metadataLock.RUnlock()
+	if len(t.metadata) == 0 {
+		return nil
+	}
+	out := make(map[string]json.RawMessage, len(t.metadata))
+	for k, v := range t.metadata {
+		out[k] = v
+	}
+	return out
+}
+
+// SetMetadata stores a JSON value under key, null removes the key. The
+// metadata is independent of the config, an update keeps it.
+func (t *Task) SetMetadata(key string, value json.RawMessage) error {
+	if key == "" || len(key) > maxMetadataKey {
+		return ErrInvalidMetadataKey
+	}
+	if len(value) > MaxMetadataSize {
+		return ErrMetadataTooLarge
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, value); err != nil {
+		return ErrInvalidMetadata
+	}
+
+	t.metadataLock.Lock()
+	defer t.metadataLock.Unlock()
+	if buf.String() == "null" {
+		delete(t.metadata, key)
+		return nil
+	}
+	if _, ok := t.metadata[key]; !ok && len(t.metadata) >= MaxMetadataKeys {
+		return ErrTooManyMetadataKeys
+	}
+	if t.metadata == nil {
+		t.metadata = map[string]json.RawMessage{}
+	}
+	t.metadata[key] = buf.Bytes()
+	return nil
+}
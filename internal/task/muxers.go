@@ -0,0 +1,168 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg/skills"
+)
+
+// extensionMuxers lists the muxers that write the files of an extension,
+// the first is the one FFmpeg picks without -f
+var extensionMuxers = map[string][]string{
+	".mp4":  {"mp4", "mov", "ismv"},
+	".m4a":  {"ipod", "mp4", "mov"},
+	".mov":  {"mov", "mp4"},
+	".ts":   {"mpegts"},
+	".m2ts": {"mpegts"},
+	".m3u8": {"hls"},
+	".mpd":  {"dash"},
+	".flv":  {"flv"},
+	".mkv":  {"matroska"},
+	".mka":  {"matroska"},
+	".webm": {"webm", "matroska"},
+	".aac":  {"adts"},
+	".mp3":  {"mp3"},
+	".ogg":  {"ogg"},
+	".opus": {"opus", "ogg"},
+	".wav":  {"wav"},
+}
+
+// containers are the muxers of extensionMuxers, -f with another muxer
+// (segment, tee, image2, null, ...) writes any extension
+var containers = func() map[string]bool {
+	m := map[string]bool{}
+	for _, muxers := range extensionMuxers {
+		for _, mux := range muxers {
+			m[mux] = true
+		}
+	}
+	return m
+}()
+
+// codecRule restricts the codecs of a muxer: only lists the codecs it
+// accepts, not those it rejects
+type codecRule struct {
+	only []string
+	not  []string
+}
+
+// codecRules are the codec/container pairs FFmpeg rejects when it writes
+// the header, i.e. after the task has started
+var codecRules = map[string]codecRule{
+	"mp4":    {not: []string{"pcm_s16le", "pcm_s24le", "vorbis", "subrip", "ass", "webvtt"}},
+	"flv":    {not: []string{"opus", "vorbis", "flac", "ac3", "eac3", "vp8", "vp9", "subrip", "webvtt"}},
+	"mpegts": {not: []string{"vorbis", "flac", "pcm_s16le", "pcm_s24le", "vp8", "vp9", "webvtt"}},
+	"hls":    {not: []string{"vorbis", "flac", "pcm_s16le", "pcm_s24le", "vp8", "vp9"}},
+	"webm":   {only: []string{"vp8", "vp9", "av1", "opus", "vorbis", "webvtt"}},
+	"adts":   {only: []string{"aac"}},
+	"mp3":    {only: []string{"mp3"}},
+}
+
+// outputMuxer returns the -f of output options, "" if there is none
+func outputMuxer(options []string) string {
+	mux := ""
+	for i := 0; i+1 < len(options); i++ {
+		if options[i] == "-f" {
+			i++
+			mux = options[i]
+		}
+	}
+	return mux
+}
+
+// hlsFMP4 reports whether HLS options select fMP4 segments
+func hlsFMP4(options []string) bool {
+	i := slices.Index(options, "-hls_segment_type")
+	return i >= 0 && i+1 < len(options) && options[i+1] == "fmp4"
+}
+
+// addressExtension returns the lower case extension of the path of an
+// address, without the query
+func addressExtension(address string) string {
+	p := address
+	if u, err := url.Parse(address); err == nil && u.Scheme != "" && len(u.Scheme) > 1 {
+		p = u.Path
+	}
+	return strings.ToLower(path.Ext(p))
+}
+
+// codecOf returns the codec of an encoder or codec ID, ok is false if it
+// isn't in the skills
+func codecOf(sk skills.Skills, name string) (codec string, ok bool) {
+	for _, codecs := range [][]skills.Codec{sk.Codecs.Video, sk.Codecs.Audio, sk.Codecs.Subtitle} {
+		for _, c := range codecs {
+			if c.Id == name || slices.Contains(c.Encoders, name) {
+				return c.Id, true
+			}
+		}
+	}
+	return "", false
+}
+
+// validateMuxers checks the muxers of the outputs, see incompatibleOutputs
+func validateMuxers(sk skills.Skills, config *Config) error {
+	if errs := incompatibleOutputs(sk, config); len(errs) != 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// incompatibleOutputs returns an error for each output whose muxer isn't in
+// the skills, doesn't write the extension of the address or rejects one of
+// the codecs selected in the options
+func incompatibleOutputs(sk skills.Skills, config *Config) []error {
+	known := formatIDs(sk.Formats.Muxers)
+
+	var errs []error
+	for _, out := range config.Output {
+		ext := addressExtension(out.Address)
+		mux := outputMuxer(out.Options)
+		if mux != "" && len(known) != 0 && !known[mux] {
+			errs = append(errs, fmt.Errorf("%w: unknown muxer %s in output %s", ErrIncompatibleOutput, mux, out.ID))
+			continue
+		}
+
+		muxers := extensionMuxers[ext]
+		if mux == "" && len(muxers) != 0 {
+			mux = muxers[0]
+		} else if len(muxers) != 0 && containers[mux] && !slices.Contains(muxers, mux) {
+			errs = append(errs, fmt.Errorf("%w: -f %s in output %s doesn't write %s files, use -f %s", ErrIncompatibleOutput, mux, out.ID, ext, muxers[0]))
+			continue
+		}
+
+		if mux == "hls" && hlsFMP4(out.Options) {
+			mux = "mp4"
+		}
+		rule, ok := codecRules[mux]
+		if !ok {
+			continue
+		}
+		for i := 0; i+1 < len(out.Options); i++ {
+			if _, ok := encoderOption(out.Options[i]); !ok {
+				continue
+			}
+			i++
+			if out.Options[i] == "copy" {
+				continue
+			}
+			codec, ok := codecOf(sk, out.Options[i])
+			if !ok {
+				// 未知的编码器由 validateEncoders 报告
+				continue
+			}
+			if (len(rule.only) != 0 && !slices.Contains(rule.only, codec)) || slices.Contains(rule.not, codec) {
+				errs = append(errs, fmt.Errorf("%w: muxer %s in output %s doesn't support codec %s", ErrIncompatibleOutput, mux, out.ID, codec))
+			}
+		}
+	}
+	return errs
+}
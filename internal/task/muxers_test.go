@@ -0,0 +1,42 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMuxerExtension(t *testing.T) {
+	s := newTestStore(t, StoreConfig{})
+
+	config := testConfig("a")
+	config.Output[0].Address = "/tmp/out.ts"
+	config.Output[0].Options = []string{"-f", "mp4"}
+	_, err := s.Add(config)
+	if !errors.Is(err, ErrIncompatibleOutput) {
+		t.Fatalf("add with -f mp4 into .ts: %v, want %v", err, ErrIncompatibleOutput)
+	}
+	// 错误中给出应使用的格式
+	if !strings.Contains(err.Error(), "use -f mpegts") {
+		t.Fatalf("error %q doesn't suggest mpegts", err)
+	}
+
+	config.Output[0].Options = []string{"-f", "mpegts"}
+	if _, err := s.Add(config); err != nil {
+		t.Fatalf("add with -f mpegts into .ts: %v", err)
+	}
+
+	// 校验可以跳过
+	config = testConfig("b")
+	config.Output[0].Address = "/tmp/out.ts"
+	config.Output[0].Options = []string{"-f", "mp4"}
+	config.SkipValidation = true
+	if _, err := s.Add(config); err != nil {
+		t.Fatalf("add with skipped validation: %v", err)
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+
+	"github.com/lithammer/shortuuid/v4"
+)
+
+// Operation kinds
+const (
+	OperationRollingRestart = "rolling_restart"
+)
+
+// Operation states
+const (
+	OperationRunning = "running"
+	OperationDone    = "done"    // all tasks were processed
+	OperationAborted = "aborted" // too many tasks failed, the rest was skipped
+)
+
+// operationRetention is how long finished operations stay queryable
+const operationRetention = time.Hour
+
+// RolloutConfig for a rolling restart
+type RolloutConfig struct {
+	// Parallel is the number of tasks restarted at a time, default 1
+	Parallel int
+	// Timeout is the longest wait for a restarted task to become healthy,
+	// default 30s
+	Timeout time.Duration
+	// Settle is how long a task must be running and report progress to be
+	// healthy, default 3s
+	Settle time.Duration
+	// MaxFailures aborts the rollout after this many failed tasks, default 1
+	MaxFailures int
+}
+
+// Operation is a snapshot of a long-running batch command
+type Operation struct {
+	ID         string
+	Kind       string
+	State      string
+	Tasks      []string          // all selected tasks, in order
+	Done       []string          // healthy again
+	Failed     map[string]string // error by task ID
+	CreatedAt  time.Time
+	FinishedAt time.Time // zero while running
+}
+
+// Pending returns the tasks that weren't processed (yet)
+func (op Operation) Pending() []string {
+	done := make(map[string]bool, len(op.Done)+len(op.Failed))
+	for _, id := range op.Done {
+		done[id] = true
+	}
+	for id := range op.Failed {
+		done[id] = true
+	}
+	var pending []string
+	for _, id := range op.Tasks {
+		if !done[id] {
+			pending = append(pending, id)
+		}
+	}
+	return pending
+}
+
+// Operations runs batch commands in the background. Finished operations
+// can be queried for an hour.
+type Operations interface {
+	// RollingRestart restarts the tasks a few at a time, higher priority
+	// first, waiting for each to become healthy before the next one
+	RollingRestart(ids []string, config RolloutConfig) Operation
+	Get(id string) (Operation, error)
+	List() []Operation
+}
+
+type operations struct {
+	store  Store
+	logger logger.Logger
+	ops    map[string]*Operation
+	lock   sync.Mutex
+}
+
+// NewOperations creates Operations for a store
+func NewOperations(store Store, log logger.Logger) Operations {
+	return &operations{
+		store:  store,
+		logger: log,
+		ops:    make(map[string]*Operation),
+	}
+}
+
+func (o *operations) RollingRestart(ids []string, config RolloutConfig) Operation {
+	if config.Parallel <= 0 {
+		config.Parallel = 1
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	if config.Settle <= 0 {
+		config.Settle = 3 * time.Second
+	}
+	if config.MaxFailures <= 0 {
+		config.MaxFailures = 1
+	}
+
+	ids = append([]string(nil), ids...)
+	sort.SliceStable(ids, func(i, j int) bool { return o.priority(ids[i]) > o.priority(ids[j]) })
+
+	op := &Operation{
+		ID:        shortuuid.New(),
+		Kind:      OperationRollingRestart,
+		State:     OperationRunning,
+		Tasks:     ids,
+		Failed:    make(map[string]string),
+		CreatedAt: time.Now(),
+	}
+
+	o.lock.Lock()
+	o.prune()
+	o.ops[op.ID] = op
+	snapshot := o.snapshot(op)
+	o.lock.Unlock()
+
+	o.logger.Info("operation %s: rolling restart of %d tasks, %d at a time", op.ID, len(ids), config.Parallel)
+	go o.rollout(op, config)
+	return snapshot
+}
+
+// priority of a task, 0 if it doesn't exist
+func (o *operations) priority(id string) int64 {
+	t, err := o.store.Get(id)
+	if err != nil {
+		return 0
+	}
+	return t.priority.Load()
+}
+
+// rollout restarts the tasks of op with config.Parallel workers
+func (o *operations) rollout(op *Operation, config RolloutConfig) {
+	aborted := func() bool {
+		o.lock.Lock()
+		defer o.lock.Unlock()
+		return len(op.Failed) >= config.MaxFailures
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for w := 0; w < config.Parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				// 发送任务时可能已有其他任务失败
+				if aborted() {
+					continue
+				}
+				err := o.restart(id, config)
+
+				o.lock.Lock()
+				if err != nil {
+					op.Failed[id] = err.Error()
+					o.logger.Error("operation %s: task %s: %s", op.ID, id, err)
+				} else {
+					op.Done = append(op.Done, id)
+				}
+				o.lock.Unlock()
+			}
+		}()
+	}
+
+	for _, id := range op.Tasks {
+		if aborted() {
+			break
+		}
+		jobs <- id
+	}
+	close(jobs)
+	wg.Wait()
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	op.State = OperationDone
+	if len(op.Failed) >= config.MaxFailures {
+		op.State = OperationAborted
+	}
+	op.FinishedAt = time.Now()
+	o.logger.Info("operation %s: %s, %d restarted, %d failed", op.ID, op.State, len(op.Done), len(op.Failed))
+}
+
+// restart restarts a task and waits until it is healthy: running for
+// config.Settle and reporting progress
+func (o *operations) restart(id string, config RolloutConfig) error {
+	if err := o.store.Restart(id); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(config.Timeout)
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	state := ""
+	for range ticker.C {
+		t, err := o.store.Get(id)
+		if err != nil {
+			return err
+		}
+		status := t.Status()
+		state = status.State
+		progress := t.Progress()
+		if state == "running" && status.Duration >= config.Settle && (progress.Frame > 0 || progress.Time > 0) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+	}
+	return fmt.Errorf("not healthy after %s, state %s", config.Timeout, state)
+}
+
+func (o *operations) Get(id string) (Operation, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	op, ok := o.ops[id]
+	if !ok {
+		return Operation{}, ErrOperationNotFound
+	}
+	return o.snapshot(op), nil
+}
+
+func (o *operations) List() []Operation {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.prune()
+	list := make([]Operation, 0, len(o.ops))
+	for _, op := range o.ops {
+		list = append(list, o.snapshot(op))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// snapshot copies an operation, the caller must hold the lock
+func (o *operations) snapshot(op *Operation) Operation {
+	s := *op
+	s.Tasks = append([]string(nil), op.Tasks...)
+	s.Done = append([]string(nil), op.Done...)
+	s.Failed = maps.Clone(op.Failed)
+	return s
+}
+
+// prune removes operations that finished before the retention, the caller
+// must hold the lock
+func (o *operations) prune() {
+	for id, op := range o.ops {
+		if !op.FinishedAt.IsZero() && time.Since(op.FinishedAt) > operationRetention {
+			delete(o.ops, id)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// Built-in placeholders, {inputid} and {outputid} only in the options and
+// address of an input or output
+var builtinPlaceholders = []string{"id", "reference", "inputid", "outputid", "date", "time", "seq"}
+
+// runPlaceholders are only in the options and address of an output. They
+// are kept by Expand and substituted before every run, see expandRun.
+var runPlaceholders = []string{"date", "time", "seq"}
+
+// %{...} 是 drawtext 等滤镜自身的展开语法，不作替换
+var placeholderRe = regexp.MustCompile(`%?\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+var variableRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// expander substitutes placeholders and remembers the first unknown one
+type expander struct {
+	values map[string]string
+	where  string
+	err    error
+}
+
+func (e *expander) expand(s string) string {
+	return placeholderRe.ReplaceAllStringFunc(s, func(m string) string {
+		if m[0] == '%' {
+			return m
+		}
+		name := m[1 : len(m)-1]
+		if v, ok := e.values[name]; ok {
+			return v
+		}
+		if e.err == nil {
+			e.err = fmt.Errorf("%w: {%s} in %s", ErrUnknownPlaceholder, name, e.where)
+		}
+		return m
+	})
+}
+
+func (e *expander) expandAll(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	out := make([]string, len(s))
+	for i := range s {
+		out[i] = e.expand(s[i])
+	}
+	return out
+}
+
+// Expand returns a copy of the config with the placeholders in options and
+// addresses substituted. Unknown placeholders are kept and reported in the
+// error; the config itself is returned either way.
+func (c *Config) Expand() (*Config, error) {
+	values := maps.Clone(c.Variables)
+	if values == nil {
+		values = map[string]string{}
+	}
+	values["id"] = c.ID
+	values["reference"] = c.Reference
+
+	e := &expander{values: values, where: "options"}
+	n := *c
+	n.Options = e.expandAll(c.Options)
+	n.Input = expandIO(e, c.Input, "inputid", "input")
+	for _, name := range runPlaceholders {
+		e.values[name] = "{" + name + "}"
+	}
+	n.Output = expandIO(e, c.Output, "outputid", "output")
+
+	return &n, e.err
+}
+
+// hasRunPlaceholders reports whether an output uses {date}, {time} or {seq}
+func (c *Config) hasRunPlaceholders() bool {
+	for _, out := range c.Output {
+		for _, s := range append([]string{out.Address}, out.Options...) {
+			for _, m := range placeholderRe.FindAllStringSubmatch(s, -1) {
+				if m[0][0] != '%' && slices.Contains(runPlaceholders, m[1]) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// expandRun substitutes the run placeholders in the command of a run:
+// {date} is the date of now as 20060102, {time} its time as 150405 and
+// {seq} the number of the run, at least 3 digits
+func expandRun(args []string, now time.Time, seq uint64) []string {
+	e := &expander{values: map[string]string{
+		"date": now.Format("20060102"),
+		"time": now.Format("150405"),
+		"seq":  fmt.Sprintf("%03d", seq),
+	}}
+	// 其他占位符已由 Expand 替换，剩下的未知占位符原样保留
+	return e.expandAll(args)
+}
+
+func expandIO(e *expander, ios []ConfigIO, key, kind string) []ConfigIO {
+	if ios == nil {
+		return nil
+	}
+	out := make([]ConfigIO, len(ios))
+	for i, io := range ios {
+		e.values[key] = io.ID
+		e.where = kind + " " + io.ID
+		out[i] = io
+		out[i].Address = e.expand(io.Address)
+		out[i].Options = e.expandAll(io.Options)
+	}
+	delete(e.values, key)
+	return out
+}
+
+// validateVariables checks the variable names and that all placeholders
+// are known
+func (c *Config) validateVariables() error {
+	for name := range c.Variables {
+		if slices.Contains(builtinPlaceholders, name) || !variableRe.MatchString(name) {
+			return fmt.Errorf("%w: %s", ErrInvalidVariable, name)
+		}
+	}
+	_, err := c.Expand()
+	return err
+}
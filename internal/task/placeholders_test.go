@@ -0,0 +1,62 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestExpandRun(t *testing.T) {
+	config := &Config{
+		ID:        "news",
+		Reference: "channel-1",
+		Input:     []ConfigIO{{ID: "in", Address: "rtmp://origin/live/{id}"}},
+		Output: []ConfigIO{
+			{ID: "rec", Address: "/archive/{reference}/rec-{date}-{time}-{seq}.mp4", Options: []string{"-metadata", "title={id} {date}", "-vf", "drawtext=text='%{localtime}'"}},
+			{ID: "live", Address: "rtmp://edge/live/{id}"},
+		},
+	}
+	if !config.hasRunPlaceholders() {
+		t.Fatal("templated output not detected")
+	}
+
+	// {id}、{reference} 在创建命令时替换，{date}、{time}、{seq} 留到运行时
+	command := config.CreateCommand()
+	now := time.Date(2026, 1, 12, 8, 30, 5, 0, time.UTC)
+	tests := []struct {
+		seq  uint64
+		now  time.Time
+		want string
+	}{
+		{1, now, "/archive/channel-1/rec-20260112-083005-001.mp4"},
+		{2, now.Add(time.Hour), "/archive/channel-1/rec-20260112-093005-002.mp4"},
+		{1000, now, "/archive/channel-1/rec-20260112-083005-1000.mp4"},
+	}
+	for _, tt := range tests {
+		want := []string{
+			"-i", "rtmp://origin/live/news",
+			"-metadata", "title=news " + tt.now.Format("20060102"), "-vf", "drawtext=text='%{localtime}'", tt.want,
+			"rtmp://edge/live/news",
+		}
+		if got := expandRun(command, tt.now, tt.seq); !slices.Equal(got, want) {
+			t.Errorf("run %d: argv %q, want %q", tt.seq, got, want)
+		}
+	}
+
+	plain := &Config{
+		ID:     "plain",
+		Input:  []ConfigIO{{ID: "in", Address: "/tmp/in.mp4"}},
+		Output: []ConfigIO{{ID: "out", Address: "/tmp/out.mp4"}},
+	}
+	if plain.hasRunPlaceholders() {
+		t.Fatal("plain output has run placeholders")
+	}
+	if got, want := plain.CreateCommand(), []string{"-i", "/tmp/in.mp4", "/tmp/out.mp4"}; !slices.Equal(got, want) {
+		t.Fatalf("plain argv %q, want %q", got, want)
+	}
+}
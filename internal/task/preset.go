@@ -0,0 +1,230 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Preset is a named partial task config
+type Preset struct {
+	Name              string   `json:"name"`
+	Options           []string `json:"options"`
+	InputOptions      []string `json:"input_options"`
+	OutputOptions     []string `json:"output_options"`
+	Reconnect         bool     `json:"reconnect"`
+	ReconnectDelay    uint64   `json:"reconnect_delay_seconds"`
+	ReconnectBackoff  float64  `json:"reconnect_backoff"`
+	ReconnectMaxDelay uint64   `json:"reconnect_max_delay_seconds"`
+	MaxReconnects     int      `json:"max_reconnects"`
+	StaleTimeout      uint64   `json:"stale_timeout_seconds"`
+	StaleOn           string   `json:"stale_on"`
+	LimitCPU          float64  `json:"limit_cpu_usage"`
+	LimitMemory       uint64   `json:"limit_memory_bytes"`
+	LimitWaitFor      uint64   `json:"limit_waitfor_seconds"`
+	CreatedAt         int64    `json:"created_at"`
+	UpdatedAt         int64    `json:"updated_at"`
+}
+
+// Apply fills the fields of config that are not set with the preset's values
+func (p *Preset) Apply(config *Config) {
+	if len(config.Options) == 0 {
+		config.Options = append([]string(nil), p.Options...)
+	}
+	for i := range config.Input {
+		if len(config.Input[i].Options) == 0 {
+			config.Input[i].Options = append([]string(nil), p.InputOptions...)
+		}
+	}
+	for i := range config.Output {
+		if len(config.Output[i].Options) == 0 {
+			config.Output[i].Options = append([]string(nil), p.OutputOptions...)
+		}
+	}
+	if !config.Reconnect {
+		config.Reconnect = p.Reconnect
+	}
+	if config.ReconnectDelay == 0 {
+		config.ReconnectDelay = p.ReconnectDelay
+	}
+	if config.ReconnectBackoff == 0 {
+		config.ReconnectBackoff = p.ReconnectBackoff
+	}
+	if config.ReconnectMaxDelay == 0 {
+		config.ReconnectMaxDelay = p.ReconnectMaxDelay
+	}
+	if config.MaxReconnects == 0 {
+		config.MaxReconnects = p.MaxReconnects
+	}
+	if config.StaleTimeout == 0 {
+		config.StaleTimeout = p.StaleTimeout
+	}
+	if config.StaleOn == "" {
+		config.StaleOn = p.StaleOn
+	}
+	if config.LimitCPU == 0 {
+		config.LimitCPU = p.LimitCPU
+	}
+	if config.LimitMemory == 0 {
+		config.LimitMemory = p.LimitMemory
+	}
+	if config.LimitWaitFor == 0 {
+		config.LimitWaitFor = p.LimitWaitFor
+	}
+}
+
+// PresetStore manages presets persisted as a JSON file
+type PresetStore interface {
+	Add(preset *Preset) (*Preset, error)
+	Get(name string) (*Preset, error)
+	List() []*Preset
+	Update(name string, preset *Preset) (*Preset, error)
+	Delete(name string) error
+}
+
+type presetStore struct {
+	path    string
+	presets map[string]*Preset
+	mu      sync.RWMutex
+}
+
+// NewPresetStore loads presets from path. A missing file is an empty store.
+func NewPresetStore(path string) (PresetStore, error) {
+	s := &presetStore{
+		path:    path,
+		presets: make(map[string]*Preset),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+
+	var presets []*Preset
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return nil, err
+	}
+	for _, p := range presets {
+		s.presets[p.Name] = p
+	}
+
+	return s, nil
+}
+
+func (s *presetStore) Add(preset *Preset) (*Preset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(preset.Name) == 0 {
+		return nil, ErrInvalidPreset
+	}
+	if _, exists := s.presets[preset.Name]; exists {
+		return nil, ErrPresetExists
+	}
+
+	now := time.Now().Unix()
+	preset.CreatedAt = now
+	preset.UpdatedAt = now
+
+	s.presets[preset.Name] = preset
+	if err := s.save(); err != nil {
+		delete(s.presets, preset.Name)
+		return nil, err
+	}
+	return preset, nil
+}
+
+func (s *presetStore) Get(name string) (*Preset, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.presets[name]
+	if !ok {
+		return nil, ErrPresetNotFound
+	}
+	return p, nil
+}
+
+func (s *presetStore) List() []*Preset {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*Preset, 0, len(s.presets))
+	for _, p := range s.presets {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (s *presetStore) Update(name string, preset *Preset) (*Preset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.presets[name]
+	if !ok {
+		return nil, ErrPresetNotFound
+	}
+
+	preset.Name = name
+	preset.CreatedAt = old.CreatedAt
+	preset.UpdatedAt = time.Now().Unix()
+
+	s.presets[name] = preset
+	if err := s.save(); err != nil {
+		s.presets[name] = old
+		return nil, err
+	}
+	return preset, nil
+}
+
+func (s *presetStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.presets[name]
+	if !ok {
+		return ErrPresetNotFound
+	}
+
+	delete(s.presets, name)
+	if err := s.save(); err != nil {
+		s.presets[name] = old
+		return err
+	}
+	return nil
+}
+
+// save writes all presets to disk. The caller must hold the lock.
+func (s *presetStore) save() error {
+	presets := make([]*Preset, 0, len(s.presets))
+	for _, p := range s.presets {
+		presets = append(presets, p)
+	}
+	sort.Slice(presets, func(i, j int) bool { return presets[i].Name < presets[j].Name })
+
+	data, err := json.MarshalIndent(presets, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
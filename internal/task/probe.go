@@ -0,0 +1,138 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg/probe"
+)
+
+// probeCacheTTL is how long a probe result is reused, so that polling
+// doesn't hammer the source
+const probeCacheTTL = 30 * time.Second
+
+// InputProbe is the probe result of one input
+type InputProbe struct {
+	ID      string
+	Address string // with the credentials masked
+	Result  probe.Result
+	Err     error
+}
+
+// probeSkip are FFmpeg input options ffprobe doesn't know, with whether
+// they take a value
+var probeSkip = map[string]bool{
+	"-re":                     false,
+	"-copyts":                 false,
+	"-readrate":               true,
+	"-readrate_initial_burst": true,
+	"-stream_loop":            true,
+	"-ss":                     true,
+	"-sseof":                  true,
+	"-t":                      true,
+	"-to":                     true,
+	"-itsoffset":              true,
+	"-itsscale":               true,
+	"-thread_queue_size":      true,
+	"-hwaccel":                true,
+	"-hwaccel_device":         true,
+	"-hwaccel_output_format":  true,
+	"-r":                      true,
+}
+
+// probeOptions returns the input options that also apply to ffprobe, e.g.
+// -f or -rtsp_transport. Decoder options are dropped.
+func probeOptions(options []string) []string {
+	var out []string
+	for i := 0; i < len(options); i++ {
+		opt := options[i]
+		hasValue, skip := probeSkip[opt]
+		if !skip {
+			switch {
+			case opt == "-c" || opt == "-codec" || strings.HasPrefix(opt, "-c:") || strings.HasPrefix(opt, "-codec:"):
+				skip, hasValue = true, true
+			}
+		}
+		if skip {
+			if hasValue {
+				i++
+			}
+			continue
+		}
+		out = append(out, opt)
+	}
+	return out
+}
+
+func (s *store) Probe(ctx context.Context, id string, refresh bool) ([]InputProbe, error) {
+	t, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	config := t.Config
+	s.mu.RUnlock()
+
+	// 探测开销较大，同一任务的并发请求等待同一次探测
+	t.probeLock.Lock()
+	defer t.probeLock.Unlock()
+
+	// 任务更新后配置指针改变，缓存随之失效
+	if t.probe != nil && t.probeConfig == config && !refresh && time.Since(t.probeTime) < probeCacheTTL {
+		return t.probe, nil
+	}
+
+	expanded, _ := config.Expand()
+	results := make([]InputProbe, len(expanded.Input))
+	var wg sync.WaitGroup
+	for i, in := range expanded.Input {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = s.probeInput(ctx, in)
+		}()
+	}
+	wg.Wait()
+
+	// 没有 ffprobe 时不缓存，直接返回错误
+	for _, r := range results {
+		if errors.Is(r.Err, ffmpeg.ErrNoFFprobe) {
+			return nil, ffmpeg.ErrNoFFprobe
+		}
+	}
+
+	t.probe = results
+	t.probeConfig = config
+	t.probeTime = time.Now()
+	return results, nil
+}
+
+// probeInput probes one input, with the credentials it references
+func (s *store) probeInput(ctx context.Context, in ConfigIO) InputProbe {
+	r := &secrets{store: s.config.Credentials}
+	p := InputProbe{ID: in.ID, Address: in.Address}
+
+	args, err := r.resolve(append(probeOptions(in.Options), in.Address))
+	if err != nil {
+		p.Err = err
+		return p
+	}
+	p.Result, err = s.ffmpeg.Probe(ctx, args[len(args)-1], args[:len(args)-1])
+	if errors.Is(err, ffmpeg.ErrNoFFprobe) {
+		p.Err = err
+	} else if err != nil {
+		// ffprobe 的错误信息可能包含凭据
+		p.Err = errors.New(r.mask(err.Error()))
+	}
+	return p
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestProbeOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []string
+		want    []string
+	}{
+		{"none", nil, nil},
+		{"kept", []string{"-f", "flv", "-rtsp_transport", "tcp"}, []string{"-f", "flv", "-rtsp_transport", "tcp"}},
+		{"flags", []string{"-re", "-copyts", "-f", "mpegts"}, []string{"-f", "mpegts"}},
+		{"with value", []string{"-stream_loop", "-1", "-ss", "10", "-analyzeduration", "10M"}, []string{"-analyzeduration", "10M"}},
+		{"hwaccel", []string{"-hwaccel", "cuda", "-hwaccel_output_format", "cuda", "-f", "rtsp"}, []string{"-f", "rtsp"}},
+		{"decoders", []string{"-c:v", "h264_cuvid", "-codec:a", "aac", "-c", "copy", "-codec", "copy"}, nil},
+		{"decoder prefix only", []string{"-cv", "x"}, []string{"-cv", "x"}},
+		{"mixed", []string{"-re", "-thread_queue_size", "512", "-c:v", "h264_qsv", "-timeout", "5000000"}, []string{"-timeout", "5000000"}},
+		{"value at the end", []string{"-f", "flv", "-ss"}, []string{"-f", "flv"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := probeOptions(tt.options); !slices.Equal(got, tt.want) {
+				t.Fatalf("probeOptions(%q) = %q, want %q", tt.options, got, tt.want)
+			}
+		})
+	}
+}
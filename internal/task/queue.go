@@ -0,0 +1,196 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"sort"
+
+	"github.com/ZSC714725/transcodemanager/internal/process"
+)
+
+// StopReasonPreempted is the StopReason of a task stopped for a higher
+// priority one
+const StopReasonPreempted = "preempted"
+
+// start runs the pre-start checks and starts the task, or queues it if
+// MaxConcurrent tasks are already running. Queued tasks are started by
+// priority, in order within the same priority, as running ones exit.
+func (s *store) start(t *Task) error {
+	if s.closing.Load() {
+		return ErrShuttingDown
+	}
+	if err := s.preCheck(t); err != nil {
+		return err
+	}
+	if s.config.MaxConcurrent <= 0 {
+		return t.proc.Start()
+	}
+
+	// 否则同时启动的任务都会看到空闲的名额
+	s.startLock.Lock()
+	defer s.startLock.Unlock()
+
+	running := s.running()
+	var victim *Task
+	if s.config.Preempt {
+		victim = s.lowestRunning()
+	}
+
+	s.queueLock.Lock()
+	defer s.queueLock.Unlock()
+
+	if t.IsQueued() || t.proc.IsRunning() || t.Status().State == "paused" {
+		return nil
+	}
+	if running >= s.config.MaxConcurrent || len(s.queue) > 0 {
+		s.queue = append(s.queue, t)
+		s.sortQueue()
+		s.logger.Info("task %s queued at %d, %d waiting", t.ID, t.QueuePosition(), len(s.queue))
+		// 腾出的名额总是给队首，其优先级不低于 t
+		if victim != nil && victim.priority.Load() < t.priority.Load() && victim.preempted.CompareAndSwap(false, true) {
+			go s.preempt(victim, t)
+		}
+		// 排队期间可能已有任务退出
+		go s.promote()
+		return nil
+	}
+
+	return t.proc.Start()
+}
+
+// dequeue removes the task from the queue and reports whether it was
+// queued. It also cancels queueing it again after a preemption.
+func (s *store) dequeue(t *Task) bool {
+	s.queueLock.Lock()
+	defer s.queueLock.Unlock()
+
+	if t.preempted.Swap(false) {
+		return true
+	}
+	if !t.IsQueued() {
+		return false
+	}
+	t.queuePos.Store(0)
+	for i, q := range s.queue {
+		if q == t {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			break
+		}
+	}
+	s.renumber()
+	return true
+}
+
+// sortQueue orders the queue by priority, keeping the order of tasks with
+// the same priority, and renumbers it. The caller must hold queueLock.
+func (s *store) sortQueue() {
+	sort.SliceStable(s.queue, func(i, j int) bool {
+		return s.queue[i].priority.Load() > s.queue[j].priority.Load()
+	})
+	s.renumber()
+}
+
+// reprioritize changes the priority of a task and moves it in the queue
+func (s *store) reprioritize(t *Task, priority int) {
+	s.queueLock.Lock()
+	defer s.queueLock.Unlock()
+
+	t.priority.Store(int64(priority))
+	if t.IsQueued() {
+		s.sortQueue()
+	}
+}
+
+// preempt stops victim to make room for t and queues it again
+func (s *store) preempt(victim, t *Task) {
+	s.logger.Info("task %s preempted by task %s (priority %d < %d)", victim.ID, t.ID, victim.priority.Load(), t.priority.Load())
+	if err := victim.proc.StopWith(process.StopOptions{Wait: true, Reason: StopReasonPreempted}); err != nil {
+		s.logger.Error("task %s preempt: %s", victim.ID, err)
+	}
+	// 停止期间被用户停止、更新或删除时不再排队
+	if !victim.preempted.Swap(false) {
+		return
+	}
+	if err := s.start(victim); err != nil {
+		s.logger.Error("task %s start after preemption: %s", victim.ID, err)
+	}
+}
+
+// renumber updates the positions after tasks left the queue, the caller
+// must hold queueLock
+func (s *store) renumber() {
+	for i, t := range s.queue {
+		t.queuePos.Store(int32(i + 1))
+	}
+}
+
+// promote starts queued tasks while there is room
+func (s *store) promote() {
+	if s.config.MaxConcurrent <= 0 || s.closing.Load() {
+		return
+	}
+
+	s.startLock.Lock()
+	defer s.startLock.Unlock()
+
+	running := s.running()
+
+	s.queueLock.Lock()
+	defer s.queueLock.Unlock()
+
+	for running < s.config.MaxConcurrent && len(s.queue) > 0 {
+		t := s.queue[0]
+		s.queue = s.queue[1:]
+		t.queuePos.Store(0)
+
+		// 排队期间输入可能已消失、磁盘可能已满，启动前重新检查
+		if err := s.preCheck(t); err != nil {
+			s.logger.Error("task %s start from queue: %s", t.ID, err)
+			t.proc.Fail(err)
+			continue
+		}
+		if err := t.proc.Start(); err != nil {
+			s.logger.Error("task %s start from queue: %s", t.ID, err)
+			continue
+		}
+		running++
+	}
+	s.renumber()
+}
+
+// running counts the tasks that occupy a concurrency slot. A paused task
+// doesn't.
+func (s *store) running() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, t := range s.tasks {
+		if t.proc.IsRunning() {
+			n++
+		}
+	}
+	return n
+}
+
+// lowestRunning returns the running task with the lowest priority that
+// isn't being preempted already, the one started last if several have it
+func (s *store) lowestRunning() *Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var lowest *Task
+	for _, t := range s.tasks {
+		if !t.proc.IsRunning() || t.preempted.Load() {
+			continue
+		}
+		if lowest == nil || t.priority.Load() < lowest.priority.Load() ||
+			(t.priority.Load() == lowest.priority.Load() && t.Status().Time.After(lowest.Status().Time)) {
+			lowest = t
+		}
+	}
+	return lowest
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+)
+
+// newTestStore creates a store of simulated processes
+func newTestStore(t *testing.T, config StoreConfig) Store {
+	t.Helper()
+	ff, err := ffmpeg.New(ffmpeg.Config{Binary: "ffmpeg", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(ff, logger.New("", logger.LevelError), config)
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+	return s
+}
+
+func testConfig(id string) *Config {
+	return &Config{
+		ID:     id,
+		Input:  []ConfigIO{{ID: "in", Address: "/tmp/" + id + ".mp4"}},
+		Output: []ConfigIO{{ID: "out", Address: "/tmp/" + id + "-out.mp4"}},
+	}
+}
+
+// waitFor polls cond until it holds or a second passed
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func TestMaxConcurrent(t *testing.T) {
+	s := newTestStore(t, StoreConfig{MaxConcurrent: 1})
+	ids := []string{"a", "b", "c"}
+	for _, id := range ids {
+		if _, err := s.Add(testConfig(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Start(id); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	states := func() (running, queued []string) {
+		for _, id := range ids {
+			task, _ := s.Get(id)
+			switch task.State() {
+			case "starting", "running":
+				running = append(running, id)
+			case "queued":
+				queued = append(queued, id)
+			}
+		}
+		return running, queued
+	}
+	running, queued := states()
+	if len(running) != 1 || len(queued) != 2 {
+		t.Fatalf("running %v, queued %v, want 1 running and 2 queued", running, queued)
+	}
+
+	// 停止运行中的任务后，队首的任务接着运行
+	for len(queued) != 0 {
+		var next string
+		for _, id := range queued {
+			if task, _ := s.Get(id); task.QueuePosition() == 1 {
+				next = id
+			}
+		}
+		if err := s.Stop(running[0]); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, fmt.Sprintf("%s to run", next), func() bool {
+			task, _ := s.Get(next)
+			return task.State() == "running"
+		})
+		running, queued = states()
+		if len(running) != 1 || running[0] != next {
+			t.Fatalf("running %v, want only %s", running, next)
+		}
+	}
+}
+
+func TestStartPaused(t *testing.T) {
+	s := newTestStore(t, StoreConfig{MaxConcurrent: 1})
+	for _, id := range []string{"a", "b"} {
+		if _, err := s.Add(testConfig(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, _ := s.Get("a")
+	if err := s.Start("a"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "a to run", func() bool { return a.State() == "running" })
+	if err := s.Pause("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 暂停的任务不占名额，b 可以运行
+	if err := s.Start("b"); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := s.Get("b")
+	waitFor(t, "b to run", func() bool { return b.State() == "running" })
+
+	// 再次 start 暂停的任务不会让它排队
+	if err := s.Start("a"); err != nil {
+		t.Fatal(err)
+	}
+	if state := a.State(); state != "paused" {
+		t.Fatalf("state %s after start, want paused", state)
+	}
+}
+
+func TestUpdatePaused(t *testing.T) {
+	s := newTestStore(t, StoreConfig{})
+	if _, err := s.Add(testConfig("a")); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := s.Get("a")
+	if err := s.Start("a"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "a to run", func() bool { return a.State() == "running" })
+	if err := s.Pause("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 更新暂停的任务后以新配置重新启动，而不是停止
+	config := testConfig("a")
+	config.Output[0].Address = "/tmp/b.mp4"
+	if _, _, err := s.Update("a", config); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "a to run again", func() bool { return a.State() == "running" })
+	if order := a.Status().Order; order != "start" {
+		t.Fatalf("order %s after update, want start", order)
+	}
+}
+
+func TestPromoteChecks(t *testing.T) {
+	s := newTestStore(t, StoreConfig{MaxConcurrent: 1})
+	if _, err := s.Add(testConfig("a")); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(t.TempDir(), "in.mp4")
+	if err := os.WriteFile(input, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := testConfig("b")
+	config.Input[0].Address = input
+	config.PreChecks = PreChecksAbort
+	if _, err := s.Add(config); err != nil {
+		t.Fatal(err)
+	}
+
+	a, _ := s.Get("a")
+	b, _ := s.Get("b")
+	if err := s.Start("a"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "a to run", func() bool { return a.State() == "running" })
+	if err := s.Start("b"); err != nil {
+		t.Fatal(err)
+	}
+	if state := b.State(); state != "queued" {
+		t.Fatalf("b is %s, want queued", state)
+	}
+
+	// 排队期间输入消失，轮到 b 时检查失败
+	if err := os.Remove(input); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Stop("a"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "b to fail", func() bool { return b.State() == "failed" })
+	if status := b.Status(); status.Order != "stop" || status.States.Running != 0 {
+		t.Fatalf("order %s, %d runs, want stop and none", status.Order, status.States.Running)
+	}
+}
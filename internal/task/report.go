@@ -0,0 +1,115 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReportFile is the FFmpeg report of a run, written with DebugReport
+type ReportFile struct {
+	Name    string
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// reportTime names the report of a run, names sort by start time
+const reportTime = "20060102-150405.000"
+
+// runHash is the hash of the config without the settings that are read
+// before every run or only used for scheduling. Configs with the same
+// runHash run the same process.
+func (c *Config) runHash() string {
+	r := *c
+	r.DebugReport = false
+	r.Priority = 0
+	r.Hooks = nil
+	r.StartAt = 0
+	r.StopAt = 0
+	r.Schedule = ""
+	return r.Hash()
+}
+
+// reportDir is the directory of a task's reports
+func (s *store) reportDir(id string) string {
+	// ID 由用户指定，转义后作为目录名
+	return filepath.Join(s.config.ReportDir, url.PathEscape(id))
+}
+
+// reportEnv returns FFREPORT for the next run of a task if its debug report
+// is enabled. Old reports beyond ReportKeep are removed first.
+func (s *store) reportEnv(t *Task) []string {
+	if !t.debugReport.Load() || s.config.ReportDir == "" {
+		return nil
+	}
+
+	dir := s.reportDir(t.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		s.logger.Error("task %s: can't create report directory: %v", t.ID, err)
+		return nil
+	}
+	files, _ := s.reportFiles(t.ID)
+	for len(files) >= s.config.ReportKeep {
+		os.Remove(files[0].Path)
+		files = files[1:]
+	}
+
+	path := filepath.Join(dir, time.Now().Format(reportTime)+".log")
+	return []string{"FFREPORT=file=" + escapeFFReport(path) + ":level=40"}
+}
+
+// escapeFFReport escapes a path for FFREPORT: ':' separates the options
+// and '%' starts a template like %p
+func escapeFFReport(path string) string {
+	r := strings.NewReplacer(`\`, `\\`, `:`, `\:`, `'`, `\'`, `%`, `%%`)
+	return r.Replace(path)
+}
+
+func (s *store) ReportFiles(id string) ([]ReportFile, error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+	return s.reportFiles(id)
+}
+
+// reportFiles returns the reports of a task, oldest first
+func (s *store) reportFiles(id string) ([]ReportFile, error) {
+	if s.config.ReportDir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(s.reportDir(id))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []ReportFile
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".log" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, ReportFile{
+			Name:    e.Name(),
+			Path:    filepath.Join(s.reportDir(id), e.Name()),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
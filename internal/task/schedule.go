@@ -0,0 +1,170 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/process"
+
+	"github.com/robfig/cron/v3"
+)
+
+// StopReasonSchedule is the StopReason of a task stopped at its StopAt
+const StopReasonSchedule = "schedule"
+
+// validateSchedule checks that StopAt is after StartAt and that Schedule
+// parses
+func (c *Config) validateSchedule() error {
+	if c.StartAt < 0 || c.StopAt < 0 {
+		return ErrInvalidStopAt
+	}
+	if c.StartAt != 0 && c.StopAt != 0 && c.StopAt <= c.StartAt {
+		return ErrInvalidStopAt
+	}
+	if c.Schedule != "" {
+		if _, err := c.cronSchedule(); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidSchedule, err)
+		}
+	}
+	return nil
+}
+
+// cronSchedule parses Schedule, in Timezone if it doesn't name a zone
+func (c *Config) cronSchedule() (cron.Schedule, error) {
+	spec := c.Schedule
+	if c.Timezone != "" && !strings.HasPrefix(spec, "TZ=") && !strings.HasPrefix(spec, "CRON_TZ=") {
+		spec = "CRON_TZ=" + c.Timezone + " " + spec
+	}
+	return cron.ParseStandard(spec)
+}
+
+// inWindow reports whether now is between StartAt and StopAt, an unset
+// time is unbounded. Autostart only starts a task within its window.
+func (c *Config) inWindow(now time.Time) bool {
+	if c.StartAt != 0 && now.Unix() < c.StartAt {
+		return false
+	}
+	if c.StopAt != 0 && now.Unix() >= c.StopAt {
+		return false
+	}
+	return true
+}
+
+// schedule replaces the start and stop timers and the cron entry of a task
+// with those of its config. Times that have passed are ignored. The caller
+// must hold s.mu.
+func (s *store) schedule(t *Task, config *Config) {
+	s.unschedule(t)
+
+	now := time.Now()
+	if config.StartAt > now.Unix() {
+		d := time.Unix(config.StartAt, 0).Sub(now)
+		t.startTimer = time.AfterFunc(d, func() { s.scheduledStart(t, config) })
+		s.logger.Info("task %s: start scheduled in %s", t.ID, d.Round(time.Second))
+	}
+	if config.StopAt > now.Unix() {
+		d := time.Unix(config.StopAt, 0).Sub(now)
+		t.stopTimer = time.AfterFunc(d, func() { s.scheduledStop(t, config) })
+		s.logger.Info("task %s: stop scheduled in %s", t.ID, d.Round(time.Second))
+	}
+	if config.Schedule != "" {
+		// 已校验
+		sched, _ := config.cronSchedule()
+		t.cronEntry = s.cron.Schedule(sched, cron.FuncJob(func() { s.cronStart(t, config) }))
+		s.logger.Info("task %s: schedule %q, next start at %s", t.ID, config.Schedule, sched.Next(now).Format(time.RFC3339))
+	}
+}
+
+// unschedule stops the timers and removes the cron entry of a task. The
+// caller must hold s.mu.
+func (s *store) unschedule(t *Task) {
+	if t.cronEntry != 0 {
+		s.cron.Remove(t.cronEntry)
+		t.cronEntry = 0
+	}
+	if t.startTimer != nil {
+		t.startTimer.Stop()
+		t.startTimer = nil
+	}
+	if t.stopTimer != nil {
+		t.stopTimer.Stop()
+		t.stopTimer = nil
+	}
+}
+
+// scheduled reports whether the timer of config is still current: the
+// task wasn't deleted or updated since
+func (s *store) scheduled(t *Task, config *Config) bool {
+	return s.tasks[t.ID] == t && t.Config == config
+}
+
+func (s *store) scheduledStart(t *Task, config *Config) {
+	s.mu.Lock()
+	// 定时器触发时可能正等待更新或删除释放锁
+	if !s.scheduled(t, config) {
+		s.mu.Unlock()
+		return
+	}
+	t.startTimer = nil
+	t.Order = "start"
+	s.mu.Unlock()
+
+	s.logger.Info("task %s: scheduled start", t.ID)
+	if err := s.start(t); err != nil {
+		s.logger.Error("task %s: scheduled start: %v", t.ID, err)
+	}
+}
+
+// cronStart starts a task on a tick of its Schedule, unless the previous
+// run is still active or the tick is outside StartAt and StopAt
+func (s *store) cronStart(t *Task, config *Config) {
+	s.mu.Lock()
+	if !s.scheduled(t, config) || s.closing.Load() {
+		s.mu.Unlock()
+		return
+	}
+	if !config.inWindow(time.Now()) {
+		s.mu.Unlock()
+		return
+	}
+	switch state := t.State(); state {
+	case "queued", "starting", "running", "finishing", "paused":
+		s.mu.Unlock()
+		s.logger.Info("task %s: schedule tick skipped, task is %s", t.ID, state)
+		return
+	}
+	t.Order = "start"
+	proc := t.proc
+	s.mu.Unlock()
+
+	// 上次运行自行结束后进程仍保持启动指令，Start 不会再运行，先复位
+	proc.Stop(false)
+	s.logger.Info("task %s: scheduled start", t.ID)
+	if err := s.start(t); err != nil {
+		s.logger.Error("task %s: scheduled start: %v", t.ID, err)
+	}
+}
+
+func (s *store) scheduledStop(t *Task, config *Config) {
+	s.mu.Lock()
+	if !s.scheduled(t, config) {
+		s.mu.Unlock()
+		return
+	}
+	t.stopTimer = nil
+	t.Order = "stop"
+	proc := t.proc
+	s.mu.Unlock()
+
+	s.logger.Info("task %s: scheduled stop", t.ID)
+	s.dequeue(t)
+	if err := proc.StopWith(process.StopOptions{Wait: true, Reason: StopReasonSchedule}); err != nil {
+		s.logger.Error("task %s: scheduled stop: %v", t.ID, err)
+	}
+}
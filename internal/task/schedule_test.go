@@ -0,0 +1,81 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+// waitState waits for a task to reach state, start_at and stop_at have a
+// resolution of one second
+func waitState(t *testing.T, s Store, id, state string, timeout time.Duration) {
+	t.Helper()
+	for deadline := time.Now().Add(timeout); ; time.Sleep(20 * time.Millisecond) {
+		task, err := s.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.State() == state {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("task %s is %s, want %s", id, task.State(), state)
+		}
+	}
+}
+
+func TestStartStopAt(t *testing.T) {
+	s := newTestStore(t, StoreConfig{})
+	now := time.Now().Unix()
+
+	config := testConfig("window")
+	config.StartAt = now + 1
+	config.StopAt = now + 2
+	if _, err := s.Add(config); err != nil {
+		t.Fatal(err)
+	}
+	// 更新后按新时间重新调度，旧的定时器不再触发
+	moved := testConfig("moved")
+	moved.StartAt = now + 1
+	if _, err := s.Add(moved); err != nil {
+		t.Fatal(err)
+	}
+	moved = testConfig("moved")
+	moved.StartAt = now + 3600
+	if _, _, err := s.Update("moved", moved); err != nil {
+		t.Fatal(err)
+	}
+	// 删除后定时器取消，同 ID 的新任务不会被旧定时器启动
+	deleted := testConfig("deleted")
+	deleted.StartAt = now + 1
+	if _, err := s.Add(deleted); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("deleted"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(testConfig("deleted")); err != nil {
+		t.Fatal(err)
+	}
+
+	if task, _ := s.Get("window"); task.State() != "finished" {
+		t.Fatalf("window is %s before start_at", task.State())
+	}
+	waitState(t, s, "window", "running", 3*time.Second)
+	waitState(t, s, "window", "finished", 3*time.Second)
+	task, _ := s.Get("window")
+	if reason := task.Status().StopReason; reason != StopReasonSchedule {
+		t.Fatalf("stop reason %q, want %q", reason, StopReasonSchedule)
+	}
+
+	if task, _ := s.Get("moved"); task.State() != "finished" {
+		t.Fatalf("rescheduled task is %s at its old start_at", task.State())
+	}
+	if task, _ := s.Get("deleted"); task.State() != "finished" {
+		t.Fatalf("task added again is %s at the start_at of the deleted one", task.State())
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg/parse"
+	"github.com/ZSC714725/transcodemanager/internal/webhook"
+)
+
+// SCTE-35 events, sent as webhook.Event.Event
+const (
+	EventScte35Stalled  = "scte35_stalled"  // no input markers for scte35_timeout_seconds
+	EventScte35Diverged = "scte35_diverged" // fewer markers written than read
+)
+
+// defaultScte35Timeout if scte35_timeout_seconds isn't set
+const defaultScte35Timeout = 300
+
+// scte35Lag is how many markers may be in flight between input and output
+const scte35Lag = 1
+
+// Scte35Status of a task with scte35_monitor
+type Scte35Status struct {
+	parse.Scte35
+	Stalled  bool
+	Diverged bool
+}
+
+// Scte35 returns the SCTE-35 counters, ok is false if the task doesn't
+// monitor them
+func (t *Task) Scte35() (status Scte35Status, ok bool) {
+	if !t.Config.Scte35Monitor || t.parser == nil {
+		return Scte35Status{}, false
+	}
+	return Scte35Status{
+		Scte35:   t.parser.Scte35(),
+		Stalled:  t.scte35Stalled.Load(),
+		Diverged: t.scte35Diverged.Load(),
+	}, true
+}
+
+// isMpegts reports whether an output is written by the mpegts muxer
+func isMpegts(out ConfigIO) bool {
+	if i := slices.Index(out.Options, "-f"); i >= 0 && i+1 < len(out.Options) {
+		return out.Options[i+1] == "mpegts"
+	}
+	address, _, _ := strings.Cut(out.Address, "?")
+	switch strings.ToLower(path.Ext(address)) {
+	case ".ts", ".m2ts", ".mts":
+		return true
+	}
+	return false
+}
+
+// mapsData reports whether the options map data streams. FFmpeg's default
+// stream selection never picks them.
+func mapsData(options []string) bool {
+	for i := 0; i+1 < len(options); i++ {
+		if options[i] != "-map" || strings.HasPrefix(options[i+1], "-") {
+			continue
+		}
+		_, spec, found := strings.Cut(options[i+1], ":")
+		// 整个输入，数据流，或无法判断类型的流序号
+		if !found || strings.HasPrefix(spec, "d") || (spec != "" && spec[0] >= '0' && spec[0] <= '9') {
+			return true
+		}
+	}
+	return false
+}
+
+// setsDataCodec reports whether the options choose the codec of data
+// streams
+func setsDataCodec(options []string) bool {
+	for _, opt := range options {
+		switch opt {
+		case "-c", "-codec", "-c:d", "-codec:d", "-dcodec":
+			return true
+		}
+	}
+	return false
+}
+
+// validateScte35 checks that every output can carry SCTE-35
+func (c *Config) validateScte35() error {
+	if !c.Scte35Monitor {
+		return nil
+	}
+	for _, out := range c.Output {
+		if !isMpegts(out) {
+			return fmt.Errorf("%w: output %s must be MPEG-TS (-f mpegts or a .ts address)", ErrInvalidScte35, out.ID)
+		}
+		if !mapsData(out.Options) {
+			return fmt.Errorf("%w: output %s must map the data streams, e.g. -map 0 or -map 0:d?", ErrInvalidScte35, out.ID)
+		}
+	}
+	return nil
+}
+
+// scte35Options adds what the monitor needs to the command: -debug_ts for
+// the packet log lines and stream copy of the data streams. -copy_unknown
+// isn't needed, FFmpeg knows scte_35 streams.
+func (c *Config) scte35Options() {
+	if !c.Scte35Monitor {
+		return
+	}
+	c.Options = append([]string{"-debug_ts"}, c.Options...)
+	for i, out := range c.Output {
+		if !setsDataCodec(out.Options) {
+			c.Output[i].Options = append(slices.Clip(out.Options), "-c:d", "copy")
+		}
+	}
+}
+
+// scte35Monitor periodically checks the tasks that monitor SCTE-35
+func (s *store) scte35Monitor() {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.RLock()
+		tasks := make([]*Task, 0, len(s.tasks))
+		for _, t := range s.tasks {
+			if t.Config.Scte35Monitor {
+				tasks = append(tasks, t)
+			}
+		}
+		s.mu.RUnlock()
+
+		for _, t := range tasks {
+			s.checkScte35(t)
+		}
+	}
+}
+
+// checkScte35 updates the stalled and diverged flags of a task and sends
+// an event when one is raised
+func (s *store) checkScte35(t *Task) {
+	counts, ok := t.Scte35()
+	status := t.Status()
+	if !ok || status.State != "running" {
+		t.scte35Stalled.Store(false)
+		t.scte35Diverged.Store(false)
+		return
+	}
+
+	timeout := time.Duration(t.Config.Normalized().Scte35Timeout) * time.Second
+	last := counts.LastIn
+	if last.IsZero() {
+		// 本次运行尚未收到，从进入 running 起计时
+		last = status.Time
+	}
+	stalled := time.Since(last) > timeout
+	diverged := counts.In > counts.Out+scte35Lag
+
+	if stalled && !t.scte35Stalled.Swap(true) {
+		s.scte35Event(t, EventScte35Stalled, fmt.Sprintf("no SCTE-35 input for %s", timeout))
+	} else if !stalled {
+		t.scte35Stalled.Store(false)
+	}
+	if diverged && !t.scte35Diverged.Swap(true) {
+		s.scte35Event(t, EventScte35Diverged, fmt.Sprintf("%d SCTE-35 packets read, %d written", counts.In, counts.Out))
+	} else if !diverged {
+		t.scte35Diverged.Store(false)
+	}
+}
+
+func (s *store) scte35Event(t *Task, event, detail string) {
+	s.logger.Error("task %s: %s", t.ID, detail)
+	if s.config.Notifier != nil {
+		s.config.Notifier.Notify(webhook.Event{
+			ID:        t.ID,
+			Reference: t.Reference,
+			Event:     event,
+			Detail:    detail,
+			Timestamp: time.Now().Unix(),
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/process"
+)
+
+// StopReasonShutdown is the stop reason of tasks stopped by StopAll
+const StopReasonShutdown = "shutdown"
+
+// killMargin is the time left to a SIGKILLed process to exit before the
+// StopAll deadline
+const killMargin = time.Second
+
+// Shutdown drops the queue, prevents further starts and stops all tasks,
+// see StopAll
+func (s *store) Shutdown(ctx context.Context) []string {
+	s.closing.Store(true)
+	return s.StopAll(ctx)
+}
+
+func (s *store) StopAll(ctx context.Context) []string {
+	s.mu.RLock()
+	tasks := make([]*Task, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		tasks = append(tasks, t)
+	}
+	s.mu.RUnlock()
+
+	// FFmpeg 在截止时间前未退出则强制结束
+	var killAfter time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		killAfter = max(time.Until(deadline)-killMargin, 100*time.Millisecond)
+	}
+
+	var killed []string
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	pending := make(map[string]bool)
+	for _, t := range tasks {
+		s.dequeue(t)
+		// 等待重连的任务也要停止，否则重连会在退出后启动 FFmpeg
+		status := t.Status()
+		active := t.proc.IsRunning() || status.State == "paused"
+		if !active && status.Order != "start" {
+			continue
+		}
+		lock.Lock()
+		pending[t.ID] = true
+		lock.Unlock()
+		wg.Add(1)
+		go func(t *Task) {
+			defer wg.Done()
+			t.proc.StopWith(process.StopOptions{Wait: true, Reason: StopReasonShutdown, KillAfter: killAfter})
+
+			lock.Lock()
+			defer lock.Unlock()
+			delete(pending, t.ID)
+			if active && t.Status().State == "killed" {
+				killed = append(killed, t.ID)
+			}
+		}(t)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	// 截止时仍未退出的任务同样计入
+	for id := range pending {
+		killed = append(killed, id)
+	}
+	sort.Strings(killed)
+	return killed
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package task
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+)
+
+func TestShutdown(t *testing.T) {
+	s := newTestStore(t, StoreConfig{MaxConcurrent: 1})
+	for _, id := range []string{"running", "paused", "queued"} {
+		if _, err := s.Add(testConfig(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	running, _ := s.Get("running")
+	paused, _ := s.Get("paused")
+	queued, _ := s.Get("queued")
+
+	// 暂停的任务不占名额
+	if err := s.Start("paused"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "paused to run", func() bool { return paused.State() == "running" })
+	if err := s.Pause("paused"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start("running"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "running to run", func() bool { return running.State() == "running" })
+	if err := s.Start("queued"); err != nil {
+		t.Fatal(err)
+	}
+	if state := queued.State(); state != "queued" {
+		t.Fatalf("queued is %s, want queued", state)
+	}
+
+	if killed := s.Shutdown(context.Background()); len(killed) != 0 {
+		t.Fatalf("killed %v, want none", killed)
+	}
+	for _, task := range []*Task{running, paused, queued} {
+		if status := task.Status(); status.Order != "stop" || task.IsRunning() || status.State == "paused" {
+			t.Fatalf("%s is %s with order %s after shutdown", task.ID, status.State, status.Order)
+		}
+	}
+	for _, task := range []*Task{running, paused} {
+		if reason := task.Status().StopReason; reason != StopReasonShutdown {
+			t.Fatalf("%s stop reason %q, want %s", task.ID, reason, StopReasonShutdown)
+		}
+	}
+	if queued.IsQueued() || queued.Status().States.Running != 0 {
+		t.Fatal("queued task ran or is still queued after shutdown")
+	}
+	if err := s.Start("queued"); err != ErrShuttingDown {
+		t.Fatalf("start after shutdown: %v, want %v", err, ErrShuttingDown)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	tmp := t.TempDir()
+	binary := filepath.Join(tmp, "ffmpeg")
+	// 任务忽略 SIGINT，只能在截止前被强制结束
+	writeScript(t, binary, `case " $* " in *" -i "*) trap '' INT; while :; do sleep 0.05; done;; esac
+echo "ffmpeg version 6.0"`)
+	ff, err := ffmpeg.New(ffmpeg.Config{Binary: binary})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(ff, logger.New("", logger.LevelError), StoreConfig{})
+	if _, err := s.Add(testConfig("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start("a"); err != nil {
+		t.Fatal(err)
+	}
+	task, _ := s.Get("a")
+	waitFor(t, "a to run", func() bool { return task.State() == "running" })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	killed := s.Shutdown(ctx)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("shutdown took %s with a 500ms deadline", elapsed)
+	}
+	if len(killed) != 1 || killed[0] != "a" {
+		t.Fatalf("killed %v, want [a]", killed)
+	}
+	waitFor(t, "a to exit", func() bool { return !task.IsRunning() })
+}
+
+func TestShutdownReconnecting(t *testing.T) {
+	tmp := t.TempDir()
+	runs := filepath.Join(tmp, "runs")
+	binary := filepath.Join(tmp, "ffmpeg")
+	// 任务的每次运行都失败，能力探测只看到版本
+	writeScript(t, binary, `case " $* " in *" -i "*) echo run >> `+runs+`; exit 1;; esac
+echo "ffmpeg version 6.0"`)
+	ff, err := ffmpeg.New(ffmpeg.Config{Binary: binary})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(ff, logger.New("", logger.LevelError), StoreConfig{})
+
+	config := testConfig("a")
+	config.Reconnect = true
+	config.ReconnectDelay = 1
+	if _, err := s.Add(config); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start("a"); err != nil {
+		t.Fatal(err)
+	}
+	task, _ := s.Get("a")
+	waitFor(t, "a to wait for a reconnect", func() bool { return task.Status().Reconnect > 0 })
+
+	if killed := s.Shutdown(context.Background()); len(killed) != 0 {
+		t.Fatalf("killed %v, want none", killed)
+	}
+	if status := task.Status(); status.Order != "stop" || status.Reconnect != -1 {
+		t.Fatalf("order %s, reconnect in %v after shutdown, want stop and none", status.Order, status.Reconnect)
+	}
+
+	// 原本的重连时间过后也没有再次运行
+	time.Sleep(1500 * time.Millisecond)
+	if data, _ := os.ReadFile(runs); strings.Count(string(data), "run") != 1 {
+		t.Fatalf("runs %q, want a single one", data)
+	}
+}
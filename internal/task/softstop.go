@@ -0,0 +1,215 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/process"
+)
+
+// Stop modes
+const (
+	StopNormal = "normal" // SIGINT, FFmpeg finishes its outputs
+	StopSoft   = "soft"   // wait for the next HLS segment, then like normal
+	StopKill   = "kill"   // SIGKILL
+)
+
+// defaultSoftStopTimeout if SoftStop.Timeout isn't set
+const defaultSoftStopTimeout = 10 * time.Second
+
+// SoftStop of an HLS output
+type SoftStop struct {
+	// Timeout is the longest wait for the segment boundary, in seconds
+	Timeout uint64 `json:"timeout_seconds"`
+	// Endlist appends #EXT-X-ENDLIST to the playlist if FFmpeg didn't
+	Endlist bool `json:"endlist"`
+}
+
+// ValidStopMode reports whether mode is a stop mode, "" is normal
+func ValidStopMode(mode string) bool {
+	return mode == "" || mode == StopNormal || mode == StopSoft || mode == StopKill
+}
+
+// isHLS reports whether an output is written by the hls muxer
+func isHLS(out ConfigIO) bool {
+	if i := slices.Index(out.Options, "-f"); i >= 0 && i+1 < len(out.Options) {
+		return out.Options[i+1] == "hls"
+	}
+	return strings.HasSuffix(out.Address, ".m3u8")
+}
+
+// validateSoftStop checks that soft_stop is only set on HLS outputs
+func (c *Config) validateSoftStop() error {
+	for _, in := range c.Input {
+		if in.SoftStop != nil {
+			return ErrInvalidSoftStop
+		}
+	}
+	for _, out := range c.Output {
+		if out.SoftStop != nil && !isHLS(out) {
+			return ErrInvalidSoftStop
+		}
+	}
+	return nil
+}
+
+// softStopTimeout returns the longest wait of the soft stop outputs, 0 if
+// there are none
+func (c *Config) softStopTimeout() time.Duration {
+	var timeout time.Duration
+	for _, out := range c.Output {
+		if out.SoftStop == nil {
+			continue
+		}
+		d := time.Duration(out.SoftStop.Timeout) * time.Second
+		if d == 0 {
+			d = defaultSoftStopTimeout
+		}
+		timeout = max(timeout, d)
+	}
+	return timeout
+}
+
+// StopMode stops a task. The stop reason of the run is "stop_" and the mode,
+// "stop_soft_timeout" if a soft stop didn't see a segment boundary in time.
+func (s *store) StopMode(id, mode string) error {
+	if !ValidStopMode(mode) {
+		return ErrInvalidStopMode
+	}
+	if mode == "" {
+		mode = StopNormal
+	}
+
+	t, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	s.dequeue(t)
+
+	s.mu.RLock()
+	config, proc, parser := t.Config, t.proc, t.parser
+	s.mu.RUnlock()
+
+	opts := process.StopOptions{Wait: true, Kill: mode == StopKill, Reason: "stop_" + mode}
+	if mode == StopSoft && proc.Status().State == "running" {
+		if timeout := config.softStopTimeout(); timeout > 0 && !waitSegment(proc, parser.Segments, timeout) {
+			opts.Reason = "stop_soft_timeout"
+			s.logger.Error("task %s soft stop: no segment boundary in %s", id, timeout)
+		}
+	}
+
+	if err := proc.StopWith(opts); err != nil {
+		return err
+	}
+	if mode == StopSoft {
+		s.appendEndlist(id, config)
+	}
+	return nil
+}
+
+// restartExitTimeout bounds the wait for the old process of a restart to
+// exit, after SIGINT FFmpeg is killed within seconds
+const restartExitTimeout = 15 * time.Second
+
+// RestartMode restarts a task, stopping it like StopMode. Unlike Restart it
+// waits until the old process has exited and only starts the task again if
+// the stop succeeded and the process ended finished, or killed in mode kill.
+// Otherwise the task stays stopped and ErrUncleanStop is returned.
+func (s *store) RestartMode(id, mode string) error {
+	t, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	s.mu.RLock()
+	proc := t.proc
+	s.mu.RUnlock()
+
+	state := proc.Status().State
+	active := state == "starting" || state == "running" || state == "finishing" || state == "paused"
+
+	if err := s.StopMode(id, mode); err != nil {
+		return err
+	}
+	// 已在 finishing 时 Stop 不等待退出
+	deadline := time.Now().Add(restartExitTimeout)
+	for state = proc.Status().State; state == "finishing"; state = proc.Status().State {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w: still finishing after %s", ErrUncleanStop, restartExitTimeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if active && state != "finished" && !(mode == StopKill && state == "killed") {
+		return fmt.Errorf("%w: ended %s", ErrUncleanStop, state)
+	}
+	return s.start(t)
+}
+
+// waitSegment waits until a new segment is opened, the process exits or
+// the timeout passes. It reports whether a segment was opened.
+func waitSegment(proc process.Process, segments func() uint64, timeout time.Duration) bool {
+	start := segments()
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-deadline:
+			return false
+		case <-ticker.C:
+			if segments() > start {
+				return true
+			}
+			if !proc.IsRunning() {
+				return false
+			}
+		}
+	}
+}
+
+// appendEndlist ends the playlists of the soft stop outputs that asked for
+// it. Only local playlists can be changed.
+func (s *store) appendEndlist(id string, config *Config) {
+	config, _ = config.Expand()
+	for _, out := range config.Output {
+		if out.SoftStop == nil || !out.SoftStop.Endlist {
+			continue
+		}
+		path, ok := localPath(out.Address)
+		if !ok || !strings.HasSuffix(path, ".m3u8") {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			s.logger.Error("task %s soft stop: %v", id, err)
+			continue
+		}
+		if bytes.Contains(data, []byte("#EXT-X-ENDLIST")) {
+			continue
+		}
+
+		end := "#EXT-X-ENDLIST\n"
+		if len(data) != 0 && data[len(data)-1] != '\n' {
+			end = "\n" + end
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err == nil {
+			_, err = f.WriteString(end)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			s.logger.Error("task %s soft stop: append endlist: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package task
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+)
+
+// newRestartStore runs a fake FFmpeg that logs its starts and exits to
+// the returned file and on SIGINT runs onInt after a while
+func newRestartStore(t *testing.T, onInt string) (Store, string) {
+	t.Helper()
+	tmp := t.TempDir()
+	events := filepath.Join(tmp, "events")
+	binary := filepath.Join(tmp, "ffmpeg")
+	writeScript(t, binary, `case " $* " in *" -i "*)
+	trap 'sleep 0.3; echo exit >> `+events+`; `+onInt+`' INT
+	echo start >> `+events+`
+	while :; do sleep 0.05 & wait $!; done;;
+esac
+echo "ffmpeg version 6.0"`)
+	ff, err := ffmpeg.New(ffmpeg.Config{Binary: binary})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(ff, logger.New("", logger.LevelError), StoreConfig{})
+	if _, err := s.Add(testConfig("a")); err != nil {
+		t.Fatal(err)
+	}
+	return s, events
+}
+
+// readEvents returns the lines of the events file
+func readEvents(path string) []string {
+	data, _ := os.ReadFile(path)
+	return strings.Fields(string(data))
+}
+
+func TestRestartWaitsForExit(t *testing.T) {
+	s, events := newRestartStore(t, "exit 0")
+	t.Cleanup(func() { s.StopMode("a", StopKill) })
+	if err := s.Start("a"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the first start", func() bool { return len(readEvents(events)) == 1 })
+
+	if err := s.RestartMode("a", StopNormal); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the second start", func() bool { return len(readEvents(events)) == 3 })
+	// 第一次运行完全退出后才再次启动
+	if got := strings.Join(readEvents(events), " "); got != "start exit start" {
+		t.Fatalf("events %q, want start exit start", got)
+	}
+}
+
+func TestRestartUnclean(t *testing.T) {
+	s, events := newRestartStore(t, "kill -9 $$")
+	t.Cleanup(func() { s.StopMode("a", StopKill) })
+	if err := s.Start("a"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the first start", func() bool { return len(readEvents(events)) == 1 })
+
+	if err := s.RestartMode("a", StopNormal); !errors.Is(err, ErrUncleanStop) {
+		t.Fatalf("restart after a killed exit: %v, want %v", err, ErrUncleanStop)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if got := strings.Join(readEvents(events), " "); got != "start exit" {
+		t.Fatalf("events %q, want start exit and no restart", got)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package task
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+)
+
+func TestStopSignal(t *testing.T) {
+	tmp := t.TempDir()
+	binary := filepath.Join(tmp, "ffmpeg")
+	// 输出地址即标记文件，收到哪个信号就写入哪个；就绪后才能停止
+	writeScript(t, binary, `case " $* " in *" -i "*)
+	for a; do out=$a; done
+	trap 'echo int > $out; exit 0' INT
+	trap 'echo term > $out; exit 0' TERM
+	trap 'echo quit > $out; exit 0' QUIT
+	echo ready > $out.ready
+	while :; do sleep 0.05 & wait $!; done;;
+esac
+echo "ffmpeg version 6.0"`)
+	ff, err := ffmpeg.New(ffmpeg.Config{Binary: binary})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(ff, logger.New("", logger.LevelError), StoreConfig{})
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+
+	tests := []struct{ name, signal, want string }{
+		{"default", "", "int"},
+		{"int", "int", "int"},
+		{"term", "term", "term"},
+		{"quit", "quit", "quit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := "stop-" + tt.name
+			marker := filepath.Join(tmp, id)
+			config := testConfig(id)
+			config.Output[0].Address = marker
+			config.StopSignal = tt.signal
+			if _, err := s.Add(config); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Start(id); err != nil {
+				t.Fatal(err)
+			}
+			waitFor(t, "the traps", func() bool {
+				_, err := os.Stat(marker + ".ready")
+				return err == nil
+			})
+
+			if err := s.Stop(id); err != nil {
+				t.Fatal(err)
+			}
+			var got []byte
+			waitFor(t, "the marker", func() bool {
+				got, _ = os.ReadFile(marker)
+				return len(got) != 0
+			})
+			if string(got) != tt.want+"\n" {
+				t.Fatalf("received %q, want %s", got, tt.want)
+			}
+		})
+	}
+
+	config := testConfig("hup")
+	config.StopSignal = "hup"
+	if _, err := s.Add(config); !errors.Is(err, ErrInvalidStopSignal) {
+		t.Fatalf("stop_signal hup: %v, want %v", err, ErrInvalidStopSignal)
+	}
+}
@@ -6,15 +6,26 @@
 package task
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"slices"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
 	"github.com/ZSC714725/transcodemanager/internal/ffmpeg/parse"
+	"github.com/ZSC714725/transcodemanager/internal/hook"
 	"github.com/ZSC714725/transcodemanager/internal/logger"
+	"github.com/ZSC714725/transcodemanager/internal/mqtt"
 	"github.com/ZSC714725/transcodemanager/internal/process"
+	"github.com/ZSC714725/transcodemanager/internal/webhook"
 
 	"github.com/lithammer/shortuuid/v4"
+	"github.com/robfig/cron/v3"
 )
 
 // Task is a transcoding task
@@ -28,6 +39,48 @@ type Task struct {
 
 	proc   process.Process
 	parser parse.Parser
+	// queuePos is the 1-based position in the start queue, 0 if the task
+	// isn't queued
+	queuePos atomic.Int32
+	priority atomic.Int64 // Config.Priority, read by the queue
+	// preempted is set while the task is stopped for a higher priority one
+	preempted atomic.Bool
+
+	debugReport atomic.Bool                         // Config.DebugReport, read before every run
+	hooks       atomic.Pointer[map[string][]string] // Config.Hooks, read on every event
+
+	// scte35Stalled and scte35Diverged are raised by the SCTE-35 monitor
+	scte35Stalled  atomic.Bool
+	scte35Diverged atomic.Bool
+
+	probe       []InputProbe // cached result for probeConfig
+	probeConfig *Config
+	probeTime   time.Time
+	probeLock   sync.Mutex
+
+	testLock sync.Mutex // held during a test run
+
+	seq atomic.Uint64 // runs with run placeholders, for {seq}
+
+	checks     []Check // of the last start
+	checksLock sync.Mutex
+
+	history     []Run // completed runs, oldest first
+	runs        uint64
+	runStart    time.Time
+	historyLock sync.Mutex
+
+	transitions     []Transition // state changes, oldest first
+	transitionsLock sync.Mutex
+
+	metadata     map[string]json.RawMessage // set through the API, kept on update
+	metadataLock sync.RWMutex
+
+	// startTimer and stopTimer fire at Config.StartAt and StopAt, cronEntry
+	// runs Config.Schedule. They are guarded by the store lock.
+	startTimer *time.Timer
+	stopTimer  *time.Timer
+	cronEntry  cron.EntryID
 }
 
 // Status returns process status
@@ -35,6 +88,15 @@ func (t *Task) Status() process.Status {
 	return t.proc.Status()
 }
 
+// State returns the process state, or queued while the task waits in the
+// start queue
+func (t *Task) State() string {
+	if t.IsQueued() {
+		return "queued"
+	}
+	return t.Status().State
+}
+
 // Progress returns parsed FFmpeg progress
 func (t *Task) Progress() parse.Progress {
 	if t.parser == nil {
@@ -43,6 +105,56 @@ func (t *Task) Progress() parse.Progress {
 	return t.parser.Progress()
 }
 
+// Outputs returns parsed per-output progress, keyed by output index
+func (t *Task) Outputs() map[int]parse.Progress {
+	if t.parser == nil {
+		return nil
+	}
+	return t.parser.Outputs()
+}
+
+// LastError returns the most recent classified FFmpeg error, nil if none
+func (t *Task) LastError() *parse.Error {
+	if t.parser == nil {
+		return nil
+	}
+	return t.parser.LastError()
+}
+
+// LastLog returns the most recent non-progress log line
+func (t *Task) LastLog() string {
+	if t.parser == nil {
+		return ""
+	}
+	return t.parser.LastLine()
+}
+
+// Prelude returns what FFmpeg printed before the first progress line of the
+// current or last run
+func (t *Task) Prelude() []string {
+	if t.parser == nil {
+		return nil
+	}
+	return t.parser.Prelude()
+}
+
+// ProgressHistory returns the progress samples of the current run, oldest
+// first
+func (t *Task) ProgressHistory() []parse.ProgressSample {
+	if t.parser == nil {
+		return nil
+	}
+	return t.parser.ProgressHistory()
+}
+
+// LogFiles returns the task's log files, oldest first
+func (t *Task) LogFiles() []string {
+	if t.parser == nil {
+		return nil
+	}
+	return t.parser.LogFiles()
+}
+
 // Log returns process log lines
 func (t *Task) Log() []process.Line {
 	if t.parser == nil {
@@ -51,37 +163,221 @@ func (t *Task) Log() []process.Line {
 	return t.parser.Log()
 }
 
+// LogAfter returns the log lines after a sequence number, see
+// parse.Parser.LogAfter
+func (t *Task) LogAfter(after uint64, limit int) ([]process.Line, bool) {
+	if t.parser == nil {
+		return nil, false
+	}
+	return t.parser.LogAfter(after, limit)
+}
+
+// Memory returns the approximate bytes held by the task's in-memory buffers:
+// the log and progress samples of its parser, its run history and its
+// state transitions
+func (t *Task) Memory() uint64 {
+	n := t.historyMemory() + t.transitionsMemory()
+	if t.parser != nil {
+		n += t.parser.Memory()
+	}
+	return n
+}
+
+// FailedOutputs returns the isolated outputs that failed in the current
+// run, keyed by output ID, with FFmpeg's error
+func (t *Task) FailedOutputs() map[string]string {
+	if t.parser == nil {
+		return nil
+	}
+	failures := t.parser.Failures()
+	if len(failures) == 0 {
+		return nil
+	}
+	ids := t.Config.isolatedOutputs()
+	out := make(map[string]string, len(failures))
+	for slave, msg := range failures {
+		if slave < len(ids) {
+			out[ids[slave]] = msg
+		}
+	}
+	return out
+}
+
+// Checks returns the results of the last pre-start checks
+func (t *Task) Checks() []Check {
+	t.checksLock.Lock()
+	defer t.checksLock.Unlock()
+	return append([]Check(nil), t.checks...)
+}
+
 // IsRunning returns whether the process is running
 func (t *Task) IsRunning() bool {
 	return t.proc.IsRunning()
 }
 
+// IsQueued returns whether the task waits for a free concurrency slot
+func (t *Task) IsQueued() bool {
+	return t.queuePos.Load() > 0
+}
+
+// QueuePosition returns the 1-based position of a queued task, 0 if it
+// isn't queued
+func (t *Task) QueuePosition() int {
+	return int(t.queuePos.Load())
+}
+
 // Store manages tasks in memory
 type Store interface {
 	Add(config *Config) (*Task, error)
 	Get(id string) (*Task, error)
-	List(ids []string, reference string) []*Task
-	Update(id string, config *Config) (*Task, error)
+	List(filter ListFilter) ([]*Task, int)
+	// Update replaces the config and restarts the task if it was running.
+	// changed is false if the config is equivalent to the current one, the
+	// task is left untouched then. A change of DebugReport or of the
+	// schedule alone takes effect without a restart.
+	Update(id string, config *Config) (t *Task, changed bool, err error)
 	Delete(id string) error
+	// Ping takes and releases the store lock, it blocks while the lock is held
+	Ping()
+	// Validate checks a config and builds its command without adding a task
+	Validate(config *Config) (Validation, error)
+	// DryRun checks a config exactly like Add, without adding a task, and
+	// returns its command with the warnings of Validate
+	DryRun(config *Config) (Validation, error)
 	Start(id string) error
 	Stop(id string) error
+	// StopMode stops a task with a stop mode, see StopNormal, StopSoft and StopKill
+	StopMode(id, mode string) error
 	Restart(id string) error
+	// RestartMode restarts a task after it has exited cleanly, see
+	// StopMode for the modes
+	RestartMode(id, mode string) error
+	Pause(id string) error
+	Resume(id string) error
+	// Probe runs ffprobe on each input of a task with its input options.
+	// The result is cached for a short time, until the task is updated or
+	// refresh is set.
+	Probe(ctx context.Context, id string, refresh bool) ([]InputProbe, error)
+	// Test runs the command of a task for one second with the outputs
+	// replaced by the null muxer, to find errors e.g. in filters before
+	// the task is started. The task itself is not affected.
+	Test(ctx context.Context, id string) (TestResult, error)
+	MemoryUsage() MemoryUsage
+	// StopAll stops all running tasks concurrently and waits for them to
+	// exit. Tasks that haven't exited shortly before ctx's deadline are
+	// killed. It returns the IDs of the killed tasks.
+	StopAll(ctx context.Context) []string
+	// Shutdown is StopAll, afterwards queued tasks are dropped and starts
+	// fail with ErrShuttingDown
+	Shutdown(ctx context.Context) []string
+	// ReportFiles returns the FFmpeg reports of a task, oldest first
+	ReportFiles(id string) ([]ReportFile, error)
+}
+
+// ListFilter selects, sorts and pages tasks
+type ListFilter struct {
+	IDs       []string
+	Reference string
+	States    []string // as in the API, including queued
+	Sort      string   // id, created_at, updated_at, state, cpu, memory; default created_at
+	Desc      bool
+	Offset    int
+	Limit     int // 0 means no limit
+}
+
+// StoreConfig for the task store
+type StoreConfig struct {
+	// MemoryBudget caps the bytes held by all tasks' buffers. 0 disables it.
+	MemoryBudget uint64
+	// MaxConcurrent caps the number of running tasks, further starts are
+	// queued by priority. 0 disables it.
+	MaxConcurrent int
+	// Preempt stops the lowest priority running task when a task with a
+	// higher priority is queued, and queues it again
+	Preempt bool
+	// Notifier receives every state change, optional
+	Notifier webhook.Notifier
+	// MQTT receives every state change and, every MQTTInterval, the
+	// progress of the running tasks, optional
+	MQTT         mqtt.Publisher
+	MQTTInterval time.Duration
+	// Hooks runs the hook commands of task events, optional
+	Hooks hook.Runner
+	// ValidateProtocols rejects addresses with a protocol that isn't in the
+	// skills of the task's binary
+	ValidateProtocols bool
+	// ValidateEncoders rejects options that select an encoder that isn't in
+	// the skills of the task's binary, otherwise they are only logged
+	ValidateEncoders bool
+	// ReportDir holds the FFmpeg reports of tasks with DebugReport, in a
+	// directory per task. Empty disables DebugReport.
+	ReportDir string
+	// ReportKeep is the number of reports kept per task, default 5
+	ReportKeep int
+	// HistoryRuns is the number of completed runs whose log is kept per
+	// task, default 5. Negative disables the history.
+	HistoryRuns int
+	// Credentials are substituted for {secret:name} in the FFmpeg command,
+	// optional
+	Credentials CredentialStore
+}
+
+// MemoryUsage of the in-memory task buffers
+type MemoryUsage struct {
+	Total   uint64
+	Budget  uint64
+	Trimmed uint64 // number of times a task buffer was shrunk
 }
 
+// minLogLines is the smallest log buffer the memory budget may shrink to
+const minLogLines = 10
+
+// maxLogLines caps Config.LogLines
+const maxLogLines = 100000
+
 type store struct {
 	ffmpeg ffmpeg.FFmpeg
 	logger logger.Logger
+	config StoreConfig
 	tasks  map[string]*Task
 	mu     sync.RWMutex
+
+	// queue is locked after mu, never before
+	queue     []*Task
+	queueLock sync.Mutex
+	// startLock makes counting the running tasks and starting or queueing
+	// one a single step, see start. It is locked before mu.
+	startLock sync.Mutex
+
+	trimmed uint64
+	closing atomic.Bool // set by Shutdown
+
+	cron *cron.Cron // runs the Schedule of tasks
 }
 
 // NewStore creates a task store
-func NewStore(ff ffmpeg.FFmpeg, log logger.Logger) Store {
-	return &store{
+func NewStore(ff ffmpeg.FFmpeg, log logger.Logger, config StoreConfig) Store {
+	s := &store{
 		ffmpeg: ff,
 		logger: log,
+		config: config,
 		tasks:  make(map[string]*Task),
+		cron:   cron.New(),
+	}
+	if s.config.ReportKeep <= 0 {
+		s.config.ReportKeep = 5
+	}
+
+	if config.MemoryBudget > 0 {
+		go s.budgeter()
+	}
+	if config.MQTT != nil && config.MQTTInterval > 0 {
+		go s.mqttProgress()
 	}
+	go s.scte35Monitor()
+	s.cron.Start()
+
+	return s
 }
 
 func (s *store) Add(config *Config) (*Task, error) {
@@ -95,17 +391,10 @@ func (s *store) Add(config *Config) (*Task, error) {
 		return nil, ErrInvalidConfig
 	}
 
-	// Validate addresses
-	for _, in := range config.Input {
-		if !s.ffmpeg.ValidateInput(in.Address) {
-			return nil, ErrInvalidInputAddress
-		}
-	}
-	for _, out := range config.Output {
-		if !s.ffmpeg.ValidateOutput(out.Address) {
-			return nil, ErrInvalidOutputAddress
-		}
+	if err := s.validate(config); err != nil {
+		return nil, err
 	}
+	s.warnInPlace(config)
 
 	if _, exists := s.tasks[config.ID]; exists {
 		return nil, ErrTaskExists
@@ -120,37 +409,218 @@ func (s *store) Add(config *Config) (*Task, error) {
 		UpdatedAt: now,
 		Order:     "stop",
 	}
+	task.debugReport.Store(config.DebugReport)
+	task.hooks.Store(&config.Hooks)
+	task.priority.Store(int64(config.Priority))
 
-	parser := s.ffmpeg.NewParser(s.logger, config.ID, config.Reference)
-
-	proc, err := s.ffmpeg.New(ffmpeg.ProcessConfig{
-		Reconnect:      config.Reconnect,
-		ReconnectDelay: time.Duration(config.ReconnectDelay) * time.Second,
-		StaleTimeout:   time.Duration(config.StaleTimeout) * time.Second,
-		Command:        config.CreateCommand(),
-		Parser:         parser,
-		Logger:         s.logger,
-		OnStateChange: func(from, to string) {
-			s.logger.Info("task %s state %s -> %s", config.ID, from, to)
-		},
-	})
+	proc, parser, err := s.newProcess(task, config)
 	if err != nil {
 		return nil, err
 	}
 
 	task.proc = proc
-	task.parser = parser.(parse.Parser)
+	task.parser = parser
 
 	s.tasks[config.ID] = task
+	s.schedule(task, config)
 
-	if config.Autostart {
-		go task.proc.Start()
+	if config.Autostart && config.inWindow(time.Now()) {
+		go s.start(task)
 		task.Order = "start"
 	}
 
 	return task, nil
 }
 
+// validate checks a config before it is used for a task
+func (s *store) validate(config *Config) error {
+	if err := config.validateVariables(); err != nil {
+		return err
+	}
+	if err := s.validateCredentials(config); err != nil {
+		return err
+	}
+	// 地址与选项按替换占位符后的值校验
+	config, _ = config.Expand()
+
+	for _, in := range config.Input {
+		if !s.ffmpeg.ValidateInput(in.Address) {
+			return ErrInvalidInputAddress
+		}
+	}
+	for _, out := range config.Output {
+		if !s.ffmpeg.ValidateOutput(out.Address) {
+			return ErrInvalidOutputAddress
+		}
+	}
+	if err := validateOutputAddresses(config); err != nil {
+		return err
+	}
+	if err := config.paceOutputs(); err != nil {
+		return err
+	}
+	switch config.StaleOn {
+	case "", process.StaleOnOutput, process.StaleOnMediaTime:
+	default:
+		return ErrInvalidStaleOn
+	}
+	if _, ok := stopSignals[config.StopSignal]; !ok {
+		return ErrInvalidStopSignal
+	}
+	if !process.ValidNice(config.Nice) {
+		return ErrInvalidNice
+	}
+	if !process.ValidIOClass(config.IONiceClass) {
+		return ErrInvalidIOClass
+	}
+	if config.LogLines < 0 || config.LogLines > maxLogLines {
+		return ErrInvalidLogLines
+	}
+	if err := s.validateHooks(config); err != nil {
+		return err
+	}
+	if s.ffmpeg.Binary(config.Binary) == "" {
+		return fmt.Errorf("%w: %s", ErrUnknownBinary, config.Binary)
+	}
+	switch config.PreChecks {
+	case PreChecksOff, PreChecksWarn, PreChecksAbort:
+	default:
+		return ErrInvalidPreChecks
+	}
+	if err := config.validateEnv(); err != nil {
+		return err
+	}
+	if err := config.validateTimezone(); err != nil {
+		return err
+	}
+	if err := config.validateSchedule(); err != nil {
+		return err
+	}
+	if err := config.validateSoftStop(); err != nil {
+		return err
+	}
+	if err := config.validateScte35(); err != nil {
+		return err
+	}
+	if config.DebugReport && s.config.ReportDir == "" {
+		return ErrReportDisabled
+	}
+	if config.ProgressPipe && runtime.GOOS == "windows" {
+		return ErrProgressPipeUnsupported
+	}
+	if !config.SkipValidation {
+		sk, _ := s.ffmpeg.BinarySkills(config.Binary)
+		if err := validateEncoders(sk, config); err != nil {
+			if s.config.ValidateEncoders {
+				return err
+			}
+			// 自行编译的 FFmpeg 可能有能力列表之外的编码器
+			s.logger.Error("task %s: %s, ffmpeg.validate_encoders is off", config.ID, err)
+		}
+		if err := validateMuxers(sk, config); err != nil {
+			return err
+		}
+		if s.config.ValidateProtocols {
+			if err := validateProtocols(sk, config); err != nil {
+				return err
+			}
+		}
+	}
+	return config.validateTee()
+}
+
+// newProcess creates the process and parser of a task for a config
+func (s *store) newProcess(t *Task, config *Config) (process.Process, parse.Parser, error) {
+	id := config.ID
+	parser := s.ffmpeg.NewParser(s.logger, id, config.Reference, config.LogLines)
+
+	var procParser process.Parser = parser
+	var runArgs func([]string) ([]string, error)
+	if len(config.Credentials()) != 0 {
+		// 凭据在每次运行前代入，FFmpeg 输出中的值替换回占位符
+		r := &secrets{store: s.config.Credentials}
+		procParser = maskingParser{Parser: parser, secrets: r}
+		runArgs = r.resolve
+	}
+	if config.hasRunPlaceholders() {
+		// 每次运行（含自动重连）前替换 {date}、{time}、{seq}，先于凭据
+		resolve, loc := runArgs, config.location()
+		runArgs = func(args []string) ([]string, error) {
+			args = expandRun(args, time.Now().In(loc), t.seq.Add(1))
+			if resolve == nil {
+				return args, nil
+			}
+			return resolve(args)
+		}
+	}
+
+	// 回调中读本次运行的进程，更新后 t.proc 可能已是新进程
+	var proc process.Process
+	proc, err := s.ffmpeg.New(ffmpeg.ProcessConfig{
+		ID:                id,
+		Reference:         config.Reference,
+		Binary:            config.Binary,
+		Reconnect:         config.Reconnect,
+		ReconnectDelay:    time.Duration(config.ReconnectDelay) * time.Second,
+		ReconnectBackoff:  config.ReconnectBackoff,
+		ReconnectMaxDelay: time.Duration(config.ReconnectMaxDelay) * time.Second,
+		MaxReconnects:     config.MaxReconnects,
+		StaleTimeout:      time.Duration(config.StaleTimeout) * time.Second,
+		StaleOn:           config.StaleOn,
+		RuntimeLimit:      time.Duration(config.RuntimeLimit) * time.Second,
+		StopSignal:        stopSignals[config.StopSignal],
+		Nice:              config.Nice,
+		IOClass:           config.IONiceClass,
+		ProgressPipe:      config.ProgressPipe,
+		Stdin:             config.Stdin,
+		Env:               config.Environ(),
+		RunEnv:            func() []string { return s.reportEnv(t) },
+		Command:           config.CreateCommand(),
+		RunArgs:           runArgs,
+		Parser:            procParser,
+		Logger:            s.logger,
+		OnExit: func() {
+			// Stop(true) 会等待 OnExit，调用方可能持有 s.mu
+			go s.promote()
+		},
+		OnStateChange: func(from, to string) {
+			s.logger.Info("task %s state %s -> %s", id, from, to)
+			status := proc.Status()
+			s.recordRun(t, parser, status, to)
+			t.recordTransition(parser, status, from, to)
+			s.runHooks(t, procParser, to)
+			if s.config.Notifier != nil {
+				e := webhook.Event{
+					ID:        id,
+					Reference: config.Reference,
+					From:      from,
+					To:        to,
+					Timestamp: time.Now().Unix(),
+				}
+				switch to {
+				case "finished", "failed", "killed":
+					e.StoppedBy = status.StoppedBy
+				}
+				s.config.Notifier.Notify(e)
+			}
+			// 删除时已清除保留消息，不再发布
+			if s.config.MQTT != nil && s.live(t) {
+				m := mqtt.State{ID: id, Reference: config.Reference, From: from, To: to, Timestamp: time.Now().Unix()}
+				switch to {
+				case "finished", "failed", "killed":
+					m.StoppedBy = status.StoppedBy
+				}
+				s.config.MQTT.State(m)
+			}
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return proc, parser, nil
+}
+
 func (s *store) Get(id string) (*Task, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -162,18 +632,23 @@ func (s *store) Get(id string) (*Task, error) {
 	return t, nil
 }
 
-func (s *store) List(ids []string, reference string) []*Task {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// List returns the tasks matching the filter and the total number of matches
+// before paging
+func (s *store) List(filter ListFilter) ([]*Task, int) {
+	less, err := taskLess(filter.Sort)
+	if err != nil {
+		return nil, 0
+	}
 
+	s.mu.RLock()
 	var out []*Task
 	for _, t := range s.tasks {
-		if len(reference) > 0 && t.Reference != reference {
+		if len(filter.Reference) > 0 && t.Reference != filter.Reference {
 			continue
 		}
-		if len(ids) > 0 {
+		if len(filter.IDs) > 0 {
 			found := false
-			for _, id := range ids {
+			for _, id := range filter.IDs {
 				if t.ID == id {
 					found = true
 					break
@@ -183,80 +658,180 @@ func (s *store) List(ids []string, reference string) []*Task {
 				continue
 			}
 		}
+		if len(filter.States) > 0 && !slices.Contains(filter.States, t.State()) {
+			continue
+		}
 		out = append(out, t)
 	}
-	return out
+	s.mu.RUnlock()
+
+	keys := make(map[*Task]sortKey, len(out))
+	for _, t := range out {
+		keys[t] = newSortKey(t, filter.Sort)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := keys[out[i]], keys[out[j]]
+		if filter.Desc {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.id < b.id
+	})
+
+	total := len(out)
+	if filter.Offset > 0 {
+		if filter.Offset >= len(out) {
+			return []*Task{}, total
+		}
+		out = out[filter.Offset:]
+	}
+	if filter.Limit > 0 && filter.Limit < len(out) {
+		out = out[:filter.Limit]
+	}
+	return out, total
+}
+
+// states are the values of Task.State
+var states = []string{"queued", "starting", "running", "finishing", "paused", "finished", "failed", "killed"}
+
+// ValidState reports whether state is a known task state
+func ValidState(state string) bool {
+	return slices.Contains(states, state)
+}
+
+// ValidSort reports whether key is a known sort key
+func ValidSort(key string) bool {
+	_, err := taskLess(key)
+	return err == nil
+}
+
+// sortKey snapshots the values a task is sorted by, so that status is only
+// queried once per task
+type sortKey struct {
+	id        string
+	createdAt int64
+	updatedAt int64
+	state     string
+	cpu       float64
+	memory    uint64
+}
+
+func newSortKey(t *Task, key string) sortKey {
+	k := sortKey{id: t.ID, createdAt: t.CreatedAt, updatedAt: t.UpdatedAt}
+	switch key {
+	case "state", "cpu", "memory":
+		status := t.Status()
+		k.state = status.State
+		k.cpu = status.CPU.Current
+		k.memory = status.Memory.Current
+	}
+	return k
+}
+
+func taskLess(key string) (func(a, b sortKey) bool, error) {
+	switch key {
+	case "", "created_at":
+		return func(a, b sortKey) bool { return a.createdAt < b.createdAt }, nil
+	case "updated_at":
+		return func(a, b sortKey) bool { return a.updatedAt < b.updatedAt }, nil
+	case "id":
+		return func(a, b sortKey) bool { return a.id < b.id }, nil
+	case "state":
+		return func(a, b sortKey) bool { return a.state < b.state }, nil
+	case "cpu":
+		return func(a, b sortKey) bool { return a.cpu < b.cpu }, nil
+	case "memory":
+		return func(a, b sortKey) bool { return a.memory < b.memory }, nil
+	}
+	return nil, ErrInvalidSort
 }
 
-func (s *store) Update(id string, config *Config) (*Task, error) {
+func (s *store) Update(id string, config *Config) (*Task, bool, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	t, ok := s.tasks[id]
 	if !ok {
-		return nil, ErrNotFound
-	}
-
-	wasRunning := t.proc.IsRunning()
-	if wasRunning {
-		t.proc.Stop(true)
+		return nil, false, ErrNotFound
 	}
 
 	config.ID = id
 	config.Reference = t.Reference
 
-	for _, in := range config.Input {
-		if !s.ffmpeg.ValidateInput(in.Address) {
-			return nil, ErrInvalidInputAddress
-		}
-	}
-	for _, out := range config.Output {
-		if !s.ffmpeg.ValidateOutput(out.Address) {
-			return nil, ErrInvalidOutputAddress
-		}
+	if err := s.validate(config); err != nil {
+		return nil, false, err
 	}
+	s.warnInPlace(config)
 
-	parser := s.ffmpeg.NewParser(s.logger, id, config.Reference)
+	// 配置等价时不重启任务
+	if config.Hash() == t.Config.Hash() {
+		return t, false, nil
+	}
+	// debug_report 在下一次运行时生效，priority 只影响排队，hooks 在下一个事件时生效，不需要重启
+	if config.runHash() == t.Config.runHash() {
+		t.Config = config
+		t.UpdatedAt = time.Now().Unix()
+		t.debugReport.Store(config.DebugReport)
+		t.hooks.Store(&config.Hooks)
+		s.reprioritize(t, config.Priority)
+		s.schedule(t, config)
+		return t, true, nil
+	}
 
-	proc, err := s.ffmpeg.New(ffmpeg.ProcessConfig{
-		Reconnect:      config.Reconnect,
-		ReconnectDelay: time.Duration(config.ReconnectDelay) * time.Second,
-		StaleTimeout:   time.Duration(config.StaleTimeout) * time.Second,
-		Command:        config.CreateCommand(),
-		Parser:         parser,
-		Logger:         s.logger,
-		OnStateChange: func(from, to string) {
-			s.logger.Info("task %s state %s -> %s", id, from, to)
-		},
-	})
+	proc, parser, err := s.newProcess(t, config)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+
+	// 暂停的任务与运行中的一样以新配置重新启动；排队中的任务更新后继续排队
+	wasRunning := t.proc.IsRunning() || t.proc.Status().State == "paused"
+	if s.dequeue(t) {
+		wasRunning = true
 	}
+	t.proc.Stop(true)
+	t.parser.Close()
 
 	t.Config = config
 	t.UpdatedAt = time.Now().Unix()
+	t.debugReport.Store(config.DebugReport)
+	t.hooks.Store(&config.Hooks)
+	t.priority.Store(int64(config.Priority))
 	t.proc = proc
-	t.parser = parser.(parse.Parser)
+	t.parser = parser
+	s.schedule(t, config)
 
-	if wasRunning || config.Autostart {
-		go t.proc.Start()
+	// 在定时窗口之外更新时不启动，等待 start_at
+	if (wasRunning || config.Autostart) && config.inWindow(time.Now()) {
+		go s.start(t)
 		t.Order = "start"
 	}
 
-	return t, nil
+	return t, true, nil
 }
 
 func (s *store) Delete(id string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	t, ok := s.tasks[id]
 	if !ok {
+		s.mu.Unlock()
 		return ErrNotFound
 	}
+	delete(s.tasks, id)
+	s.unschedule(t)
+	s.mu.Unlock()
 
+	s.dequeue(t)
 	t.proc.Stop(true)
-	delete(s.tasks, id)
+	t.parser.Close()
+	if s.config.MQTT != nil {
+		// 停止时的状态变化已发布，之后再清除保留消息
+		s.config.MQTT.Clear(id)
+	}
 	return nil
 }
 
@@ -265,22 +840,157 @@ func (s *store) Start(id string) error {
 	if err != nil {
 		return err
 	}
-	return t.proc.Start()
+	return s.start(t)
+}
+
+func (s *store) Ping() {
+	s.mu.Lock()
+	s.mu.Unlock()
 }
 
 func (s *store) Stop(id string) error {
+	return s.StopMode(id, StopNormal)
+}
+
+func (s *store) Restart(id string) error {
 	t, err := s.Get(id)
 	if err != nil {
 		return err
 	}
-	return t.proc.Stop(true)
+	s.dequeue(t)
+	t.proc.Stop(true)
+	return s.start(t)
 }
 
-func (s *store) Restart(id string) error {
+func (s *store) Pause(id string) error {
 	t, err := s.Get(id)
 	if err != nil {
 		return err
 	}
-	t.proc.Stop(true)
-	return t.proc.Start()
+	if err := t.proc.Pause(); err != nil {
+		return err
+	}
+	// 暂停的任务不占用并发名额
+	s.promote()
+	return nil
+}
+
+func (s *store) Resume(id string) error {
+	t, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	return t.proc.Resume()
+}
+
+func (s *store) MemoryUsage() MemoryUsage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u := MemoryUsage{
+		Budget:  s.config.MemoryBudget,
+		Trimmed: s.trimmed,
+	}
+	for _, t := range s.tasks {
+		u.Total += t.Memory()
+	}
+	return u
+}
+
+// budgeter periodically enforces the memory budget
+func (s *store) budgeter() {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.enforceBudget()
+	}
+}
+
+// live reports whether t is still in the store, deleted tasks aren't
+func (s *store) live(t *Task) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tasks[t.ID] == t
+}
+
+// mqttProgress periodically publishes the progress of the running tasks
+func (s *store) mqttProgress() {
+	ticker := time.NewTicker(s.config.MQTTInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.RLock()
+		tasks := make([]*Task, 0, len(s.tasks))
+		for _, t := range s.tasks {
+			tasks = append(tasks, t)
+		}
+		s.mu.RUnlock()
+
+		now := time.Now().Unix()
+		for _, t := range tasks {
+			if t.Status().State != "running" {
+				continue
+			}
+			s.config.MQTT.Progress(mqtt.Progress{ID: t.ID, Reference: t.Reference, Progress: t.Progress(), Timestamp: now})
+		}
+	}
+}
+
+// enforceBudget halves the log buffer and the run history of the largest
+// tasks until the total fits into the budget or no buffer can shrink any
+// further
+func (s *store) enforceBudget() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type usage struct {
+		task  *Task
+		bytes uint64
+	}
+
+	var total uint64
+	tasks := make([]usage, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		if t.parser == nil {
+			continue
+		}
+		u := usage{task: t, bytes: t.Memory()}
+		total += u.bytes
+		tasks = append(tasks, u)
+	}
+	if total <= s.config.MemoryBudget {
+		return
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].bytes != tasks[j].bytes {
+			return tasks[i].bytes > tasks[j].bytes
+		}
+		return tasks[i].task.CreatedAt < tasks[j].task.CreatedAt
+	})
+
+	for _, u := range tasks {
+		if total <= s.config.MemoryBudget {
+			break
+		}
+		lines := u.task.parser.LogLines()
+		n := max(lines/2, minLogLines)
+		// 历史中的日志是运行结束时日志缓冲的副本，一并裁剪
+		runs := u.task.trimHistory()
+		if n >= lines && runs == 0 {
+			continue
+		}
+		if n < lines {
+			u.task.parser.SetLogLines(n)
+		} else {
+			n = lines
+		}
+		after := u.task.Memory()
+		if after < u.bytes {
+			total -= u.bytes - after
+		}
+		s.trimmed++
+		s.logger.Info("memory budget exceeded, trimmed task %s log buffer from %d to %d lines and dropped %d runs of its history (%d -> %d bytes)", u.task.ID, lines, n, runs, u.bytes, after)
+	}
 }
@@ -0,0 +1,143 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Isolated outputs are written through a single tee muxer with
+// onfail=ignore, so a failing destination is dropped while the others keep
+// running. The streams are encoded once for all of them, hence they must
+// share their encoding options. Only muxer level options may differ per
+// output; they become tee slave options.
+
+// teeMuxerOptions maps per-output FFmpeg options to tee slave options
+var teeMuxerOptions = map[string]string{
+	"-f":        "f",
+	"-movflags": "movflags",
+	"-flvflags": "flvflags",
+}
+
+// teeSpecial are characters with a meaning in the tee syntax. Values
+// containing them are rejected instead of escaped, the escaping rules
+// differ between the option list and the slave addresses.
+const teeSpecial = `[]|:\'`
+
+// teeSlave is an isolated output in tee syntax
+type teeSlave struct {
+	options []string // slave options, key=value
+	encode  []string // options applied to the tee output as a whole
+	address string
+}
+
+func (s teeSlave) String() string {
+	return "[" + strings.Join(append(slices.Clip(s.options), "onfail=ignore"), ":") + "]" + s.address
+}
+
+// newTeeSlave splits the output's options into slave and encoding options
+func newTeeSlave(out ConfigIO) (teeSlave, error) {
+	slave := teeSlave{address: out.Address}
+
+	if out.Address == "" || strings.ContainsAny(out.Address, `|\'`) || strings.HasPrefix(out.Address, "[") {
+		return slave, fmt.Errorf("%w: output %s: address must not contain | \\ ' or start with [", ErrInvalidIsolated, out.ID)
+	}
+
+	for i := 0; i < len(out.Options); i++ {
+		opt := out.Options[i]
+
+		key, ok := teeMuxerOptions[opt]
+		if !ok && (opt == "-bsf" || strings.HasPrefix(opt, "-bsf:")) {
+			key, ok = "bsfs", true
+			if stream, found := strings.CutPrefix(opt, "-bsf:"); found {
+				key += "/" + stream
+			}
+		}
+		if !ok {
+			slave.encode = append(slave.encode, opt)
+			continue
+		}
+
+		if i+1 >= len(out.Options) {
+			return slave, fmt.Errorf("%w: output %s: %s needs a value", ErrInvalidIsolated, out.ID, opt)
+		}
+		i++
+		value := out.Options[i]
+		if opt == "-f" && value == "tee" {
+			return slave, fmt.Errorf("%w: output %s: -f tee is added automatically", ErrInvalidIsolated, out.ID)
+		}
+		if strings.ContainsAny(value, teeSpecial) {
+			return slave, fmt.Errorf("%w: output %s: value of %s must not contain any of %s", ErrInvalidIsolated, out.ID, opt, teeSpecial)
+		}
+		slave.options = append(slave.options, key+"="+value)
+	}
+
+	return slave, nil
+}
+
+// teeSlaves returns the isolated outputs in config order
+func (c *Config) teeSlaves() ([]teeSlave, error) {
+	var slaves []teeSlave
+	for _, out := range c.Output {
+		if !out.Isolated {
+			continue
+		}
+		slave, err := newTeeSlave(out)
+		if err != nil {
+			return nil, err
+		}
+		if len(slaves) != 0 && !slices.Equal(slave.encode, slaves[0].encode) {
+			return nil, fmt.Errorf("%w: output %s: isolated outputs must share their encoding options, only -f, -bsf, -movflags and -flvflags may differ", ErrInvalidIsolated, out.ID)
+		}
+		slaves = append(slaves, slave)
+	}
+	return slaves, nil
+}
+
+// validateTee checks that the isolated outputs can be expressed with tee
+func (c *Config) validateTee() error {
+	_, err := c.teeSlaves()
+	return err
+}
+
+// isolatedOutputs returns the IDs of the isolated outputs, indexed like the
+// tee slaves. Outputs without ID are named by their index.
+func (c *Config) isolatedOutputs() []string {
+	var ids []string
+	for i, out := range c.Output {
+		if !out.Isolated {
+			continue
+		}
+		id := out.ID
+		if id == "" {
+			id = strconv.Itoa(i)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// teeCommand builds the tee output for the isolated outputs, nil if there
+// are none
+func (c *Config) teeCommand() []string {
+	slaves, err := c.teeSlaves()
+	if err != nil || len(slaves) == 0 {
+		return nil
+	}
+
+	addresses := make([]string, len(slaves))
+	for i, s := range slaves {
+		addresses[i] = s.String()
+	}
+
+	var cmd []string
+	cmd = append(cmd, slaves[0].encode...)
+	cmd = append(cmd, "-f", "tee", strings.Join(addresses, "|"))
+	return cmd
+}
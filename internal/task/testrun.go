@@ -0,0 +1,111 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+)
+
+// testTimeout bounds a test run, e.g. of an input that doesn't answer
+const testTimeout = 15 * time.Second
+
+// TestResult is the outcome of Store.Test
+type TestResult struct {
+	ffmpeg.TestResult
+	Command []string // with the credential placeholders
+}
+
+// testDropOptions are muxer and protocol options of outputs, by name or
+// prefix ending in _. The null muxer of a test run rejects them.
+var testDropOptions = []string{
+	"-f", "-hls_", "-segment_", "-dash_", "-mpegts_", "-rtmp_", "-srt_", "-tls_", "-fifo_",
+	"-movflags", "-flvflags", "-master_pl_name", "-var_stream_map", "-method", "-headers",
+	"-seg_duration", "-init_seg_name", "-media_seg_name", "-use_template", "-use_timeline",
+	"-window_size", "-extra_window_size", "-adaptation_sets", "-streaming", "-ldash", "-lhls",
+	"-frag_duration", "-min_frag_duration", "-strftime", "-reset_timestamps", "-pkt_size",
+	"-timeout", "-rw_timeout", "-listen", "-latency", "-passphrase", "-pbkeylen", "-streamid",
+	"-mode", "-content_type", "-user_agent", "-chunked_post", "-onfail", "-use_fifo",
+}
+
+// testDropped reports whether an output option is dropped in a test run
+func testDropped(opt string) bool {
+	for _, d := range testDropOptions {
+		if opt == d || (strings.HasSuffix(d, "_") && strings.HasPrefix(opt, d)) {
+			return true
+		}
+	}
+	return false
+}
+
+// testCommand is the command of the config with each output writing one
+// second to the null muxer instead, the encoders and filters are those of
+// the task
+func (c *Config) testCommand() []string {
+	t := *c
+	t.ProgressPipe = false
+	t.MaxOutputBandwidth = 0
+	t.Scte35Monitor = false
+
+	// 只输出错误，已有的 -loglevel 优先，须去掉
+	t.Options = nil
+	for i := 0; i < len(c.Options); i++ {
+		if c.Options[i] == "-loglevel" || c.Options[i] == "-v" {
+			i++
+			continue
+		}
+		t.Options = append(t.Options, c.Options[i])
+	}
+	t.Options = append(t.Options, "-nostdin", "-nostats", "-loglevel", "error")
+
+	t.Output = make([]ConfigIO, len(c.Output))
+	for i, out := range c.Output {
+		var options []string
+		for j := 0; j < len(out.Options); j++ {
+			if testDropped(out.Options[j]) {
+				j++
+				continue
+			}
+			options = append(options, out.Options[j])
+		}
+		t.Output[i] = ConfigIO{ID: out.ID, Address: "-", Options: append(options, "-t", "1", "-f", "null")}
+	}
+	return t.CreateCommand()
+}
+
+func (s *store) Test(ctx context.Context, id string) (TestResult, error) {
+	t, err := s.Get(id)
+	if err != nil {
+		return TestResult{}, err
+	}
+	s.mu.RLock()
+	config := t.Config
+	s.mu.RUnlock()
+
+	// 同一任务同时只做一次测试
+	if !t.testLock.TryLock() {
+		return TestResult{}, ErrTestRunning
+	}
+	defer t.testLock.Unlock()
+
+	r := &secrets{store: s.config.Credentials}
+	command := config.testCommand()
+	args, err := r.resolve(expandRun(command, time.Now().In(config.location()), t.seq.Load()+1))
+	if err != nil {
+		return TestResult{}, err
+	}
+	res, err := s.ffmpeg.TestRun(ctx, config.Binary, args, config.Environ(), testTimeout)
+	if err != nil {
+		return TestResult{}, err
+	}
+	for i, line := range res.Lines {
+		res.Lines[i] = r.mask(line)
+	}
+	return TestResult{TestResult: res, Command: command}, nil
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTestDropped(t *testing.T) {
+	tests := []struct {
+		opt  string
+		want bool
+	}{
+		{"-f", true},
+		{"-movflags", true},
+		{"-hls_time", true},         // 前缀 -hls_
+		{"-hls_segment_type", true}, // 前缀 -hls_
+		{"-rtmp_live", true},
+		{"-srt_latency", true},
+		{"-passphrase", true},
+		{"-headers", true},
+		{"-hls", false}, // 只有以 _ 结尾的项按前缀匹配
+		{"-format", false},
+		{"-mode_x", false},
+		{"-c:v", false},
+		{"-b:v", false},
+		{"-vf", false},
+		{"-map", false},
+		{"-t", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.opt, func(t *testing.T) {
+			if got := testDropped(tt.opt); got != tt.want {
+				t.Fatalf("testDropped(%q) = %v, want %v", tt.opt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTestCommand(t *testing.T) {
+	c := &Config{
+		ID:      "test",
+		Options: []string{"-loglevel", "info", "-y"},
+		Input:   []ConfigIO{{ID: "in", Address: "rtmp://example.com/live/in"}},
+		Output: []ConfigIO{{
+			ID:      "out",
+			Address: "/tmp/out.m3u8",
+			Options: []string{"-c:v", "libx264", "-b:v", "2M", "-f", "hls", "-hls_time", "4", "-hls_list_size", "5"},
+		}},
+	}
+	cmd := c.testCommand()
+
+	i := slices.Index(cmd, "rtmp://example.com/live/in")
+	if i < 0 {
+		t.Fatalf("command %q lacks the input", cmd)
+	}
+	// 去掉原有的 -loglevel，只输出错误
+	if global := cmd[:i]; slices.Contains(global, "info") || !slices.Contains(global, "error") {
+		t.Fatalf("global options %q, want -loglevel error only", global)
+	}
+	want := []string{"-c:v", "libx264", "-b:v", "2M", "-t", "1", "-f", "null", "-"}
+	if out := cmd[len(cmd)-len(want):]; !slices.Equal(out, want) {
+		t.Fatalf("output %q, want %q", out, want)
+	}
+	if slices.Contains(cmd, "/tmp/out.m3u8") || slices.Contains(cmd, "hls") {
+		t.Fatalf("command %q still writes the HLS output", cmd)
+	}
+}
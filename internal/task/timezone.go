@@ -0,0 +1,66 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// localZone is the zone FFmpeg uses without TZ. FFmpeg doesn't inherit our
+// environment, so that is the system's /etc/localtime, not our TZ.
+var localZone = sync.OnceValue(func() string {
+	if target, err := filepath.EvalSymlinks("/etc/localtime"); err == nil {
+		if _, name, ok := strings.Cut(target, "zoneinfo/"); ok {
+			return name
+		}
+	}
+	return "UTC"
+})
+
+// validateTimezone checks that Timezone is a known IANA zone
+func (c *Config) validateTimezone() error {
+	if c.Timezone == "" {
+		return nil
+	}
+	// LoadLocation 接受 "Local"，对 FFmpeg 无意义
+	if _, err := time.LoadLocation(c.Timezone); err != nil || c.Timezone == "Local" {
+		return fmt.Errorf("%w: %s", ErrInvalidTimezone, c.Timezone)
+	}
+	return nil
+}
+
+// EffectiveTimezone returns the zone FFmpeg runs in, e.g. for strftime
+// output names
+func (c *Config) EffectiveTimezone() string {
+	if c.Timezone != "" {
+		return c.Timezone
+	}
+	return localZone()
+}
+
+// location returns the EffectiveTimezone, UTC if it can't be loaded
+func (c *Config) location() *time.Location {
+	loc, err := time.LoadLocation(c.EffectiveTimezone())
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Environ returns the environment of the FFmpeg process. Timezone overrides
+// a TZ in Env.
+func (c *Config) Environ() []string {
+	if c.Timezone == "" {
+		return c.Env
+	}
+	env := make([]string, 0, len(c.Env)+1)
+	env = append(env, c.Env...)
+	return append(env, "TZ="+c.Timezone)
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package task
+
+import (
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg/parse"
+	"github.com/ZSC714725/transcodemanager/internal/process"
+)
+
+// maxTransitions bounds the state history of a task
+const maxTransitions = 200
+
+// transitionSize approximates the bytes of a Transition
+const transitionSize = 96
+
+// Transition is a state change of a task
+type Transition struct {
+	From string
+	To   string
+	Time time.Time
+	// Reason is why a run ended: the stop reason or, without one, who
+	// stopped it if it was asked to stop, else the category of the last
+	// error if it failed. Empty otherwise.
+	Reason string
+}
+
+// Transitions returns the state changes kept for the task, oldest first.
+// They belong to the task and are kept when an update replaces its process.
+func (t *Task) Transitions() []Transition {
+	t.transitionsLock.Lock()
+	defer t.transitionsLock.Unlock()
+	return append([]Transition{}, t.transitions...)
+}
+
+// transitionsMemory approximates the bytes held by the state transitions
+func (t *Task) transitionsMemory() uint64 {
+	t.transitionsLock.Lock()
+	defer t.transitionsLock.Unlock()
+	return uint64(len(t.transitions)) * transitionSize
+}
+
+// ClearTransitions forgets the state changes of the task
+func (t *Task) ClearTransitions() {
+	t.transitionsLock.Lock()
+	defer t.transitionsLock.Unlock()
+	t.transitions = nil
+}
+
+// recordTransition keeps a state change, parser and status are those of
+// the run's process
+func (t *Task) recordTransition(parser parse.Parser, status process.Status, from, to string) {
+	tr := Transition{From: from, To: to, Time: time.Now()}
+	switch to {
+	case "finished", "failed", "killed":
+		if status.StopReason != "" {
+			tr.Reason = status.StopReason
+		} else if status.StoppedBy != "" {
+			tr.Reason = status.StoppedBy
+		} else if err := parser.LastError(); to == "failed" && err != nil {
+			tr.Reason = err.Category
+		}
+	}
+
+	t.transitionsLock.Lock()
+	defer t.transitionsLock.Unlock()
+	t.transitions = append(t.transitions, tr)
+	if len(t.transitions) > maxTransitions {
+		t.transitions = append(t.transitions[:0:0], t.transitions[len(t.transitions)-maxTransitions:]...)
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+)
+
+// Actions on persistent failure
+const (
+	ActionNone = ""     // only report
+	ActionExit = "exit" // exit so that the supervisor restarts us
+)
+
+// errPending is the failure of a check whose previous run still hangs
+var errPending = errors.New("previous run still pending")
+
+// Check exercises a critical path of the manager
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Config for the watchdog
+type Config struct {
+	Interval time.Duration // between rounds, default 10s
+	Timeout  time.Duration // per check, default 5s
+	// Threshold is the number of consecutive failed rounds before Action
+	// is taken, default 3
+	Threshold int
+	Action    string
+}
+
+// Watchdog runs checks periodically. A failed round makes the manager
+// unready until a round passes again.
+type Watchdog interface {
+	// Ready returns the names of the checks that failed in the last round,
+	// none if the manager is ready
+	Ready() (bool, []string)
+	// Failures returns the number of failed runs by check name
+	Failures() map[string]uint64
+}
+
+type watchdog struct {
+	config Config
+	checks []Check
+	logger logger.Logger
+
+	failing  []string
+	failures map[string]uint64
+	pending  map[string]bool
+	lock     sync.Mutex
+}
+
+// New starts a watchdog running the checks
+func New(config Config, checks []Check, log logger.Logger) Watchdog {
+	if config.Interval <= 0 {
+		config.Interval = 10 * time.Second
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.Threshold <= 0 {
+		config.Threshold = 3
+	}
+
+	w := &watchdog{
+		config:   config,
+		checks:   checks,
+		logger:   log,
+		failures: make(map[string]uint64),
+		pending:  make(map[string]bool),
+	}
+	go w.run()
+	return w
+}
+
+func (w *watchdog) Ready() (bool, []string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return len(w.failing) == 0, append([]string(nil), w.failing...)
+}
+
+func (w *watchdog) Failures() map[string]uint64 {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	failures := make(map[string]uint64, len(w.checks))
+	for _, c := range w.checks {
+		failures[c.Name] = w.failures[c.Name]
+	}
+	return failures
+}
+
+func (w *watchdog) run() {
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	rounds := 0
+	for range ticker.C {
+		if failing := w.round(); len(failing) == 0 {
+			rounds = 0
+			continue
+		}
+		rounds++
+		if rounds >= w.config.Threshold && w.config.Action == ActionExit {
+			w.logger.Error("watchdog: checks failed %d times in a row, exiting", rounds)
+			os.Exit(1)
+		}
+	}
+}
+
+// round runs all checks in parallel and returns the names of the failed ones
+func (w *watchdog) round() []string {
+	errs := make([]error, len(w.checks))
+	var wg sync.WaitGroup
+	for i, c := range w.checks {
+		wg.Add(1)
+		go func(i int, c Check) {
+			defer wg.Done()
+			errs[i] = w.check(c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	var failing []string
+	w.lock.Lock()
+	for i, c := range w.checks {
+		if errs[i] != nil {
+			failing = append(failing, c.Name)
+			w.failures[c.Name]++
+			w.logger.Error("watchdog: check %s failed: %v", c.Name, errs[i])
+		}
+	}
+	sort.Strings(failing)
+	w.failing = failing
+	w.lock.Unlock()
+
+	return failing
+}
+
+// check runs a check with the timeout. A check that hangs keeps running
+// in the background and fails every round until it returns, so that a
+// wedged lock doesn't pile up goroutines.
+func (w *watchdog) check(c Check) error {
+	w.lock.Lock()
+	if w.pending[c.Name] {
+		w.lock.Unlock()
+		return errPending
+	}
+	w.pending[c.Name] = true
+	w.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.config.Timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		err := c.Run(ctx)
+		w.lock.Lock()
+		delete(w.pending, c.Name)
+		w.lock.Unlock()
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WriteCheck returns a check that writes a byte to a file in dir and
+// removes it again
+func WriteCheck(name, dir string) Check {
+	return Check{Name: name, Run: func(ctx context.Context) error {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		f, err := os.CreateTemp(dir, ".watchdog-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+
+		if _, err := f.Write([]byte{0}); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}}
+}
+
+// LockCheck returns a check that takes and releases a lock
+func LockCheck(name string, lock func()) Check {
+	return Check{Name: name, Run: func(ctx context.Context) error {
+		lock()
+		return nil
+	}}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package watchdog
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+)
+
+func TestStaleLock(t *testing.T) {
+	var mu sync.Mutex
+	checks := []Check{
+		LockCheck("store", func() { mu.Lock(); mu.Unlock() }),
+		WriteCheck("persist", t.TempDir()),
+	}
+	// 轮次由测试直接驱动，不等定时器
+	w := New(Config{Interval: time.Hour, Timeout: 50 * time.Millisecond}, checks, logger.New("", logger.LevelError)).(*watchdog)
+
+	if failing := w.round(); len(failing) != 0 {
+		t.Fatalf("healthy round failed %v", failing)
+	}
+	if ready, _ := w.Ready(); !ready {
+		t.Fatal("not ready after a healthy round")
+	}
+
+	// 锁被卡住：第一轮超时，之后的轮次因上次仍未返回而失败
+	mu.Lock()
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		if failing := w.round(); !reflect.DeepEqual(failing, []string{"store"}) {
+			t.Fatalf("round %d failed %v, want store", i, failing)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("round %d took %s, want the 50ms timeout", i, elapsed)
+		}
+	}
+	if ready, failing := w.Ready(); ready || !reflect.DeepEqual(failing, []string{"store"}) {
+		t.Fatalf("ready %v, failing %v, want unready on store", ready, failing)
+	}
+	if failures := w.Failures(); failures["store"] != 3 || failures["persist"] != 0 {
+		t.Fatalf("failures %v, want 3 for store only", failures)
+	}
+
+	// 锁释放后挂起的检查返回，下一轮恢复就绪
+	mu.Unlock()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		w.lock.Lock()
+		pending := w.pending["store"]
+		w.lock.Unlock()
+		if !pending {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("hung check didn't return after the lock was released")
+		}
+	}
+	if failing := w.round(); len(failing) != 0 {
+		t.Fatalf("round after release failed %v", failing)
+	}
+	if ready, _ := w.Ready(); !ready {
+		t.Fatal("not ready after the lock was released")
+	}
+}
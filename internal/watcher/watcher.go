@@ -0,0 +1,485 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+// Package watcher creates transcoding tasks for files that appear in
+// watched directories.
+package watcher
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+	"github.com/ZSC714725/transcodemanager/internal/task"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Actions on the source file after its task finished successfully
+const (
+	AfterKeep   = ""       // leave the file
+	AfterMove   = "move"   // move it to MoveTo
+	AfterDelete = "delete" // remove it
+)
+
+// Labels of the tasks created by a watcher
+const (
+	LabelOrigin   = "origin"
+	LabelWatcher  = "watcher"
+	OriginWatcher = "watcher"
+)
+
+// Config for a watched directory
+type Config struct {
+	Name string
+	Dir  string
+	// Glob is matched against the file name, default "*". Hidden files
+	// are ignored.
+	Glob string
+	// Preset is the template of the tasks, optional
+	Preset string
+	// Output address, may use the file placeholders {file}, {filename},
+	// {name}, {ext} and {dir}
+	Output string
+	// Variables of the tasks, the values may use the file placeholders
+	Variables map[string]string
+	// Settle is how long size and modification time must be unchanged
+	// before the file is used, default 5s
+	Settle time.Duration
+	// Rescan lists the directory periodically to catch missed events,
+	// default 30s
+	Rescan time.Duration
+	After  string
+	MoveTo string
+}
+
+// Status of a watcher
+type Status struct {
+	Name    string
+	Dir     string
+	Seen    uint64 // files that matched
+	Created uint64 // tasks created
+	Errors  uint64
+	// Pending files are waiting to settle, Running ones have a task that
+	// hasn't finished yet
+	Pending     int
+	Running     int
+	LastError   string
+	LastErrorAt time.Time
+}
+
+// Watchers runs the directory watchers
+type Watchers interface {
+	Status() []Status
+	// Close stops watching, tasks that were created keep running
+	Close()
+}
+
+// New validates the configs and starts a watcher for each
+func New(configs []Config, store task.Store, presets task.PresetStore, log logger.Logger) (Watchers, error) {
+	ws := &watchers{}
+	names := make(map[string]bool)
+	for _, config := range configs {
+		if err := validate(&config); err != nil {
+			ws.Close()
+			return nil, fmt.Errorf("watcher %q: %w", config.Name, err)
+		}
+		if names[config.Name] {
+			ws.Close()
+			return nil, fmt.Errorf("watcher %q: duplicate name", config.Name)
+		}
+		names[config.Name] = true
+
+		w, err := start(config, store, presets, log)
+		if err != nil {
+			ws.Close()
+			return nil, fmt.Errorf("watcher %q: %w", config.Name, err)
+		}
+		ws.list = append(ws.list, w)
+	}
+	return ws, nil
+}
+
+func validate(config *Config) error {
+	if config.Name == "" {
+		return errors.New("name required")
+	}
+	if config.Output == "" {
+		return errors.New("output required")
+	}
+	if config.Glob == "" {
+		config.Glob = "*"
+	}
+	if _, err := filepath.Match(config.Glob, ""); err != nil {
+		return fmt.Errorf("glob: %w", err)
+	}
+	if config.Settle <= 0 {
+		config.Settle = 5 * time.Second
+	}
+	if config.Rescan <= 0 {
+		config.Rescan = 30 * time.Second
+	}
+	switch config.After {
+	case AfterKeep, AfterDelete:
+	case AfterMove:
+		if config.MoveTo == "" {
+			return errors.New("after: move requires move_to")
+		}
+		if info, err := os.Stat(config.MoveTo); err != nil || !info.IsDir() {
+			return fmt.Errorf("move_to %s is not a directory", config.MoveTo)
+		}
+	default:
+		return fmt.Errorf("unknown after %q: must be move or delete", config.After)
+	}
+	if info, err := os.Stat(config.Dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("dir %s is not a directory", config.Dir)
+	}
+	return nil
+}
+
+type watchers struct {
+	list []*watcher
+}
+
+func (ws *watchers) Status() []Status {
+	list := make([]Status, 0, len(ws.list))
+	for _, w := range ws.list {
+		list = append(list, w.status())
+	}
+	return list
+}
+
+func (ws *watchers) Close() {
+	for _, w := range ws.list {
+		w.close()
+	}
+}
+
+// file states
+const (
+	fileSettling = iota
+	fileRunning  // task created
+	fileDone     // task ended or couldn't be created, until the file changes
+)
+
+type file struct {
+	size    int64
+	modTime time.Time
+	changed time.Time // when size or modTime last changed
+	state   int
+	task    string
+}
+
+type watcher struct {
+	config  Config
+	store   task.Store
+	presets task.PresetStore
+	logger  logger.Logger
+	fs      *fsnotify.Watcher
+	done    chan struct{}
+	wg      sync.WaitGroup
+
+	files       map[string]*file
+	seen        uint64
+	created     uint64
+	errors      uint64
+	lastError   string
+	lastErrorAt time.Time
+	lock        sync.Mutex
+}
+
+func start(config Config, store task.Store, presets task.PresetStore, log logger.Logger) (*watcher, error) {
+	fs, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.Add(config.Dir); err != nil {
+		fs.Close()
+		return nil, err
+	}
+
+	w := &watcher{
+		config:  config,
+		store:   store,
+		presets: presets,
+		logger:  log,
+		fs:      fs,
+		done:    make(chan struct{}),
+		files:   make(map[string]*file),
+	}
+	w.wg.Add(1)
+	go w.run()
+	log.Info("watcher %s: watching %s for %s", config.Name, config.Dir, config.Glob)
+	return w, nil
+}
+
+func (w *watcher) close() {
+	close(w.done)
+	w.fs.Close()
+	w.wg.Wait()
+}
+
+func (w *watcher) run() {
+	defer w.wg.Done()
+
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	rescan := time.NewTicker(w.config.Rescan)
+	defer rescan.Stop()
+
+	w.scan()
+	for {
+		select {
+		case <-w.done:
+			return
+		case ev, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			w.event(ev)
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			// 事件队列溢出等，由定期扫描补上
+			w.lock.Lock()
+			w.fail("watch %s: %s", w.config.Dir, err)
+			w.lock.Unlock()
+		case <-rescan.C:
+			w.scan()
+		case <-tick.C:
+			w.check()
+		}
+	}
+}
+
+func (w *watcher) event(ev fsnotify.Event) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename) {
+		delete(w.files, ev.Name)
+		return
+	}
+	w.touch(ev.Name)
+}
+
+// scan lists the directory, adding new files and forgetting removed ones
+func (w *watcher) scan() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	entries, err := os.ReadDir(w.config.Dir)
+	if err != nil {
+		w.fail("scan %s: %s", w.config.Dir, err)
+		return
+	}
+
+	present := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		path := filepath.Join(w.config.Dir, e.Name())
+		present[path] = true
+		w.touch(path)
+	}
+	for path := range w.files {
+		if !present[path] {
+			delete(w.files, path)
+		}
+	}
+}
+
+// touch records a new file or a change of a known one. The caller must
+// hold the lock.
+func (w *watcher) touch(path string) {
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, ".") {
+		return
+	}
+	if ok, _ := filepath.Match(w.config.Glob, name); !ok {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return
+	}
+
+	f, ok := w.files[path]
+	if !ok {
+		w.files[path] = &file{size: info.Size(), modTime: info.ModTime(), changed: time.Now()}
+		w.seen++
+		return
+	}
+	if f.size == info.Size() && f.modTime.Equal(info.ModTime()) {
+		return
+	}
+	if f.state == fileRunning {
+		// 任务正在读取，不重复创建
+		return
+	}
+	f.size, f.modTime, f.changed = info.Size(), info.ModTime(), time.Now()
+	f.state = fileSettling
+}
+
+// check creates the tasks of settled files and follows the running ones
+func (w *watcher) check() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	for path, f := range w.files {
+		switch f.state {
+		case fileSettling:
+			if time.Since(f.changed) < w.config.Settle {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				delete(w.files, path)
+				continue
+			}
+			if f.size != info.Size() || !f.modTime.Equal(info.ModTime()) {
+				f.size, f.modTime, f.changed = info.Size(), info.ModTime(), time.Now()
+				continue
+			}
+			w.create(path, f)
+		case fileRunning:
+			w.follow(path, f)
+		}
+	}
+}
+
+// create adds a once-mode task for the file. The caller must hold the lock.
+func (w *watcher) create(path string, f *file) {
+	f.state = fileDone
+
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	values := map[string]string{
+		"file":     path,
+		"filename": base,
+		"name":     strings.TrimSuffix(base, ext),
+		"ext":      strings.TrimPrefix(ext, "."),
+		"dir":      filepath.Dir(path),
+	}
+	pairs := make([]string, 0, 2*len(values))
+	for k, v := range values {
+		pairs = append(pairs, "{"+k+"}", v)
+	}
+	r := strings.NewReplacer(pairs...)
+
+	variables := make(map[string]string, len(w.config.Variables)+len(values))
+	for k, v := range w.config.Variables {
+		variables[k] = r.Replace(v)
+	}
+	for k, v := range values {
+		variables[k] = v
+	}
+
+	config := &task.Config{
+		Input:     []task.ConfigIO{{Address: path}},
+		Output:    []task.ConfigIO{{Address: w.config.Output}},
+		Variables: variables,
+		Labels:    map[string]string{LabelOrigin: OriginWatcher, LabelWatcher: w.config.Name},
+		Autostart: true,
+	}
+	if w.config.Preset != "" {
+		preset, err := w.presets.Get(w.config.Preset)
+		if err != nil {
+			w.fail("%s: preset %s: %s", base, w.config.Preset, err)
+			return
+		}
+		preset.Apply(config)
+	}
+	// 一次性任务，结束后不重连
+	config.Reconnect = false
+
+	t, err := w.store.Add(config)
+	if err != nil {
+		w.fail("%s: %s", base, err)
+		return
+	}
+	f.state = fileRunning
+	f.task = t.ID
+	w.created++
+	w.logger.Info("watcher %s: created task %s for %s", w.config.Name, t.ID, path)
+}
+
+// follow applies the after action once the task of the file finished.
+// The caller must hold the lock.
+func (w *watcher) follow(path string, f *file) {
+	t, err := w.store.Get(f.task)
+	if err != nil {
+		// 任务已被删除
+		f.state = fileDone
+		return
+	}
+	status := t.Status()
+	if status.States.Starting == 0 {
+		// 尚未启动，如在排队
+		return
+	}
+	switch status.State {
+	case "finished", "failed", "killed":
+	default:
+		return
+	}
+	if status.State != "finished" || status.ExitCode != 0 {
+		f.state = fileDone
+		w.fail("%s: task %s ended %s, exit code %d", filepath.Base(path), f.task, status.State, status.ExitCode)
+		return
+	}
+
+	f.state = fileDone
+	switch w.config.After {
+	case AfterMove:
+		err = os.Rename(path, filepath.Join(w.config.MoveTo, filepath.Base(path)))
+	case AfterDelete:
+		err = os.Remove(path)
+	default:
+		return
+	}
+	if err != nil {
+		w.fail("%s: %s: %s", filepath.Base(path), w.config.After, err)
+		return
+	}
+	delete(w.files, path)
+}
+
+// fail records an error. The caller must hold the lock.
+func (w *watcher) fail(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	w.logger.Error("watcher %s: %s", w.config.Name, msg)
+	w.errors++
+	w.lastError = msg
+	w.lastErrorAt = time.Now()
+}
+
+func (w *watcher) status() Status {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	s := Status{
+		Name:        w.config.Name,
+		Dir:         w.config.Dir,
+		Seen:        w.seen,
+		Created:     w.created,
+		Errors:      w.errors,
+		LastError:   w.lastError,
+		LastErrorAt: w.lastErrorAt,
+	}
+	for _, f := range w.files {
+		switch f.state {
+		case fileSettling:
+			s.Pending++
+		case fileRunning:
+			s.Running++
+		}
+	}
+	return s
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+	"github.com/ZSC714725/transcodemanager/internal/task"
+)
+
+func TestCreateTask(t *testing.T) {
+	ff, err := ffmpeg.New(ffmpeg.Config{Binary: "ffmpeg", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := logger.New("", logger.LevelError)
+	store := task.NewStore(ff, log, task.StoreConfig{})
+	t.Cleanup(func() { store.Shutdown(context.Background()) })
+	presets, err := task.NewPresetStore(filepath.Join(t.TempDir(), "presets.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	ws, err := New([]Config{{
+		Name:      "in",
+		Dir:       dir,
+		Glob:      "*.mp4",
+		Output:    "/tmp/{name}-out.mp4",
+		Variables: map[string]string{"title": "{name}.{ext}"},
+		Settle:    100 * time.Millisecond,
+	}}, store, presets, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(ws.Close)
+
+	// 隐藏文件与不匹配的文件被忽略
+	for _, name := range []string{"clip.mp4", ".partial.mp4", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var tasks []*task.Task
+	for deadline := time.Now().Add(5 * time.Second); len(tasks) == 0; time.Sleep(50 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for a task, status %+v", ws.Status())
+		}
+		tasks, _ = store.List(task.ListFilter{})
+	}
+	time.Sleep(200 * time.Millisecond)
+	if tasks, _ = store.List(task.ListFilter{}); len(tasks) != 1 {
+		t.Fatalf("%d tasks, want 1", len(tasks))
+	}
+
+	config := tasks[0].Config
+	if in := config.Input[0].Address; in != filepath.Join(dir, "clip.mp4") {
+		t.Fatalf("input %s, want the new file", in)
+	}
+	if config.Labels[LabelOrigin] != OriginWatcher || config.Labels[LabelWatcher] != "in" {
+		t.Fatalf("labels %v, want the watcher's", config.Labels)
+	}
+	if config.Variables["name"] != "clip" || config.Variables["title"] != "clip.mp4" {
+		t.Fatalf("variables %v, want name clip and title clip.mp4", config.Variables)
+	}
+	if !config.Autostart || config.Reconnect {
+		t.Fatal("task isn't a once-mode task started right away")
+	}
+
+	status := ws.Status()[0]
+	if status.Seen != 1 || status.Created != 1 || status.Errors != 0 {
+		t.Fatalf("status %+v, want 1 file seen and 1 task created", status)
+	}
+}
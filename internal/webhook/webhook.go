@@ -0,0 +1,117 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+)
+
+const (
+	attempts   = 3
+	retryDelay = time.Second // doubled after each failed attempt
+	queueSize  = 256
+	timeout    = 5 * time.Second
+)
+
+// Event is the payload posted on a task state change or, with Event set,
+// on another task event
+type Event struct {
+	ID        string `json:"id"`
+	Reference string `json:"reference"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	StoppedBy string `json:"stopped_by,omitempty"` // user, system or limit if the run ended because it was asked to stop
+	Event     string `json:"event,omitempty"`      // e.g. scte35_stalled, empty for a state change
+	Detail    string `json:"detail,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Notifier delivers events to webhooks
+type Notifier interface {
+	// Notify queues the event for delivery and never blocks. Events are
+	// dropped if an endpoint falls too far behind.
+	Notify(e Event)
+}
+
+type endpoint struct {
+	url   string
+	queue chan Event
+}
+
+type notifier struct {
+	endpoints []*endpoint
+	client    *http.Client
+	logger    logger.Logger
+}
+
+// New creates a Notifier that posts events as JSON to each URL. Every URL
+// has its own queue, so a slow endpoint only delays its own events, and
+// events are delivered to it in order.
+func New(urls []string, log logger.Logger) Notifier {
+	n := &notifier{
+		client: &http.Client{Timeout: timeout},
+		logger: log,
+	}
+
+	for _, u := range urls {
+		e := &endpoint{url: u, queue: make(chan Event, queueSize)}
+		n.endpoints = append(n.endpoints, e)
+		go n.deliver(e)
+	}
+
+	return n
+}
+
+func (n *notifier) Notify(e Event) {
+	for _, ep := range n.endpoints {
+		select {
+		case ep.queue <- e:
+		default:
+			n.logger.Error("webhook %s: queue full, dropping event %s %s -> %s", ep.url, e.ID, e.From, e.To)
+		}
+	}
+}
+
+func (n *notifier) deliver(ep *endpoint) {
+	for e := range ep.queue {
+		body, err := json.Marshal(e)
+		if err != nil {
+			continue
+		}
+
+		delay := retryDelay
+		for i := 1; ; i++ {
+			err = n.post(ep.url, body)
+			if err == nil {
+				break
+			}
+			if i == attempts {
+				n.logger.Error("webhook %s: giving up on event %s %s -> %s after %d attempts: %s", ep.url, e.ID, e.From, e.To, attempts, err)
+				break
+			}
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+}
+
+func (n *notifier) post(url string, body []byte) error {
+	resp, err := n.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("server returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+//go:build !windows
+
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/ZSC714725/transcodemanager/internal/webhook"
+)
+
+// recorder is an endpoint that keeps the events posted to it
+type recorder struct {
+	events []webhook.Event
+	fail   int // number of requests answered with 500 first
+	lock   sync.Mutex
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.fail > 0 {
+		r.fail--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var e webhook.Event
+	if err := json.NewDecoder(req.Body).Decode(&e); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.events = append(r.events, e)
+}
+
+// wait returns the events once one of them goes to state
+func (r *recorder) wait(t *testing.T, state string, timeout time.Duration) []webhook.Event {
+	t.Helper()
+	for deadline := time.Now().Add(timeout); ; time.Sleep(10 * time.Millisecond) {
+		r.lock.Lock()
+		events := append([]webhook.Event{}, r.events...)
+		r.lock.Unlock()
+		for _, e := range events {
+			if e.To == state {
+				return events
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for an event to %s, got %+v", state, events)
+		}
+	}
+}
+
+func TestFailed(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	t.Cleanup(srv.Close)
+
+	// 能力探测只看到版本，任务的运行失败
+	binary := filepath.Join(t.TempDir(), "ffmpeg")
+	script := `#!/bin/sh
+case " $* " in *" -i "*) echo "in.mp4: No such file or directory" >&2; exit 1;; esac
+echo "ffmpeg version 6.0"
+`
+	if err := os.WriteFile(binary, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ff, err := ffmpeg.New(ffmpeg.Config{Binary: binary})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := logger.New("", logger.LevelError)
+	store := task.NewStore(ff, log, task.StoreConfig{Notifier: webhook.New([]string{srv.URL}, log)})
+	t.Cleanup(func() { store.Shutdown(context.Background()) })
+
+	if _, err := store.Add(&task.Config{
+		ID:        "news",
+		Reference: "channel-1",
+		Input:     []task.ConfigIO{{ID: "in", Address: "/tmp/in.mp4"}},
+		Output:    []task.ConfigIO{{ID: "out", Address: "/tmp/out.mp4"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Unix()
+	if err := store.Start("news"); err != nil {
+		t.Fatal(err)
+	}
+
+	events := rec.wait(t, "failed", 5*time.Second)
+	e := events[len(events)-1]
+	if e.ID != "news" || e.Reference != "channel-1" || e.From != "running" || e.To != "failed" {
+		t.Fatalf("last event %+v, want news of channel-1 from running to failed", e)
+	}
+	if e.StoppedBy != "" {
+		t.Fatalf("stopped_by %q for a failure, want none", e.StoppedBy)
+	}
+	if e.Timestamp < start || e.Timestamp > time.Now().Unix() {
+		t.Fatalf("timestamp %d not between %d and now", e.Timestamp, start)
+	}
+	// 事件按顺序送达
+	if events[0].To != "starting" {
+		t.Fatalf("first event %+v, want the start", events[0])
+	}
+}
+
+func TestRetry(t *testing.T) {
+	rec := &recorder{fail: 1}
+	srv := httptest.NewServer(rec)
+	t.Cleanup(srv.Close)
+
+	n := webhook.New([]string{srv.URL}, logger.New("", logger.LevelError))
+	n.Notify(webhook.Event{ID: "a", From: "running", To: "failed"})
+
+	// 第一次投递失败，一秒后重试成功
+	if events := rec.wait(t, "failed", 3*time.Second); len(events) != 1 {
+		t.Fatalf("%d events, want 1", len(events))
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+// Package client is a Go client for the TranscodeManager API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Config for a client
+type Config struct {
+	// Address of the server, e.g. http://localhost:8080
+	Address string
+	// Token is sent as Authorization: Bearer, a static token or the token
+	// of a login session
+	Token string
+	// HTTPClient defaults to http.DefaultClient
+	HTTPClient *http.Client
+	// Retries of idempotent requests (GET, PUT, DELETE) after a network
+	// error or a 502, 503 or 504, default 3. Negative disables retries.
+	Retries int
+	// RetryDelay before the first retry, doubled for every further one,
+	// default 200ms
+	RetryDelay time.Duration
+}
+
+// Error is an error response of the server
+type Error struct {
+	Status  int // HTTP status code
+	Message string
+	Detail  string
+}
+
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%d %s", e.Status, e.Message)
+	}
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Message, e.Detail)
+}
+
+// IsNotFound reports whether err is a 404, e.g. an unknown process ID
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is a 401, a missing or invalid token
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// IsBadRequest reports whether err is a 400, e.g. an invalid config
+func IsBadRequest(err error) bool {
+	return hasStatus(err, http.StatusBadRequest)
+}
+
+func hasStatus(err error, status int) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Status == status
+}
+
+// Client for the TranscodeManager API. It is safe for concurrent use.
+type Client struct {
+	config  Config
+	address string
+}
+
+// New creates a client
+func New(config Config) (*Client, error) {
+	u, err := url.Parse(config.Address)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid address %q", config.Address)
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	if config.Retries == 0 {
+		config.Retries = 3
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = 200 * time.Millisecond
+	}
+
+	return &Client{
+		config:  config,
+		address: strings.TrimRight(config.Address, "/"),
+	}, nil
+}
+
+// call sends a JSON request and decodes the JSON response into out, if
+// out isn't nil
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, in, out any) (http.Header, error) {
+	resp, err := c.do(ctx, method, path, query, in)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return resp.Header, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return nil, fmt.Errorf("decode %s %s: %w", method, path, err)
+	}
+	return resp.Header, nil
+}
+
+// do sends a request and returns a successful response, retrying
+// idempotent requests. The caller must close the body.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in any) (*http.Response, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return nil, err
+		}
+	}
+
+	retries := 0
+	if method != http.MethodPost {
+		retries = max(c.config.Retries, 0)
+	}
+	delay := c.config.RetryDelay
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, query, body)
+		if err == nil && resp.StatusCode < 300 {
+			return resp, nil
+		}
+		if err == nil {
+			err = decodeError(resp)
+		}
+		if attempt >= retries || !retryable(err) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body []byte) (*http.Response, error) {
+	u := c.address + path
+	if len(query) != 0 {
+		u += "?" + query.Encode()
+	}
+
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+	return c.config.HTTPClient.Do(req)
+}
+
+// mustJSON encodes a request type, which can't fail
+func mustJSON(v any) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+// decodeError reads an error response and closes its body
+func decodeError(resp *http.Response) error {
+	defer resp.Body.Close()
+
+	e := &Error{Status: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+	var body ErrorResponse
+	if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Message != "" {
+		e.Message = body.Message
+		e.Detail = body.Detail
+	}
+	return e
+}
+
+// retryable reports whether a failed request may succeed when repeated
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		// 网络错误
+		return true
+	}
+	switch e.Status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
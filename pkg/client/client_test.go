@@ -0,0 +1,182 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ZSC714725/transcodemanager/internal/api"
+	"github.com/ZSC714725/transcodemanager/internal/auth"
+	"github.com/ZSC714725/transcodemanager/internal/ffmpeg"
+	"github.com/ZSC714725/transcodemanager/internal/logger"
+	"github.com/ZSC714725/transcodemanager/internal/task"
+	"github.com/gin-gonic/gin"
+)
+
+const testToken = "test-token"
+
+// newTestServer runs the API handlers of a dry-run store, requiring
+// testToken
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	ff, err := ffmpeg.New(ffmpeg.Config{Binary: "ffmpeg", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := task.NewStore(ff, logger.New("", logger.LevelError), task.StoreConfig{})
+	t.Cleanup(func() { store.Shutdown(context.Background()) })
+	presets, err := task.NewPresetStore(filepath.Join(t.TempDir(), "presets.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := api.NewHandler(store, presets, ff, api.Config{
+		Auth: auth.New(auth.Config{Tokens: []string{testToken}}),
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	v3 := r.Group("/api/v3", h.RequireAuth)
+	v3.GET("/process", h.ListProcesses)
+	v3.POST("/process", h.AddProcess)
+	v3.GET("/process/:id", h.GetProcess)
+	v3.PUT("/process/:id", h.UpdateProcess)
+	v3.DELETE("/process/:id", h.DeleteProcess)
+	v3.GET("/process/:id/config", h.GetConfig)
+	v3.GET("/process/:id/state", h.GetState)
+	v3.PUT("/process/:id/command", h.Command)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestClient(t *testing.T, address, token string) *Client {
+	t.Helper()
+	c, err := New(Config{Address: address, Token: token, RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func testProcess(id string) ProcessConfigRequest {
+	return ProcessConfigRequest{
+		ID:     id,
+		Input:  []ProcessConfigIO{{ID: "in", Address: "/tmp/" + id + ".mp4"}},
+		Output: []ProcessConfigIO{{ID: "out", Address: "/tmp/" + id + "-out.mp4"}},
+	}
+}
+
+func TestProcessLifecycle(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, newTestServer(t).URL, testToken)
+
+	for _, id := range []string{"a", "b", "c"} {
+		if _, err := c.AddProcess(ctx, testProcess(id), true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	procs, total, err := c.ListProcesses(ctx, ListOptions{Sort: "id", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || len(procs) != 2 || procs[0].ID != "a" || procs[1].ID != "b" {
+		t.Fatalf("listed %d of %d processes, want a and b of 3", len(procs), total)
+	}
+
+	if err := c.Command(ctx, "a", "start", ""); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		state, err := c.ProcessState(ctx, "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.State == "running" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for a to run, state %s", state.State)
+		}
+	}
+
+	update := testProcess("a")
+	update.Options = []string{"-re"}
+	if _, err := c.UpdateProcess(ctx, "a", update, true); err != nil {
+		t.Fatal(err)
+	}
+	config, err := c.ProcessConfig(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Options) != 1 || config.Options[0] != "-re" {
+		t.Fatalf("options %q after update, want -re", config.Options)
+	}
+
+	if err := c.DeleteProcess(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetProcess(ctx, "a", ""); !IsNotFound(err) {
+		t.Fatalf("get after delete: %v, want not found", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	srv := newTestServer(t)
+
+	if _, _, err := newTestClient(t, srv.URL, "").ListProcesses(ctx, ListOptions{}); !IsUnauthorized(err) {
+		t.Fatalf("list without token: %v, want unauthorized", err)
+	}
+	if _, _, err := newTestClient(t, srv.URL, "wrong").ListProcesses(ctx, ListOptions{}); !IsUnauthorized(err) {
+		t.Fatalf("list with a wrong token: %v, want unauthorized", err)
+	}
+
+	c := newTestClient(t, srv.URL, testToken)
+	if _, err := c.AddProcess(ctx, ProcessConfigRequest{ID: "a"}, true); !IsBadRequest(err) {
+		t.Fatalf("add without inputs: %v, want bad request", err)
+	}
+	if _, err := c.ProcessState(ctx, "missing"); !IsNotFound(err) {
+		t.Fatalf("state of an unknown process: %v, want not found", err)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 前两次返回 503，之后成功
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("X-Total-Count", "0")
+		w.Write([]byte("[]"))
+	}))
+	t.Cleanup(srv.Close)
+	ctx := context.Background()
+
+	if _, _, err := newTestClient(t, srv.URL, "").ListProcesses(ctx, ListOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("%d calls, want 3", n)
+	}
+
+	// POST 不是幂等的，不重试
+	calls.Store(0)
+	if _, err := newTestClient(t, srv.URL, "").AddProcess(ctx, testProcess("a"), true); err == nil {
+		t.Fatal("add succeeded after a 503")
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("%d calls for a POST, want 1", n)
+	}
+}
@@ -0,0 +1,462 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListOptions selects, sorts and pages processes
+type ListOptions struct {
+	IDs       []string
+	Reference string
+	States    []string // e.g. running, failed, queued
+	// Filter selects the parts of a process: config, state, report,
+	// comma separated. Empty returns all.
+	Filter string
+	Sort   string // id, created_at, updated_at, state, cpu or memory
+	Desc   bool
+	Offset int
+	Limit  int
+	// Metadata includes the metadata of the processes
+	Metadata bool
+}
+
+func (o ListOptions) query() url.Values {
+	q := url.Values{}
+	if len(o.IDs) != 0 {
+		q.Set("id", strings.Join(o.IDs, ","))
+	}
+	if o.Reference != "" {
+		q.Set("reference", o.Reference)
+	}
+	if len(o.States) != 0 {
+		q.Set("state", strings.Join(o.States, ","))
+	}
+	if o.Filter != "" {
+		q.Set("filter", o.Filter)
+	}
+	if o.Sort != "" {
+		q.Set("sort", o.Sort)
+	}
+	if o.Desc {
+		q.Set("order", "desc")
+	}
+	if o.Offset > 0 {
+		q.Set("offset", strconv.Itoa(o.Offset))
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Metadata {
+		q.Set("metadata", "true")
+	}
+	return q
+}
+
+// ReportOptions selects log lines, all set fields must match
+type ReportOptions struct {
+	Grep  string    // regular expression, matched against the redacted line
+	Since time.Time // lines at or after
+	Last  int       // the newest Last matching lines
+	After uint64    // lines after this sequence number
+	Limit int       // the oldest Limit matching lines
+}
+
+func (o ReportOptions) query() url.Values {
+	q := url.Values{}
+	if o.Grep != "" {
+		q.Set("grep", o.Grep)
+	}
+	if !o.Since.IsZero() {
+		q.Set("since", o.Since.Format(time.RFC3339Nano))
+	}
+	if o.Last > 0 {
+		q.Set("last", strconv.Itoa(o.Last))
+	}
+	if o.After > 0 {
+		q.Set("after", strconv.FormatUint(o.After, 10))
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	return q
+}
+
+func processPath(id string, parts ...string) string {
+	return "/api/v3/process/" + url.PathEscape(id) + strings.Join(parts, "")
+}
+
+// ListProcesses returns a page of processes and the total number of
+// matches
+func (c *Client) ListProcesses(ctx context.Context, opts ListOptions) ([]Process, int, error) {
+	var procs []Process
+	header, err := c.call(ctx, http.MethodGet, "/api/v3/process", opts.query(), nil, &procs)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := strconv.Atoi(header.Get("X-Total-Count"))
+	if err != nil {
+		total = len(procs)
+	}
+	return procs, total, nil
+}
+
+// Summary returns the number of tasks by state and the CPU and memory of
+// the running ones, reference "" is all tasks
+func (c *Client) Summary(ctx context.Context, reference string) (*ProcessSummary, error) {
+	var query url.Values
+	if reference != "" {
+		query = url.Values{"reference": {reference}}
+	}
+	var out ProcessSummary
+	if _, err := c.call(ctx, http.MethodGet, "/api/v3/process/summary", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AddProcess creates a process. skipValidation skips checking the encoders
+// against the FFmpeg skills.
+func (c *Client) AddProcess(ctx context.Context, config ProcessConfigRequest, skipValidation bool) (*ProcessConfig, error) {
+	var out ProcessConfig
+	if _, err := c.call(ctx, http.MethodPost, "/api/v3/process", validationQuery(skipValidation), config, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DryRunProcess checks a config like AddProcess and returns the command it
+// would run, without creating a process
+func (c *Client) DryRunProcess(ctx context.Context, config ProcessConfigRequest, skipValidation bool) (*DryRunResponse, error) {
+	q := validationQuery(skipValidation)
+	if q == nil {
+		q = url.Values{}
+	}
+	q.Set("dryrun", "true")
+	var out DryRunResponse
+	if _, err := c.call(ctx, http.MethodPost, "/api/v3/process", q, config, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ValidateProcess checks a config without creating a process
+func (c *Client) ValidateProcess(ctx context.Context, config ProcessConfigRequest) (*ValidateResponse, error) {
+	var out ValidateResponse
+	if _, err := c.call(ctx, http.MethodPost, "/api/v3/process/validate", nil, config, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetProcess returns a process, filter is like in ListOptions
+func (c *Client) GetProcess(ctx context.Context, id, filter string) (*Process, error) {
+	q := url.Values{}
+	if filter != "" {
+		q.Set("filter", filter)
+	}
+	var out Process
+	if _, err := c.call(ctx, http.MethodGet, processPath(id), q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateProcess replaces the config of a process
+func (c *Client) UpdateProcess(ctx context.Context, id string, config ProcessConfigRequest, skipValidation bool) (*UpdateResponse, error) {
+	var out UpdateResponse
+	if _, err := c.call(ctx, http.MethodPut, processPath(id), validationQuery(skipValidation), config, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteProcess stops and removes a process
+func (c *Client) DeleteProcess(ctx context.Context, id string) error {
+	_, err := c.call(ctx, http.MethodDelete, processPath(id), nil, nil, nil)
+	return err
+}
+
+// ProcessConfig returns the config of a process
+func (c *Client) ProcessConfig(ctx context.Context, id string) (*ProcessConfig, error) {
+	var out ProcessConfig
+	if _, err := c.call(ctx, http.MethodGet, processPath(id, "/config"), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ProcessState returns the state and progress of a process
+func (c *Client) ProcessState(ctx context.Context, id string) (*ProcessState, error) {
+	var out ProcessState
+	if _, err := c.call(ctx, http.MethodGet, processPath(id, "/state"), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ProcessReport returns the in-memory log of a process
+func (c *Client) ProcessReport(ctx context.Context, id string) (*ProcessReport, error) {
+	var out ProcessReport
+	if _, err := c.call(ctx, http.MethodGet, processPath(id, "/report"), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ProcessReportAfter returns up to limit log lines after the sequence
+// number after, 0 limit returns all. Pass the LastSeq of the result to get
+// the following lines; Gap reports lost lines.
+func (c *Client) ProcessReportAfter(ctx context.Context, id string, after uint64, limit int) (*ProcessReport, error) {
+	q := url.Values{"after": {strconv.FormatUint(after, 10)}}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var out ProcessReport
+	if _, err := c.call(ctx, http.MethodGet, processPath(id, "/report"), q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// FilterReport returns the log lines of a process that match opts
+func (c *Client) FilterReport(ctx context.Context, id string, opts ReportOptions) (*ProcessReport, error) {
+	var out ProcessReport
+	if _, err := c.call(ctx, http.MethodGet, processPath(id, "/report"), opts.query(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DownloadLog returns the task log file of a process. The caller must
+// close it.
+func (c *Client) DownloadLog(ctx context.Context, id string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, processPath(id, "/report"), url.Values{"download": {"true"}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// DownloadReport returns the in-memory log of a process as text. The
+// caller must close it.
+func (c *Client) DownloadReport(ctx context.Context, id string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, processPath(id, "/report/download"), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// ProgressHistory returns the progress samples of the current run of a
+// process, oldest first
+func (c *Client) ProgressHistory(ctx context.Context, id string) ([]ProgressSample, error) {
+	var out []ProgressSample
+	if _, err := c.call(ctx, http.MethodGet, processPath(id, "/progress/history"), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Audit lists who added, updated, deleted or commanded a process, oldest
+// first
+func (c *Client) Audit(ctx context.Context, id string) ([]ProcessAuditEntry, error) {
+	var out []ProcessAuditEntry
+	if _, err := c.call(ctx, http.MethodGet, processPath(id, "/history"), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Transitions lists the last state changes of a process, oldest first
+func (c *Client) Transitions(ctx context.Context, id string) ([]ProcessTransition, error) {
+	var out []ProcessTransition
+	if _, err := c.call(ctx, http.MethodGet, processPath(id, "/state/history"), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ClearTransitions forgets the state changes of a process
+func (c *Client) ClearTransitions(ctx context.Context, id string) error {
+	_, err := c.call(ctx, http.MethodDelete, processPath(id, "/state/history"), nil, nil, nil)
+	return err
+}
+
+// History lists the completed runs of a process, newest first
+func (c *Client) History(ctx context.Context, id string) ([]ProcessRun, error) {
+	var out []ProcessRun
+	if _, err := c.call(ctx, http.MethodGet, processPath(id, "/report/history"), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// HistoryRun returns the log of the completed run with number n
+func (c *Client) HistoryRun(ctx context.Context, id string, n uint64) (*ProcessRunReport, error) {
+	var out ProcessRunReport
+	if _, err := c.call(ctx, http.MethodGet, processPath(id, "/report/history/", strconv.FormatUint(n, 10)), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReportFiles lists the FFmpeg reports of a process, see debug_report
+func (c *Client) ReportFiles(ctx context.Context, id string) ([]ReportFile, error) {
+	var out []ReportFile
+	if _, err := c.call(ctx, http.MethodGet, processPath(id, "/report/files"), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DownloadReportFile returns an FFmpeg report. The caller must close it.
+func (c *Client) DownloadReportFile(ctx context.Context, id, name string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, processPath(id, "/report/files/", url.PathEscape(name)), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// DebugBundle returns the debug bundle of a process as JSON
+func (c *Client) DebugBundle(ctx context.Context, id string) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, processPath(id, "/debug-bundle"), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// Probe returns the streams of each input of a process
+func (c *Client) Probe(ctx context.Context, id string, refresh bool) (*ProbeResponse, error) {
+	q := url.Values{}
+	if refresh {
+		q.Set("refresh", "true")
+	}
+	var out ProbeResponse
+	if _, err := c.call(ctx, http.MethodGet, processPath(id, "/probe"), q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Metadata decodes the metadata value of a process stored under key into out
+func (c *Client) Metadata(ctx context.Context, id, key string, out any) error {
+	_, err := c.call(ctx, http.MethodGet, processPath(id, "/metadata/", url.PathEscape(key)), nil, nil, out)
+	return err
+}
+
+// SetMetadata stores value as JSON under key, nil removes the key
+func (c *Client) SetMetadata(ctx context.Context, id, key string, value any) error {
+	if value == nil {
+		value = json.RawMessage("null")
+	}
+	_, err := c.call(ctx, http.MethodPut, processPath(id, "/metadata/", url.PathEscape(key)), nil, value, nil)
+	return err
+}
+
+// Command sends start, stop, restart, pause or resume to a process. mode
+// is the stop mode: normal, soft or kill, empty is normal.
+func (c *Client) Command(ctx context.Context, id, command, mode string) error {
+	_, err := c.call(ctx, http.MethodPut, processPath(id, "/command"), nil, CommandRequest{Command: command, Mode: mode}, nil)
+	return err
+}
+
+// TestProcess runs the command of a process for one second into the null
+// muxer and returns the errors, without touching the process
+func (c *Client) TestProcess(ctx context.Context, id string) (*TestResult, error) {
+	var out TestResult
+	if _, err := c.call(ctx, http.MethodPut, processPath(id, "/command"), nil, CommandRequest{Command: "test"}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// BatchCommand sends a command to the processes selected by IDs and/or
+// reference and returns the result for each
+func (c *Client) BatchCommand(ctx context.Context, req BatchCommandRequest) ([]CommandResult, error) {
+	var out []CommandResult
+	if _, err := c.call(ctx, http.MethodPut, "/api/v3/process/command", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Reference returns the combined view of the processes with a reference
+func (c *Client) Reference(ctx context.Context, ref string) (*Reference, error) {
+	var out Reference
+	if _, err := c.call(ctx, http.MethodGet, "/api/v3/reference/"+url.PathEscape(ref), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReferenceCommand sends a command to every process with a reference and
+// returns the result for each
+func (c *Client) ReferenceCommand(ctx context.Context, ref, command, mode string) ([]CommandResult, error) {
+	var out []CommandResult
+	req := BatchCommandRequest{Command: command, Mode: mode}
+	if _, err := c.call(ctx, http.MethodPut, "/api/v3/reference/"+url.PathEscape(ref)+"/command", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RollingRestart starts a rolling restart of the processes selected by
+// req.IDs and/or req.Reference, req.Command is ignored. Poll the operation
+// with GetOperation.
+func (c *Client) RollingRestart(ctx context.Context, req BatchCommandRequest) (*Operation, error) {
+	req.Command = "rolling_restart"
+	var out Operation
+	// 非幂等，不重试
+	resp, err := c.send(ctx, http.MethodPut, "/api/v3/process/command", nil, mustJSON(req))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, decodeError(resp)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListOperations returns the running and recently finished operations
+func (c *Client) ListOperations(ctx context.Context) ([]Operation, error) {
+	var out []Operation
+	if _, err := c.call(ctx, http.MethodGet, "/api/v3/operations", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetOperation returns the progress of an operation
+func (c *Client) GetOperation(ctx context.Context, id string) (*Operation, error) {
+	var out Operation
+	if _, err := c.call(ctx, http.MethodGet, "/api/v3/operations/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func validationQuery(skip bool) url.Values {
+	if !skip {
+		return nil
+	}
+	return url.Values{"skip_validation": {"true"}}
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// System returns the status of the manager
+func (c *Client) System(ctx context.Context) (*SystemInfo, error) {
+	var out SystemInfo
+	if _, err := c.call(ctx, http.MethodGet, "/api/v3/system", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Ready returns nil if the manager is ready, the error lists the failing
+// watchdog checks otherwise. It isn't retried.
+func (c *Client) Ready(ctx context.Context) error {
+	resp, err := c.send(ctx, http.MethodGet, "/readyz", nil, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return decodeError(resp)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Metrics returns the Prometheus metrics in text format
+func (c *Client) Metrics(ctx context.Context) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/metrics", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// Skills returns the capabilities of the FFmpeg binary
+func (c *Client) Skills(ctx context.Context) (*SkillsResponse, error) {
+	var out SkillsResponse
+	if _, err := c.call(ctx, http.MethodGet, "/api/v3/skills", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// BinarySkills returns the capabilities of a binary from ffmpeg.binaries
+func (c *Client) BinarySkills(ctx context.Context, binary string) (*SkillsResponse, error) {
+	var out SkillsResponse
+	if _, err := c.call(ctx, http.MethodGet, "/api/v3/skills", url.Values{"binary": {binary}}, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReloadSkills probes the FFmpeg binaries again
+func (c *Client) ReloadSkills(ctx context.Context) (*SkillsResponse, error) {
+	var out SkillsResponse
+	if _, err := c.call(ctx, http.MethodPost, "/api/v3/skills/reload", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReloadValidators applies the address rules of the server's config file
+// to tasks added or updated afterwards
+func (c *Client) ReloadValidators(ctx context.Context) error {
+	_, err := c.call(ctx, http.MethodPost, "/api/v3/validators/reload", nil, nil, nil)
+	return err
+}
+
+// ListWatchers returns the state of the watched directories
+func (c *Client) ListWatchers(ctx context.Context) ([]WatcherStatus, error) {
+	var out []WatcherStatus
+	if _, err := c.call(ctx, http.MethodGet, "/api/v3/watchers", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListPresets returns all presets
+func (c *Client) ListPresets(ctx context.Context) ([]Preset, error) {
+	var out []Preset
+	if _, err := c.call(ctx, http.MethodGet, "/api/v3/presets", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AddPreset creates a preset
+func (c *Client) AddPreset(ctx context.Context, preset Preset) (*Preset, error) {
+	var out Preset
+	if _, err := c.call(ctx, http.MethodPost, "/api/v3/presets", nil, preset, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetPreset returns a preset
+func (c *Client) GetPreset(ctx context.Context, name string) (*Preset, error) {
+	var out Preset
+	if _, err := c.call(ctx, http.MethodGet, "/api/v3/presets/"+url.PathEscape(name), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdatePreset replaces a preset
+func (c *Client) UpdatePreset(ctx context.Context, name string, preset Preset) (*Preset, error) {
+	var out Preset
+	if _, err := c.call(ctx, http.MethodPut, "/api/v3/presets/"+url.PathEscape(name), nil, preset, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeletePreset removes a preset
+func (c *Client) DeletePreset(ctx context.Context, name string) error {
+	_, err := c.call(ctx, http.MethodDelete, "/api/v3/presets/"+url.PathEscape(name), nil, nil, nil)
+	return err
+}
+
+// ListCredentials returns the credential names, values are never returned
+func (c *Client) ListCredentials(ctx context.Context) ([]Credential, error) {
+	var out []Credential
+	if _, err := c.call(ctx, http.MethodGet, "/api/v3/credentials", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AddCredential stores a secret under name, the server must have
+// credentials.key set
+func (c *Client) AddCredential(ctx context.Context, name, value string) (*Credential, error) {
+	var out Credential
+	if _, err := c.call(ctx, http.MethodPost, "/api/v3/credentials", nil, CredentialRequest{Name: name, Value: value}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateCredential replaces the value of a credential
+func (c *Client) UpdateCredential(ctx context.Context, name, value string) (*Credential, error) {
+	var out Credential
+	if _, err := c.call(ctx, http.MethodPut, "/api/v3/credentials/"+url.PathEscape(name), nil, CredentialRequest{Value: value}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteCredential removes a credential, it fails with a 409 while tasks
+// reference it
+func (c *Client) DeleteCredential(ctx context.Context, name string) error {
+	_, err := c.call(ctx, http.MethodDelete, "/api/v3/credentials/"+url.PathEscape(name), nil, nil, nil)
+	return err
+}
+
+// InjectFault injects a fault into a process, the server must have chaos
+// enabled
+func (c *Client) InjectFault(ctx context.Context, id string, req ChaosRequest) (*ChaosInjection, error) {
+	var out ChaosInjection
+	if _, err := c.call(ctx, http.MethodPost, processPath(id, "/chaos"), nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListFaults returns the active fault injections
+func (c *Client) ListFaults(ctx context.Context) ([]ChaosInjection, error) {
+	var out []ChaosInjection
+	if _, err := c.call(ctx, http.MethodGet, "/api/v3/chaos", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RemoveFault cancels a fault injection
+func (c *Client) RemoveFault(ctx context.Context, id string) error {
+	_, err := c.call(ctx, http.MethodDelete, "/api/v3/chaos/"+url.PathEscape(id), nil, nil, nil)
+	return err
+}
+
+// Login creates a session. Use its token as Config.Token of a new client.
+func (c *Client) Login(ctx context.Context, username, password string) (*LoginResponse, error) {
+	var out LoginResponse
+	if _, err := c.call(ctx, http.MethodPost, "/api/v3/login", nil, LoginRequest{Username: username, Password: password}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Logout ends the session of Config.Token
+func (c *Client) Logout(ctx context.Context) error {
+	_, err := c.call(ctx, http.MethodPost, "/api/v3/logout", nil, nil, nil)
+	return err
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2026 Kevin Zang (kevinzang). All rights reserved.
+// Use of this source code is governed by the MIT License.
+//
+// TranscodeManager - FFmpeg 转码任务管理工具
+
+package client
+
+import "github.com/ZSC714725/transcodemanager/internal/api"
+
+// The request and response types are those of the server, so that the
+// client can't drift from the API.
+type (
+	ProcessConfigIO      = api.ProcessConfigIO
+	ProcessSoftStop      = api.ProcessSoftStop
+	ProcessConfigLimits  = api.ProcessConfigLimits
+	ProcessConfigRequest = api.ProcessConfigRequest
+	Process              = api.Process
+	ProcessConfig        = api.ProcessConfig
+	UpdateResponse       = api.UpdateResponse
+	DryRunResponse       = api.DryRunResponse
+	TestResult           = api.TestResult
+	ProcessState         = api.ProcessState
+	ProcessSummary       = api.ProcessSummary
+	ProcessError         = api.ProcessError
+	ProcessCheck         = api.ProcessCheck
+	Progress             = api.Progress
+	ProgressSample       = api.ProgressSample
+	ProcessReport        = api.ProcessReport
+	CommandRequest       = api.CommandRequest
+	BatchCommandRequest  = api.BatchCommandRequest
+	CommandResult        = api.CommandResult
+	Reference            = api.Reference
+	Operation            = api.Operation
+	WatcherStatus        = api.WatcherStatus
+	ErrorResponse        = api.ErrorResponse
+	ValidateResponse     = api.ValidateResponse
+	ProbeResponse        = api.ProbeResponse
+	ProbeStream          = api.ProbeStream
+	ProbeFormat          = api.ProbeFormat
+	ProbeInput           = api.ProbeInput
+	ReportFile           = api.ReportFile
+	ProcessRun           = api.ProcessRun
+	ProcessTransition    = api.ProcessTransition
+	ProcessAuditEntry    = api.ProcessAuditEntry
+	ProcessConfigDiff    = api.ProcessConfigDiff
+	ProcessRunReport     = api.ProcessRunReport
+	Preset               = api.Preset
+	Credential           = api.Credential
+	CredentialRequest    = api.CredentialRequest
+	SkillsResponse       = api.SkillsResponse
+	SkillsCodec          = api.SkillsCodec
+	SkillsHWEncoder      = api.SkillsHWEncoder
+	SystemInfo           = api.SystemInfo
+	ChaosRequest         = api.ChaosRequest
+	ChaosInjection       = api.ChaosInjection
+	LoginRequest         = api.LoginRequest
+	LoginResponse        = api.LoginResponse
+)